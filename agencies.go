@@ -0,0 +1,69 @@
+package nextbus
+
+import (
+	"strings"
+)
+
+// Agencies adds helpers to a list of agencies, such as the one returned by
+// GetAgencyList:
+//
+//	agencies, err := nb.GetAgencyList()
+//	regions := nextbus.Agencies(agencies).GroupByRegion()
+type Agencies []Agency
+
+// AgencyRegion is a region and the agencies operating in it.
+type AgencyRegion struct {
+	Title    string
+	Agencies Agencies
+}
+
+// FilterByRegion returns the agencies whose RegionTitle is regionTitle,
+// ignoring case.
+func (a Agencies) FilterByRegion(regionTitle string) Agencies {
+	var result Agencies
+	for _, agency := range a {
+		if strings.EqualFold(agency.RegionTitle, regionTitle) {
+			result = append(result, agency)
+		}
+	}
+	return result
+}
+
+// GroupByRegion groups the agencies by RegionTitle. Regions are ordered by
+// their first appearance in the list.
+func (a Agencies) GroupByRegion() []AgencyRegion {
+	index := map[string]int{}
+	var result []AgencyRegion
+	for _, agency := range a {
+		i, seen := index[agency.RegionTitle]
+		if !seen {
+			i = len(result)
+			index[agency.RegionTitle] = i
+			result = append(result, AgencyRegion{Title: agency.RegionTitle})
+		}
+		result[i].Agencies = append(result[i].Agencies, agency)
+	}
+	return result
+}
+
+// FindByTitle returns the agencies whose Title contains substr, ignoring case.
+func (a Agencies) FindByTitle(substr string) Agencies {
+	substr = strings.ToLower(substr)
+	var result Agencies
+	for _, agency := range a {
+		if strings.Contains(strings.ToLower(agency.Title), substr) {
+			result = append(result, agency)
+		}
+	}
+	return result
+}
+
+// Find returns the agency with the given tag.
+func (a Agencies) Find(agencyTag string) (Agency, bool) {
+	for _, agency := range a {
+		if agency.Tag == agencyTag {
+			return agency, true
+		}
+	}
+	return Agency{}, false
+}
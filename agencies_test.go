@@ -0,0 +1,31 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestAgencies(t *testing.T) {
+	agencies := Agencies{
+		{Tag: "sf-muni", Title: "San Francisco Muni", RegionTitle: "California-Northern"},
+		{Tag: "ttc", Title: "Toronto Transit Commission", RegionTitle: "Ontario"},
+		{Tag: "actransit", Title: "AC Transit", RegionTitle: "California-Northern"},
+	}
+
+	equals(t, Agencies{agencies[0], agencies[2]}, agencies.FilterByRegion("california-northern"))
+	equals(t, Agencies{agencies[1], agencies[2]}, agencies.FindByTitle("TRANSIT"))
+	equals(t, []AgencyRegion{
+		{"California-Northern", Agencies{agencies[0], agencies[2]}},
+		{"Ontario", Agencies{agencies[1]}},
+	}, agencies.GroupByRegion())
+
+	found, exists := agencies.Find("ttc")
+	assert(t, exists, "expected to find ttc")
+	equals(t, "Ontario", found.RegionTitle)
+}
+
+func TestAgenciesFromClient(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	list, err := nb.GetAgencyList()
+	ok(t, err)
+	equals(t, 1, len(Agencies(list).FilterByRegion("Never never land")))
+}
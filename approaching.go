@@ -0,0 +1,126 @@
+package nextbus
+
+import (
+	"math"
+	"sort"
+)
+
+// maxVehicleSnapDistance is how far in meters a vehicle may be from the line
+// through a direction's stops and still be snapped to it. It is larger than
+// maxSnapDistance because that line cuts corners the real path doesn't.
+const maxVehicleSnapDistance = 150
+
+// ApproachingVehicle is a vehicle on its way to a stop.
+type ApproachingVehicle struct {
+	Vehicle VehicleLocation
+	// Distance is how far the vehicle still has to travel along the route to
+	// reach the stop, in meters.
+	Distance float64
+}
+
+// directionLine returns a line through the located stops of a direction whose
+// cumulative lengths are the stops' distances along the route.
+func (rc RouteConfig) directionLine(dirTag string) (planar, polyline, []StopPosition) {
+	seq := rc.StopSequence(dirTag)
+	var proj planar
+	var line polyline
+	for _, pos := range seq {
+		lat, lon, located := parseLatLon(pos.Stop.Lat, pos.Stop.Lon)
+		if !located {
+			continue
+		}
+		if line.pts == nil {
+			proj = newPlanar(lat, lon)
+		}
+		line.pts = append(line.pts, proj.toXY(lat, lon))
+		line.cum = append(line.cum, pos.Distance)
+	}
+	return proj, line, seq
+}
+
+// SnapVehicle locates a vehicle along the direction it reports traveling in,
+// returning its distance in meters from the first stop of that direction. It
+// returns false if the vehicle isn't on this route, its direction is unknown,
+// or it is too far from the route.
+func (rc RouteConfig) SnapVehicle(v VehicleLocation) (float64, bool) {
+	if v.RouteTag != rc.Tag {
+		return 0, false
+	}
+	proj, line, _ := rc.directionLine(v.DirTag)
+	return snapToLine(proj, line, v)
+}
+
+func snapToLine(proj planar, line polyline, v VehicleLocation) (float64, bool) {
+	lat, lon, located := parseLatLon(v.Lat, v.Lon)
+	if !located || len(line.pts) < 2 {
+		return 0, false
+	}
+	offset, dist := line.projectWeighted(proj.toXY(lat, lon))
+	return offset, dist <= maxVehicleSnapDistance
+}
+
+// projectWeighted is like project but interpolates the line's own cumulative
+// values, which need not be the planar segment lengths.
+func (l polyline) projectWeighted(q xy) (offset, dist float64) {
+	plain := polyline{pts: l.pts, cum: make([]float64, len(l.pts))}
+	for i := 1; i < len(l.pts); i++ {
+		a, b := l.pts[i-1], l.pts[i]
+		plain.cum[i] = plain.cum[i-1] + math.Hypot(b.x-a.x, b.y-a.y)
+	}
+	planarOffset, dist := plain.project(q)
+	for i := 1; i < len(l.pts); i++ {
+		if planarOffset > plain.cum[i] && i != len(l.pts)-1 {
+			continue
+		}
+		seg := plain.cum[i] - plain.cum[i-1]
+		t := 0.0
+		if seg > 0 {
+			t = (planarOffset - plain.cum[i-1]) / seg
+		}
+		return l.cum[i-1] + t*(l.cum[i]-l.cum[i-1]), dist
+	}
+	return l.cum[0], dist
+}
+
+// VehiclesApproaching returns the vehicles of the route that are upstream of
+// stopTag in a direction serving it, ordered by the distance they have left to
+// travel, closest first. This gives a location-based check on predictions.
+func (rc RouteConfig) VehiclesApproaching(stopTag string, vehicles []VehicleLocation) []ApproachingVehicle {
+	type dirInfo struct {
+		proj   planar
+		line   polyline
+		stopAt float64
+		serves bool
+	}
+	dirs := map[string]*dirInfo{}
+	var result []ApproachingVehicle
+	for _, v := range vehicles {
+		if v.RouteTag != rc.Tag {
+			continue
+		}
+		info, seen := dirs[v.DirTag]
+		if !seen {
+			info = &dirInfo{}
+			var seq []StopPosition
+			info.proj, info.line, seq = rc.directionLine(v.DirTag)
+			for _, pos := range seq {
+				if pos.Stop.Tag == stopTag {
+					info.stopAt = pos.Distance
+					info.serves = true
+					break
+				}
+			}
+			dirs[v.DirTag] = info
+		}
+		if !info.serves {
+			continue
+		}
+		at, snapped := snapToLine(info.proj, info.line, v)
+		if !snapped || at > info.stopAt {
+			continue
+		}
+		result = append(result, ApproachingVehicle{v, info.stopAt - at})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Distance < result[j].Distance })
+	return result
+}
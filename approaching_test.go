@@ -0,0 +1,52 @@
+package nextbus
+
+import (
+	"math"
+	"testing"
+)
+
+func approachRoute() RouteConfig {
+	return RouteConfig{
+		Tag: "1",
+		StopList: []Stop{
+			{Tag: "a", Lat: "37.7000", Lon: "-122.4000"},
+			{Tag: "b", Lat: "37.7100", Lon: "-122.4000"},
+			{Tag: "c", Lat: "37.7200", Lon: "-122.4000"},
+		},
+		DirList: []Direction{
+			{Tag: "north", StopMarkerList: stopMarkers("a", "b", "c")},
+			{Tag: "south", StopMarkerList: stopMarkers("c", "b", "a")},
+		},
+	}
+}
+
+func TestSnapVehicle(t *testing.T) {
+	rc := approachRoute()
+	at, snapped := rc.SnapVehicle(VehicleLocation{RouteTag: "1", DirTag: "north", Lat: "37.7050", Lon: "-122.4001"})
+	assert(t, snapped, "expected the vehicle to snap to the route")
+	assert(t, math.Abs(at-556) < 2, "unexpected position %v", at)
+
+	_, snapped = rc.SnapVehicle(VehicleLocation{RouteTag: "1", DirTag: "north", Lat: "37.7050", Lon: "-122.3000"})
+	assert(t, !snapped, "did not expect a far away vehicle to snap")
+	_, snapped = rc.SnapVehicle(VehicleLocation{RouteTag: "2", DirTag: "north", Lat: "37.7050", Lon: "-122.4000"})
+	assert(t, !snapped, "did not expect a vehicle on another route to snap")
+}
+
+func TestVehiclesApproaching(t *testing.T) {
+	rc := approachRoute()
+	vehicles := []VehicleLocation{
+		{ID: "far", RouteTag: "1", DirTag: "north", Lat: "37.7010", Lon: "-122.4000"},
+		{ID: "near", RouteTag: "1", DirTag: "north", Lat: "37.7080", Lon: "-122.4000"},
+		{ID: "passed", RouteTag: "1", DirTag: "north", Lat: "37.7150", Lon: "-122.4000"},
+		{ID: "southbound", RouteTag: "1", DirTag: "south", Lat: "37.7150", Lon: "-122.4000"},
+		{ID: "unknown", RouteTag: "1", DirTag: "east", Lat: "37.7080", Lon: "-122.4000"},
+	}
+
+	found := rc.VehiclesApproaching("b", vehicles)
+	var ids []string
+	for _, av := range found {
+		ids = append(ids, av.Vehicle.ID)
+	}
+	equals(t, []string{"near", "southbound", "far"}, ids)
+	assert(t, math.Abs(found[0].Distance-222) < 2, "unexpected distance %v", found[0].Distance)
+}
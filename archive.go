@@ -0,0 +1,171 @@
+package nextbus
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ArrivalRecord is an observed arrival of a vehicle at a stop.
+type ArrivalRecord struct {
+	RouteTag string
+	StopTag  string
+	DirTag   string
+	Vehicle  string
+	TripTag  string
+	Time     time.Time
+	// Predicted is the arrival time the vehicle was last predicted at. Unlike
+	// Time, which an Archiver limits to the time of the poll that revealed
+	// the arrival, it comes from the feed alone, so stores can recognize an
+	// arrival recorded again from a replay of the same predictions.
+	Predicted time.Time
+}
+
+// ArrivalStore keeps the arrivals recorded by an Archiver.
+type ArrivalStore interface {
+	// Save adds records to the store.
+	Save(records []ArrivalRecord) error
+	// Arrivals returns the records with a Time in [from, to), in order.
+	Arrivals(from, to time.Time) ([]ArrivalRecord, error)
+}
+
+// MemoryArrivalStore is an ArrivalStore that keeps its records in memory. The
+// zero value is ready to use.
+type MemoryArrivalStore struct {
+	mu      sync.RWMutex
+	records []ArrivalRecord
+	saved   map[string]bool
+}
+
+// Save adds records to the store. Like the SQL stores, it skips records
+// already saved for the same route, stop, vehicle, trip and predicted time.
+func (s *MemoryArrivalStore) Save(records []ArrivalRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saved == nil {
+		s.saved = map[string]bool{}
+	}
+	for _, r := range records {
+		predicted := r.Predicted
+		if predicted.IsZero() {
+			predicted = r.Time
+		}
+		key := r.RouteTag + "|" + r.StopTag + "|" + r.Vehicle + "|" + r.TripTag + "|" + strconv.FormatInt(predicted.UnixNano(), 10)
+		if s.saved[key] {
+			continue
+		}
+		s.saved[key] = true
+		s.records = append(s.records, r)
+	}
+	return nil
+}
+
+// Arrivals returns the records with a Time in [from, to), in order.
+func (s *MemoryArrivalStore) Arrivals(from, to time.Time) ([]ArrivalRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var result []ArrivalRecord
+	for _, r := range s.records {
+		if !r.Time.Before(from) && r.Time.Before(to) {
+			result = append(result, r)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Time.Before(result[j].Time) })
+	return result, nil
+}
+
+// DefaultArrivedWithin is the Archiver's ArrivedWithin when it is zero.
+const DefaultArrivedWithin = 2 * time.Minute
+
+// Archiver records when vehicles actually arrive at stops by watching
+// successive predictions: a vehicle that was about to arrive at a stop and is
+// no longer predicted there has arrived. Feed it every poll of a
+// PredictionWatcher from OnUpdate.
+type Archiver struct {
+	Store ArrivalStore
+	// ArrivedWithin is how close to a stop a vehicle must have been predicted
+	// for its disappearance to count as an arrival rather than, say, a
+	// vehicle taken out of service.
+	ArrivedWithin time.Duration
+	// OnError, if set, is called with the errors of the predictions fed to
+	// the Archiver by its Service.
+	OnError func(error)
+
+	mu      sync.Mutex
+	pending map[string]ArrivalRecord
+	dropped int
+}
+
+// NewArchiver creates an Archiver that saves to store.
+func NewArchiver(store ArrivalStore) *Archiver {
+	return &Archiver{Store: store}
+}
+
+// ObservePredictions compares predictions fetched at t with the previous ones
+// and saves the arrivals they reveal. Only stops present in predictions are
+// compared, so polls of different stops can be mixed.
+func (a *Archiver) ObservePredictions(t time.Time, predictions []PredictionData) error {
+	within := a.ArrivedWithin
+	if within <= 0 {
+		within = DefaultArrivedWithin
+	}
+
+	current := map[string]ArrivalRecord{}
+	polled := map[RouteStop]bool{}
+	for _, pd := range predictions {
+		polled[RouteStop{pd.RouteTag, pd.StopTag}] = true
+		for _, dir := range pd.PredictionDirectionList {
+			for _, p := range dir.PredictionList {
+				if p.Vehicle == "" {
+					continue
+				}
+				arrival := p.ArrivalTime()
+				if arrival.IsZero() {
+					continue
+				}
+				// A vehicle can be predicted for its current trip and the
+				// next one; keep each, and the earliest of any without trips.
+				key := pd.RouteTag + "|" + pd.StopTag + "|" + p.Vehicle + "|" + p.TripTag
+				if r, found := current[key]; found && !arrival.Before(r.Time) {
+					continue
+				}
+				current[key] = ArrivalRecord{
+					RouteTag:  pd.RouteTag,
+					StopTag:   pd.StopTag,
+					DirTag:    p.DirTag,
+					Vehicle:   p.Vehicle,
+					TripTag:   p.TripTag,
+					Time:      arrival,
+					Predicted: arrival,
+				}
+			}
+		}
+	}
+
+	a.mu.Lock()
+	var arrived []ArrivalRecord
+	for key, r := range a.pending {
+		if _, still := current[key]; still {
+			continue
+		}
+		if !polled[RouteStop{r.RouteTag, r.StopTag}] {
+			current[key] = r
+			continue
+		}
+		if r.Time.Sub(t) <= within {
+			if r.Time.After(t) {
+				r.Time = t
+			}
+			arrived = append(arrived, r)
+		}
+	}
+	a.pending = current
+	a.mu.Unlock()
+
+	if len(arrived) == 0 {
+		return nil
+	}
+	sort.Slice(arrived, func(i, j int) bool { return arrived[i].Time.Before(arrived[j].Time) })
+	return a.Store.Save(arrived)
+}
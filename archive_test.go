@@ -0,0 +1,83 @@
+package nextbus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArchiver(t *testing.T) {
+	store := &MemoryArrivalStore{}
+	a := NewArchiver(store)
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+
+	ok(t, a.ObservePredictions(now, arrivingAt("1500", now.Add(time.Minute))))
+	// Another stop's poll leaves the vehicle's prediction at 5205 pending.
+	other := arrivingAt("1600", now.Add(10*time.Minute))
+	other[0].StopTag = "5206"
+	ok(t, a.ObservePredictions(now.Add(30*time.Second), other))
+
+	// Vehicle 1500 is no longer predicted at 5205: it has arrived.
+	ok(t, a.ObservePredictions(now.Add(90*time.Second), append(arrivingAt("1700", now.Add(20*time.Minute)), other...)))
+	// Vehicle 1600 disappears while still far away and is not counted.
+	ok(t, a.ObservePredictions(now.Add(2*time.Minute), arrivingAt("1700", now.Add(19*time.Minute))))
+	ok(t, a.ObservePredictions(now.Add(2*time.Minute), []PredictionData{{RouteTag: "N", StopTag: "5206"}}))
+
+	records, err := store.Arrivals(now, now.Add(time.Hour))
+	ok(t, err)
+	equals(t, 1, len(records))
+	equals(t, "1500", records[0].Vehicle)
+	equals(t, "5205", records[0].StopTag)
+	assert(t, records[0].Time.Equal(now.Add(time.Minute)), "unexpected arrival time %v", records[0].Time)
+
+	none, err := store.Arrivals(now.Add(time.Hour), now.Add(2*time.Hour))
+	ok(t, err)
+	equals(t, 0, len(none))
+}
+
+func TestArchiverTwoTrips(t *testing.T) {
+	store := &MemoryArrivalStore{}
+	a := NewArchiver(store)
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+
+	predictions := arrivingAt("1500", now.Add(time.Minute))
+	next := arrivingAt("1500", now.Add(40*time.Minute))
+	predictions[0].PredictionDirectionList[0].PredictionList[0].TripTag = "1"
+	next[0].PredictionDirectionList[0].PredictionList[0].TripTag = "2"
+	predictions[0].PredictionDirectionList[0].PredictionList = append(predictions[0].PredictionDirectionList[0].PredictionList, next[0].PredictionDirectionList[0].PredictionList...)
+	ok(t, a.ObservePredictions(now, predictions))
+	// Only the next trip is left: the current one has arrived.
+	ok(t, a.ObservePredictions(now.Add(90*time.Second), next))
+
+	records, err := store.Arrivals(now, now.Add(time.Hour))
+	ok(t, err)
+	equals(t, 1, len(records))
+	equals(t, "1", records[0].TripTag)
+}
+
+func TestMemoryArrivalStoreSkipsReplays(t *testing.T) {
+	var store MemoryArrivalStore
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	record := ArrivalRecord{RouteTag: "N", StopTag: "5205", Vehicle: "1500", TripTag: "1", Time: now, Predicted: now.Add(time.Minute)}
+	ok(t, store.Save([]ArrivalRecord{record}))
+	ok(t, store.Save([]ArrivalRecord{record}))
+	records, err := store.Arrivals(now, now.Add(time.Hour))
+	ok(t, err)
+	equals(t, 1, len(records))
+}
+
+func TestArchiverKeepsPredictedTime(t *testing.T) {
+	store := &MemoryArrivalStore{}
+	a := NewArchiver(store)
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+
+	ok(t, a.ObservePredictions(now, arrivingAt("1500", now.Add(time.Minute))))
+	// The vehicle arrives ahead of its prediction, so its time is that of
+	// the poll that revealed the arrival.
+	ok(t, a.ObservePredictions(now.Add(30*time.Second), []PredictionData{{RouteTag: "N", StopTag: "5205"}}))
+
+	records, err := store.Arrivals(now, now.Add(time.Hour))
+	ok(t, err)
+	equals(t, 1, len(records))
+	assert(t, records[0].Time.Equal(now.Add(30*time.Second)), "unexpected arrival time %v", records[0].Time)
+	assert(t, records[0].Predicted.Equal(now.Add(time.Minute)), "unexpected predicted time %v", records[0].Predicted)
+}
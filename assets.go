@@ -0,0 +1,301 @@
+package nextbus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAssetMaxAge is how long an AssetServer lets browsers and CDNs cache
+// an asset when its MaxAge is zero.
+const DefaultAssetMaxAge = time.Hour
+
+// DefaultSimplifyTolerance is the Tolerance, in meters, of an AssetServer
+// whose Tolerance is zero.
+const DefaultSimplifyTolerance = 5.0
+
+// SimplifyPath returns path with the points removed that lie within tolerance
+// meters of the line through those kept, using the Douglas-Peucker algorithm.
+// Points that can't be parsed are dropped.
+func SimplifyPath(path Path, tolerance float64) Path {
+	var points []Point
+	var located []LatLon
+	for _, pt := range path.PointList {
+		if p, ok := pt.LatLon(); ok {
+			points = append(points, pt)
+			located = append(located, p)
+		}
+	}
+	if len(points) < 3 {
+		return Path{PointList: points}
+	}
+	proj := newPlanar(located[0].Lat, located[0].Lon)
+	projected := make([]xy, len(located))
+	for i, p := range located {
+		projected[i] = proj.toXY(p.Lat, p.Lon)
+	}
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	simplify(projected, 0, len(points)-1, tolerance, keep)
+
+	result := Path{}
+	for i, pt := range points {
+		if keep[i] {
+			result.PointList = append(result.PointList, pt)
+		}
+	}
+	return result
+}
+
+// simplify marks the points between first and last that must be kept for the
+// line to stay within tolerance of pts.
+func simplify(pts []xy, first, last int, tolerance float64, keep []bool) {
+	farthest, maxDist := -1, tolerance
+	for i := first + 1; i < last; i++ {
+		if d := segmentDistance(pts[i], pts[first], pts[last]); d > maxDist {
+			farthest, maxDist = i, d
+		}
+	}
+	if farthest < 0 {
+		return
+	}
+	keep[farthest] = true
+	simplify(pts, first, farthest, tolerance, keep)
+	simplify(pts, farthest, last, tolerance, keep)
+}
+
+// segmentDistance returns the distance from q to the segment from a to b.
+func segmentDistance(q, a, b xy) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	t := 0.0
+	if seg := dx*dx + dy*dy; seg > 0 {
+		t = math.Max(0, math.Min(1, ((q.x-a.x)*dx+(q.y-a.y)*dy)/seg))
+	}
+	return math.Hypot(q.x-(a.x+t*dx), q.y-(a.y+t*dy))
+}
+
+// EncodePolyline encodes points in the Encoded Polyline Algorithm Format used
+// by Google Maps, Mapbox and Leaflet plugins, with a precision of five
+// decimal places. Points that can't be parsed are skipped.
+func EncodePolyline(points []Point) string {
+	var b strings.Builder
+	var lastLat, lastLon int64
+	for _, pt := range points {
+		p, ok := pt.LatLon()
+		if !ok {
+			continue
+		}
+		lat, lon := int64(math.Round(p.Lat*1e5)), int64(math.Round(p.Lon*1e5))
+		encodePolylineValue(&b, lat-lastLat)
+		encodePolylineValue(&b, lon-lastLon)
+		lastLat, lastLon = lat, lon
+	}
+	return b.String()
+}
+
+func encodePolylineValue(b *strings.Builder, v int64) {
+	u := uint64(v) << 1
+	if v < 0 {
+		u = ^u
+	}
+	for u >= 0x20 {
+		b.WriteByte(byte(0x20|u&0x1f) + 63)
+		u >>= 5
+	}
+	b.WriteByte(byte(u) + 63)
+}
+
+// The assets rendered by an AssetServer.
+type (
+	routeAsset struct {
+		Tag           string `json:"tag"`
+		Title         string `json:"title"`
+		Color         string `json:"color,omitempty"`
+		OppositeColor string `json:"oppositeColor,omitempty"`
+	}
+	polylineAsset struct {
+		Route string   `json:"route"`
+		Color string   `json:"color,omitempty"`
+		Paths []string `json:"paths"`
+	}
+	geoJSONCollection struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+	}
+	geoJSONFeature struct {
+		Type       string                 `json:"type"`
+		Geometry   geoJSONGeometry        `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	}
+	geoJSONGeometry struct {
+		Type        string      `json:"type"`
+		Coordinates interface{} `json:"coordinates"`
+	}
+)
+
+// asset is a rendered response of an AssetServer.
+type asset struct {
+	contentType string
+	etag        string
+	data        []byte
+}
+
+func newAsset(contentType string, v interface{}) (asset, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return asset{}, err
+	}
+	sum := sha256.Sum256(data)
+	return asset{contentType, `"` + hex.EncodeToString(sum[:16]) + `"`, data}, nil
+}
+
+// AssetServer serves static assets rendered from an AgencySnapshot, so that
+// web maps load route geometry from an origin a CDN can cache instead of from
+// the feed. It serves:
+//
+//	/routes.json                 the routes, with their titles and colors
+//	/routes/{tag}.geojson        a route's merged paths and stops as GeoJSON
+//	/routes/{tag}.polyline.json  a route's simplified paths as encoded polylines
+//	/routes/{tag}.stops.json     a route's stops, as StopRecords, in order
+//
+// Every asset is rendered by Update and served with an ETag and a
+// Cache-Control max-age, so unchanged assets are revalidated without being
+// sent again. Mount it under a prefix with http.StripPrefix, and keep it
+// current by calling Update from a Directory's OnRefresh. The zero value
+// serves nothing until Update is called.
+type AssetServer struct {
+	// MaxAge is how long clients may cache an asset without revalidating it.
+	MaxAge time.Duration
+	// Tolerance is how far, in meters, simplified polylines may stray from
+	// the route's paths.
+	Tolerance float64
+
+	mu      sync.RWMutex
+	assets  map[string]asset
+	modTime time.Time
+}
+
+// NewAssetServer creates an AssetServer serving the assets of snapshot.
+func NewAssetServer(snapshot *AgencySnapshot) (*AssetServer, error) {
+	s := &AssetServer{}
+	if err := s.Update(snapshot); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Update renders the assets of snapshot and replaces those served.
+func (s *AssetServer) Update(snapshot *AgencySnapshot) error {
+	tolerance := s.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultSimplifyTolerance
+	}
+	records := map[string]StopRecord{}
+	for _, r := range snapshot.StopRecords() {
+		records[r.StopTag] = r
+	}
+
+	assets := map[string]asset{}
+	add := func(name, contentType string, v interface{}) error {
+		a, err := newAsset(contentType, v)
+		if err != nil {
+			return fmt.Errorf("could not render %s: %v", name, err)
+		}
+		assets[name] = a
+		return nil
+	}
+	routes := make([]routeAsset, len(snapshot.Routes))
+	for i, rc := range snapshot.Routes {
+		routes[i] = routeAsset{rc.Tag, rc.Title, rc.Color, rc.OppositeColor}
+
+		stops := []StopRecord{}
+		for _, stop := range rc.StopList {
+			if r, located := records[stop.Tag]; located {
+				stops = append(stops, r)
+			}
+		}
+		merged := MergePaths(rc.PathList)
+		polylines := polylineAsset{Route: rc.Tag, Color: rc.Color, Paths: []string{}}
+		for _, path := range merged {
+			polylines.Paths = append(polylines.Paths, EncodePolyline(SimplifyPath(path, tolerance).PointList))
+		}
+		prefix := "routes/" + rc.Tag
+		if err := add(prefix+".geojson", "application/geo+json", routeGeoJSON(rc, merged, stops)); err != nil {
+			return err
+		}
+		if err := add(prefix+".polyline.json", "application/json", polylines); err != nil {
+			return err
+		}
+		if err := add(prefix+".stops.json", "application/json", stops); err != nil {
+			return err
+		}
+	}
+	if err := add("routes.json", "application/json", routes); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.assets, s.modTime = assets, snapshot.FetchedAt
+	s.mu.Unlock()
+	return nil
+}
+
+// routeGeoJSON returns a FeatureCollection of a route's paths, as
+// LineStrings, and its stops, as Points.
+func routeGeoJSON(rc RouteConfig, paths []Path, stops []StopRecord) geoJSONCollection {
+	fc := geoJSONCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+	for _, path := range paths {
+		var line [][]float64
+		for _, pt := range path.PointList {
+			if p, located := pt.LatLon(); located {
+				line = append(line, []float64{p.Lon, p.Lat})
+			}
+		}
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "LineString", Coordinates: line},
+			Properties: map[string]interface{}{"route": rc.Tag, "color": rc.Color},
+		})
+	}
+	for _, stop := range stops {
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONGeometry{Type: "Point", Coordinates: []float64{stop.Lon, stop.Lat}},
+			Properties: map[string]interface{}{"tag": stop.StopTag, "stopId": stop.StopID, "title": stop.Title},
+		})
+	}
+	return fc
+}
+
+// ServeHTTP serves the asset named by the request path, answering requests
+// whose If-None-Match matches its ETag with 304 Not Modified.
+func (s *AssetServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.RLock()
+	a, found := s.assets[strings.TrimPrefix(r.URL.Path, "/")]
+	modTime := s.modTime
+	s.mu.RUnlock()
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	maxAge := s.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultAssetMaxAge
+	}
+	w.Header().Set("Content-Type", a.contentType)
+	w.Header().Set("ETag", a.etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge/time.Second)))
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(a.data))
+}
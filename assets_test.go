@@ -0,0 +1,85 @@
+package nextbus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEncodePolyline(t *testing.T) {
+	// The example from the format's documentation.
+	points := []Point{{Lat: "38.5", Lon: "-120.2"}, {Lat: "40.7", Lon: "-120.95"}, {Lat: "43.252", Lon: "-126.453"}}
+	equals(t, "_p~iF~ps|U_ulLnnqC_mqNvxq`@", EncodePolyline(points))
+}
+
+func TestSimplifyPath(t *testing.T) {
+	path := Path{PointList: []Point{
+		{Lat: "37.7700", Lon: "-122.4300"},
+		{Lat: "37.77001", Lon: "-122.4295"}, // about a meter off the line
+		{Lat: "37.7700", Lon: "-122.4290"},
+		{Lat: "37.7710", Lon: "-122.4290"},
+		{Lat: "bad", Lon: "-122.4290"},
+	}}
+	simplified := SimplifyPath(path, 5)
+	equals(t, []Point{path.PointList[0], path.PointList[2], path.PointList[3]}, simplified.PointList)
+	equals(t, 4, len(SimplifyPath(path, 0.1).PointList))
+}
+
+func TestAssetServer(t *testing.T) {
+	snapshot := exportSnapshot()
+	snapshot.FetchedAt = time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	snapshot.Routes[0].Color = "005b95"
+	snapshot.Routes[0].PathList = []Path{{PointList: []Point{{Lat: "37.7693", Lon: "-122.4293"}, {Lat: "37.7752", Lon: "-122.4192"}}}}
+	s, err := NewAssetServer(snapshot)
+	ok(t, err)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/routes/N.geojson", nil))
+	equals(t, http.StatusOK, rec.Code)
+	equals(t, "application/geo+json", rec.Header().Get("Content-Type"))
+	equals(t, "public, max-age=3600", rec.Header().Get("Cache-Control"))
+	var fc struct {
+		Features []struct {
+			Geometry struct{ Type string }
+		}
+	}
+	ok(t, json.Unmarshal(rec.Body.Bytes(), &fc))
+	equals(t, 3, len(fc.Features))
+	equals(t, "LineString", fc.Features[0].Geometry.Type)
+	equals(t, "Point", fc.Features[1].Geometry.Type)
+
+	etag := rec.Header().Get("ETag")
+	req := httptest.NewRequest(http.MethodGet, "/routes/N.geojson", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	equals(t, http.StatusNotModified, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/routes/J.stops.json", nil))
+	var stops []StopRecord
+	ok(t, json.Unmarshal(rec.Body.Bytes(), &stops))
+	equals(t, 1, len(stops))
+	equals(t, "5205", stops[0].StopTag)
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/routes/N.polyline.json", nil))
+	var polylines struct{ Paths []string }
+	ok(t, json.Unmarshal(rec.Body.Bytes(), &polylines))
+	equals(t, []string{EncodePolyline(snapshot.Routes[0].PathList[0].PointList)}, polylines.Paths)
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/routes/F.geojson", nil))
+	equals(t, http.StatusNotFound, rec.Code)
+
+	// A new snapshot changes the ETag of the assets that changed.
+	snapshot.Routes[0].Color = "ff0000"
+	ok(t, s.Update(snapshot))
+	req = httptest.NewRequest(http.MethodGet, "/routes/N.geojson", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	equals(t, http.StatusOK, rec.Code)
+}
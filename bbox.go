@@ -0,0 +1,98 @@
+package nextbus
+
+import (
+	"math"
+
+	"github.com/dinedal/nextbus/geo"
+)
+
+// LatLon is a geographical location in degrees. It is the same type as
+// geo.LatLon, so the functions of the geo package apply to it directly.
+type LatLon = geo.LatLon
+
+// LatLon parses the stop's location.
+func (s Stop) LatLon() (LatLon, bool) {
+	lat, lon, ok := parseLatLon(s.Lat, s.Lon)
+	return LatLon{Lat: lat, Lon: lon}, ok
+}
+
+// LatLon parses the point's location.
+func (p Point) LatLon() (LatLon, bool) {
+	lat, lon, ok := parseLatLon(p.Lat, p.Lon)
+	return LatLon{Lat: lat, Lon: lon}, ok
+}
+
+// LatLon parses the vehicle's location.
+func (v VehicleLocation) LatLon() (LatLon, bool) {
+	lat, lon, ok := parseLatLon(v.Lat, v.Lon)
+	return LatLon{Lat: lat, Lon: lon}, ok
+}
+
+// BoundingBox is a rectangular area between two latitudes and two
+// longitudes. Boxes crossing the antimeridian aren't supported.
+type BoundingBox struct {
+	MinLat float64
+	MaxLat float64
+	MinLon float64
+	MaxLon float64
+}
+
+// Bounds parses the route's latMin, latMax, lonMin and lonMax attributes.
+func (rc RouteConfig) Bounds() (BoundingBox, bool) {
+	minLat, minLon, okMin := parseLatLon(rc.LatMin, rc.LonMin)
+	maxLat, maxLon, okMax := parseLatLon(rc.LatMax, rc.LonMax)
+	if !okMin || !okMax {
+		return BoundingBox{}, false
+	}
+	return BoundingBox{
+		MinLat: math.Min(minLat, maxLat),
+		MaxLat: math.Max(minLat, maxLat),
+		MinLon: math.Min(minLon, maxLon),
+		MaxLon: math.Max(minLon, maxLon),
+	}, true
+}
+
+// Contains reports whether p lies within or on the edge of the box.
+func (b BoundingBox) Contains(p LatLon) bool {
+	return p.Lat >= b.MinLat && p.Lat <= b.MaxLat && p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+}
+
+// Intersects reports whether the two boxes overlap or touch.
+func (b BoundingBox) Intersects(o BoundingBox) bool {
+	return b.MinLat <= o.MaxLat && o.MinLat <= b.MaxLat && b.MinLon <= o.MaxLon && o.MinLon <= b.MaxLon
+}
+
+// Center returns the midpoint of the box.
+func (b BoundingBox) Center() LatLon {
+	return LatLon{Lat: (b.MinLat + b.MaxLat) / 2, Lon: (b.MinLon + b.MaxLon) / 2}
+}
+
+// Union returns the smallest box containing both boxes.
+func (b BoundingBox) Union(o BoundingBox) BoundingBox {
+	return BoundingBox{
+		MinLat: math.Min(b.MinLat, o.MinLat),
+		MaxLat: math.Max(b.MaxLat, o.MaxLat),
+		MinLon: math.Min(b.MinLon, o.MinLon),
+		MaxLon: math.Max(b.MaxLon, o.MaxLon),
+	}
+}
+
+// Bounds returns the smallest box containing every route of the snapshot
+// whose bounds are known.
+func (s *AgencySnapshot) Bounds() (BoundingBox, bool) {
+	var result BoundingBox
+	found := false
+	for _, rc := range s.Routes {
+		b, ok := rc.Bounds()
+		if !ok {
+			continue
+		}
+		if found {
+			result = result.Union(b)
+		} else {
+			result = b
+			found = true
+		}
+	}
+	return result, found
+}
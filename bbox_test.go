@@ -0,0 +1,42 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestRouteConfigBounds(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	configs, err := nb.GetRouteConfig("alpha")
+	ok(t, err)
+
+	b, found := configs[0].Bounds()
+	assert(t, found, "expected the route to have bounds")
+	equals(t, BoundingBox{12.3456789, 45.6789012, -456.78901, -123.4567890}, b)
+
+	_, found = RouteConfig{}.Bounds()
+	assert(t, !found, "did not expect bounds for an empty route")
+}
+
+func TestBoundingBox(t *testing.T) {
+	a := BoundingBox{MinLat: 37.70, MaxLat: 37.80, MinLon: -122.50, MaxLon: -122.40}
+	b := BoundingBox{MinLat: 37.75, MaxLat: 37.85, MinLon: -122.45, MaxLon: -122.35}
+	c := BoundingBox{MinLat: 38.00, MaxLat: 38.10, MinLon: -122.50, MaxLon: -122.40}
+
+	assert(t, a.Contains(LatLon{Lat: 37.75, Lon: -122.45}), "expected the point to be inside")
+	assert(t, !a.Contains(LatLon{Lat: 37.75, Lon: -122.30}), "expected the point to be outside")
+	assert(t, a.Intersects(b) && b.Intersects(a), "expected a and b to intersect")
+	assert(t, !a.Intersects(c), "did not expect a and c to intersect")
+	equals(t, LatLon{Lat: 37.75, Lon: -122.45}, a.Center())
+	equals(t, BoundingBox{37.70, 37.85, -122.50, -122.35}, a.Union(b))
+}
+
+func TestSnapshotBounds(t *testing.T) {
+	snapshot := AgencySnapshot{Routes: []RouteConfig{
+		{LatMin: "37.70", LatMax: "37.80", LonMin: "-122.50", LonMax: "-122.40"},
+		{},
+		{LatMin: "37.75", LatMax: "37.85", LonMin: "-122.45", LonMax: "-122.35"},
+	}}
+	b, found := snapshot.Bounds()
+	assert(t, found, "expected the snapshot to have bounds")
+	equals(t, BoundingBox{37.70, 37.85, -122.50, -122.35}, b)
+}
@@ -0,0 +1,96 @@
+package nextbus
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// The fixtures below are generated to match the size of SF Muni's feed: about
+// 80 routes averaging 60 stops and 20 path segments each, and around 1000
+// vehicles reporting at rush hour.
+const (
+	benchRoutes        = 80
+	benchStopsPerRoute = 60
+	benchPathsPerRoute = 20
+	benchVehicles      = 1000
+)
+
+// largeLocationResponse builds a vehicleLocations response with the given
+// number of vehicles.
+func largeLocationResponse(vehicles int) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8" ?>` + "\n")
+	b.WriteString(`<body copyright="All data copyright some transit company.">` + "\n")
+	for i := 0; i < vehicles; i++ {
+		fmt.Fprintf(&b, `<vehicle id="%d" routeTag="%d" dirTag="%d____O_F00" lat="37.7%04d" lon="-122.4%04d" secsSinceReport="%d" predictable="true" heading="%d" speedKmHr="%d" leadingVehicleId=""/>`+"\n",
+			1000+i, i%80, i%80, i, i, i%60, i%360, i%50)
+	}
+	b.WriteString(`<lastTime time="1490564618948"/>` + "\n</body>\n")
+	return []byte(b.String())
+}
+
+// largeRouteConfig builds a routeConfig response for every route of an
+// agency.
+func largeRouteConfig(routes, stops, paths int) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8" ?>` + "\n")
+	b.WriteString(`<body copyright="All data copyright some transit company.">` + "\n")
+	for r := 0; r < routes; r++ {
+		fmt.Fprintf(&b, `<route tag="%d" title="%d-Route" color="660000" oppositeColor="ffffff" latMin="37.70" latMax="37.80" lonMin="-122.50" lonMax="-122.38">`+"\n", r, r)
+		for s := 0; s < stops; s++ {
+			fmt.Fprintf(&b, `<stop tag="%d%03d" title="Stop %d on %d" lat="37.7%04d" lon="-122.4%04d" stopId="1%d%03d"/>`+"\n", r, s, s, r, s*13, s*17, r, s)
+		}
+		for _, dir := range []string{"O", "I"} {
+			fmt.Fprintf(&b, `<direction tag="%d____%s_F00" title="%s to somewhere" name="%s" useForUI="true">`+"\n", r, dir, dir, dir)
+			for s := 0; s < stops; s++ {
+				fmt.Fprintf(&b, `<stop tag="%d%03d"/>`+"\n", r, s)
+			}
+			b.WriteString("</direction>\n")
+		}
+		for p := 0; p < paths; p++ {
+			b.WriteString("<path>\n")
+			for pt := 0; pt < 10; pt++ {
+				fmt.Fprintf(&b, `<point lat="37.7%04d" lon="-122.4%04d"/>`+"\n", p*10+pt, p*7+pt)
+			}
+			b.WriteString("</path>\n")
+		}
+		b.WriteString("</route>\n")
+	}
+	b.WriteString("</body>\n")
+	return []byte(b.String())
+}
+
+// fixedResponseClient returns a Client whose every request is answered with
+// data.
+func fixedResponseClient(data []byte) *Client {
+	return NewClient(cannedFeed(string(data)).Client())
+}
+
+func BenchmarkGetRouteConfig(b *testing.B) {
+	data := largeRouteConfig(benchRoutes, benchStopsPerRoute, benchPathsPerRoute)
+	nb := fixedResponseClient(data)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		configs, err := nb.GetRouteConfig("alpha")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(configs) != benchRoutes {
+			b.Fatalf("expected %d routes, got %d", benchRoutes, len(configs))
+		}
+	}
+}
+
+func BenchmarkGetVehicleLocations(b *testing.B) {
+	data := largeLocationResponse(benchVehicles)
+	nb := fixedResponseClient(data)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if _, err := nb.GetVehicleLocations("alpha"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
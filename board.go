@@ -0,0 +1,185 @@
+package nextbus
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"time"
+)
+
+// BoardTheme sets the colors and font of a departure board.
+type BoardTheme struct {
+	Background string
+	Foreground string
+	Accent     string
+	Font       string
+}
+
+// DefaultBoardTheme is a high contrast theme suited to wall mounted screens.
+var DefaultBoardTheme = BoardTheme{
+	Background: "#111",
+	Foreground: "#f5f5f5",
+	Accent:     "#f5a623",
+	Font:       "Helvetica, Arial, sans-serif",
+}
+
+// BoardConfig configures a departure board.
+type BoardConfig struct {
+	Title string
+	// Refresh is how often the page reloads itself. Zero disables reloading.
+	Refresh time.Duration
+	// Routes and Stops restrict the board to the given route and stop tags.
+	// Empty lists show everything the watcher fetches.
+	Routes []string
+	Stops  []string
+	// Limit is the greatest number of departures shown per row. Zero shows
+	// them all.
+	Limit int
+	Theme BoardTheme
+	// MinConfidence hides predictions less reliable than it. The default shows
+	// every prediction; those not based on the vehicle's position are marked
+	// with an asterisk.
+	MinConfidence Confidence
+	// Formatter renders countdowns, direction titles and stop titles. The
+	// zero value uses English and shows titles as the agency publishes them.
+	Formatter Formatter
+	// Template replaces the built-in page. It is executed with a BoardPage.
+	Template *template.Template
+}
+
+// BoardRow is one route, stop and direction on a departure board.
+type BoardRow struct {
+	RouteTag   string
+	RouteTitle string
+	StopTitle  string
+	Direction  string
+	Minutes    []string
+	// Countdowns are the departures formatted for display, such as "3 min".
+	Countdowns []string
+	// Approximate holds, for each countdown, whether the prediction is less
+	// reliable than one based on the vehicle's position.
+	Approximate []bool
+}
+
+// BoardPage is the data a departure board template is executed with.
+type BoardPage struct {
+	Title          string
+	RefreshSeconds int
+	Updated        time.Time
+	Theme          BoardTheme
+	Rows           []BoardRow
+	Messages       []string
+}
+
+var defaultBoardTemplate = template.Must(template.New("board").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+{{if .RefreshSeconds}}<meta http-equiv="refresh" content="{{.RefreshSeconds}}">{{end}}
+<style>
+body { background: {{.Theme.Background}}; color: {{.Theme.Foreground}}; font-family: {{.Theme.Font}}; margin: 2em; }
+h1 { color: {{.Theme.Accent}}; }
+table { width: 100%; border-collapse: collapse; font-size: 2em; }
+td { padding: 0.3em 0.5em; border-bottom: 1px solid {{.Theme.Accent}}; }
+.route { color: {{.Theme.Accent}}; font-weight: bold; }
+.minutes { text-align: right; }
+.messages { margin-top: 1em; font-size: 1.2em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+{{range .Rows}}<tr>
+<td class="route">{{.RouteTitle}}</td>
+<td>{{.Direction}}<br><small>{{.StopTitle}}</small></td>
+<td class="minutes">{{if .Countdowns}}{{$row := .}}{{range $i, $c := .Countdowns}}{{if $i}}, {{end}}{{$c}}{{if index $row.Approximate $i}}*{{end}}{{end}}{{else}}&mdash;{{end}}</td>
+</tr>
+{{end}}</table>
+{{if .Messages}}<div class="messages">{{range .Messages}}<p>{{.}}</p>{{end}}</div>{{end}}
+{{if not .Updated.IsZero}}<p><small>Updated {{.Updated.Format "15:04:05"}}</small></p>{{end}}
+</body>
+</html>
+`))
+
+type departureBoard struct {
+	watcher *PredictionWatcher
+	config  BoardConfig
+}
+
+// NewDepartureBoard returns an http.Handler that renders the predictions held
+// by watcher as a departure board page. The watcher must be run separately.
+func NewDepartureBoard(watcher *PredictionWatcher, config BoardConfig) http.Handler {
+	if config.Theme == (BoardTheme{}) {
+		config.Theme = DefaultBoardTheme
+	}
+	if config.Template == nil {
+		config.Template = defaultBoardTemplate
+	}
+	if config.Title == "" {
+		config.Title = "Departures"
+	}
+	return &departureBoard{watcher, config}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *departureBoard) page() BoardPage {
+	predictions, updated := b.watcher.Predictions()
+	now := b.watcher.client.now()
+	page := BoardPage{
+		Title:          b.config.Title,
+		RefreshSeconds: int(b.config.Refresh / time.Second),
+		Updated:        updated,
+		Theme:          b.config.Theme,
+	}
+	seenMessages := map[string]bool{}
+	for _, pd := range predictions {
+		if len(b.config.Routes) != 0 && !contains(b.config.Routes, pd.RouteTag) {
+			continue
+		}
+		if len(b.config.Stops) != 0 && !contains(b.config.Stops, pd.StopTag) {
+			continue
+		}
+		for _, dir := range pd.PredictionDirectionList {
+			row := BoardRow{RouteTag: pd.RouteTag, RouteTitle: pd.RouteTitle, StopTitle: b.config.Formatter.StopTitle(pd.StopTitle), Direction: b.config.Formatter.Text(dir.Title)}
+			for _, p := range dir.PredictionList {
+				if b.config.Limit > 0 && len(row.Minutes) == b.config.Limit {
+					break
+				}
+				if p.Confidence() < b.config.MinConfidence {
+					continue
+				}
+				row.Minutes = append(row.Minutes, p.At(now).Minutes)
+				row.Countdowns = append(row.Countdowns, b.config.Formatter.CountdownAt(p, now))
+				row.Approximate = append(row.Approximate, p.Confidence() < ConfidenceGPS)
+			}
+			page.Rows = append(page.Rows, row)
+		}
+		for _, m := range pd.MessageList {
+			if !seenMessages[m.Text] {
+				seenMessages[m.Text] = true
+				page.Messages = append(page.Messages, m.Text)
+			}
+		}
+	}
+	return page
+}
+
+func (b *departureBoard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := b.config.Template.Execute(&buf, b.page()); err != nil {
+		http.Error(w, "could not render departure board: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	buf.WriteTo(w)
+}
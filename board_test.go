@@ -0,0 +1,76 @@
+package nextbus
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixtureFetched is when the fixture predictions were fetched: their first
+// prediction is 181 seconds away.
+var fixtureFetched = time.Unix(0, 1487277081162*int64(time.Millisecond)).Add(-181 * time.Second)
+
+// boardWatcher returns a watcher of the fixture stops that has polled once at
+// the time in now.
+func boardWatcher(t *testing.T, now *time.Time) *PredictionWatcher {
+	w := NewPredictionWatcher(NewClient(testingClient(t), WithClock(fixedClock(now))), "alpha", RouteStop{"1", "1123"}, RouteStop{"1", "1124"})
+	ok(t, w.Poll())
+	return w
+}
+
+func TestDepartureBoard(t *testing.T) {
+	now := fixtureFetched
+	w := boardWatcher(t, &now)
+	board := NewDepartureBoard(w, BoardConfig{Title: "Home", Refresh: 30 * time.Second, Limit: 1})
+
+	rec := httptest.NewRecorder()
+	board.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	equals(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert(t, strings.Contains(body, "<title>Home</title>"), "expected the title in %s", body)
+	assert(t, strings.Contains(body, `content="30"`), "expected a refresh in %s", body)
+	assert(t, strings.Contains(body, "3 min"), "expected the first departure in %s", body)
+	assert(t, !strings.Contains(body, "9 min") && !strings.Contains(body, "3, 9"), "expected the limit to apply in %s", body)
+	assert(t, strings.Contains(body, "No Elevator at Blah blah Station"), "expected the message in %s", body)
+}
+
+func TestDepartureBoardCustomTemplate(t *testing.T) {
+	now := fixtureFetched
+	w := boardWatcher(t, &now)
+	tmpl := template.Must(template.New("rows").Parse(`{{range .Rows}}{{.StopTitle}};{{end}}`))
+	board := NewDepartureBoard(w, BoardConfig{Stops: []string{"1124"}, Template: tmpl})
+
+	rec := httptest.NewRecorder()
+	board.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	equals(t, "Some Other Station Outbound;", rec.Body.String())
+}
+
+func TestDepartureBoardConfidence(t *testing.T) {
+	now := fixtureFetched
+	w := boardWatcher(t, &now)
+
+	rec := httptest.NewRecorder()
+	NewDepartureBoard(w, BoardConfig{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert(t, strings.Contains(rec.Body.String(), "3 min, 9 min*"), "expected the layover prediction to be marked in %s", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	NewDepartureBoard(w, BoardConfig{MinConfidence: ConfidenceGPS}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := rec.Body.String()
+	assert(t, strings.Contains(body, "3 min<") && !strings.Contains(body, "9 min"), "expected the layover prediction to be hidden in %s", body)
+}
+
+func TestDepartureBoardCountsDown(t *testing.T) {
+	now := fixtureFetched
+	w := boardWatcher(t, &now)
+	board := NewDepartureBoard(w, BoardConfig{Limit: 1})
+
+	// A page served between polls counts down from the epoch time.
+	now = now.Add(2 * time.Minute)
+	rec := httptest.NewRecorder()
+	board.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	body := rec.Body.String()
+	assert(t, strings.Contains(body, "1 min") && !strings.Contains(body, "3 min"), "expected a recomputed countdown in %s", body)
+}
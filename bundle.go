@@ -0,0 +1,160 @@
+package nextbus
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// BundleSchemaVersion is the version of the bundle format written by
+// WriteBundle. ReadBundle rejects bundles with a newer version.
+const BundleSchemaVersion = 1
+
+// The files in a bundle. The agency and route configs are stored as the
+// agencyList and routeConfig XML NextBus serves.
+const (
+	bundleManifest = "manifest.json"
+	bundleAgency   = "agency.xml"
+	bundleRoutes   = "routeConfig.xml"
+)
+
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// BundleManifest describes the contents of a bundle.
+type BundleManifest struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	AgencyTag     string    `json:"agency"`
+	FetchedAt     time.Time `json:"fetchedAt"`
+	// Files holds the hex encoded SHA-256 of each file in the bundle.
+	Files map[string]string `json:"files"`
+}
+
+// WriteBundle writes a snapshot to w as a gzipped tar archive holding a
+// manifest and the snapshot's data, so that it can be copied to devices
+// without network access and loaded with ReadBundle.
+func WriteBundle(w io.Writer, s *AgencySnapshot) error {
+	agency, agencyErr := xml.Marshal(AgencyResponse{AgencyList: []Agency{s.Agency}})
+	if agencyErr != nil {
+		return fmt.Errorf("could not write bundle: %v", agencyErr)
+	}
+	routes, routesErr := xml.Marshal(RouteConfigResponse{RouteList: s.Routes})
+	if routesErr != nil {
+		return fmt.Errorf("could not write bundle: %v", routesErr)
+	}
+	files := []bundleFile{{bundleAgency, agency}, {bundleRoutes, routes}}
+
+	manifest := BundleManifest{
+		SchemaVersion: BundleSchemaVersion,
+		AgencyTag:     s.Agency.Tag,
+		FetchedAt:     s.FetchedAt,
+		Files:         map[string]string{},
+	}
+	for _, f := range files {
+		manifest.Files[f.name] = checksum(f.data)
+	}
+	manifestData, manifestErr := json.MarshalIndent(manifest, "", "  ")
+	if manifestErr != nil {
+		return fmt.Errorf("could not write bundle: %v", manifestErr)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	// A snapshot built by hand may have no fetch time; the epoch keeps its
+	// bundle reproducible.
+	modTime := s.FetchedAt
+	if modTime.IsZero() {
+		modTime = time.Unix(0, 0)
+	}
+	for _, f := range append([]bundleFile{{bundleManifest, manifestData}}, files...) {
+		header := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data)), ModTime: modTime}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("could not write bundle: %v", err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return fmt.Errorf("could not write bundle: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("could not write bundle: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("could not write bundle: %v", err)
+	}
+	return nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ReadBundle loads a snapshot written by WriteBundle, checking every file
+// against the checksums in the manifest.
+func ReadBundle(r io.Reader) (*AgencySnapshot, error) {
+	gz, gzErr := gzip.NewReader(r)
+	if gzErr != nil {
+		return nil, fmt.Errorf("could not read bundle: %v", gzErr)
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read bundle: %v", err)
+		}
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(tr); err != nil {
+			return nil, fmt.Errorf("could not read bundle: %v", err)
+		}
+		files[header.Name] = buf.Bytes()
+	}
+
+	manifestData, found := files[bundleManifest]
+	if !found {
+		return nil, fmt.Errorf("could not read bundle: missing %s", bundleManifest)
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("could not read bundle manifest: %v", err)
+	}
+	if manifest.SchemaVersion > BundleSchemaVersion {
+		return nil, fmt.Errorf("could not read bundle: unsupported schema version %d", manifest.SchemaVersion)
+	}
+	for _, name := range []string{bundleAgency, bundleRoutes} {
+		data, present := files[name]
+		if !present {
+			return nil, fmt.Errorf("could not read bundle: missing %s", name)
+		}
+		if checksum(data) != manifest.Files[name] {
+			return nil, fmt.Errorf("could not read bundle: checksum mismatch for %s", name)
+		}
+	}
+
+	var agencies AgencyResponse
+	if err := decode("agencyList", files[bundleAgency], &agencies); err != nil {
+		return nil, err
+	}
+	if len(agencies.AgencyList) != 1 {
+		return nil, fmt.Errorf("could not read bundle: expected one agency, found %d", len(agencies.AgencyList))
+	}
+	var routes RouteConfigResponse
+	if err := decode("routeConfig", files[bundleRoutes], &routes); err != nil {
+		return nil, err
+	}
+	return &AgencySnapshot{Agency: agencies.AgencyList[0], Routes: routes.RouteList, FetchedAt: manifest.FetchedAt}, nil
+}
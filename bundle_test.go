@@ -0,0 +1,57 @@
+package nextbus
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	snapshot, err := NewClient(testingClient(t)).GetAgencySnapshot("alpha")
+	ok(t, err)
+
+	var buf bytes.Buffer
+	ok(t, WriteBundle(&buf, snapshot))
+	loaded, err := ReadBundle(&buf)
+	ok(t, err)
+	equals(t, snapshot.Agency, loaded.Agency)
+	equals(t, snapshot.Routes, loaded.Routes)
+	assert(t, snapshot.FetchedAt.Equal(loaded.FetchedAt), "expected %v, got %v", snapshot.FetchedAt, loaded.FetchedAt)
+}
+
+func TestBundleChecksumMismatch(t *testing.T) {
+	snapshot, err := NewClient(testingClient(t)).GetAgencySnapshot("alpha")
+	ok(t, err)
+	var buf bytes.Buffer
+	ok(t, WriteBundle(&buf, snapshot))
+
+	// Rewrite the bundle with a tampered route config.
+	gz, err := gzip.NewReader(&buf)
+	ok(t, err)
+	tr := tar.NewReader(gz)
+	var out bytes.Buffer
+	gzOut := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gzOut)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			break
+		}
+		var data bytes.Buffer
+		data.ReadFrom(tr)
+		content := data.Bytes()
+		if header.Name == bundleRoutes {
+			content = bytes.Replace(content, []byte("First stop"), []byte("Fishy stop"), 1)
+		}
+		header.Size = int64(len(content))
+		ok(t, tw.WriteHeader(header))
+		tw.Write(content)
+	}
+	ok(t, tw.Close())
+	ok(t, gzOut.Close())
+
+	_, err = ReadBundle(&out)
+	assert(t, err != nil && strings.Contains(err.Error(), "checksum mismatch"), "expected a checksum error, got %v", err)
+}
@@ -0,0 +1,166 @@
+package nextbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Topic identifies the kind of a BusEvent.
+type Topic string
+
+// The topics published by the subsystems of this package when they are given
+// a Bus. The Payload of each is noted alongside.
+const (
+	// TopicVehicles events carry the *LocationResponse returned by each
+	// VehicleLocationSession.Next.
+	TopicVehicles Topic = "vehicles"
+	// TopicPredictions events carry the []PredictionData from each
+	// PredictionWatcher.Poll.
+	TopicPredictions Topic = "predictions"
+	// TopicMessages events carry an EventServiceMessage Event for each
+	// message that a PredictionWatcher hadn't seen in its previous poll.
+	TopicMessages Topic = "messages"
+	// TopicAlerts events carry an EventArrivalAlert Event for each vehicle
+	// that a PredictionWatcher with AlertWithin set finds newly predicted to
+	// arrive within it.
+	TopicAlerts Topic = "alerts"
+	// TopicPredictionChanges events carry the []PredictionChange found by
+	// each PredictionWatcher.Poll after the first, when there are any.
+	TopicPredictionChanges Topic = "prediction_changes"
+	// TopicHealth events carry the HealthStatus of a HealthMonitor when it
+	// turns healthy or unhealthy.
+	TopicHealth Topic = "health"
+	// TopicRefresh events carry the RefreshEvent of each Directory fetch.
+	TopicRefresh Topic = "refresh"
+)
+
+// BusEvent is a message published on a Bus.
+type BusEvent struct {
+	Topic   Topic
+	Time    time.Time
+	Payload interface{}
+}
+
+// Bus delivers events from the subsystems that publish to it to any number of
+// subscribers, so that they can share one stream of updates. The zero value
+// is ready to use.
+type Bus struct {
+	// Clock, if set, gives the Time of events published without one. Nil
+	// uses SystemClock. The subsystems of this package stamp their events
+	// with their Client's Clock.
+	Clock Clock
+
+	mu   sync.RWMutex
+	subs map[int]subscription
+	next int
+}
+
+type subscription struct {
+	fn     func(BusEvent)
+	topics map[Topic]bool
+}
+
+// NewBus creates an empty Bus.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers fn to be called with every event published on the given
+// topics, or on every topic if none are given, until unsubscribe is called.
+// Events are delivered synchronously by the publisher, so fn should return
+// quickly and hand off slow work, such as network calls, to another
+// goroutine.
+func (b *Bus) Subscribe(fn func(BusEvent), topics ...Topic) (unsubscribe func()) {
+	sub := subscription{fn: fn}
+	if len(topics) != 0 {
+		sub.topics = make(map[Topic]bool, len(topics))
+		for _, topic := range topics {
+			sub.topics[topic] = true
+		}
+	}
+
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = map[int]subscription{}
+	}
+	id := b.next
+	b.next++
+	b.subs[id] = sub
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		delete(b.subs, id)
+		b.mu.Unlock()
+	}
+}
+
+// Publish delivers an event to the subscribers of its topic. A zero Time is
+// set to the time of the Bus's Clock.
+func (b *Bus) Publish(e BusEvent) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		clock := b.Clock
+		if clock == nil {
+			clock = SystemClock
+		}
+		e.Time = clock.Now()
+	}
+	b.mu.RLock()
+	var fns []func(BusEvent)
+	for _, sub := range b.subs {
+		if sub.topics == nil || sub.topics[e.Topic] {
+			fns = append(fns, sub.fn)
+		}
+	}
+	b.mu.RUnlock()
+	for _, fn := range fns {
+		fn(e)
+	}
+}
+
+// ErrEventDropped is passed to the onError of NotifyFrom for each Event
+// dropped because the notifier had fallen too far behind.
+var ErrEventDropped = errors.New("nextbus: notification queue full, event dropped")
+
+// notifyQueue is the number of events NotifyFrom holds while its Notifier
+// catches up.
+const notifyQueue = 64
+
+// NotifyFrom sends every Event published on the bus to n, such as a
+// WebhookNotifier, until ctx is done. Notifications are sent from a separate
+// goroutine, in order, so a slow Notifier doesn't hold up publishers; when
+// notifyQueue events are already waiting, further ones are dropped. Errors,
+// including ErrEventDropped from the publisher's goroutine, are passed to
+// onError if it isn't nil.
+func NotifyFrom(ctx context.Context, bus *Bus, n Notifier, onError func(error)) {
+	events := make(chan Event, notifyQueue)
+	unsubscribe := bus.Subscribe(func(e BusEvent) {
+		if event, isEvent := e.Payload.(Event); isEvent {
+			select {
+			case events <- event:
+			default:
+				if onError != nil {
+					onError(ErrEventDropped)
+				}
+			}
+		}
+	})
+	go func() {
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-events:
+				if err := n.Notify(ctx, event); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
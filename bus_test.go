@@ -0,0 +1,104 @@
+package nextbus
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var all, health []BusEvent
+	bus.Subscribe(func(e BusEvent) { all = append(all, e) })
+	unsubscribe := bus.Subscribe(func(e BusEvent) { health = append(health, e) }, TopicHealth)
+
+	bus.Publish(BusEvent{Topic: TopicVehicles})
+	bus.Publish(BusEvent{Topic: TopicHealth, Payload: HealthStatus{Healthy: true}})
+	unsubscribe()
+	bus.Publish(BusEvent{Topic: TopicHealth})
+
+	equals(t, 3, len(all))
+	equals(t, 1, len(health))
+	equals(t, HealthStatus{Healthy: true}, health[0].Payload)
+	assert(t, !health[0].Time.IsZero(), "expected the time to be set")
+
+	// A nil Bus, as in subsystems that weren't given one, ignores events.
+	var none *Bus
+	none.Publish(BusEvent{Topic: TopicVehicles})
+}
+
+func TestPredictionWatcherPublishesNewMessages(t *testing.T) {
+	bus := NewBus()
+	var messages []Event
+	var predictions int
+	bus.Subscribe(func(e BusEvent) {
+		switch e.Topic {
+		case TopicPredictions:
+			predictions++
+		case TopicMessages:
+			messages = append(messages, e.Payload.(Event))
+		}
+	})
+
+	w := NewPredictionWatcher(NewClient(testingClient(t)), "alpha", RouteStop{"1", "1123"}, RouteStop{"1", "1124"})
+	w.Bus = bus
+	ok(t, w.Poll())
+	ok(t, w.Poll())
+	equals(t, 2, predictions)
+	equals(t, 1, len(messages))
+	equals(t, "No Elevator at Blah blah Station", messages[0].MessageText)
+	equals(t, "1124", messages[0].StopTag)
+}
+
+type notifierFunc func(ctx context.Context, e Event) error
+
+func (f notifierFunc) Notify(ctx context.Context, e Event) error { return f(ctx, e) }
+
+func TestNotifyFrom(t *testing.T) {
+	bus := NewBus()
+	received := make(chan Event, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	NotifyFrom(ctx, bus, notifierFunc(func(ctx context.Context, e Event) error {
+		received <- e
+		return nil
+	}), nil)
+
+	bus.Publish(BusEvent{Topic: TopicPredictions, Payload: []PredictionData{}})
+	bus.Publish(BusEvent{Topic: TopicMessages, Payload: Event{Type: EventServiceMessage, MessageText: "hi"}})
+	select {
+	case e := <-received:
+		equals(t, "hi", e.MessageText)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a notification")
+	}
+}
+
+func TestNotifyFromDropsWhenFull(t *testing.T) {
+	bus := NewBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var dropped int
+	NotifyFrom(ctx, bus, notifierFunc(func(ctx context.Context, e Event) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	}), func(err error) {
+		equals(t, ErrEventDropped, err)
+		dropped++
+	})
+	defer close(release)
+
+	bus.Publish(BusEvent{Topic: TopicMessages, Payload: Event{Type: EventServiceMessage}})
+	<-started
+	// The notifier is stuck, so the queue fills and publishing carries on.
+	for i := 0; i < notifyQueue+2; i++ {
+		bus.Publish(BusEvent{Topic: TopicMessages, Payload: Event{Type: EventServiceMessage}})
+	}
+	equals(t, 2, dropped)
+}
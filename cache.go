@@ -0,0 +1,143 @@
+package nextbus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultCacheTTLs are how long a DiskCache keeps the responses of each
+// command. Static data changes rarely; predictions and vehicle locations are
+// never cached.
+var DefaultCacheTTLs = map[string]time.Duration{
+	"agencyList":  24 * time.Hour,
+	"routeList":   24 * time.Hour,
+	"routeConfig": 24 * time.Hour,
+	"schedule":    24 * time.Hour,
+}
+
+// DiskCache stores feed responses as files in a directory so that they are
+// reused across processes. A cached response is fresh for the TTL of its
+// command, measured from when it was downloaded; commands without a TTL
+// aren't cached. Errors reading or writing the cache are treated as misses.
+type DiskCache struct {
+	dir string
+
+	// TTLs are the freshness lifetimes by command. NewDiskCache sets them to
+	// a copy of DefaultCacheTTLs.
+	TTLs map[string]time.Duration
+}
+
+// NewDiskCache creates a cache that stores its files in dir, creating it if
+// needed.
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create cache directory: %v", err)
+	}
+	ttls := make(map[string]time.Duration, len(DefaultCacheTTLs))
+	for command, ttl := range DefaultCacheTTLs {
+		ttls[command] = ttl
+	}
+	return &DiskCache{dir: dir, TTLs: ttls}, nil
+}
+
+// WithDiskCache makes a Client answer requests from cache when it holds a
+// fresh response, and store the successful responses it downloads there.
+func WithDiskCache(cache *DiskCache) Option {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// cacheKey identifies a request in a DiskCache: its URL at the Client's feed
+// and the headers set with WithHeader, so that clients of different feeds or
+// with different credentials sharing a cache never see each other's
+// responses. Params are sorted so that the same request always has the same
+// key regardless of how it was built.
+func (c *Client) cacheKey(command string, params []string) string {
+	sorted := append([]string(nil), params...)
+	sort.Strings(sorted)
+	key := c.requestURL(command, sorted)
+	names := make([]string, 0, len(c.headers))
+	for name := range c.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range c.headers[name] {
+			key += "\n" + name + ": " + value
+		}
+	}
+	return key
+}
+
+// path returns the file for the request identified by key. Keys are hashed,
+// so credentials in them aren't written to disk.
+func (d *DiskCache) path(command, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, command+"-"+hex.EncodeToString(sum[:16])+".xml")
+}
+
+// get returns the cached response for a request if it is fresh at now.
+func (d *DiskCache) get(now time.Time, command, key string) ([]byte, bool) {
+	ttl := d.TTLs[command]
+	if ttl <= 0 {
+		return nil, false
+	}
+	path := d.path(command, key)
+	info, statErr := os.Stat(path)
+	if statErr != nil || now.Sub(info.ModTime()) > ttl {
+		return nil, false
+	}
+	data, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// put stores a response downloaded at now, replacing any cached one
+// atomically so that concurrent readers never see a partial file.
+func (d *DiskCache) put(now time.Time, command, key string, data []byte) error {
+	if d.TTLs[command] <= 0 {
+		return nil
+	}
+	tmp, err := os.CreateTemp(d.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not write cache: %v", err)
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil {
+		writeErr = os.Chtimes(tmp.Name(), now, now)
+	}
+	if writeErr == nil {
+		writeErr = os.Rename(tmp.Name(), d.path(command, key))
+	}
+	if writeErr != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not write cache: %v", writeErr)
+	}
+	return nil
+}
+
+// Clear removes every cached response.
+func (d *DiskCache) Clear() error {
+	matches, err := filepath.Glob(filepath.Join(d.dir, "*.xml"))
+	if err != nil {
+		return fmt.Errorf("could not clear cache: %v", err)
+	}
+	for _, path := range matches {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("could not clear cache: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package nextbus
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiskCache(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+	ok(t, err)
+
+	feed := cannedFeed(`<body><route tag="1" title="1-first"/></body>`)
+	nb := NewClient(feed.Client(), WithDiskCache(cache))
+	for i := 0; i < 2; i++ {
+		routes, err := nb.GetRouteList("alpha")
+		ok(t, err)
+		equals(t, "1-first", routes[0].Title)
+	}
+	equals(t, 1, feed.Count())
+
+	// Another client, such as a later run of the same program, shares the
+	// cached response.
+	other := NewClient(feed.Client(), WithDiskCache(cache))
+	_, err = other.GetRouteList("alpha")
+	ok(t, err)
+	equals(t, 1, feed.Count())
+
+	_, err = nb.GetRouteList("beta")
+	ok(t, err)
+	equals(t, 2, feed.Count())
+
+	ok(t, cache.Clear())
+	_, err = nb.GetRouteList("alpha")
+	ok(t, err)
+	equals(t, 3, feed.Count())
+}
+
+func TestDiskCacheExpires(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+	ok(t, err)
+
+	feed := cannedFeed(`<body><route tag="1" title="1-first"/></body>`)
+	nb := NewClient(feed.Client(), WithDiskCache(cache))
+	_, err = nb.GetRouteList("alpha")
+	ok(t, err)
+
+	old := time.Now().Add(-25 * time.Hour)
+	path := cache.path("routeList", nb.cacheKey("routeList", []string{"a=alpha"}))
+	ok(t, os.Chtimes(path, old, old))
+	_, err = nb.GetRouteList("alpha")
+	ok(t, err)
+	equals(t, 2, feed.Count())
+}
+
+func TestDiskCacheSkipsErrorsAndUncachedCommands(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDiskCache(dir)
+	ok(t, err)
+
+	nb := NewClient(cannedFeed(`<body><Error shouldRetry="false">No such agency</Error></body>`).Client(), WithDiskCache(cache))
+	_, err = nb.GetRouteList("alpha")
+	assert(t, err != nil, "expected an error")
+	_, err = nb.GetVehicleLocations("alpha")
+	assert(t, err != nil, "expected an error")
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	equals(t, 0, len(files))
+}
+
+func TestDiskCacheSeparatesFeedsAndCredentials(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+	ok(t, err)
+
+	titles := []string{"1-default", "1-mirror", "1-first-key", "1-second-key"}
+	var feeds []*fakeFeed
+	for _, title := range titles {
+		feeds = append(feeds, cannedFeed(`<body><route tag="1" title="`+title+`"/></body>`))
+	}
+	clients := []*Client{
+		NewClient(feeds[0].Client(), WithDiskCache(cache)),
+		NewClient(feeds[1].Client(), WithDiskCache(cache),
+			WithFeedURL("https://mirror.example/service/publicXMLFeed")),
+		NewClient(feeds[2].Client(), WithDiskCache(cache),
+			WithFeedURL("https://mirror.example/service/publicXMLFeed"), WithHeader("Authorization", "Bearer first")),
+		NewClient(feeds[3].Client(), WithDiskCache(cache),
+			WithFeedURL("https://mirror.example/service/publicXMLFeed"), WithHeader("Authorization", "Bearer second")),
+	}
+	for pass := 0; pass < 2; pass++ {
+		for i, nb := range clients {
+			routes, err := nb.GetRouteList("alpha")
+			ok(t, err)
+			equals(t, titles[i], routes[0].Title)
+		}
+	}
+	for _, feed := range feeds {
+		equals(t, 1, feed.Count())
+	}
+}
@@ -0,0 +1,130 @@
+package nextbus
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+)
+
+// CharsetReader converts input in the named charset to UTF-8. It has the
+// signature of xml.Decoder's CharsetReader field.
+type CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+
+// WithCharsetReader sets how a Client decodes responses whose XML declaration
+// names an encoding other than UTF-8. Some agency-hosted feeds are served in
+// Latin-1 or Windows-1252; DefaultCharsetReader, used when this option isn't
+// given, covers those.
+func WithCharsetReader(r CharsetReader) Option {
+	return func(c *Client) {
+		c.charsetReader = r
+	}
+}
+
+// windows1252 maps bytes 0x80 to 0x9F of Windows-1252 to runes. The rest of
+// the charset is the same as Latin-1, and the holes in this range map to the
+// control characters Latin-1 has there.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// DefaultCharsetReader converts US-ASCII, ISO-8859-1 (Latin-1) and
+// Windows-1252 to UTF-8. Other charsets return an error.
+func DefaultCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	var high func(b byte) rune
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8":
+		return input, nil
+	case "us-ascii", "ascii", "iso-8859-1", "iso8859-1", "latin1", "l1":
+		high = func(b byte) rune { return rune(b) }
+	case "windows-1252", "cp1252":
+		high = func(b byte) rune {
+			if b >= 0x80 && b < 0xa0 {
+				return windows1252[b-0x80]
+			}
+			return rune(b)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+
+	data, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(data))
+	for _, b := range data {
+		if b < utf8.RuneSelf {
+			out = append(out, b)
+		} else {
+			out = utf8.AppendRune(out, high(b))
+		}
+	}
+	return bytes.NewReader(out), nil
+}
+
+// newXMLDecoder returns a decoder for a response. HTML entities such as
+// &nbsp;, which some feeds use in titles, decode to their characters instead
+// of failing.
+func newXMLDecoder(r io.Reader) *xml.Decoder {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = DefaultCharsetReader
+	d.Entity = xml.HTMLEntity
+	return d
+}
+
+// declaredEncoding returns the encoding named by data's XML declaration and
+// the position of its value, or "" if there is none.
+func declaredEncoding(data []byte) (string, int, int) {
+	if !bytes.HasPrefix(data, []byte("<?xml")) {
+		return "", 0, 0
+	}
+	end := bytes.Index(data, []byte("?>"))
+	if end < 0 {
+		return "", 0, 0
+	}
+	i := bytes.Index(data[:end], []byte("encoding="))
+	if i < 0 || i+len("encoding=") >= end {
+		return "", 0, 0
+	}
+	start := i + len("encoding=")
+	quote := data[start]
+	if quote != '"' && quote != '\'' {
+		return "", 0, 0
+	}
+	length := bytes.IndexByte(data[start+1:end], quote)
+	if length < 0 {
+		return "", 0, 0
+	}
+	return string(data[start+1 : start+1+length]), start + 1, start + 1 + length
+}
+
+// toUTF8 converts a response in another encoding to UTF-8 with the Client's
+// CharsetReader and rewrites its XML declaration to match, so every decoder,
+// the cache and Do see UTF-8. It returns nil for responses already in UTF-8.
+func (c *Client) toUTF8(command string, data []byte) ([]byte, error) {
+	charset, start, end := declaredEncoding(data)
+	if charset == "" || strings.EqualFold(charset, "utf-8") {
+		return nil, nil
+	}
+	reader := c.charsetReader
+	if reader == nil {
+		reader = DefaultCharsetReader
+	}
+	r, err := reader(charset, bytes.NewReader(data[end:]))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s response body: %v", describe(command), err)
+	}
+	rest, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s response body: %v", describe(command), err)
+	}
+	converted := make([]byte, 0, start+len("UTF-8")+len(rest))
+	converted = append(converted, data[:start]...)
+	converted = append(converted, "UTF-8"...)
+	return append(converted, rest...), nil
+}
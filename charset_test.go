@@ -0,0 +1,52 @@
+package nextbus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestLatin1Response(t *testing.T) {
+	body := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<body><route tag=\"1\" title=\"Caf\xe9 Pe\xf1a\"/></body>"
+	nb := NewClient(cannedFeed(body).Client())
+	routes, err := nb.GetRouteList("alpha")
+	ok(t, err)
+	equals(t, "Café Peña", routes[0].Title)
+
+	data, err := nb.Do(context.Background(), "routeList", "a=alpha")
+	ok(t, err)
+	assert(t, strings.HasPrefix(string(data), `<?xml version="1.0" encoding="UTF-8"?>`), "expected a UTF-8 declaration, got %q", data)
+}
+
+func TestWindows1252Response(t *testing.T) {
+	body := "<?xml version='1.0' encoding='windows-1252'?><body><route tag=\"1\" title=\"\x93Muni\x94 \x96 Metro\"/></body>"
+	routes, err := NewClient(cannedFeed(body).Client()).GetRouteList("alpha")
+	ok(t, err)
+	equals(t, "“Muni” – Metro", routes[0].Title)
+}
+
+func TestHTMLEntities(t *testing.T) {
+	routes, err := NewClient(cannedFeed(`<body><route tag="1" title="Market&nbsp;St"/></body>`).Client()).GetRouteList("alpha")
+	ok(t, err)
+	equals(t, "Market St", routes[0].Title)
+}
+
+func TestCharsetReader(t *testing.T) {
+	body := `<?xml version="1.0" encoding="x-shouting"?><body><route tag="1" title="quiet"/></body>`
+	_, err := NewClient(cannedFeed(body).Client()).GetRouteList("alpha")
+	assert(t, err != nil, "expected an error for an unsupported charset")
+
+	shout := func(charset string, input io.Reader) (io.Reader, error) {
+		if charset != "x-shouting" {
+			return nil, errors.New("unexpected charset " + charset)
+		}
+		data, _ := ioutil.ReadAll(input)
+		return strings.NewReader(strings.Replace(string(data), "quiet", "QUIET", 1)), nil
+	}
+	routes, err := NewClient(cannedFeed(body).Client(), WithCharsetReader(shout)).GetRouteList("alpha")
+	ok(t, err)
+	equals(t, "QUIET", routes[0].Title)
+}
@@ -0,0 +1,40 @@
+package nextbus
+
+import (
+	"time"
+)
+
+// Clock tells the current time. Time-dependent features such as pollers,
+// cache freshness and event timestamps read the time from the Client's Clock,
+// so tests and replays of recorded feeds can control it.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to a Clock.
+type ClockFunc func() time.Time
+
+// Now calls f.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock is the Clock used when WithClock isn't.
+var SystemClock Clock = ClockFunc(time.Now)
+
+// WithClock makes a Client and the watchers, monitors and caches that use it
+// read the time from clock instead of SystemClock. Delays between requests
+// and polls are still measured in real time.
+func WithClock(clock Clock) Option {
+	return func(c *Client) {
+		c.clock = clock
+	}
+}
+
+// now returns the time according to the Client's Clock.
+func (c *Client) now() time.Time {
+	if c.clock == nil {
+		return SystemClock.Now()
+	}
+	return c.clock.Now()
+}
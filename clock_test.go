@@ -0,0 +1,74 @@
+package nextbus
+
+import (
+	"testing"
+	"time"
+)
+
+func fixedClock(t *time.Time) Clock {
+	return ClockFunc(func() time.Time { return *t })
+}
+
+func TestClockExpiresDiskCache(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+	ok(t, err)
+
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	feed := cannedFeed(`<body><route tag="1" title="1-first"/></body>`)
+	nb := NewClient(feed.Client(), WithDiskCache(cache), WithClock(fixedClock(&now)))
+	_, err = nb.GetRouteList("alpha")
+	ok(t, err)
+	now = now.Add(23 * time.Hour)
+	_, err = nb.GetRouteList("alpha")
+	ok(t, err)
+	equals(t, 1, feed.Count())
+
+	now = now.Add(2 * time.Hour)
+	_, err = nb.GetRouteList("alpha")
+	ok(t, err)
+	equals(t, 2, feed.Count())
+}
+
+func TestClockStampsPolls(t *testing.T) {
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	nb := NewClient(testingClient(t), WithClock(fixedClock(&now)))
+	w := NewPredictionWatcher(nb, "alpha", RouteStop{"1", "1123"}, RouteStop{"1", "1124"})
+	var messages []Event
+	w.Bus = &Bus{}
+	w.Bus.Subscribe(func(e BusEvent) { messages = append(messages, e.Payload.(Event)) }, TopicMessages)
+	ok(t, w.Poll())
+	_, updated := w.Predictions()
+	equals(t, now, updated)
+	equals(t, 1, len(messages))
+	equals(t, now, messages[0].Time)
+}
+
+func TestClockStampsEvents(t *testing.T) {
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	nb := NewClient(testingClient(t), WithClock(fixedClock(&now)))
+	bus := &Bus{}
+	var events []BusEvent
+	bus.Subscribe(func(e BusEvent) { events = append(events, e) })
+
+	w := NewPredictionWatcher(nb, "alpha", RouteStop{"1", "1123"}, RouteStop{"1", "1124"})
+	w.Bus = bus
+	ok(t, w.Poll())
+	s := NewVehicleLocationSession(nb, "alpha", "")
+	s.Bus = bus
+	_, err := s.Next()
+	ok(t, err)
+	assert(t, len(events) >= 3, "expected predictions, messages and vehicles events, got %d", len(events))
+	for _, e := range events {
+		equals(t, now, e.Time)
+	}
+
+	bus.Clock = fixedClock(&now)
+	events = nil
+	bus.Publish(BusEvent{Topic: "custom"})
+	equals(t, now, events[0].Time)
+
+	d := &Deduplicator{Window: time.Minute, Clock: fixedClock(&now)}
+	assert(t, d.AllowEvent(Event{Type: EventServiceMessage}), "expected the first event to be allowed")
+	now = now.Add(time.Minute)
+	assert(t, d.AllowEvent(Event{Type: EventServiceMessage}), "expected the clock to end the window")
+}
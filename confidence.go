@@ -0,0 +1,67 @@
+package nextbus
+
+// Confidence describes how a prediction was made and so how much it can be
+// trusted. Higher values are more reliable.
+type Confidence int
+
+// The kinds of prediction, from least to most reliable.
+const (
+	// ConfidenceSchedule predictions come from the timetable because the
+	// vehicle isn't reporting its position.
+	ConfidenceSchedule Confidence = iota
+	// ConfidenceLayover predictions assume a vehicle currently on a layover
+	// will leave on time, which it often doesn't.
+	ConfidenceLayover
+	// ConfidenceGPS predictions are based on the vehicle's reported position.
+	ConfidenceGPS
+)
+
+func (c Confidence) String() string {
+	switch c {
+	case ConfidenceSchedule:
+		return "schedule"
+	case ConfidenceLayover:
+		return "layover"
+	default:
+		return "gps"
+	}
+}
+
+// Confidence classifies the prediction by how it was made.
+func (p Prediction) Confidence() Confidence {
+	switch {
+	case p.IsScheduleBased == "true":
+		return ConfidenceSchedule
+	case p.AffectedByLayover == "true":
+		return ConfidenceLayover
+	default:
+		return ConfidenceGPS
+	}
+}
+
+// IsDelayed reports whether NextBus has flagged the vehicle as delayed.
+func (p Prediction) IsDelayed() bool {
+	return p.Delayed == "true"
+}
+
+// FilterByConfidence returns a copy of predictions keeping only the individual
+// predictions with at least the given confidence. Directions left without
+// predictions are kept so that their titles can still be shown.
+func FilterByConfidence(predictions []PredictionData, min Confidence) []PredictionData {
+	result := make([]PredictionData, len(predictions))
+	for i, pd := range predictions {
+		result[i] = pd
+		result[i].PredictionDirectionList = make([]PredictionDirection, len(pd.PredictionDirectionList))
+		for j, dir := range pd.PredictionDirectionList {
+			filtered := dir
+			filtered.PredictionList = nil
+			for _, p := range dir.PredictionList {
+				if p.Confidence() >= min {
+					filtered.PredictionList = append(filtered.PredictionList, p)
+				}
+			}
+			result[i].PredictionDirectionList[j] = filtered
+		}
+	}
+	return result
+}
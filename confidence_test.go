@@ -0,0 +1,26 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestPredictionConfidence(t *testing.T) {
+	equals(t, ConfidenceGPS, Prediction{}.Confidence())
+	equals(t, ConfidenceLayover, Prediction{AffectedByLayover: "true"}.Confidence())
+	equals(t, ConfidenceSchedule, Prediction{AffectedByLayover: "true", IsScheduleBased: "true"}.Confidence())
+	equals(t, "layover", ConfidenceLayover.String())
+	assert(t, Prediction{Delayed: "true"}.IsDelayed(), "expected a delayed prediction")
+}
+
+func TestFilterByConfidence(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	predictions, err := nb.GetPredictionsForMultiStops("alpha", PredReqStop("1", "1123"), PredReqStop("1", "1124"))
+	ok(t, err)
+
+	filtered := FilterByConfidence(predictions, ConfidenceGPS)
+	equals(t, 1, len(filtered[0].PredictionDirectionList[0].PredictionList))
+	equals(t, "1111", filtered[0].PredictionDirectionList[0].PredictionList[0].Vehicle)
+	equals(t, 0, len(filtered[1].PredictionDirectionList[0].PredictionList))
+	equals(t, "Outbound", filtered[1].PredictionDirectionList[0].Title)
+	equals(t, 2, len(predictions[0].PredictionDirectionList[0].PredictionList))
+}
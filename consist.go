@@ -0,0 +1,60 @@
+package nextbus
+
+// Consist is a train made up of one or more coupled vehicles reported
+// separately by NextBus, as happens with light rail.
+type Consist struct {
+	// Cars are ordered from the lead car backwards.
+	Cars []VehicleLocation
+}
+
+// Lead returns the first car of the consist.
+func (c Consist) Lead() VehicleLocation {
+	return c.Cars[0]
+}
+
+// AssembleConsists groups vehicles into consists by following the
+// LeadingVehicleID of each car to the car in front of it. A vehicle with no
+// leading vehicle, or whose leading vehicle isn't in vehicles, starts a new
+// consist. Consists are returned in the order their lead cars appear.
+func AssembleConsists(vehicles []VehicleLocation) []Consist {
+	byID := make(map[string]int, len(vehicles))
+	for i, v := range vehicles {
+		byID[v.ID] = i
+	}
+
+	followers := map[string][]int{}
+	var leads []int
+	for i, v := range vehicles {
+		if _, known := byID[v.LeadingVehicleID]; v.LeadingVehicleID == "" || v.LeadingVehicleID == v.ID || !known {
+			leads = append(leads, i)
+			continue
+		}
+		followers[v.LeadingVehicleID] = append(followers[v.LeadingVehicleID], i)
+	}
+
+	used := make([]bool, len(vehicles))
+	var result []Consist
+	for _, lead := range leads {
+		result = append(result, Consist{collectCars(vehicles, followers, used, lead, nil)})
+	}
+	// Anything left over is part of a cycle with no lead car; break it at the
+	// first car seen.
+	for i := range vehicles {
+		if !used[i] {
+			result = append(result, Consist{collectCars(vehicles, followers, used, i, nil)})
+		}
+	}
+	return result
+}
+
+func collectCars(vehicles []VehicleLocation, followers map[string][]int, used []bool, i int, cars []VehicleLocation) []VehicleLocation {
+	if used[i] {
+		return cars
+	}
+	used[i] = true
+	cars = append(cars, vehicles[i])
+	for _, f := range followers[vehicles[i].ID] {
+		cars = collectCars(vehicles, followers, used, f, cars)
+	}
+	return cars
+}
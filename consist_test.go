@@ -0,0 +1,38 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func consistIDs(consists []Consist) [][]string {
+	var result [][]string
+	for _, c := range consists {
+		var ids []string
+		for _, car := range c.Cars {
+			ids = append(ids, car.ID)
+		}
+		result = append(result, ids)
+	}
+	return result
+}
+
+func TestAssembleConsists(t *testing.T) {
+	vehicles := []VehicleLocation{
+		{ID: "1502", LeadingVehicleID: "1501"},
+		{ID: "1401"},
+		{ID: "1501"},
+		{ID: "1503", LeadingVehicleID: "1502"},
+		{ID: "1601", LeadingVehicleID: "1600"},
+	}
+	consists := AssembleConsists(vehicles)
+	equals(t, [][]string{{"1401"}, {"1501", "1502", "1503"}, {"1601"}}, consistIDs(consists))
+	equals(t, "1501", consists[1].Lead().ID)
+}
+
+func TestAssembleConsistsCycle(t *testing.T) {
+	vehicles := []VehicleLocation{
+		{ID: "1", LeadingVehicleID: "2"},
+		{ID: "2", LeadingVehicleID: "1"},
+	}
+	equals(t, [][]string{{"1", "2"}}, consistIDs(AssembleConsists(vehicles)))
+}
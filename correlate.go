@@ -0,0 +1,100 @@
+package nextbus
+
+import (
+	"sort"
+)
+
+// VehicleArrival is a vehicle's predicted arrival at one stop.
+type VehicleArrival struct {
+	StopTag   string
+	StopTitle string
+	// Direction is the title of the direction the vehicle is traveling in.
+	Direction  string
+	Prediction Prediction
+}
+
+// TrackedVehicle is where a vehicle is now together with when it is predicted
+// to arrive at the stops it is on its way to.
+type TrackedVehicle struct {
+	Vehicle VehicleLocation
+	// Arrivals are ordered by predicted arrival time.
+	Arrivals []VehicleArrival
+}
+
+// CorrelateVehicles joins vehicle locations with predictions by route and
+// vehicle ID. Every vehicle is returned, in the order given, with the
+// predictions that name it; predictions for vehicles not among them are
+// ignored.
+func CorrelateVehicles(vehicles []VehicleLocation, predictions []PredictionData) []TrackedVehicle {
+	type vehicleKey struct{ routeTag, id string }
+	arrivals := map[vehicleKey][]VehicleArrival{}
+	for _, pd := range predictions {
+		for _, dir := range pd.PredictionDirectionList {
+			for _, p := range dir.PredictionList {
+				if p.Vehicle == "" {
+					continue
+				}
+				key := vehicleKey{pd.RouteTag, p.Vehicle}
+				arrivals[key] = append(arrivals[key], VehicleArrival{
+					StopTag:    pd.StopTag,
+					StopTitle:  pd.StopTitle,
+					Direction:  dir.Title,
+					Prediction: p,
+				})
+			}
+		}
+	}
+
+	result := make([]TrackedVehicle, len(vehicles))
+	for i, v := range vehicles {
+		a := arrivals[vehicleKey{v.RouteTag, v.ID}]
+		sort.SliceStable(a, func(x, y int) bool {
+			return a[x].Prediction.ArrivalTime().Before(a[y].Prediction.ArrivalTime())
+		})
+		result[i] = TrackedVehicle{Vehicle: v, Arrivals: a}
+	}
+	return result
+}
+
+// TrackVehicles fetches predictions for stops of an agency and the locations
+// of the vehicles on their routes, and returns the vehicles on their way to
+// at least one of the stops.
+func (c *Client) TrackVehicles(agencyTag string, stops ...RouteStop) ([]TrackedVehicle, error) {
+	var predictions []PredictionData
+	var routeTags []string
+	seen := map[string]bool{}
+	for start := 0; start < len(stops); start += maxStopsPerRequest {
+		end := start + maxStopsPerRequest
+		if end > len(stops) {
+			end = len(stops)
+		}
+		params := make([]PredReqParam, 0, end-start)
+		for _, rs := range stops[start:end] {
+			params = append(params, PredReqStop(rs.RouteTag, rs.StopTag))
+			if !seen[rs.RouteTag] {
+				seen[rs.RouteTag] = true
+				routeTags = append(routeTags, rs.RouteTag)
+			}
+		}
+		data, err := c.GetPredictionsForMultiStops(agencyTag, params...)
+		if err != nil {
+			return nil, err
+		}
+		predictions = append(predictions, data...)
+	}
+	if len(routeTags) == 0 {
+		return nil, nil
+	}
+
+	locations, err := c.GetVehicleLocationsForRoutes(agencyTag, routeTags)
+	if err != nil {
+		return nil, err
+	}
+	var result []TrackedVehicle
+	for _, tv := range CorrelateVehicles(locations.VehicleList, predictions) {
+		if len(tv.Arrivals) != 0 {
+			result = append(result, tv)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package nextbus
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCorrelateVehicles(t *testing.T) {
+	predictions, err := DecodePredictions(strings.NewReader(fakes[makeURL("predictionsForMultiStops", "a", "alpha", "stops", "1|1123", "stops", "1|1124")]))
+	ok(t, err)
+	vehicles := []VehicleLocation{
+		{ID: "4444", RouteTag: "1"},
+		{ID: "1111", RouteTag: "1"},
+		{ID: "1111", RouteTag: "2"},
+	}
+
+	tracked := CorrelateVehicles(vehicles, predictions)
+	equals(t, 3, len(tracked))
+	equals(t, "4444", tracked[0].Vehicle.ID)
+	equals(t, 1, len(tracked[0].Arrivals))
+	equals(t, "1124", tracked[0].Arrivals[0].StopTag)
+	equals(t, "Outbound", tracked[0].Arrivals[0].Direction)
+	equals(t, "Some Station Outbound", tracked[1].Arrivals[0].StopTitle)
+	equals(t, "3", tracked[1].Arrivals[0].Prediction.Minutes)
+	equals(t, 0, len(tracked[2].Arrivals))
+}
+
+func TestTrackVehicles(t *testing.T) {
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		res := statusResponse(req, http.StatusOK)
+		body := fakes[makeURL("predictionsForMultiStops", "a", "alpha", "stops", "1|1123", "stops", "1|1124")]
+		if req.URL.Query().Get("command") == "vehicleLocations" {
+			equals(t, "1", req.URL.Query().Get("r"))
+			body = `<body><vehicle id="2222" routeTag="1" lat="37.7" lon="-122.4"/><vehicle id="3333" routeTag="1"/><lastTime time="1"/></body>`
+		}
+		res.Body = ioutil.NopCloser(strings.NewReader(body))
+		return res, nil
+	})}
+
+	tracked, err := NewClient(httpClient).TrackVehicles("alpha", RouteStop{"1", "1123"}, RouteStop{"1", "1124"})
+	ok(t, err)
+	equals(t, 1, len(tracked))
+	equals(t, "2222", tracked[0].Vehicle.ID)
+	equals(t, "37.7", tracked[0].Vehicle.Lat)
+	equals(t, "9", tracked[0].Arrivals[0].Prediction.Minutes)
+}
@@ -0,0 +1,66 @@
+package nextbus
+
+import (
+	"time"
+)
+
+// At returns p with Seconds and Minutes recomputed from its EpochTime as seen
+// at now, instead of when it was fetched, so that cached predictions still
+// count down correctly. A prediction without a valid EpochTime is returned
+// unchanged.
+func (p Prediction) At(now time.Time) Prediction {
+	arrival := p.ArrivalTime()
+	if arrival.IsZero() {
+		return p
+	}
+	return withArrival(p, now, arrival)
+}
+
+// Until returns the time from now until the predicted arrival, which is
+// negative once it has passed, or zero if p has no valid EpochTime.
+func (p Prediction) Until(now time.Time) time.Duration {
+	arrival := p.ArrivalTime()
+	if arrival.IsZero() {
+		return 0
+	}
+	return arrival.Sub(now)
+}
+
+// CountdownsAt returns a copy of predictions with every countdown recomputed
+// at now with Prediction.At, leaving out arrivals that have already passed.
+func CountdownsAt(now time.Time, predictions []PredictionData) []PredictionData {
+	result := make([]PredictionData, len(predictions))
+	for i, pd := range predictions {
+		dirs := make([]PredictionDirection, len(pd.PredictionDirectionList))
+		for j, dir := range pd.PredictionDirectionList {
+			var list []Prediction
+			for _, p := range dir.PredictionList {
+				if arrival := p.ArrivalTime(); !arrival.IsZero() && arrival.Before(now) {
+					continue
+				}
+				list = append(list, p.At(now))
+			}
+			dir.PredictionList = list
+			dirs[j] = dir
+		}
+		pd.PredictionDirectionList = dirs
+		result[i] = pd
+	}
+	return result
+}
+
+// Current returns the set's predictions with their countdowns recomputed at
+// the current time; see CountdownsAt.
+func (s *PredictionSet) Current() []PredictionData {
+	clock := s.clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	return CountdownsAt(clock.Now(), s.Predictions)
+}
+
+// CountdownAt formats the time until a prediction like Countdown, computing
+// it from the prediction's EpochTime at now.
+func (f Formatter) CountdownAt(p Prediction, now time.Time) string {
+	return f.Countdown(p.At(now))
+}
@@ -0,0 +1,42 @@
+package nextbus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPredictionAt(t *testing.T) {
+	fetched := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	p := withArrival(Prediction{Vehicle: "1500"}, fetched, fetched.Add(5*time.Minute+30*time.Second))
+	equals(t, "5", p.Minutes)
+
+	later := p.At(fetched.Add(2 * time.Minute))
+	equals(t, "3", later.Minutes)
+	equals(t, "210", later.Seconds)
+	equals(t, p.EpochTime, later.EpochTime)
+	equals(t, 3*time.Minute+30*time.Second, p.Until(fetched.Add(2*time.Minute)))
+
+	equals(t, "0", p.At(fetched.Add(10*time.Minute)).Minutes)
+	equals(t, Prediction{Minutes: "4"}, Prediction{Minutes: "4"}.At(fetched))
+	equals(t, "Arriving", Formatter{}.CountdownAt(p, fetched.Add(5*time.Minute)))
+}
+
+func TestCountdownsAt(t *testing.T) {
+	fetched := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	predictions := []PredictionData{{RouteTag: "N", StopTag: "5205", PredictionDirectionList: []PredictionDirection{{PredictionList: []Prediction{
+		withArrival(Prediction{Vehicle: "1"}, fetched, fetched.Add(time.Minute)),
+		withArrival(Prediction{Vehicle: "2"}, fetched, fetched.Add(8*time.Minute)),
+	}}}}}
+
+	current := CountdownsAt(fetched.Add(3*time.Minute), predictions)
+	list := current[0].PredictionDirectionList[0].PredictionList
+	equals(t, 1, len(list))
+	equals(t, "2", list[0].Vehicle)
+	equals(t, "5", list[0].Minutes)
+	equals(t, 2, len(predictions[0].PredictionDirectionList[0].PredictionList))
+	equals(t, "1", predictions[0].PredictionDirectionList[0].PredictionList[0].Minutes)
+
+	now := fetched.Add(3 * time.Minute)
+	set := &PredictionSet{Predictions: predictions, FetchedAt: fetched, clock: fixedClock(&now)}
+	equals(t, current, set.Current())
+}
@@ -0,0 +1,67 @@
+package nextbus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// decodeReader reads a whole response from r and decodes it into a T.
+func decodeReader[T any](r io.Reader, command string) (T, error) {
+	var result T
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return result, fmt.Errorf("could not parse %s response body: %v", describe(command), err)
+	}
+	return result, decode(command, buf.Bytes(), &result)
+}
+
+// The Decode functions parse feed responses obtained some other way than
+// through a Client, such as from saved files, a proxy or a message queue,
+// the same way the Client does. An Error reported by NextBus is returned as a
+// *FeedError.
+
+// DecodeAgencyList parses an agencyList response.
+func DecodeAgencyList(r io.Reader) ([]Agency, error) {
+	a, err := decodeReader[AgencyResponse](r, "agencyList")
+	return a.AgencyList, err
+}
+
+// DecodeRouteList parses a routeList response.
+func DecodeRouteList(r io.Reader) ([]Route, error) {
+	a, err := decodeReader[RouteResponse](r, "routeList")
+	return a.RouteList, err
+}
+
+// DecodeRouteConfig parses a routeConfig response.
+func DecodeRouteConfig(r io.Reader) ([]RouteConfig, error) {
+	a, err := decodeReader[RouteConfigResponse](r, "routeConfig")
+	return a.RouteList, err
+}
+
+// DecodePredictions parses a predictions or predictionsForMultiStops
+// response.
+func DecodePredictions(r io.Reader) ([]PredictionData, error) {
+	a, err := decodeReader[PredictionResponse](r, "predictions")
+	return a.PredictionDataList, err
+}
+
+// DecodeSchedule parses a schedule response.
+func DecodeSchedule(r io.Reader) ([]Schedule, error) {
+	a, err := decodeReader[ScheduleResponse](r, "schedule")
+	return a.ScheduleList, err
+}
+
+// DecodeVehicleLocations parses a vehicleLocations response. Unlike
+// Client.GetVehicleLocations it applies no stale vehicle filter.
+func DecodeVehicleLocations(r io.Reader) (*LocationResponse, error) {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, fmt.Errorf("could not parse %s response body: %v", describe("vehicleLocations"), err)
+	}
+	var result LocationResponse
+	if err := decodeLocationResponse(buf.Bytes(), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
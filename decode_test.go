@@ -0,0 +1,33 @@
+package nextbus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeFunctions(t *testing.T) {
+	agencies, err := DecodeAgencyList(strings.NewReader(fakes[makeURL("agencyList")]))
+	ok(t, err)
+	equals(t, "alpha", agencies[0].Tag)
+
+	configs, err := DecodeRouteConfig(strings.NewReader(fakes[makeURL("routeConfig", "a", "alpha")]))
+	ok(t, err)
+	found, err := NewClient(testingClient(t)).GetRouteConfig("alpha")
+	ok(t, err)
+	equals(t, found, configs)
+
+	predictions, err := DecodePredictions(strings.NewReader(fakes[makeURL("predictionsForMultiStops", "a", "alpha", "stops", "1|1123")]))
+	ok(t, err)
+	equals(t, "1123", predictions[0].StopTag)
+
+	locations, err := DecodeVehicleLocations(strings.NewReader(fakes[makeURL("vehicleLocations", "a", "alpha", "t", "0")]))
+	ok(t, err)
+	assert(t, len(locations.VehicleList) != 0, "expected vehicles")
+
+	_, err = DecodePredictions(strings.NewReader(fakes[makeURL("predictionsForMultiStops", "a", "alpha", "stops", "1|1123", "stops", "2|1123")]))
+	_, isFeedErr := err.(*FeedError)
+	assert(t, isFeedErr, "expected a *FeedError, got %v", err)
+
+	_, err = DecodeRouteList(strings.NewReader("<body"))
+	assert(t, err != nil, "expected a parse error")
+}
@@ -0,0 +1,146 @@
+package nextbus
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDedupWindow is the Window of a Deduplicator when it is zero.
+const DefaultDedupWindow = 10 * time.Minute
+
+// EventKey returns the key by which a Deduplicator recognizes repeats of an
+// Event.
+type EventKey func(e Event) string
+
+// DefaultEventKey considers events repeats if they are of the same type about
+// the same stop, vehicle, trip and message.
+func DefaultEventKey(e Event) string {
+	return strings.Join([]string{string(e.Type), e.AgencyTag, e.RouteTag, e.StopTag, e.Vehicle, e.TripTag, e.MessageText}, "|")
+}
+
+// PredictionChangeKey returns the key by which a Deduplicator recognizes
+// repeats of a PredictionChange.
+type PredictionChangeKey func(c PredictionChange) string
+
+// DefaultPredictionChangeKey considers changes repeats if they are of the same
+// kind about the same vehicle at the same stop, so that a prediction slipping
+// back and forth between polls is reported once per window.
+func DefaultPredictionChangeKey(c PredictionChange) string {
+	return strings.Join([]string{string(c.Kind), c.RouteTag, c.StopTag, c.Vehicle}, "|")
+}
+
+// Deduplicator suppresses repeats of events and prediction changes, so that
+// predictions oscillating between polls don't cause a storm of alerts. Once
+// something is emitted, its repeats within Window are suppressed. The same
+// Deduplicator can be shared by a PredictionWatcher and the Notifiers it
+// feeds; each Notifier keeps its own record of what it passed, so an event
+// the watcher emitted still reaches them once. The zero value is ready to
+// use.
+type Deduplicator struct {
+	// Window is how long repeats are suppressed after an emission.
+	Window time.Duration
+	// EventKey identifies repeated events. Nil uses DefaultEventKey.
+	EventKey EventKey
+	// ChangeKey identifies repeated prediction changes. Nil uses
+	// DefaultPredictionChangeKey.
+	ChangeKey PredictionChangeKey
+	// Clock, if set, gives the time of events without a Time. Nil uses
+	// SystemClock.
+	Clock Clock
+
+	mu        sync.Mutex
+	emitted   map[string]time.Time
+	swept     time.Time
+	notifiers int
+}
+
+// Allow reports whether something identified by key may be emitted at t,
+// recording the emission if so.
+func (d *Deduplicator) Allow(key string, t time.Time) bool {
+	window := d.Window
+	if window <= 0 {
+		window = DefaultDedupWindow
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.emitted == nil {
+		d.emitted = map[string]time.Time{}
+	}
+	if t.Sub(d.swept) >= window {
+		for k, at := range d.emitted {
+			if t.Sub(at) >= window {
+				delete(d.emitted, k)
+			}
+		}
+		d.swept = t
+	}
+	if at, seen := d.emitted[key]; seen && t.Sub(at) < window {
+		return false
+	}
+	d.emitted[key] = t
+	return true
+}
+
+// AllowEvent reports whether e may be emitted, as of its Time or, if it has
+// none, the time of the Deduplicator's Clock.
+func (d *Deduplicator) AllowEvent(e Event) bool {
+	return d.allowEvent("event|", e)
+}
+
+// allowEvent is AllowEvent for the consumer whose keys start with namespace.
+func (d *Deduplicator) allowEvent(namespace string, e Event) bool {
+	key := d.EventKey
+	if key == nil {
+		key = DefaultEventKey
+	}
+	t := e.Time
+	if t.IsZero() {
+		clock := d.Clock
+		if clock == nil {
+			clock = SystemClock
+		}
+		t = clock.Now()
+	}
+	return d.Allow(namespace+key(e), t)
+}
+
+// Changes returns the changes found at t that may be emitted.
+func (d *Deduplicator) Changes(t time.Time, changes []PredictionChange) []PredictionChange {
+	key := d.ChangeKey
+	if key == nil {
+		key = DefaultPredictionChangeKey
+	}
+	var allowed []PredictionChange
+	for _, c := range changes {
+		if d.Allow("change|"+key(c), t) {
+			allowed = append(allowed, c)
+		}
+	}
+	return allowed
+}
+
+// Notifier returns a Notifier that passes events to n unless they repeat one
+// it passed within the window.
+func (d *Deduplicator) Notifier(n Notifier) Notifier {
+	d.mu.Lock()
+	d.notifiers++
+	namespace := "notifier" + strconv.Itoa(d.notifiers) + "|"
+	d.mu.Unlock()
+	return dedupNotifier{d, n, namespace}
+}
+
+type dedupNotifier struct {
+	dedup     *Deduplicator
+	next      Notifier
+	namespace string
+}
+
+func (n dedupNotifier) Notify(ctx context.Context, e Event) error {
+	if !n.dedup.allowEvent(n.namespace, e) {
+		return nil
+	}
+	return n.next.Notify(ctx, e)
+}
@@ -0,0 +1,91 @@
+package nextbus
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d := &Deduplicator{Window: 5 * time.Minute}
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	alert := Event{Type: EventArrivalAlert, AgencyTag: "sf-muni", RouteTag: "N", StopTag: "5205", Vehicle: "1500", Time: now}
+
+	assert(t, d.AllowEvent(alert), "expected the first alert to be allowed")
+	alert.Minutes = "3"
+	alert.Time = now.Add(4 * time.Minute)
+	assert(t, !d.AllowEvent(alert), "expected a repeat within the window to be suppressed")
+	other := alert
+	other.Vehicle = "1501"
+	assert(t, d.AllowEvent(other), "expected another vehicle's alert to be allowed")
+	alert.Time = now.Add(5 * time.Minute)
+	assert(t, d.AllowEvent(alert), "expected a repeat after the window to be allowed")
+
+	slip := PredictionChange{Kind: ArrivalSlipped, RouteTag: "N", StopTag: "5205", Vehicle: "1500"}
+	equals(t, []PredictionChange{slip}, d.Changes(now, []PredictionChange{slip, slip}))
+	equals(t, 0, len(d.Changes(now.Add(time.Minute), []PredictionChange{slip})))
+
+	var notified int
+	n := d.Notifier(notifierFunc(func(ctx context.Context, e Event) error {
+		notified++
+		return nil
+	}))
+	alert.Time = now.Add(20 * time.Minute)
+	ok(t, n.Notify(context.Background(), alert))
+	ok(t, n.Notify(context.Background(), alert))
+	equals(t, 1, notified)
+}
+
+func TestWatcherDedup(t *testing.T) {
+	withMessage := `<body><predictions routeTag="N" stopTag="5205"><message text="Delays" priority="Normal"/></predictions></body>`
+	without := `<body><predictions routeTag="N" stopTag="5205"></predictions></body>`
+	bodies := []string{withMessage, without, withMessage}
+	calls := 0
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(bodies[calls%len(bodies)]))
+		calls++
+		return res, nil
+	})}
+	now := time.Unix(1487246400, 0)
+	w := NewPredictionWatcher(NewClient(client, WithClock(fixedClock(&now))), "sf-muni", RouteStop{"N", "5205"})
+	w.Dedup = &Deduplicator{}
+	w.Bus = &Bus{}
+	var messages int
+	w.Bus.Subscribe(func(BusEvent) { messages++ }, TopicMessages)
+
+	for i := 0; i < 3; i++ {
+		ok(t, w.Poll())
+		now = now.Add(time.Minute)
+	}
+	equals(t, 1, messages)
+}
+
+func TestDeduplicatorSharedWithNotifier(t *testing.T) {
+	body := `<body><predictions routeTag="N" stopTag="5205"><message text="Delays" priority="Normal"/></predictions></body>`
+	now := time.Unix(1487246400, 0)
+	w := NewPredictionWatcher(NewClient(cannedFeed(body).Client(), WithClock(fixedClock(&now))), "sf-muni", RouteStop{"N", "5205"})
+	dedup := &Deduplicator{}
+	w.Dedup = dedup
+	w.Bus = &Bus{}
+
+	var delivered int
+	n := dedup.Notifier(notifierFunc(func(ctx context.Context, e Event) error {
+		delivered++
+		return nil
+	}))
+	w.Bus.Subscribe(func(be BusEvent) {
+		ok(t, n.Notify(context.Background(), be.Payload.(Event)))
+	}, TopicMessages)
+
+	ok(t, w.Poll())
+	equals(t, 1, delivered)
+
+	// A later repeat reaching the Notifier directly is still suppressed.
+	repeat := ServiceMessageEvent("sf-muni", PredictionData{RouteTag: "N", StopTag: "5205"}, Message{Text: "Delays", Priority: "Normal"}, now.Add(time.Minute))
+	ok(t, n.Notify(context.Background(), repeat))
+	equals(t, 1, delivered)
+}
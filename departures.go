@@ -0,0 +1,113 @@
+package nextbus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// StopDepartures are the upcoming departures from one stop in the "stop
+// departures" JSON schema used by Transiter and similar transit middleware,
+// so that this package can act as a data source for them. Times are Unix
+// seconds encoded as strings, as in those systems' protobuf JSON.
+type StopDepartures struct {
+	ID        string              `json:"id"`
+	Name      string              `json:"name"`
+	StopTimes []DepartureStopTime `json:"stopTimes"`
+}
+
+// DepartureStopTime is one vehicle's predicted visit to a stop.
+type DepartureStopTime struct {
+	Trip      DepartureTrip      `json:"trip"`
+	Arrival   *DepartureEstimate `json:"arrival,omitempty"`
+	Departure *DepartureEstimate `json:"departure,omitempty"`
+	Future    bool               `json:"future"`
+	Headsign  string             `json:"headsign,omitempty"`
+}
+
+// DepartureTrip identifies the trip making a visit.
+type DepartureTrip struct {
+	ID          string            `json:"id"`
+	DirectionID string            `json:"directionId,omitempty"`
+	Route       DepartureRoute    `json:"route"`
+	Vehicle     *DepartureVehicle `json:"vehicle,omitempty"`
+}
+
+// DepartureRoute identifies a route.
+type DepartureRoute struct {
+	ID        string `json:"id"`
+	ShortName string `json:"shortName,omitempty"`
+}
+
+// DepartureVehicle identifies a vehicle.
+type DepartureVehicle struct {
+	ID string `json:"id"`
+}
+
+// DepartureEstimate is a predicted time.
+type DepartureEstimate struct {
+	Time int64 `json:"time,string"`
+}
+
+// NewStopDepartures groups predictions by stop, across routes, and converts
+// them to StopDepartures in order of first appearance. The stop times of each
+// stop are ordered by predicted time; those before now are marked as not
+// future.
+func NewStopDepartures(predictions []PredictionData, now time.Time) []StopDepartures {
+	var result []StopDepartures
+	index := map[string]int{}
+	for _, pd := range predictions {
+		i, found := index[pd.StopTag]
+		if !found {
+			i = len(result)
+			index[pd.StopTag] = i
+			result = append(result, StopDepartures{ID: pd.StopTag, Name: pd.StopTitle, StopTimes: []DepartureStopTime{}})
+		}
+		for _, dir := range pd.PredictionDirectionList {
+			for _, p := range dir.PredictionList {
+				at := p.ArrivalTime()
+				st := DepartureStopTime{
+					Trip: DepartureTrip{
+						ID:          p.TripTag,
+						DirectionID: p.DirTag,
+						Route:       DepartureRoute{ID: pd.RouteTag, ShortName: pd.RouteTitle},
+					},
+					Future:   !at.Before(now),
+					Headsign: dir.Title,
+				}
+				if p.Vehicle != "" {
+					st.Trip.Vehicle = &DepartureVehicle{ID: p.Vehicle}
+				}
+				estimate := &DepartureEstimate{Time: at.Unix()}
+				if p.IsDeparture == "true" {
+					st.Departure = estimate
+				} else {
+					st.Arrival = estimate
+				}
+				result[i].StopTimes = append(result[i].StopTimes, st)
+			}
+		}
+	}
+	for _, sd := range result {
+		times := sd.StopTimes
+		sort.SliceStable(times, func(a, b int) bool { return times[a].time() < times[b].time() })
+	}
+	return result
+}
+
+func (st DepartureStopTime) time() int64 {
+	if st.Departure != nil {
+		return st.Departure.Time
+	}
+	return st.Arrival.Time
+}
+
+// WriteDeparturesJSON writes departures as a JSON array.
+func WriteDeparturesJSON(w io.Writer, departures []StopDepartures) error {
+	if err := json.NewEncoder(w).Encode(departures); err != nil {
+		return fmt.Errorf("could not write departures: %v", err)
+	}
+	return nil
+}
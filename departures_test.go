@@ -0,0 +1,40 @@
+package nextbus
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestStopDepartures(t *testing.T) {
+	predictions := []PredictionData{
+		{RouteTag: "1", RouteTitle: "1-First", StopTag: "1123", StopTitle: "Main St", PredictionDirectionList: []PredictionDirection{{
+			Title:          "Outbound",
+			PredictionList: []Prediction{{EpochTime: "1487277463000", TripTag: "t2", DirTag: "out"}},
+		}}},
+		{RouteTag: "2", StopTag: "1123", PredictionDirectionList: []PredictionDirection{{
+			PredictionList: []Prediction{{EpochTime: "1487277081000", TripTag: "t1", Vehicle: "1111", IsDeparture: "true"}},
+		}}},
+		{RouteTag: "1", StopTag: "1124", StopTitle: "Side St"},
+	}
+
+	departures := NewStopDepartures(predictions, time.Unix(1487277100, 0))
+	equals(t, 2, len(departures))
+	equals(t, "Main St", departures[0].Name)
+	times := departures[0].StopTimes
+	equals(t, 2, len(times))
+	equals(t, "t1", times[0].Trip.ID)
+	equals(t, false, times[0].Future)
+	equals(t, &DepartureEstimate{1487277081}, times[0].Departure)
+	equals(t, "1", times[1].Trip.Route.ID)
+	equals(t, "Outbound", times[1].Headsign)
+	equals(t, 0, len(departures[1].StopTimes))
+
+	var buf bytes.Buffer
+	ok(t, WriteDeparturesJSON(&buf, departures[1:]))
+	equals(t, `[{"id":"1124","name":"Side St","stopTimes":[]}]`+"\n", buf.String())
+
+	buf.Reset()
+	ok(t, WriteDeparturesJSON(&buf, []StopDepartures{{ID: "1123", StopTimes: times[:1]}}))
+	equals(t, `[{"id":"1123","name":"","stopTimes":[{"trip":{"id":"t1","route":{"id":"2"},"vehicle":{"id":"1111"}},"departure":{"time":"1487277081"},"future":false}]}]`+"\n", buf.String())
+}
@@ -0,0 +1,47 @@
+package nextbus
+
+// ForUI reports whether the agency intends the direction to be shown to
+// riders. Directions such as school trips and short turns are usually
+// hidden.
+func (d Direction) ForUI() bool {
+	return d.UseForUI == "true"
+}
+
+// AllDirections returns every direction of the route. Hidden directions are
+// only included when the config was fetched with RouteConfigVerbose.
+func (rc RouteConfig) AllDirections() []Direction {
+	return rc.DirList
+}
+
+// UIDirections returns the directions the agency intends to be shown to
+// riders.
+func (rc RouteConfig) UIDirections() []Direction {
+	return filterDirections(rc.DirList, true)
+}
+
+// HiddenDirections returns the directions the agency doesn't intend to be
+// shown to riders. It is always empty unless the config was fetched with
+// RouteConfigVerbose.
+func (rc RouteConfig) HiddenDirections() []Direction {
+	return filterDirections(rc.DirList, false)
+}
+
+// Direction returns the direction with the given tag.
+func (rc RouteConfig) Direction(dirTag string) (Direction, bool) {
+	for _, d := range rc.DirList {
+		if d.Tag == dirTag {
+			return d, true
+		}
+	}
+	return Direction{}, false
+}
+
+func filterDirections(dirs []Direction, forUI bool) []Direction {
+	var result []Direction
+	for _, d := range dirs {
+		if d.ForUI() == forUI {
+			result = append(result, d)
+		}
+	}
+	return result
+}
@@ -0,0 +1,23 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestRouteConfigDirections(t *testing.T) {
+	rc := RouteConfig{DirList: []Direction{
+		{Tag: "out", UseForUI: "true"},
+		{Tag: "school", UseForUI: "false"},
+		{Tag: "in", UseForUI: "true"},
+	}}
+
+	equals(t, 3, len(rc.AllDirections()))
+	equals(t, []Direction{rc.DirList[0], rc.DirList[2]}, rc.UIDirections())
+	equals(t, []Direction{rc.DirList[1]}, rc.HiddenDirections())
+
+	d, found := rc.Direction("school")
+	assert(t, found, "expected to find the school direction")
+	assert(t, !d.ForUI(), "expected the school direction to be hidden")
+	_, found = rc.Direction("express")
+	assert(t, !found, "did not expect to find an express direction")
+}
@@ -0,0 +1,245 @@
+package nextbus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RefreshEvent reports the outcome of fetching an agency's snapshot for a
+// Directory.
+type RefreshEvent struct {
+	AgencyTag string
+	// Snapshot is the new snapshot, or nil if the fetch failed.
+	Snapshot *AgencySnapshot
+	// Err is the error from a failed fetch. The Directory keeps the previous
+	// snapshot, if any.
+	Err error
+}
+
+// DirectoryStore holds the snapshots of a Directory. NewDirectory keeps them
+// in memory; a store backed by a database lets services with many agencies
+// keep only the snapshots in use in memory, and reuse them after a restart.
+type DirectoryStore interface {
+	// Put stores the snapshot of an agency, replacing any previous one.
+	Put(ctx context.Context, snapshot *AgencySnapshot) error
+	// Get returns the snapshot of an agency, or nil if there is none.
+	Get(ctx context.Context, agencyTag string) (*AgencySnapshot, error)
+	// Agencies returns the tags of the agencies with a snapshot.
+	Agencies(ctx context.Context) ([]string, error)
+}
+
+// RouteStore is a DirectoryStore that can load one route of an agency
+// without the rest of its snapshot, so that Directory.Route keeps only the
+// routes asked for in memory.
+type RouteStore interface {
+	DirectoryStore
+	// Route returns the config of one route of an agency. A route that isn't
+	// stored is reported as a *NotFoundError.
+	Route(ctx context.Context, agencyTag, routeTag string) (RouteConfig, error)
+}
+
+// memoryStore is the DirectoryStore used by NewDirectory.
+type memoryStore struct {
+	mu        sync.RWMutex
+	snapshots map[string]*AgencySnapshot
+}
+
+func (m *memoryStore) Put(ctx context.Context, snapshot *AgencySnapshot) error {
+	m.mu.Lock()
+	m.snapshots[snapshot.Agency.Tag] = snapshot
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memoryStore) Get(ctx context.Context, agencyTag string) (*AgencySnapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.snapshots[agencyTag], nil
+}
+
+func (m *memoryStore) Agencies(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tags := make([]string, 0, len(m.snapshots))
+	for tag := range m.snapshots {
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// Directory holds the snapshots of several agencies, fetching them on demand
+// with WarmUp and keeping them current with Run or StartAutoRefresh. Requests
+// go through the Directory's Client, so its rate limit and cache apply.
+type Directory struct {
+	client *Client
+	store  DirectoryStore
+
+	// Bus, if set, receives a TopicRefresh event after every snapshot fetch.
+	Bus *Bus
+
+	mu        sync.RWMutex
+	requested map[string]bool
+	listeners []func(RefreshEvent)
+}
+
+// NewDirectory creates an empty Directory that keeps its snapshots in
+// memory.
+func NewDirectory(client *Client) *Directory {
+	return NewDirectoryWithStore(client, &memoryStore{snapshots: map[string]*AgencySnapshot{}})
+}
+
+// NewDirectoryWithStore creates a Directory that keeps its snapshots in
+// store. Agencies already in the store are part of the Directory.
+func NewDirectoryWithStore(client *Client, store DirectoryStore) *Directory {
+	return &Directory{client: client, store: store, requested: map[string]bool{}}
+}
+
+// OnRefresh registers fn to be called after every snapshot fetch, successful
+// or not, so that indexes built from the snapshots can be rebuilt.
+func (d *Directory) OnRefresh(fn func(RefreshEvent)) {
+	d.mu.Lock()
+	d.listeners = append(d.listeners, fn)
+	d.mu.Unlock()
+}
+
+// Snapshot returns the latest snapshot of an agency. An error reading the
+// Directory's store is reported as no snapshot.
+func (d *Directory) Snapshot(agencyTag string) (*AgencySnapshot, bool) {
+	s, err := d.store.Get(context.Background(), agencyTag)
+	return s, err == nil && s != nil
+}
+
+// Route returns the config of one route of an agency. If the Directory's
+// store is a RouteStore, only that route is loaded. A route the Directory
+// doesn't hold is reported as a *NotFoundError.
+func (d *Directory) Route(ctx context.Context, agencyTag, routeTag string) (RouteConfig, error) {
+	if rs, isRouteStore := d.store.(RouteStore); isRouteStore {
+		return rs.Route(ctx, agencyTag, routeTag)
+	}
+	s, err := d.store.Get(ctx, agencyTag)
+	if err != nil {
+		return RouteConfig{}, err
+	}
+	if s != nil {
+		for _, rc := range s.Routes {
+			if rc.Tag == routeTag {
+				return rc, nil
+			}
+		}
+	}
+	return RouteConfig{}, &NotFoundError{Kind: "route", AgencyTag: agencyTag, RouteTag: routeTag}
+}
+
+// Agencies returns the tags of the agencies the Directory holds or has been
+// asked to fetch, in sorted order.
+func (d *Directory) Agencies() []string {
+	stored, _ := d.store.Agencies(context.Background())
+	d.mu.RLock()
+	seen := map[string]bool{}
+	tags := make([]string, 0, len(d.requested)+len(stored))
+	for _, list := range [][]string{stored, keys(d.requested)} {
+		for _, tag := range list {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	d.mu.RUnlock()
+	sort.Strings(tags)
+	return tags
+}
+
+func keys(set map[string]bool) []string {
+	result := make([]string, 0, len(set))
+	for k := range set {
+		result = append(result, k)
+	}
+	return result
+}
+
+// WarmUp fetches the snapshots of the given agencies, adding them to the
+// Directory. An agency whose fetch fails is still added, without a snapshot,
+// so later refreshes retry it. The first error is returned after every agency
+// has been tried.
+func (d *Directory) WarmUp(ctx context.Context, agencyTags ...string) error {
+	d.mu.Lock()
+	for _, tag := range agencyTags {
+		d.requested[tag] = true
+	}
+	d.mu.Unlock()
+	return d.fetch(ctx, agencyTags)
+}
+
+// Refresh fetches the snapshots of every agency in the Directory again.
+func (d *Directory) Refresh(ctx context.Context) error {
+	return d.fetch(ctx, d.Agencies())
+}
+
+func (d *Directory) fetch(ctx context.Context, agencyTags []string) error {
+	if len(agencyTags) == 0 {
+		return nil
+	}
+	agencies, listErr := fetchAndDecode[AgencyResponse](ctx, d.client, "agencyList", nil)
+	var firstErr error
+	for _, tag := range agencyTags {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if firstErr == nil {
+				firstErr = ctxErr
+			}
+			break
+		}
+		var event RefreshEvent
+		if listErr != nil {
+			event = RefreshEvent{AgencyTag: tag, Err: listErr}
+		} else {
+			snapshot, err := d.client.agencySnapshot(ctx, agencies.AgencyList, tag)
+			event = RefreshEvent{AgencyTag: tag, Snapshot: snapshot, Err: err}
+		}
+		if event.Snapshot != nil {
+			if putErr := d.store.Put(ctx, event.Snapshot); putErr != nil {
+				event.Err = fmt.Errorf("could not store %s snapshot: %v", tag, putErr)
+			}
+		}
+		if event.Err != nil && firstErr == nil {
+			firstErr = event.Err
+		}
+
+		d.mu.RLock()
+		listeners := d.listeners
+		d.mu.RUnlock()
+		for _, fn := range listeners {
+			fn(event)
+		}
+		d.Bus.Publish(BusEvent{Topic: TopicRefresh, Time: d.client.now(), Payload: event})
+	}
+	return firstErr
+}
+
+// Run refreshes every agency in the Directory each interval until ctx is
+// done. Errors are reported through the OnRefresh listeners.
+func (d *Directory) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		d.Refresh(ctx)
+	}
+}
+
+// StartAutoRefresh runs Run in a new goroutine and returns a function that
+// stops it.
+func (d *Directory) StartAutoRefresh(interval time.Duration) (stop func()) {
+	service := d.Service(interval)
+	service.Start(context.Background())
+	return func() {
+		service.Stop(context.Background())
+	}
+}
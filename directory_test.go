@@ -0,0 +1,120 @@
+package nextbus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDirectoryWarmUp(t *testing.T) {
+	d := NewDirectory(NewClient(testingClient(t)))
+	var events []RefreshEvent
+	d.OnRefresh(func(e RefreshEvent) { events = append(events, e) })
+
+	err := d.WarmUp(context.Background(), "alpha", "gamma")
+	_, notFound := err.(*NotFoundError)
+	assert(t, notFound, "expected a *NotFoundError for gamma, got %v", err)
+	equals(t, []string{"alpha", "gamma"}, d.Agencies())
+	equals(t, 2, len(events))
+	equals(t, "alpha", events[0].AgencyTag)
+	ok(t, events[0].Err)
+
+	s, found := d.Snapshot("alpha")
+	assert(t, found, "expected a snapshot of alpha")
+	equals(t, "The First", s.Agency.Title)
+	equals(t, 1, len(s.Routes))
+	_, found = d.Snapshot("gamma")
+	assert(t, !found, "expected no snapshot of gamma")
+}
+
+func TestDirectoryAutoRefresh(t *testing.T) {
+	d := NewDirectory(NewClient(testingClient(t)))
+	ok(t, d.WarmUp(context.Background(), "alpha"))
+	first, _ := d.Snapshot("alpha")
+
+	var once sync.Once
+	refreshed := make(chan RefreshEvent)
+	d.OnRefresh(func(e RefreshEvent) {
+		once.Do(func() { refreshed <- e })
+	})
+	stop := d.StartAutoRefresh(time.Millisecond)
+	defer stop()
+
+	select {
+	case e := <-refreshed:
+		ok(t, e.Err)
+		assert(t, e.Snapshot != first, "expected a new snapshot")
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a refresh")
+	}
+}
+
+type failingStore struct {
+	memoryStore
+	err error
+}
+
+func (s *failingStore) Put(ctx context.Context, snapshot *AgencySnapshot) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.memoryStore.Put(ctx, snapshot)
+}
+
+func TestDirectoryWithStore(t *testing.T) {
+	store := &failingStore{memoryStore: memoryStore{snapshots: map[string]*AgencySnapshot{
+		"beta": {Agency: Agency{Tag: "beta"}},
+	}}}
+	d := NewDirectoryWithStore(NewClient(testingClient(t)), store)
+	equals(t, []string{"beta"}, d.Agencies())
+
+	ok(t, d.WarmUp(context.Background(), "alpha"))
+	equals(t, []string{"alpha", "beta"}, d.Agencies())
+	s, err := store.Get(context.Background(), "alpha")
+	ok(t, err)
+	equals(t, "The First", s.Agency.Title)
+
+	store.err = errors.New("disk full")
+	var events []RefreshEvent
+	d.OnRefresh(func(e RefreshEvent) { events = append(events, e) })
+	err = d.WarmUp(context.Background(), "alpha")
+	assert(t, err != nil && strings.Contains(err.Error(), "disk full"), "expected the store's error, got %v", err)
+	equals(t, 1, len(events))
+	assert(t, events[0].Err != nil, "expected the event to report the store's error")
+}
+
+// routeOnlyStore is a RouteStore that fails whole-snapshot loads.
+type routeOnlyStore struct {
+	memoryStore
+	routes int
+}
+
+func (s *routeOnlyStore) Get(ctx context.Context, agencyTag string) (*AgencySnapshot, error) {
+	return nil, errors.New("whole snapshot loaded")
+}
+
+func (s *routeOnlyStore) Route(ctx context.Context, agencyTag, routeTag string) (RouteConfig, error) {
+	s.routes++
+	return RouteConfig{Tag: routeTag}, nil
+}
+
+func TestDirectoryRoute(t *testing.T) {
+	d := NewDirectory(NewClient(testingClient(t)))
+	ok(t, d.WarmUp(context.Background(), "alpha"))
+	s, _ := d.Snapshot("alpha")
+	rc, err := d.Route(context.Background(), "alpha", s.Routes[0].Tag)
+	ok(t, err)
+	equals(t, s.Routes[0], rc)
+	_, err = d.Route(context.Background(), "alpha", "nope")
+	_, notFound := err.(*NotFoundError)
+	assert(t, notFound, "expected a *NotFoundError, got %v", err)
+
+	store := &routeOnlyStore{memoryStore: memoryStore{snapshots: map[string]*AgencySnapshot{}}}
+	rc, err = NewDirectoryWithStore(NewClient(testingClient(t)), store).Route(context.Background(), "alpha", "1")
+	ok(t, err)
+	equals(t, "1", rc.Tag)
+	equals(t, 1, store.routes)
+}
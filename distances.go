@@ -0,0 +1,117 @@
+package nextbus
+
+import (
+	"sort"
+)
+
+// StopPair is two consecutive stops of a route, in the order it serves them.
+type StopPair struct {
+	RouteTag string
+	From     string
+	To       string
+}
+
+// routeDirection identifies a direction of a route.
+type routeDirection struct {
+	routeTag string
+	dirTag   string
+}
+
+// stopOffsets are the stops of a direction in order with their distance
+// along it.
+type stopOffsets struct {
+	offsets []float64
+	// indexes holds the positions of each stop in offsets; a stop can appear
+	// more than once on a loop.
+	indexes map[string][]int
+}
+
+// DistanceMatrix holds the along-route distances between the stops of an
+// agency's routes, projected once, so that ETA, headway and speed analytics
+// can look them up instead of projecting each stop onto the route paths again.
+type DistanceMatrix struct {
+	pairs      map[StopPair]float64
+	directions map[routeDirection]stopOffsets
+}
+
+// NewDistanceMatrix computes the distances between the stops of every
+// direction of the given routes. A pair of stops served consecutively by
+// more than one direction of a route, as by branches sharing a trunk, keeps
+// the distance of the first.
+func NewDistanceMatrix(routes []RouteConfig) *DistanceMatrix {
+	m := &DistanceMatrix{pairs: map[StopPair]float64{}, directions: map[routeDirection]stopOffsets{}}
+	for _, rc := range routes {
+		for _, dir := range rc.DirList {
+			seq := rc.StopSequence(dir.Tag)
+			d := stopOffsets{offsets: make([]float64, len(seq)), indexes: map[string][]int{}}
+			for i, pos := range seq {
+				d.offsets[i] = pos.Distance
+				d.indexes[pos.Stop.Tag] = append(d.indexes[pos.Stop.Tag], i)
+				if i == 0 {
+					continue
+				}
+				pair := StopPair{rc.Tag, seq[i-1].Stop.Tag, pos.Stop.Tag}
+				if _, seen := m.pairs[pair]; !seen {
+					m.pairs[pair] = pos.Distance - seq[i-1].Distance
+				}
+			}
+			m.directions[routeDirection{rc.Tag, dir.Tag}] = d
+		}
+	}
+	return m
+}
+
+// DistanceMatrix computes the distances between the stops of the snapshot's
+// routes.
+func (s *AgencySnapshot) DistanceMatrix() *DistanceMatrix {
+	return NewDistanceMatrix(s.Routes)
+}
+
+// Distance returns the distance in meters along a route from one stop to the
+// next, or false if the route doesn't serve them consecutively in that
+// order.
+func (m *DistanceMatrix) Distance(routeTag, fromTag, toTag string) (float64, bool) {
+	d, found := m.pairs[StopPair{routeTag, fromTag, toTag}]
+	return d, found
+}
+
+// Between returns the distance in meters along a route direction from one of
+// its stops to a later one, or false if the direction doesn't serve them in
+// that order. On a loop serving a stop twice, the first visit to fromTag and
+// the next visit to toTag after it are used.
+func (m *DistanceMatrix) Between(routeTag, dirTag, fromTag, toTag string) (float64, bool) {
+	d, found := m.directions[routeDirection{routeTag, dirTag}]
+	if !found {
+		return 0, false
+	}
+	from, to := d.indexes[fromTag], d.indexes[toTag]
+	if len(from) == 0 {
+		return 0, false
+	}
+	for _, i := range to {
+		if i > from[0] {
+			return d.offsets[i] - d.offsets[from[0]], true
+		}
+	}
+	return 0, false
+}
+
+// Pairs returns every pair of consecutive stops in the matrix, sorted by
+// route and stop tags.
+func (m *DistanceMatrix) Pairs() []StopPair {
+	pairs := make([]StopPair, 0, len(m.pairs))
+	for pair := range m.pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		a, b := pairs[i], pairs[j]
+		if a.RouteTag != b.RouteTag {
+			return a.RouteTag < b.RouteTag
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		return a.To < b.To
+	})
+	return pairs
+}
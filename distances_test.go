@@ -0,0 +1,34 @@
+package nextbus
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceMatrix(t *testing.T) {
+	routes := testNetwork()
+	// A loop back to b after c.
+	routes[0].DirList = append(routes[0].DirList, Direction{Tag: "1_loop", StopMarkerList: stopMarkers("b", "c", "a", "b")})
+	m := NewDistanceMatrix(routes)
+	equals(t, []StopPair{{"1", "a", "b"}, {"1", "b", "c"}, {"1", "c", "a"}, {"2", "b", "d"}}, m.Pairs())
+
+	ab, found := m.Distance("1", "a", "b")
+	assert(t, found, "expected a distance from a to b")
+	assert(t, math.Abs(ab-556) < 5, "unexpected distance from a to b %v", ab)
+	_, found = m.Distance("1", "b", "a")
+	assert(t, !found, "expected no distance from b to a")
+	_, found = m.Distance("2", "a", "b")
+	assert(t, !found, "expected no distance on route 2")
+
+	ac, found := m.Between("1", "1_out", "a", "c")
+	assert(t, found, "expected a distance from a to c")
+	bc, _ := m.Distance("1", "b", "c")
+	assert(t, math.Abs(ac-ab-bc) < 1e-6, "expected a to c to be a to b plus b to c, got %v", ac)
+	_, found = m.Between("1", "1_out", "c", "a")
+	assert(t, !found, "expected no distance from c back to a")
+
+	// On the loop, a is followed by the second visit to b.
+	loop, found := m.Between("1", "1_loop", "a", "b")
+	assert(t, found, "expected a distance from a to b around the loop")
+	assert(t, math.Abs(loop-ab) < 1, "unexpected distance around the loop %v", loop)
+}
@@ -0,0 +1,54 @@
+package nextbus
+
+// A response whose body has no children, such as <body/>, decodes without
+// error to a value whose list is nil. The Empty methods report these
+// responses, letting callers tell "nothing to report right now" apart from a
+// request or parse that failed, which returns an error instead.
+
+// Empty reports whether the response lists no agencies.
+func (r AgencyResponse) Empty() bool {
+	return len(r.AgencyList) == 0
+}
+
+// Empty reports whether the response lists no routes.
+func (r RouteResponse) Empty() bool {
+	return len(r.RouteList) == 0
+}
+
+// Empty reports whether the response has no route configs.
+func (r RouteConfigResponse) Empty() bool {
+	return len(r.RouteList) == 0
+}
+
+// Empty reports whether no stop in the response has a prediction.
+func (r PredictionResponse) Empty() bool {
+	for _, pd := range r.PredictionDataList {
+		if !pd.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty reports whether there are no predictions for the stop, as when no
+// vehicle is in service on the route. The stop's titles and messages may
+// still be set.
+func (pd PredictionData) Empty() bool {
+	for _, dir := range pd.PredictionDirectionList {
+		if len(dir.PredictionList) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty reports whether the response has no vehicles. A response without a
+// lastTime has a zero LastTime, which VehicleLocationSession ignores.
+func (r LocationResponse) Empty() bool {
+	return len(r.VehicleList) == 0
+}
+
+// Empty reports whether the response has no schedules.
+func (r ScheduleResponse) Empty() bool {
+	return len(r.ScheduleList) == 0
+}
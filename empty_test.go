@@ -0,0 +1,36 @@
+package nextbus
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestEmptyPredictions(t *testing.T) {
+	predictions, err := DecodePredictions(strings.NewReader(fakes[makeURL("predictionsForMultiStops", "a", "alpha", "stops", "1|1123", "stops", "1|1234")]))
+	ok(t, err)
+	equals(t, 2, len(predictions))
+	assert(t, !predictions[0].Empty(), "expected predictions for the first stop")
+	assert(t, predictions[1].Empty(), "expected no predictions for the second stop")
+	equals(t, "Outbound to somewhere", predictions[1].DirTitleBecauseNoPredictions)
+	assert(t, !PredictionResponse{PredictionDataList: predictions}.Empty(), "expected a non-empty response")
+	assert(t, PredictionResponse{PredictionDataList: predictions[1:]}.Empty(), "expected an empty response")
+}
+
+func TestEmptyBodies(t *testing.T) {
+	for _, body := range []string{"<body/>", "<body></body>", `<body copyright="c">` + "\n</body>"} {
+		var fast, slow LocationResponse
+		ok(t, decodeLocationResponse([]byte(body), &fast))
+		ok(t, xml.Unmarshal([]byte(body), &slow))
+		equals(t, slow, fast)
+		assert(t, fast.Empty(), "expected %q to be empty", body)
+		equals(t, "", fast.LastTime.Time)
+
+		var routes RouteConfigResponse
+		ok(t, xml.Unmarshal([]byte(body), &routes))
+		assert(t, routes.Empty(), "expected %q to be empty", body)
+	}
+
+	_, err := DecodeRouteConfig(strings.NewReader(""))
+	assert(t, err != nil, "expected an error for a missing body")
+}
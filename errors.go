@@ -0,0 +1,48 @@
+package nextbus
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+)
+
+// FeedError is an error reported by NextBus in the body of an otherwise
+// successful response.
+type FeedError struct {
+	XMLName     xml.Name `xml:"Error"`
+	ShouldRetry string   `xml:"shouldRetry,attr"`
+	Message     string   `xml:",chardata"`
+}
+
+func (e *FeedError) Error() string {
+	return "nextbus: " + strings.TrimSpace(e.Message)
+}
+
+// Retryable reports whether NextBus indicated that the same request may
+// succeed if tried again later.
+func (e *FeedError) Retryable() bool {
+	return e.ShouldRetry == "true"
+}
+
+// ErrRateLimited matches any *RateLimitError with errors.Is.
+var ErrRateLimited = errors.New("nextbus: rate limited")
+
+// RateLimitError reports that NextBus is throttling or has blocked the client,
+// either with an Error in the response body or with a 403 or 429 status.
+type RateLimitError struct {
+	// RetryAfter is how long NextBus asked the client to wait, or an estimate
+	// if it didn't say. The Client holds back all of its requests for this
+	// long.
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *RateLimitError) Error() string {
+	return "nextbus: rate limited, retry after " + e.RetryAfter.String() + ": " + e.Message
+}
+
+// Is reports whether target is ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
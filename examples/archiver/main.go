@@ -0,0 +1,111 @@
+// Command archiver records when vehicles arrive at a set of stops, writing
+// each arrival as a CSV row as it is detected. When interrupted, it prints an
+// on-time performance report for the arrivals it saw against the routes'
+// schedules. Run it with -sim to use a simulated agency instead of the live
+// feed; the simulation has no schedules, so its report is empty.
+//
+//	go run ./examples/archiver -agency sf-muni -stops 'N|5205,N|4448' > arrivals.csv
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dinedal/nextbus"
+	"github.com/dinedal/nextbus/nextbustest"
+	"github.com/dinedal/nextbus/report"
+)
+
+// csvStore writes arrivals to a CSV file as they are saved and keeps them in
+// memory for the report.
+type csvStore struct {
+	nextbus.MemoryArrivalStore
+
+	mu sync.Mutex
+	w  *csv.Writer
+}
+
+func (s *csvStore) Save(records []nextbus.ArrivalRecord) error {
+	s.mu.Lock()
+	for _, r := range records {
+		s.w.Write([]string{r.Time.Format(time.RFC3339), r.RouteTag, r.StopTag, r.DirTag, r.Vehicle, r.TripTag})
+	}
+	s.w.Flush()
+	err := s.w.Error()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.MemoryArrivalStore.Save(records)
+}
+
+func parseStops(s string) ([]nextbus.RouteStop, []string) {
+	var stops []nextbus.RouteStop
+	var routes []string
+	seen := map[string]bool{}
+	for _, rs := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(rs), "|", 2)
+		if len(parts) != 2 {
+			log.Fatalf("invalid stop %q: want route|stop", rs)
+		}
+		stops = append(stops, nextbus.RouteStop{RouteTag: parts[0], StopTag: parts[1]})
+		if !seen[parts[0]] {
+			seen[parts[0]] = true
+			routes = append(routes, parts[0])
+		}
+	}
+	return stops, routes
+}
+
+func main() {
+	agency := flag.String("agency", "sf-muni", "agency tag")
+	stopList := flag.String("stops", "N|5205", "comma separated route|stop pairs")
+	interval := flag.Duration("interval", 30*time.Second, "time between polls")
+	sim := flag.Bool("sim", false, "use a simulated agency; try -stops '1|1_5,2|2_5'")
+	flag.Parse()
+
+	httpClient := http.DefaultClient
+	if *sim {
+		server := nextbustest.NewServer(nextbustest.Generate(nextbustest.Config{Tag: *agency}))
+		defer server.Close()
+		httpClient = server.Client()
+	}
+	nb := nextbus.NewClient(httpClient, nextbus.WithRetries(2, time.Second), nextbus.WithRateLimit(time.Second))
+	stops, routes := parseStops(*stopList)
+
+	store := &csvStore{w: csv.NewWriter(os.Stdout)}
+	archiver := nextbus.NewArchiver(store)
+	watcher := nextbus.NewPredictionWatcher(nb, *agency, stops...)
+	watcher.OnUpdate = func(predictions []nextbus.PredictionData) {
+		if err := archiver.ObservePredictions(time.Now(), predictions); err != nil {
+			log.Print(err)
+		}
+	}
+	watcher.OnError = func(err error) { log.Print(err) }
+
+	start := time.Now()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	watcher.Run(ctx, *interval)
+
+	arrivals, _ := store.Arrivals(start, time.Now())
+	var schedules []nextbus.Schedule
+	if !*sim {
+		var err error
+		if schedules, err = nb.GetSchedules(*agency, routes...); err != nil {
+			log.Fatal(err)
+		}
+	}
+	r := report.Generate(arrivals, nextbus.NewTimetables(schedules), start, time.Now(), report.Options{})
+	if err := r.WriteCSV(os.Stderr); err != nil {
+		log.Fatal(err)
+	}
+}
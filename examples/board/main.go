@@ -0,0 +1,75 @@
+// Command board serves a departure board for a set of stops, with smoothed
+// countdowns, and the same departures as JSON. Run it with -sim to use a
+// simulated agency instead of the live feed.
+//
+//	go run ./examples/board -agency sf-muni -stops 'N|5205,J|4006'
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/dinedal/nextbus"
+	"github.com/dinedal/nextbus/nextbustest"
+)
+
+func parseStops(s string) []nextbus.RouteStop {
+	var stops []nextbus.RouteStop
+	for _, rs := range strings.Split(s, ",") {
+		parts := strings.SplitN(strings.TrimSpace(rs), "|", 2)
+		if len(parts) != 2 {
+			log.Fatalf("invalid stop %q: want route|stop", rs)
+		}
+		stops = append(stops, nextbus.RouteStop{RouteTag: parts[0], StopTag: parts[1]})
+	}
+	return stops
+}
+
+func main() {
+	agency := flag.String("agency", "sf-muni", "agency tag")
+	stopList := flag.String("stops", "N|5205", "comma separated route|stop pairs")
+	title := flag.String("title", "Departures", "board title")
+	addr := flag.String("addr", "localhost:8080", "address to serve on")
+	sim := flag.Bool("sim", false, "use a simulated agency; try -stops '1|1_5,2|2_5'")
+	flag.Parse()
+
+	httpClient := http.DefaultClient
+	if *sim {
+		server := nextbustest.NewServer(nextbustest.Generate(nextbustest.Config{Tag: *agency}))
+		defer server.Close()
+		httpClient = server.Client()
+	}
+	nb := nextbus.NewClient(httpClient, nextbus.WithRetries(2, time.Second), nextbus.WithRateLimit(time.Second))
+
+	watcher := nextbus.NewPredictionWatcher(nb, *agency, parseStops(*stopList)...)
+	watcher.Smoother = &nextbus.Smoother{}
+	watcher.OnError = func(err error) { log.Print(err) }
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go watcher.RunStrategy(ctx, nextbus.AdaptiveInterval{})
+
+	http.Handle("/", nextbus.NewDepartureBoard(watcher, nextbus.BoardConfig{Title: *title, Refresh: 30 * time.Second, Limit: 3}))
+	http.HandleFunc("/departures.json", func(w http.ResponseWriter, r *http.Request) {
+		predictions, _ := watcher.Predictions()
+		w.Header().Set("Content-Type", "application/json")
+		if err := nextbus.WriteDeparturesJSON(w, nextbus.NewStopDepartures(predictions, time.Now())); err != nil {
+			log.Print(err)
+		}
+	})
+	server := &http.Server{Addr: *addr}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	log.Printf("serving the board on http://%s/", *addr)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,162 @@
+// Command livemap polls an agency's vehicle locations and serves them as a
+// GeoJSON FeatureCollection for a web map to draw, along with the route
+// paths. Run it with -sim to use a simulated agency instead of the live feed.
+//
+//	go run ./examples/livemap -agency sf-muni -route N
+//	curl localhost:8080/vehicles.geojson
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dinedal/nextbus"
+	"github.com/dinedal/nextbus/nextbustest"
+)
+
+type feature struct {
+	Type       string                 `json:"type"`
+	Geometry   geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+func vehicleFeatures(vehicles map[string]nextbus.VehicleLocation) featureCollection {
+	fc := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+	for _, v := range vehicles {
+		p, located := v.LatLon()
+		if !located {
+			continue
+		}
+		heading, _ := strconv.Atoi(v.Heading)
+		fc.Features = append(fc.Features, feature{
+			Type:     "Feature",
+			Geometry: geometry{Type: "Point", Coordinates: []float64{p.Lon, p.Lat}},
+			Properties: map[string]interface{}{
+				"id":      v.ID,
+				"route":   v.RouteTag,
+				"dirTag":  v.DirTag,
+				"heading": heading,
+			},
+		})
+	}
+	return fc
+}
+
+func pathFeatures(configs []nextbus.RouteConfig) featureCollection {
+	fc := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+	for _, rc := range configs {
+		for _, path := range nextbus.MergePaths(rc.PathList) {
+			var line [][]float64
+			for _, pt := range path.PointList {
+				if p, located := pt.LatLon(); located {
+					line = append(line, []float64{p.Lon, p.Lat})
+				}
+			}
+			fc.Features = append(fc.Features, feature{
+				Type:       "Feature",
+				Geometry:   geometry{Type: "LineString", Coordinates: line},
+				Properties: map[string]interface{}{"route": rc.Tag, "color": "#" + rc.Color},
+			})
+		}
+	}
+	return fc
+}
+
+func serveJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/geo+json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Print(err)
+	}
+}
+
+func main() {
+	agency := flag.String("agency", "sf-muni", "agency tag")
+	route := flag.String("route", "", "route tag; empty for every route")
+	interval := flag.Duration("interval", 15*time.Second, "time between polls")
+	addr := flag.String("addr", "localhost:8080", "address to serve on")
+	sim := flag.Bool("sim", false, "use a simulated agency")
+	flag.Parse()
+
+	httpClient := http.DefaultClient
+	if *sim {
+		server := nextbustest.NewServer(nextbustest.Generate(nextbustest.Config{Tag: *agency}))
+		defer server.Close()
+		httpClient = server.Client()
+	}
+	nb := nextbus.NewClient(httpClient, nextbus.WithRateLimit(time.Second))
+
+	var configParams []nextbus.RouteConfigParam
+	if *route != "" {
+		configParams = append(configParams, nextbus.RouteConfigTag(*route))
+	}
+	configs, err := nb.GetRouteConfig(*agency, configParams...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The session only returns vehicles that have reported since the last
+	// poll, so keep the latest location of each.
+	var mu sync.Mutex
+	vehicles := map[string]nextbus.VehicleLocation{}
+	session := nextbus.NewVehicleLocationSession(nb, *agency, *route)
+	session.Bus = &nextbus.Bus{}
+	session.Bus.Subscribe(func(e nextbus.BusEvent) {
+		mu.Lock()
+		for _, v := range e.Payload.(*nextbus.LocationResponse).VehicleList {
+			vehicles[v.ID] = v
+		}
+		mu.Unlock()
+	}, nextbus.TopicVehicles)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		for {
+			if _, err := session.Next(); err != nil {
+				log.Print(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(*interval):
+			}
+		}
+	}()
+
+	http.HandleFunc("/vehicles.geojson", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fc := vehicleFeatures(vehicles)
+		mu.Unlock()
+		serveJSON(w, fc)
+	})
+	http.HandleFunc("/paths.geojson", func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(w, pathFeatures(configs))
+	})
+	server := &http.Server{Addr: *addr}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	log.Printf("serving %s vehicles on http://%s/vehicles.geojson", *agency, *addr)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
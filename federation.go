@@ -0,0 +1,185 @@
+package nextbus
+
+import (
+	"context"
+	"sync"
+)
+
+// Feed is the set of feed commands, implemented by Client for one feed server
+// and by Federation across several. Code written against Feed works with
+// either.
+type Feed interface {
+	GetAgencyListContext(ctx context.Context) ([]Agency, error)
+	GetRouteListContext(ctx context.Context, agencyTag string) ([]Route, error)
+	GetRouteConfigContext(ctx context.Context, agencyTag string, configParams ...RouteConfigParam) ([]RouteConfig, error)
+	GetPredictionsContext(ctx context.Context, agencyTag, routeTag, stopTag string) ([]PredictionData, error)
+	GetStopPredictionsContext(ctx context.Context, agencyTag, stopID string) ([]PredictionData, error)
+	GetPredictionsForMultiStopsContext(ctx context.Context, agencyTag string, params ...PredReqParam) ([]PredictionData, error)
+	GetVehicleLocationsContext(ctx context.Context, agencyTag string, configParams ...VehicleLocationParam) (*LocationResponse, error)
+	GetScheduleContext(ctx context.Context, agencyTag, routeTag string) ([]Schedule, error)
+}
+
+var (
+	_ Feed = (*Client)(nil)
+	_ Feed = (*Federation)(nil)
+)
+
+// Federation is a Feed spanning agencies served by different feed servers,
+// as when an agency runs its own NextBus server. Each agency's requests go to
+// the Client it was added with, which carries the server's URL, credentials
+// and rate limit:
+//
+//	f := nextbus.NewFederation(nextbus.NewRecommendedClient())
+//	f.Add("metro", nextbus.NewClient(nil,
+//		nextbus.WithFeedURL("https://nextbus.metro.example/service/publicXMLFeed"),
+//		nextbus.WithHeader("Authorization", "Bearer "+token)))
+//
+// Requests for other agencies go to the default client.
+type Federation struct {
+	defaultClient *Client
+
+	mu      sync.RWMutex
+	clients map[string]*Client
+	order   []*Client
+}
+
+// NewFederation creates a Federation sending requests for agencies that
+// weren't added to defaultClient. A nil defaultClient makes those agencies
+// unknown.
+func NewFederation(defaultClient *Client) *Federation {
+	return &Federation{defaultClient: defaultClient, clients: map[string]*Client{}}
+}
+
+// Add sends the requests for an agency to c, replacing any client it was
+// added with before.
+func (f *Federation) Add(agencyTag string, c *Client) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clients[agencyTag] = c
+	if !containsClient(f.order, c) {
+		f.order = append(f.order, c)
+	}
+}
+
+func containsClient(clients []*Client, c *Client) bool {
+	for _, known := range clients {
+		if known == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Client returns the client serving an agency. An agency without one is
+// reported as a *NotFoundError.
+func (f *Federation) Client(agencyTag string) (*Client, error) {
+	f.mu.RLock()
+	c, added := f.clients[agencyTag]
+	f.mu.RUnlock()
+	if added {
+		return c, nil
+	}
+	if f.defaultClient == nil {
+		return nil, &NotFoundError{Kind: "agency", AgencyTag: agencyTag}
+	}
+	return f.defaultClient, nil
+}
+
+// GetAgencyListContext lists the agencies of every client: those added to
+// each client, as it lists them, followed by the agencies of the default
+// client that weren't added elsewhere. The first error is returned.
+func (f *Federation) GetAgencyListContext(ctx context.Context) ([]Agency, error) {
+	f.mu.RLock()
+	clients := map[string]*Client{}
+	for tag, c := range f.clients {
+		clients[tag] = c
+	}
+	order := append([]*Client(nil), f.order...)
+	f.mu.RUnlock()
+	if f.defaultClient != nil && !containsClient(order, f.defaultClient) {
+		order = append(order, f.defaultClient)
+	}
+
+	var result []Agency
+	for _, c := range order {
+		agencies, err := c.GetAgencyListContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range agencies {
+			if owner, added := clients[a.Tag]; (added && owner == c) || (!added && c == f.defaultClient) {
+				result = append(result, a)
+			}
+		}
+	}
+	return result, nil
+}
+
+// GetRouteListContext fetches the routes of an agency from its client.
+func (f *Federation) GetRouteListContext(ctx context.Context, agencyTag string) ([]Route, error) {
+	c, err := f.Client(agencyTag)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetRouteListContext(ctx, agencyTag)
+}
+
+// GetRouteConfigContext fetches the route configs of an agency from its
+// client.
+func (f *Federation) GetRouteConfigContext(ctx context.Context, agencyTag string, configParams ...RouteConfigParam) ([]RouteConfig, error) {
+	c, err := f.Client(agencyTag)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetRouteConfigContext(ctx, agencyTag, configParams...)
+}
+
+// GetPredictionsContext fetches the predictions for a stop from its agency's
+// client.
+func (f *Federation) GetPredictionsContext(ctx context.Context, agencyTag, routeTag, stopTag string) ([]PredictionData, error) {
+	c, err := f.Client(agencyTag)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetPredictionsContext(ctx, agencyTag, routeTag, stopTag)
+}
+
+// GetStopPredictionsContext fetches the predictions for a stop ID from its
+// agency's client.
+func (f *Federation) GetStopPredictionsContext(ctx context.Context, agencyTag, stopID string) ([]PredictionData, error) {
+	c, err := f.Client(agencyTag)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetStopPredictionsContext(ctx, agencyTag, stopID)
+}
+
+// GetPredictionsForMultiStopsContext fetches the predictions for several
+// stops from their agency's client.
+func (f *Federation) GetPredictionsForMultiStopsContext(ctx context.Context, agencyTag string, params ...PredReqParam) ([]PredictionData, error) {
+	c, err := f.Client(agencyTag)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetPredictionsForMultiStopsContext(ctx, agencyTag, params...)
+}
+
+// GetVehicleLocationsContext fetches the vehicle locations of an agency from
+// its client.
+func (f *Federation) GetVehicleLocationsContext(ctx context.Context, agencyTag string, configParams ...VehicleLocationParam) (*LocationResponse, error) {
+	c, err := f.Client(agencyTag)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetVehicleLocationsContext(ctx, agencyTag, configParams...)
+}
+
+// GetScheduleContext fetches the schedule of a route from its agency's
+// client.
+func (f *Federation) GetScheduleContext(ctx context.Context, agencyTag, routeTag string) ([]Schedule, error) {
+	c, err := f.Client(agencyTag)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetScheduleContext(ctx, agencyTag, routeTag)
+}
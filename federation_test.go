@@ -0,0 +1,42 @@
+package nextbus
+
+import (
+	"context"
+	"testing"
+)
+
+// feedServer returns a feed server listing agencies.
+func feedServer(agencies string) *fakeFeed {
+	return &fakeFeed{Bodies: map[string]string{
+		"agencyList": `<body>` + agencies + `</body>`,
+		"":           `<body><route tag="1" title="1-first"/></body>`,
+	}}
+}
+
+func TestFederation(t *testing.T) {
+	public := feedServer(`<agency tag="sf-muni"/><agency tag="metro" title="Stale listing"/>`)
+	metro := feedServer(`<agency tag="metro" title="Metro"/><agency tag="other"/>`)
+	f := NewFederation(NewClient(public.Client()))
+	f.Add("metro", NewClient(metro.Client(),
+		WithFeedURL("https://nextbus.metro.example/feed"), WithHeader("Authorization", "Bearer token")))
+
+	_, err := f.GetRouteListContext(context.Background(), "metro")
+	ok(t, err)
+	equals(t, 1, metro.Count())
+	req := metro.Requests()[0]
+	equals(t, "https://nextbus.metro.example/feed?command=routeList&a=metro", req.URL.String())
+	equals(t, "Bearer token", req.Header.Get("Authorization"))
+	_, err = f.GetRouteListContext(context.Background(), "sf-muni")
+	ok(t, err)
+	equals(t, 1, public.Count())
+
+	agencies, err := f.GetAgencyListContext(context.Background())
+	ok(t, err)
+	equals(t, 2, len(agencies))
+	equals(t, "Metro", agencies[0].Title)
+	equals(t, "sf-muni", agencies[1].Tag)
+
+	_, err = NewFederation(nil).GetScheduleContext(context.Background(), "sf-muni", "N")
+	_, notFound := err.(*NotFoundError)
+	assert(t, notFound, "expected a *NotFoundError, got %v", err)
+}
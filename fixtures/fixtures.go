@@ -0,0 +1,99 @@
+// Package fixtures embeds recorded NextBus feed responses, with identifying
+// details removed, for use in tests and examples that shouldn't depend on the
+// live feed. The responses are small but have the shape of the real ones,
+// including their quirks such as stops without predictions.
+//
+// The helpers panic if there is no fixture for an agency, as that is a
+// mistake in the calling test rather than a condition to handle.
+package fixtures
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	"github.com/dinedal/nextbus"
+)
+
+//go:embed data
+var data embed.FS
+
+// Raw returns the recorded response to a feed command for an agency. The
+// agencyList response has no agency; pass an empty agencyTag for it.
+func Raw(agencyTag, command string) ([]byte, error) {
+	b, err := data.ReadFile(path.Join("data", agencyTag, command+".xml"))
+	if err != nil {
+		return nil, fmt.Errorf("could not read fixture: %v", err)
+	}
+	return b, nil
+}
+
+// Agencies returns the tags of the agencies that have fixtures.
+func Agencies() []string {
+	entries, _ := fs.ReadDir(data, "data")
+	var tags []string
+	for _, e := range entries {
+		if e.IsDir() {
+			tags = append(tags, e.Name())
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func must[T any](agencyTag, command string, decode func(b []byte) (T, error)) T {
+	b, err := Raw(agencyTag, command)
+	if err != nil {
+		panic(err)
+	}
+	v, err := decode(b)
+	if err != nil {
+		panic(fmt.Sprintf("fixtures: %s %s: %v", agencyTag, command, err))
+	}
+	return v
+}
+
+// AgencyList returns the recorded agency list.
+func AgencyList() []nextbus.Agency {
+	return must("", "agencyList", func(b []byte) ([]nextbus.Agency, error) {
+		return nextbus.DecodeAgencyList(bytes.NewReader(b))
+	})
+}
+
+// RouteList returns the recorded route list of an agency.
+func RouteList(agencyTag string) []nextbus.Route {
+	return must(agencyTag, "routeList", func(b []byte) ([]nextbus.Route, error) {
+		return nextbus.DecodeRouteList(bytes.NewReader(b))
+	})
+}
+
+// RouteConfig returns the recorded route configs of every route of an agency.
+func RouteConfig(agencyTag string) []nextbus.RouteConfig {
+	return must(agencyTag, "routeConfig", func(b []byte) ([]nextbus.RouteConfig, error) {
+		return nextbus.DecodeRouteConfig(bytes.NewReader(b))
+	})
+}
+
+// Predictions returns recorded predictions for some stops of an agency.
+func Predictions(agencyTag string) []nextbus.PredictionData {
+	return must(agencyTag, "predictionsForMultiStops", func(b []byte) ([]nextbus.PredictionData, error) {
+		return nextbus.DecodePredictions(bytes.NewReader(b))
+	})
+}
+
+// VehicleLocations returns recorded vehicle locations of an agency.
+func VehicleLocations(agencyTag string) *nextbus.LocationResponse {
+	return must(agencyTag, "vehicleLocations", func(b []byte) (*nextbus.LocationResponse, error) {
+		return nextbus.DecodeVehicleLocations(bytes.NewReader(b))
+	})
+}
+
+// Schedule returns the recorded schedule of a route of an agency.
+func Schedule(agencyTag string) []nextbus.Schedule {
+	return must(agencyTag, "schedule", func(b []byte) ([]nextbus.Schedule, error) {
+		return nextbus.DecodeSchedule(bytes.NewReader(b))
+	})
+}
@@ -0,0 +1,43 @@
+package fixtures
+
+import (
+	"testing"
+)
+
+func TestFixtures(t *testing.T) {
+	if tags := Agencies(); len(tags) != 1 || tags[0] != "sf-muni" {
+		t.Errorf("agencies: got %v", tags)
+	}
+	if agencies := AgencyList(); len(agencies) != 2 || agencies[1].Tag != "sf-muni" {
+		t.Errorf("agency list: got %+v", agencies)
+	}
+	if routes := RouteList("sf-muni"); len(routes) != 2 {
+		t.Errorf("route list: got %+v", routes)
+	}
+	configs := RouteConfig("sf-muni")
+	if len(configs) != 2 || configs[1].Tag != "N" || configs[1].StopList[0].Title != "Duboce St & Noe St" {
+		t.Errorf("route config: got %+v", configs)
+	}
+	predictions := Predictions("sf-muni")
+	if len(predictions) != 2 || predictions[0].Empty() || !predictions[1].Empty() {
+		t.Errorf("predictions: got %+v", predictions)
+	}
+	if locations := VehicleLocations("sf-muni"); len(locations.VehicleList) != 3 || locations.LastTime.Time != "1487276900000" {
+		t.Errorf("vehicle locations: got %+v", locations)
+	}
+	if schedules := Schedule("sf-muni"); len(schedules) != 1 || len(schedules[0].BlockList) != 3 {
+		t.Errorf("schedule: got %+v", schedules)
+	}
+}
+
+func TestMissingFixture(t *testing.T) {
+	if _, err := Raw("nope", "routeConfig"); err == nil {
+		t.Error("expected an error for a missing agency")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a missing agency")
+		}
+	}()
+	RouteConfig("nope")
+}
@@ -0,0 +1,98 @@
+package nextbus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The message keys used by Formatter. Translations registered in a message
+// catalog under these keys replace the English text.
+const (
+	MsgArriving      = "Arriving"
+	MsgDeparting     = "Departing"
+	MsgMinutes       = "%d min"
+	MsgNoPredictions = "No predictions"
+)
+
+// Printer renders a message key with arguments in some language. The key is
+// a format string in the style of fmt. A golang.org/x/text/message Printer,
+// backed by a message catalog, can be used by adapting it with a PrinterFunc:
+//
+//	p := message.NewPrinter(language.Spanish)
+//	f := nextbus.Formatter{Printer: nextbus.PrinterFunc(func(key string, args []interface{}) string {
+//		return p.Sprintf(key, args...)
+//	})}
+type Printer interface {
+	Message(key string, args []interface{}) string
+}
+
+// PrinterFunc adapts a function to a Printer.
+type PrinterFunc func(key string, args []interface{}) string
+
+// Message calls f.
+func (f PrinterFunc) Message(key string, args []interface{}) string {
+	return f(key, args)
+}
+
+// englishPrinter uses the message keys as English format strings.
+type englishPrinter struct{}
+
+func (englishPrinter) Message(key string, args []interface{}) string {
+	return fmt.Sprintf(key, args...)
+}
+
+// Formatter renders prediction data as user-facing strings. The zero value
+// formats in English.
+type Formatter struct {
+	Printer Printer
+	// Titles, if set, cleans the stop titles rendered by StopTitle.
+	Titles *TitleCleaner
+}
+
+func (f Formatter) printer() Printer {
+	if f.Printer == nil {
+		return englishPrinter{}
+	}
+	return f.Printer
+}
+
+// Minutes formats a countdown of n minutes, such as "3 min".
+func (f Formatter) Minutes(n int) string {
+	return f.printer().Message(MsgMinutes, []interface{}{n})
+}
+
+// Countdown formats the time until a prediction, using "Arriving" or
+// "Departing" once it is less than a minute away.
+func (f Formatter) Countdown(p Prediction) string {
+	minutes, err := strconv.Atoi(p.Minutes)
+	if err != nil {
+		return f.printer().Message(MsgNoPredictions, nil)
+	}
+	if minutes <= 0 {
+		if p.IsDeparture == "true" {
+			return f.printer().Message(MsgDeparting, nil)
+		}
+		return f.printer().Message(MsgArriving, nil)
+	}
+	return f.Minutes(minutes)
+}
+
+// Text translates agency-provided text such as a direction title ("Inbound")
+// if the Printer has a translation for it, and returns it unchanged
+// otherwise. Since keys are format strings, the text is looked up with any
+// "%" doubled, so "50% Off" is translated under the key "50%% Off".
+func (f Formatter) Text(s string) string {
+	if f.Printer == nil {
+		return s
+	}
+	return f.Printer.Message(strings.ReplaceAll(s, "%", "%%"), nil)
+}
+
+// StopTitle renders an agency's stop title, cleaned by Titles if it is set.
+func (f Formatter) StopTitle(s string) string {
+	if f.Titles == nil {
+		return s
+	}
+	return f.Titles.Clean(s)
+}
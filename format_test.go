@@ -0,0 +1,37 @@
+package nextbus
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFormatterEnglish(t *testing.T) {
+	var f Formatter
+	equals(t, "3 min", f.Countdown(Prediction{Minutes: "3"}))
+	equals(t, "1 min", f.Countdown(Prediction{Minutes: "1"}))
+	equals(t, "Arriving", f.Countdown(Prediction{Minutes: "0"}))
+	equals(t, "Departing", f.Countdown(Prediction{Minutes: "0", IsDeparture: "true"}))
+	equals(t, "No predictions", f.Countdown(Prediction{}))
+	equals(t, "Inbound", f.Text("Inbound"))
+}
+
+func TestFormatterCatalog(t *testing.T) {
+	catalog := map[string]string{
+		MsgArriving: "Llegando",
+		MsgMinutes:  "%d min.",
+		"Inbound":   "Hacia el centro",
+	}
+	f := Formatter{Printer: PrinterFunc(func(key string, args []interface{}) string {
+		if translated, found := catalog[key]; found {
+			key = translated
+		}
+		return fmt.Sprintf(key, args...)
+	})}
+
+	equals(t, "Llegando", f.Countdown(Prediction{Minutes: "0"}))
+	equals(t, "7 min.", f.Countdown(Prediction{Minutes: "7"}))
+	equals(t, "Hacia el centro", f.Text("Inbound"))
+	equals(t, "Outbound", f.Text("Outbound"))
+	equals(t, "Route 5% Slower", f.Text("Route 5% Slower"))
+	equals(t, "100%d Electric", f.Text("100%d Electric"))
+}
@@ -0,0 +1,175 @@
+package nextbus
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TimeBand is a named period of the service day used to group scheduled
+// trips, such as the morning peak.
+type TimeBand struct {
+	Name string
+	// Start and End bound the band as offsets from midnight of the service
+	// day. End is exclusive.
+	Start time.Duration
+	End   time.Duration
+	// ServiceClasses restricts the band to schedules with one of these service
+	// classes. An empty list matches every schedule.
+	ServiceClasses []string
+}
+
+func (b TimeBand) matches(serviceClass string, t time.Duration) bool {
+	if t < b.Start || t >= b.End {
+		return false
+	}
+	if len(b.ServiceClasses) == 0 {
+		return true
+	}
+	for _, sc := range b.ServiceClasses {
+		if sc == serviceClass {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultTimeBands splits weekday service into peak and off-peak and treats
+// weekends as a single band. The first matching band is used for each trip.
+var DefaultTimeBands = []TimeBand{
+	{"peak", 6 * time.Hour, 9 * time.Hour, []string{"wkd"}},
+	{"peak", 15 * time.Hour, 19 * time.Hour, []string{"wkd"}},
+	{"offpeak", 0, 30 * time.Hour, []string{"wkd"}},
+	{"weekend", 0, 30 * time.Hour, []string{"sat", "sun"}},
+}
+
+// Frequency summarizes how often a route runs in one direction during one
+// time band.
+type Frequency struct {
+	RouteTag     string
+	Direction    string
+	ServiceClass string
+	Band         string
+	// Trips is the number of scheduled trips departing in the band.
+	Trips int
+	// The headways between consecutive departures. They are zero when there
+	// are fewer than two trips in the band.
+	AverageHeadway time.Duration
+	MinHeadway     time.Duration
+	MaxHeadway     time.Duration
+}
+
+// FrequencyProfile is the service frequency of a set of routes.
+type FrequencyProfile struct {
+	Frequencies []Frequency
+}
+
+// Get returns the frequency of a route in a direction during the named band.
+// If the band covers several service classes the first one found is returned.
+func (p FrequencyProfile) Get(routeTag, direction, band string) (Frequency, bool) {
+	for _, f := range p.Frequencies {
+		if f.RouteTag == routeTag && f.Direction == direction && f.Band == band {
+			return f, true
+		}
+	}
+	return Frequency{}, false
+}
+
+// NewFrequencyProfile computes headways from schedules. Each trip is timed at
+// the schedule's busiest timepoint and assigned to the first of bands that
+// matches it; trips matching no band are ignored.
+func NewFrequencyProfile(schedules []Schedule, bands []TimeBand) FrequencyProfile {
+	type key struct{ route, direction, serviceClass, band string }
+	type departure struct {
+		t    time.Duration
+		band int
+	}
+	departures := map[key][]departure{}
+	var order []key
+	for _, s := range schedules {
+		ref := referenceTimepoint(s)
+		for _, block := range s.BlockList {
+			t, found := scheduledTime(block, ref)
+			if !found {
+				continue
+			}
+			for i, b := range bands {
+				if !b.matches(s.ServiceClass, t) {
+					continue
+				}
+				k := key{s.Tag, s.Direction, s.ServiceClass, b.Name}
+				if _, seen := departures[k]; !seen {
+					order = append(order, k)
+				}
+				departures[k] = append(departures[k], departure{t, i})
+				break
+			}
+		}
+	}
+
+	var profile FrequencyProfile
+	for _, k := range order {
+		times := departures[k]
+		sort.Slice(times, func(i, j int) bool { return times[i].t < times[j].t })
+		f := Frequency{RouteTag: k.route, Direction: k.direction, ServiceClass: k.serviceClass, Band: k.band, Trips: len(times)}
+		// A name may be shared by several bands, like the two daily peaks, so
+		// only gaps between departures in the same band are headways.
+		var total time.Duration
+		gaps := 0
+		for i := 1; i < len(times); i++ {
+			if times[i].band != times[i-1].band {
+				continue
+			}
+			gap := times[i].t - times[i-1].t
+			if gaps == 0 || gap < f.MinHeadway {
+				f.MinHeadway = gap
+			}
+			if gap > f.MaxHeadway {
+				f.MaxHeadway = gap
+			}
+			total += gap
+			gaps++
+		}
+		if gaps != 0 {
+			f.AverageHeadway = total / time.Duration(gaps)
+		}
+		profile.Frequencies = append(profile.Frequencies, f)
+	}
+	return profile
+}
+
+// referenceTimepoint returns the tag of the header stop with the most
+// scheduled times.
+func referenceTimepoint(s Schedule) string {
+	counts := map[string]int{}
+	for _, block := range s.BlockList {
+		for _, stop := range block.StopList {
+			if ms, err := strconv.ParseInt(stop.EpochTime, 10, 64); err == nil && ms >= 0 {
+				counts[stop.Tag]++
+			}
+		}
+	}
+	best, bestCount := "", 0
+	for _, h := range s.Header.StopList {
+		if counts[h.Tag] > bestCount {
+			best, bestCount = h.Tag, counts[h.Tag]
+		}
+	}
+	return best
+}
+
+// scheduledTime returns the time a block reaches stopTag, as an offset from
+// midnight.
+func scheduledTime(block ScheduleBlock, stopTag string) (time.Duration, bool) {
+	for _, stop := range block.StopList {
+		if stop.Tag != stopTag {
+			continue
+		}
+		ms, err := strconv.ParseInt(stop.EpochTime, 10, 64)
+		if err != nil || ms < 0 {
+			return 0, false
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	return 0, false
+}
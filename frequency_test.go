@@ -0,0 +1,47 @@
+package nextbus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetSchedule(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	found, err := nb.GetSchedule("alpha", "1")
+	ok(t, err)
+
+	equals(t, 1, len(found))
+	equals(t, "wkd", found[0].ServiceClass)
+	equals(t, "Outbound", found[0].Direction)
+	equals(t, ScheduleHeaderStop{xmlName("stop"), "1234", "Second stop"}, found[0].Header.StopList[1])
+	equals(t, 5, len(found[0].BlockList))
+	equals(t, ScheduleStop{xmlName("stop"), "1123", "-1", "--"}, found[0].BlockList[2].StopList[0])
+}
+
+func TestNewFrequencyProfile(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	schedules, err := nb.GetSchedule("alpha", "1")
+	ok(t, err)
+
+	profile := NewFrequencyProfile(schedules, DefaultTimeBands)
+	peak, found := profile.Get("1", "Outbound", "peak")
+	assert(t, found, "expected a peak frequency")
+	equals(t, Frequency{
+		RouteTag:       "1",
+		Direction:      "Outbound",
+		ServiceClass:   "wkd",
+		Band:           "peak",
+		Trips:          4,
+		AverageHeadway: 10 * time.Minute,
+		MinHeadway:     10 * time.Minute,
+		MaxHeadway:     10 * time.Minute,
+	}, peak)
+
+	offpeak, found := profile.Get("1", "Outbound", "offpeak")
+	assert(t, found, "expected an offpeak frequency")
+	equals(t, 1, offpeak.Trips)
+	equals(t, time.Duration(0), offpeak.AverageHeadway)
+
+	_, found = profile.Get("1", "Outbound", "weekend")
+	assert(t, !found, "did not expect a weekend frequency")
+}
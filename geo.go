@@ -0,0 +1,111 @@
+package nextbus
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/dinedal/nextbus/geo"
+)
+
+// earthRadius is the mean radius of the Earth in meters.
+const earthRadius = geo.EarthRadius
+
+// haversine returns the great-circle distance in meters between two points
+// given in degrees.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	return geo.Distance(geo.LatLon{Lat: lat1, Lon: lon1}, geo.LatLon{Lat: lat2, Lon: lon2})
+}
+
+// parseLatLon parses a latitude and longitude as reported by NextBus.
+func parseLatLon(lat, lon string) (float64, float64, bool) {
+	la, latErr := strconv.ParseFloat(lat, 64)
+	lo, lonErr := strconv.ParseFloat(lon, 64)
+	return la, lo, latErr == nil && lonErr == nil
+}
+
+// stopDistance returns the distance in meters between two stops, or false if
+// either has no usable location.
+func stopDistance(a, b Stop) (float64, bool) {
+	lat1, lon1, ok1 := parseLatLon(a.Lat, a.Lon)
+	lat2, lon2, ok2 := parseLatLon(b.Lat, b.Lon)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	return haversine(lat1, lon1, lat2, lon2), true
+}
+
+// xy is a point in meters on a local flat projection.
+type xy struct{ x, y float64 }
+
+// planar is an equirectangular projection around a reference point. It is
+// accurate enough for distances within a single city.
+type planar struct {
+	lat0, lon0 float64
+	kx, ky     float64
+}
+
+func newPlanar(lat0, lon0 float64) planar {
+	ky := earthRadius * math.Pi / 180
+	return planar{lat0, lon0, ky * math.Cos(lat0*math.Pi/180), ky}
+}
+
+func (p planar) toXY(lat, lon float64) xy {
+	return xy{(lon - p.lon0) * p.kx, (lat - p.lat0) * p.ky}
+}
+
+// polyline is a projected line with the cumulative length at each vertex.
+type polyline struct {
+	pts []xy
+	cum []float64
+}
+
+// polylinesFromPaths projects a route's paths, dropping points that can't be
+// parsed.
+func polylinesFromPaths(proj planar, paths []Path) []polyline {
+	var result []polyline
+	for _, path := range paths {
+		var line polyline
+		for _, pt := range path.PointList {
+			lat, lon, ok := parseLatLon(pt.Lat, pt.Lon)
+			if !ok {
+				continue
+			}
+			q := proj.toXY(lat, lon)
+			length := 0.0
+			if n := len(line.pts); n != 0 {
+				length = line.cum[n-1] + math.Hypot(q.x-line.pts[n-1].x, q.y-line.pts[n-1].y)
+			}
+			line.pts = append(line.pts, q)
+			line.cum = append(line.cum, length)
+		}
+		if len(line.pts) > 1 {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// length returns the total length of the line in meters.
+func (l polyline) length() float64 {
+	return l.cum[len(l.cum)-1]
+}
+
+// project finds the point on the line closest to q, returning its distance
+// from the start of the line and its distance from q.
+func (l polyline) project(q xy) (offset, dist float64) {
+	dist = math.Inf(1)
+	for i := 1; i < len(l.pts); i++ {
+		a, b := l.pts[i-1], l.pts[i]
+		dx, dy := b.x-a.x, b.y-a.y
+		t := 0.0
+		if seg := dx*dx + dy*dy; seg > 0 {
+			t = math.Max(0, math.Min(1, ((q.x-a.x)*dx+(q.y-a.y)*dy)/seg))
+		}
+		px, py := a.x+t*dx, a.y+t*dy
+		if d := math.Hypot(q.x-px, q.y-py); d < dist {
+			dist = d
+			offset = l.cum[i-1] + t*math.Hypot(dx, dy)
+		}
+	}
+	return offset, dist
+}
@@ -0,0 +1,62 @@
+// Package geo provides the spherical geometry used throughout the nextbus
+// package: distances, bearings and destinations on the Earth's surface.
+package geo
+
+import (
+	"math"
+)
+
+// EarthRadius is the mean radius of the Earth in meters.
+const EarthRadius = 6371008.8
+
+// LatLon is a geographical location in degrees.
+type LatLon struct {
+	Lat float64
+	Lon float64
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
+
+// Distance returns the great-circle distance between a and b in meters,
+// using the haversine formula.
+func Distance(a, b LatLon) float64 {
+	φ1, φ2 := radians(a.Lat), radians(b.Lat)
+	dφ := radians(b.Lat - a.Lat)
+	dλ := radians(b.Lon - a.Lon)
+	h := math.Sin(dφ/2)*math.Sin(dφ/2) + math.Cos(φ1)*math.Cos(φ2)*math.Sin(dλ/2)*math.Sin(dλ/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Bearing returns the initial bearing from a to b in degrees clockwise from
+// north, in the range [0, 360).
+func Bearing(a, b LatLon) float64 {
+	φ1, φ2 := radians(a.Lat), radians(b.Lat)
+	dλ := radians(b.Lon - a.Lon)
+	y := math.Sin(dλ) * math.Cos(φ2)
+	x := math.Cos(φ1)*math.Sin(φ2) - math.Sin(φ1)*math.Cos(φ2)*math.Cos(dλ)
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360)
+}
+
+// Destination returns the point reached by traveling distance meters from p
+// along the given initial bearing in degrees.
+func Destination(p LatLon, bearing, distance float64) LatLon {
+	φ1, λ1 := radians(p.Lat), radians(p.Lon)
+	θ := radians(bearing)
+	δ := distance / EarthRadius
+	φ2 := math.Asin(math.Sin(φ1)*math.Cos(δ) + math.Cos(φ1)*math.Sin(δ)*math.Cos(θ))
+	λ2 := λ1 + math.Atan2(math.Sin(θ)*math.Sin(δ)*math.Cos(φ1), math.Cos(δ)-math.Sin(φ1)*math.Sin(φ2))
+	return LatLon{degrees(φ2), math.Mod(degrees(λ2)+540, 360) - 180}
+}
+
+// Midpoint returns the point halfway along the great circle between a and b.
+func Midpoint(a, b LatLon) LatLon {
+	φ1, λ1 := radians(a.Lat), radians(a.Lon)
+	φ2 := radians(b.Lat)
+	dλ := radians(b.Lon - a.Lon)
+	bx := math.Cos(φ2) * math.Cos(dλ)
+	by := math.Cos(φ2) * math.Sin(dλ)
+	φ3 := math.Atan2(math.Sin(φ1)+math.Sin(φ2), math.Sqrt((math.Cos(φ1)+bx)*(math.Cos(φ1)+bx)+by*by))
+	λ3 := λ1 + math.Atan2(by, math.Cos(φ1)+bx)
+	return LatLon{degrees(φ3), math.Mod(degrees(λ3)+540, 360) - 180}
+}
@@ -0,0 +1,53 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+var (
+	ferryBuilding = LatLon{37.7955, -122.3937}
+	oceanBeach    = LatLon{37.7601, -122.5105}
+)
+
+func TestDistance(t *testing.T) {
+	if d := Distance(LatLon{37, -122}, LatLon{38, -122}); !near(d, 111195, 10) {
+		t.Errorf("one degree of latitude: got %v", d)
+	}
+	if d := Distance(ferryBuilding, oceanBeach); !near(d, 10994, 5) {
+		t.Errorf("ferry building to ocean beach: got %v", d)
+	}
+	if d := Distance(ferryBuilding, ferryBuilding); d != 0 {
+		t.Errorf("same point: got %v", d)
+	}
+}
+
+func TestBearing(t *testing.T) {
+	if b := Bearing(LatLon{0, 0}, LatLon{1, 0}); !near(b, 0, 1e-9) {
+		t.Errorf("north: got %v", b)
+	}
+	if b := Bearing(LatLon{0, 0}, LatLon{0, -1}); !near(b, 270, 1e-9) {
+		t.Errorf("west: got %v", b)
+	}
+}
+
+func TestDestination(t *testing.T) {
+	b := Bearing(ferryBuilding, oceanBeach)
+	d := Distance(ferryBuilding, oceanBeach)
+	got := Destination(ferryBuilding, b, d)
+	if !near(got.Lat, oceanBeach.Lat, 1e-6) || !near(got.Lon, oceanBeach.Lon, 1e-6) {
+		t.Errorf("expected %v, got %v", oceanBeach, got)
+	}
+}
+
+func TestMidpoint(t *testing.T) {
+	m := Midpoint(ferryBuilding, oceanBeach)
+	d1, d2 := Distance(ferryBuilding, m), Distance(m, oceanBeach)
+	if !near(d1, d2, 0.01) {
+		t.Errorf("midpoint %v is not equidistant: %v and %v", m, d1, d2)
+	}
+}
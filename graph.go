@@ -0,0 +1,124 @@
+package nextbus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Edge is a link between two consecutive stops of a route direction.
+type Edge struct {
+	From     string
+	To       string
+	RouteTag string
+	DirTag   string
+	// Distance is how far apart the stops are along the route in meters.
+	Distance float64
+}
+
+// Graph is the network of an agency's routes, with stops as nodes and the
+// links between consecutive stops of each direction as edges. Stops are
+// identified by tag, so routes that share a stop tag meet at the same node.
+type Graph struct {
+	// Stops holds the nodes of the graph by stop tag.
+	Stops map[string]Stop
+	// Edges holds every edge in the order of the routes and directions they
+	// were built from.
+	Edges []Edge
+
+	out map[string][]int
+}
+
+// NewGraph builds the network of the given routes. Only directions are
+// considered, so routes without any yield no edges.
+func NewGraph(routes []RouteConfig) *Graph {
+	g := &Graph{Stops: map[string]Stop{}, out: map[string][]int{}}
+	for _, rc := range routes {
+		for _, stop := range rc.StopList {
+			if _, known := g.Stops[stop.Tag]; !known {
+				g.Stops[stop.Tag] = stop
+			}
+		}
+		for _, dir := range rc.DirList {
+			seq := rc.StopSequence(dir.Tag)
+			for i := 1; i < len(seq); i++ {
+				g.addEdge(Edge{
+					From:     seq[i-1].Stop.Tag,
+					To:       seq[i].Stop.Tag,
+					RouteTag: rc.Tag,
+					DirTag:   dir.Tag,
+					Distance: seq[i].Distance - seq[i-1].Distance,
+				})
+			}
+		}
+	}
+	return g
+}
+
+// Graph builds the network of the snapshot's routes.
+func (s *AgencySnapshot) Graph() *Graph {
+	return NewGraph(s.Routes)
+}
+
+func (g *Graph) addEdge(e Edge) {
+	g.out[e.From] = append(g.out[e.From], len(g.Edges))
+	g.Edges = append(g.Edges, e)
+}
+
+// From returns the edges leaving a stop.
+func (g *Graph) From(stopTag string) []Edge {
+	indexes := g.out[stopTag]
+	result := make([]Edge, len(indexes))
+	for i, index := range indexes {
+		result[i] = g.Edges[index]
+	}
+	return result
+}
+
+// Reachable returns the tags of the stops that can be reached from stopTag by
+// following edges, not including stopTag itself, in sorted order.
+func (g *Graph) Reachable(stopTag string) []string {
+	seen := map[string]bool{stopTag: true}
+	queue := []string{stopTag}
+	var result []string
+	for len(queue) != 0 {
+		tag := queue[0]
+		queue = queue[1:]
+		for _, index := range g.out[tag] {
+			to := g.Edges[index].To
+			if !seen[to] {
+				seen[to] = true
+				result = append(result, to)
+				queue = append(queue, to)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. Nodes are labeled
+// with the stop titles and edges with their route tags.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	tags := make([]string, 0, len(g.Stops))
+	for tag := range g.Stops {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph nextbus {")
+	for _, tag := range tags {
+		fmt.Fprintf(bw, "\t%s [label=%s];\n", strconv.Quote(tag), strconv.Quote(g.Stops[tag].Title))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "\t%s -> %s [label=%s];\n", strconv.Quote(e.From), strconv.Quote(e.To), strconv.Quote(e.RouteTag))
+	}
+	fmt.Fprintln(bw, "}")
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("could not write graph: %v", err)
+	}
+	return nil
+}
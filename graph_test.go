@@ -0,0 +1,56 @@
+package nextbus
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testNetwork() []RouteConfig {
+	return []RouteConfig{
+		{
+			Tag: "1",
+			StopList: []Stop{
+				{Tag: "a", Title: "A St", Lat: "37.7000", Lon: "-122.4000"},
+				{Tag: "b", Title: "B St", Lat: "37.7050", Lon: "-122.4000"},
+				{Tag: "c", Title: "C St", Lat: "37.7100", Lon: "-122.4000"},
+			},
+			DirList: []Direction{{Tag: "1_out", StopMarkerList: stopMarkers("a", "b", "c")}},
+		},
+		{
+			Tag: "2",
+			StopList: []Stop{
+				{Tag: "b", Title: "B St", Lat: "37.7050", Lon: "-122.4000"},
+				{Tag: "d", Title: "D St", Lat: "37.7050", Lon: "-122.3900"},
+			},
+			DirList: []Direction{{Tag: "2_east", StopMarkerList: stopMarkers("b", "d")}},
+		},
+	}
+}
+
+func TestGraph(t *testing.T) {
+	g := NewGraph(testNetwork())
+	equals(t, 4, len(g.Stops))
+	equals(t, 3, len(g.Edges))
+
+	from := g.From("b")
+	equals(t, 2, len(from))
+	equals(t, "c", from[0].To)
+	equals(t, "1", from[0].RouteTag)
+	equals(t, "d", from[1].To)
+	equals(t, "2_east", from[1].DirTag)
+	assert(t, from[1].Distance > 870 && from[1].Distance < 890, "unexpected distance %v", from[1].Distance)
+
+	equals(t, []string{"b", "c", "d"}, g.Reachable("a"))
+	equals(t, []string(nil), g.Reachable("d"))
+}
+
+func TestGraphWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	ok(t, NewGraph(testNetwork()[1:]).WriteDOT(&buf))
+	equals(t, `digraph nextbus {
+	"b" [label="B St"];
+	"d" [label="D St"];
+	"b" -> "d" [label="2"];
+}
+`, buf.String())
+}
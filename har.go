@@ -0,0 +1,267 @@
+package nextbus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// The HAR 1.2 document written by HARRecorder. Only the fields this package
+// records are included.
+type (
+	harLog struct {
+		Log harBody `json:"log"`
+	}
+	harBody struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	harEntry struct {
+		StartedDateTime time.Time   `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+	harRequest struct {
+		Method      string    `json:"method"`
+		URL         string    `json:"url"`
+		HTTPVersion string    `json:"httpVersion"`
+		Headers     []harPair `json:"headers"`
+		QueryString []harPair `json:"queryString"`
+		Cookies     []harPair `json:"cookies"`
+		HeadersSize int       `json:"headersSize"`
+		BodySize    int       `json:"bodySize"`
+	}
+	harResponse struct {
+		Status      int        `json:"status"`
+		StatusText  string     `json:"statusText"`
+		HTTPVersion string     `json:"httpVersion"`
+		Headers     []harPair  `json:"headers"`
+		Cookies     []harPair  `json:"cookies"`
+		Content     harContent `json:"content"`
+		RedirectURL string     `json:"redirectURL"`
+		HeadersSize int        `json:"headersSize"`
+		BodySize    int        `json:"bodySize"`
+	}
+	harContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+	}
+	harPair struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	harTimings struct {
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+	}
+)
+
+// HARRecorder records the requests a Client makes to NextBus and their
+// responses, to be written as an HTTP Archive (HAR) file that browsers and
+// HAR viewers can open. Attaching one to a bug report shows exactly what the
+// feed returned; running one alongside a poller audits what it requested.
+// The values of the Authorization, Cookie and Proxy-Authorization headers,
+// and of every header set with WithHeader, are recorded as "REDACTED".
+type HARRecorder struct {
+	// Limit is the most entries kept; older ones are dropped first. Zero
+	// keeps every entry.
+	Limit int
+	// Redactor, if set, redacts the vehicle and block IDs in the recorded
+	// responses.
+	Redactor *Redactor
+
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// WithHARRecorder makes a Client record every request it sends, including
+// retries, in r. Responses answered from a DiskCache aren't requests and
+// aren't recorded.
+func WithHARRecorder(r *HARRecorder) Option {
+	return func(c *Client) {
+		c.har = r
+	}
+}
+
+// harRedacted is recorded in place of the value of a header that may carry
+// credentials.
+const harRedacted = "REDACTED"
+
+// harSecretHeaders are the request headers always redacted in a HAR file.
+var harSecretHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+// wrap returns a copy of httpClient whose transport records to r, redacting
+// the well-known credential headers and those in secret, which are the
+// headers the Client was given with WithHeader.
+func (r *HARRecorder) wrap(httpClient *http.Client, secret http.Header) *http.Client {
+	next := httpClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	redact := map[string]bool{}
+	for _, name := range harSecretHeaders {
+		redact[name] = true
+	}
+	for name := range secret {
+		redact[http.CanonicalHeaderKey(name)] = true
+	}
+	result := *httpClient
+	result.Transport = &harTransport{r, next, redact}
+	return &result
+}
+
+// harTransport records the requests sent through next.
+type harTransport struct {
+	recorder *HARRecorder
+	next     http.RoundTripper
+	// redact holds the canonical names of the request headers whose values
+	// aren't recorded.
+	redact map[string]bool
+}
+
+func (t *harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r, next := t.recorder, t.next
+	start := time.Now()
+	entry := harEntry{StartedDateTime: start, Request: harRequest{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		HTTPVersion: "HTTP/1.1",
+		Headers:     harHeaders(req.Header, t.redact),
+		QueryString: []harPair{},
+		Cookies:     []harPair{},
+		HeadersSize: -1,
+	}}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, harPair{name, v})
+		}
+	}
+
+	resp, err := next.RoundTrip(req)
+	waited := time.Since(start)
+	if err != nil {
+		entry.Time = milliseconds(waited)
+		entry.Timings = harTimings{Wait: entry.Time}
+		entry.Response = harResponse{Headers: []harPair{}, Cookies: []harPair{}, HeadersSize: -1, BodySize: -1}
+		entry.Comment = err.Error()
+		r.add(entry)
+		return nil, err
+	}
+
+	body, readErr := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	total := time.Since(start)
+	entry.Time = milliseconds(total)
+	entry.Timings = harTimings{Wait: milliseconds(waited), Receive: milliseconds(total - waited)}
+	entry.Response = harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: resp.Proto,
+		Headers:     harHeaders(resp.Header, nil),
+		Cookies:     []harPair{},
+		Content:     harContent{Size: len(body), MimeType: resp.Header.Get("Content-Type"), Text: string(r.Redactor.XML(body))},
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if entry.Response.HTTPVersion == "" {
+		entry.Response.HTTPVersion = "HTTP/1.1"
+	}
+	if readErr != nil {
+		entry.Comment = readErr.Error()
+		r.add(entry)
+		return nil, readErr
+	}
+	r.add(entry)
+	return resp, nil
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// harHeaders returns the pairs of h, with the values of the headers in
+// redact replaced by harRedacted.
+func harHeaders(h http.Header, redact map[string]bool) []harPair {
+	pairs := []harPair{}
+	for name, values := range h {
+		for _, v := range values {
+			if redact[http.CanonicalHeaderKey(name)] {
+				v = harRedacted
+			}
+			pairs = append(pairs, harPair{name, v})
+		}
+	}
+	return pairs
+}
+
+func (r *HARRecorder) add(entry harEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+	if r.Limit > 0 && len(r.entries) > r.Limit {
+		r.entries = append(r.entries[:0:0], r.entries[len(r.entries)-r.Limit:]...)
+	}
+}
+
+// Len returns the number of entries recorded.
+func (r *HARRecorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// Reset drops every recorded entry.
+func (r *HARRecorder) Reset() {
+	r.mu.Lock()
+	r.entries = nil
+	r.mu.Unlock()
+}
+
+// Write writes the recorded entries as a HAR document.
+func (r *HARRecorder) Write(w io.Writer) error {
+	r.mu.Lock()
+	doc := harLog{harBody{
+		Version: "1.2",
+		Creator: harCreator{"nextbus-go", Version},
+		Entries: append([]harEntry{}, r.entries...),
+	}}
+	r.mu.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("could not write HAR: %v", err)
+	}
+	return nil
+}
+
+// Save writes the recorded entries to a HAR file at path.
+func (r *HARRecorder) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("could not write HAR: %v", err)
+	}
+	writeErr := r.Write(f)
+	if closeErr := f.Close(); writeErr == nil && closeErr != nil {
+		writeErr = fmt.Errorf("could not write HAR: %v", closeErr)
+	}
+	return writeErr
+}
@@ -0,0 +1,94 @@
+package nextbus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHARRecorder(t *testing.T) {
+	var rec HARRecorder
+	nb := NewClient(testingClient(t), WithHARRecorder(&rec), WithContact("ops@example.com"))
+	routes, err := nb.GetRouteList("alpha")
+	ok(t, err)
+	equals(t, 2, len(routes))
+	equals(t, 1, rec.Len())
+
+	var buf bytes.Buffer
+	ok(t, rec.Write(&buf))
+	var doc struct {
+		Log struct {
+			Version string
+			Entries []struct {
+				Request struct {
+					URL         string
+					Headers     []struct{ Name, Value string }
+					QueryString []struct{ Name, Value string }
+				}
+				Response struct {
+					Status  int
+					Content struct{ Text string }
+				}
+			}
+		}
+	}
+	ok(t, json.Unmarshal(buf.Bytes(), &doc))
+	equals(t, "1.2", doc.Log.Version)
+	entry := doc.Log.Entries[0]
+	equals(t, makeURL("routeList", "a", "alpha"), entry.Request.URL)
+	equals(t, 2, len(entry.Request.QueryString))
+	assert(t, strings.Contains(buf.String(), `"ops@example.com"`), "expected the From header in %s", buf.String())
+	equals(t, http.StatusOK, entry.Response.Status)
+	assert(t, strings.Contains(entry.Response.Content.Text, "<route"), "expected the body in %q", entry.Response.Content.Text)
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	ok(t, rec.Save(path))
+	saved, err := ioutil.ReadFile(path)
+	ok(t, err)
+	equals(t, buf.String(), string(saved))
+}
+
+func TestHARRecorderRetriesAndLimit(t *testing.T) {
+	attempts := 0
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("connection reset")
+		}
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(`<body><route tag="1" title="1-first"/></body>`))
+		return res, nil
+	})}
+	rec := &HARRecorder{Limit: 2}
+	_, err := NewClient(client, WithHARRecorder(rec), WithRetries(2, time.Millisecond)).GetRouteList("alpha")
+	ok(t, err)
+	equals(t, 2, rec.Len())
+
+	var buf bytes.Buffer
+	ok(t, rec.Write(&buf))
+	assert(t, strings.Count(buf.String(), "connection reset") == 1, "expected one failed attempt in %s", buf.String())
+	rec.Reset()
+	equals(t, 0, rec.Len())
+}
+
+func TestHARRecorderRedactsCredentials(t *testing.T) {
+	var rec HARRecorder
+	nb := NewClient(testingClient(t), WithHARRecorder(&rec), WithContact("ops@example.com"),
+		WithHeader("Authorization", "Bearer hunter2"), WithHeader("x-api-key", "s3cret"))
+	_, err := nb.GetRouteList("alpha")
+	ok(t, err)
+
+	var buf bytes.Buffer
+	ok(t, rec.Write(&buf))
+	for _, secret := range []string{"hunter2", "s3cret"} {
+		assert(t, !strings.Contains(buf.String(), secret), "expected %q to be redacted in %s", secret, buf.String())
+	}
+	equals(t, 2, strings.Count(buf.String(), `"REDACTED"`))
+	assert(t, strings.Contains(buf.String(), `"ops@example.com"`), "expected the From header in %s", buf.String())
+}
@@ -0,0 +1,175 @@
+package nextbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The names of the checks made by HealthMonitor.
+const (
+	CheckAgencyList  = "agencyList"
+	CheckVehicles    = "vehicleLocations"
+	CheckPredictions = "predictions"
+)
+
+// CheckResult is the outcome of one health check.
+type CheckResult struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// HealthStatus is the outcome of a round of health checks.
+type HealthStatus struct {
+	Healthy   bool          `json:"healthy"`
+	CheckedAt time.Time     `json:"checkedAt"`
+	Checks    []CheckResult `json:"checks"`
+}
+
+// HealthMonitor periodically checks that an agency's feed is working: that the
+// agency list can be fetched, that the lastTime of its vehicle locations keeps
+// advancing, and that there are predictions for a set of stops while the
+// agency is in service. It is an http.Handler that reports the latest status,
+// for use as a /healthz endpoint.
+type HealthMonitor struct {
+	client    *Client
+	agencyTag string
+	stops     []RouteStop
+
+	// StaleAfter is how long the vehicle locations' lastTime may stay the same
+	// before the feed is considered stale. It defaults to five minutes.
+	StaleAfter time.Duration
+	// InService, if set, reports whether the agency runs service at a time.
+	// Predictions are only required while it does. If nil, they always are.
+	InService func(t time.Time) bool
+	// OnUpdate, if set, is called with the status after each round of checks.
+	OnUpdate func(status HealthStatus)
+	// Bus, if set, receives a TopicHealth event whenever the feed turns
+	// healthy or unhealthy, including after the first round of checks.
+	Bus *Bus
+
+	mu           sync.RWMutex
+	status       HealthStatus
+	lastTime     string
+	lastAdvanced time.Time
+}
+
+// NewHealthMonitor creates a monitor for an agency's feed. Predictions are
+// checked at the given stops; if there are none, that check is skipped.
+func NewHealthMonitor(client *Client, agencyTag string, stops ...RouteStop) *HealthMonitor {
+	return &HealthMonitor{client: client, agencyTag: agencyTag, stops: stops, StaleAfter: 5 * time.Minute}
+}
+
+// Check runs every health check once and returns the resulting status.
+func (m *HealthMonitor) Check() HealthStatus {
+	return m.CheckContext(context.Background())
+}
+
+// CheckContext is like Check but uses ctx for the requests.
+func (m *HealthMonitor) CheckContext(ctx context.Context) HealthStatus {
+	now := m.client.now()
+	status := HealthStatus{Healthy: true, CheckedAt: now}
+	add := func(name string, err error) {
+		result := CheckResult{Name: name, OK: err == nil}
+		if err != nil {
+			result.Message = err.Error()
+			status.Healthy = false
+		}
+		status.Checks = append(status.Checks, result)
+	}
+
+	_, agencyErr := m.client.GetAgencyListContext(ctx)
+	add(CheckAgencyList, agencyErr)
+	add(CheckVehicles, m.checkVehicles(ctx, now))
+	if len(m.stops) != 0 && (m.InService == nil || m.InService(now)) {
+		add(CheckPredictions, m.checkPredictions(ctx))
+	}
+
+	m.mu.Lock()
+	changed := m.status.CheckedAt.IsZero() || m.status.Healthy != status.Healthy
+	m.status = status
+	m.mu.Unlock()
+	if m.OnUpdate != nil {
+		m.OnUpdate(status)
+	}
+	if changed {
+		m.Bus.Publish(BusEvent{Topic: TopicHealth, Time: now, Payload: status})
+	}
+	return status
+}
+
+func (m *HealthMonitor) checkVehicles(ctx context.Context, now time.Time) error {
+	resp, err := m.client.GetVehicleLocationsContext(ctx, m.agencyTag)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if resp.LastTime.Time != m.lastTime {
+		m.lastTime = resp.LastTime.Time
+		m.lastAdvanced = now
+		return nil
+	}
+	if stale := now.Sub(m.lastAdvanced); stale > m.StaleAfter {
+		return fmt.Errorf("vehicle locations have not been updated for %v", stale.Round(time.Second))
+	}
+	return nil
+}
+
+func (m *HealthMonitor) checkPredictions(ctx context.Context) error {
+	params := make([]PredReqParam, 0, len(m.stops))
+	for _, rs := range m.stops {
+		params = append(params, PredReqStop(rs.RouteTag, rs.StopTag))
+	}
+	predictions, err := m.client.GetPredictionsForMultiStopsContext(ctx, m.agencyTag, params...)
+	if err != nil {
+		return err
+	}
+	for _, pd := range predictions {
+		for _, dir := range pd.PredictionDirectionList {
+			if len(dir.PredictionList) != 0 {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no predictions for any of %d stops", len(m.stops))
+}
+
+// Status returns the status from the latest round of checks. Its CheckedAt is
+// zero if no checks have been made yet.
+func (m *HealthMonitor) Status() HealthStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// Run checks immediately and then every interval until ctx is done.
+func (m *HealthMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.CheckContext(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeHTTP writes the latest status as JSON, with status 200 if the feed is
+// healthy and 503 otherwise, including before the first round of checks.
+func (m *HealthMonitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := m.Status()
+	w.Header().Set("Content-Type", "application/json")
+	if status.Healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,52 @@
+package nextbus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthMonitor(t *testing.T) {
+	feed := &fakeFeed{Bodies: map[string]string{
+		"agencyList":               `<body><agency tag="alpha" title="The First"/></body>`,
+		"vehicleLocations":         `<body><lastTime time="1000"/></body>`,
+		"predictionsForMultiStops": `<body><predictions routeTag="1" stopTag="1123"><direction title="Outbound"><prediction minutes="3"/></direction></predictions></body>`,
+	}}
+	m := NewHealthMonitor(NewClient(feed.Client()), "alpha", RouteStop{"1", "1123"})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	equals(t, http.StatusServiceUnavailable, rec.Code)
+
+	var updates []HealthStatus
+	m.OnUpdate = func(status HealthStatus) { updates = append(updates, status) }
+	status := m.Check()
+	assert(t, status.Healthy, "expected a healthy feed, got %+v", status)
+	equals(t, 3, len(status.Checks))
+	equals(t, 1, len(updates))
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	equals(t, http.StatusOK, rec.Code)
+	var served HealthStatus
+	ok(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	assert(t, served.Healthy, "expected the served status to be healthy")
+
+	// The same lastTime is fine until StaleAfter has passed.
+	m.StaleAfter = 0
+	feed.Set("predictionsForMultiStops", `<body><predictions routeTag="1" stopTag="1123"></predictions></body>`)
+	status = m.Check()
+	assert(t, !status.Healthy, "expected an unhealthy feed")
+	equals(t, CheckResult{Name: CheckAgencyList, OK: true}, status.Checks[0])
+	equals(t, false, status.Checks[1].OK)
+	equals(t, CheckResult{Name: CheckPredictions, Message: "no predictions for any of 1 stops"}, status.Checks[2])
+
+	// Predictions aren't required out of service.
+	feed.Set("vehicleLocations", `<body><lastTime time="2000"/></body>`)
+	m.InService = func(time.Time) bool { return false }
+	status = m.Check()
+	assert(t, status.Healthy, "expected a healthy feed, got %+v", status)
+	equals(t, 2, len(status.Checks))
+}
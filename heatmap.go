@@ -0,0 +1,121 @@
+package nextbus
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// SpeedSample is a vehicle location as reported at a known time.
+type SpeedSample struct {
+	Time    time.Time
+	Vehicle VehicleLocation
+}
+
+// SpeedSamples stamps the vehicles of a response fetched at t with the time
+// each last reported, for archiving and SpeedHeatmap.
+func SpeedSamples(t time.Time, resp *LocationResponse) []SpeedSample {
+	samples := make([]SpeedSample, 0, len(resp.VehicleList))
+	for _, v := range resp.VehicleList {
+		at := t
+		if secs, err := strconv.Atoi(v.SecsSinceReport); err == nil {
+			at = t.Add(-time.Duration(secs) * time.Second)
+		}
+		samples = append(samples, SpeedSample{Time: at, Vehicle: v})
+	}
+	return samples
+}
+
+// SegmentSpeed is the average speed of vehicles on one segment of a route's
+// path during one time-of-day bucket.
+type SegmentSpeed struct {
+	// Segment numbers the segments of the route's merged paths in order.
+	Segment  int
+	From, To LatLon
+	// Start is the beginning of the time-of-day bucket, as an offset from
+	// midnight.
+	Start       time.Duration
+	Samples     int
+	AverageKmHr float64
+}
+
+// SpeedHeatmap buckets the speeds in samples by the path segment each vehicle
+// was on and the time of day it reported, in buckets of the given width, for
+// rendering congestion along a route. Samples from other routes, without a
+// speed, or too far from the route's paths are ignored. The result is ordered
+// by segment and then bucket.
+func (rc RouteConfig) SpeedHeatmap(samples []SpeedSample, bucket time.Duration) []SegmentSpeed {
+	if bucket <= 0 {
+		bucket = time.Hour
+	}
+	type segment struct {
+		from, to LatLon
+		line     polyline
+	}
+	var segments []segment
+	var proj planar
+	for _, path := range MergePaths(rc.PathList) {
+		for i := 1; i < len(path.PointList); i++ {
+			lat1, lon1, _ := parseLatLon(path.PointList[i-1].Lat, path.PointList[i-1].Lon)
+			lat2, lon2, _ := parseLatLon(path.PointList[i].Lat, path.PointList[i].Lon)
+			if segments == nil {
+				proj = newPlanar(lat1, lon1)
+			}
+			a, b := proj.toXY(lat1, lon1), proj.toXY(lat2, lon2)
+			segments = append(segments, segment{
+				from: LatLon{Lat: lat1, Lon: lon1},
+				to:   LatLon{Lat: lat2, Lon: lon2},
+				line: polyline{pts: []xy{a, b}, cum: []float64{0, math.Hypot(b.x-a.x, b.y-a.y)}},
+			})
+		}
+	}
+
+	type cell struct {
+		segment int
+		start   time.Duration
+	}
+	totals := map[cell]*SegmentSpeed{}
+	for _, s := range samples {
+		v := s.Vehicle
+		if v.RouteTag != rc.Tag {
+			continue
+		}
+		speed, speedErr := strconv.ParseFloat(v.SpeedKmHr, 64)
+		lat, lon, located := parseLatLon(v.Lat, v.Lon)
+		if speedErr != nil || speed < 0 || !located || segments == nil {
+			continue
+		}
+		q := proj.toXY(lat, lon)
+		best, bestDist := -1, math.Inf(1)
+		for i, seg := range segments {
+			if _, dist := seg.line.project(q); dist < bestDist {
+				best, bestDist = i, dist
+			}
+		}
+		if bestDist > maxVehicleSnapDistance {
+			continue
+		}
+		c := cell{best, s.Time.Sub(midnight(s.Time)) / bucket * bucket}
+		total := totals[c]
+		if total == nil {
+			total = &SegmentSpeed{Segment: best, From: segments[best].from, To: segments[best].to, Start: c.start}
+			totals[c] = total
+		}
+		total.Samples++
+		total.AverageKmHr += speed
+	}
+
+	result := make([]SegmentSpeed, 0, len(totals))
+	for _, total := range totals {
+		total.AverageKmHr /= float64(total.Samples)
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Segment != result[j].Segment {
+			return result[i].Segment < result[j].Segment
+		}
+		return result[i].Start < result[j].Start
+	})
+	return result
+}
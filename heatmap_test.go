@@ -0,0 +1,42 @@
+package nextbus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpeedSamples(t *testing.T) {
+	now := time.Date(2017, 2, 16, 8, 0, 0, 0, time.UTC)
+	samples := SpeedSamples(now, &LocationResponse{VehicleList: []VehicleLocation{{ID: "1", SecsSinceReport: "15"}, {ID: "2"}}})
+	equals(t, now.Add(-15*time.Second), samples[0].Time)
+	equals(t, now, samples[1].Time)
+}
+
+func TestSpeedHeatmap(t *testing.T) {
+	rc := RouteConfig{Tag: "N", PathList: []Path{pathOf("37.70", "-122.40", "37.71", "-122.40", "37.72", "-122.40")}}
+	eight := time.Date(2017, 2, 16, 8, 10, 0, 0, time.UTC)
+	sample := func(at time.Time, routeTag, lat, speed string) SpeedSample {
+		return SpeedSample{Time: at, Vehicle: VehicleLocation{RouteTag: routeTag, Lat: lat, Lon: "-122.40", SpeedKmHr: speed}}
+	}
+	samples := []SpeedSample{
+		sample(eight, "N", "37.705", "10"),
+		sample(eight.Add(20*time.Minute), "N", "37.705", "20"),
+		sample(eight, "N", "37.715", "30"),
+		sample(eight.Add(time.Hour), "N", "37.705", "40"),
+		sample(eight, "N", "37.80", "50"),
+		sample(eight, "J", "37.705", "60"),
+		sample(eight, "N", "37.705", ""),
+	}
+
+	heatmap := rc.SpeedHeatmap(samples, time.Hour)
+	equals(t, 3, len(heatmap))
+	equals(t, 0, heatmap[0].Segment)
+	equals(t, 8*time.Hour, heatmap[0].Start)
+	equals(t, 2, heatmap[0].Samples)
+	equals(t, 15.0, heatmap[0].AverageKmHr)
+	equals(t, LatLon{Lat: 37.70, Lon: -122.40}, heatmap[0].From)
+	equals(t, 9*time.Hour, heatmap[1].Start)
+	equals(t, 40.0, heatmap[1].AverageKmHr)
+	equals(t, 1, heatmap[2].Segment)
+	equals(t, 30.0, heatmap[2].AverageKmHr)
+}
@@ -0,0 +1,151 @@
+//go:build go1.23
+
+package nextbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"iter"
+	"time"
+)
+
+// RouteConfigs fetches route configs for an agency like GetRouteConfig, but
+// yields each RouteConfig as soon as it has been decoded from the response
+// instead of buffering the whole document. Iteration stops at the first error.
+//
+// The request is retried and observed like any other, and a fresh response in
+// the disk cache is used, but since the response isn't buffered it is not
+// added to the cache, and an Error in it is yielded rather than retried.
+func (c *Client) RouteConfigs(ctx context.Context, agencyTag string, configParams ...RouteConfigParam) iter.Seq2[RouteConfig, error] {
+	params, err := buildQuery("routeConfig", agencyTag, toParams(configParams))
+	if err != nil {
+		return failed[RouteConfig](err)
+	}
+	return streamElements[RouteConfig](ctx, c, "routeConfig", params, "route")
+}
+
+// StreamVehicleLocations fetches vehicle locations like GetVehicleLocations,
+// but yields each VehicleLocation as it is decoded. The response's lastTime is
+// not reported; use GetVehicleLocations when it is needed. The request is made
+// as for RouteConfigs.
+func (c *Client) StreamVehicleLocations(ctx context.Context, agencyTag string, configParams ...VehicleLocationParam) iter.Seq2[VehicleLocation, error] {
+	params, err := vehicleLocationParams(agencyTag, configParams)
+	if err != nil {
+		return failed[VehicleLocation](err)
+	}
+	vehicles := streamElements[VehicleLocation](ctx, c, "vehicleLocations", params, "vehicle")
+	if c.stalePolicy == nil {
+		return vehicles
+	}
+	return func(yield func(VehicleLocation, error) bool) {
+		for v, err := range vehicles {
+			if err == nil && !c.stalePolicy.Keep(v) {
+				continue
+			}
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// streamElements returns a sequence of every element named local found in the
+// response to a feed command. An Error reported by NextBus is yielded as a
+// *FeedError.
+func streamElements[T any](ctx context.Context, c *Client, command string, params []string, local string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		body, openErr := c.openStream(ctx, command, params)
+		if openErr != nil {
+			yield(zero, openErr)
+			return
+		}
+		defer body.Close()
+
+		d := newXMLDecoder(body)
+		d.CharsetReader = c.charsetReader
+		if d.CharsetReader == nil {
+			d.CharsetReader = DefaultCharsetReader
+		}
+		for {
+			tok, tokErr := d.Token()
+			if tokErr == io.EOF {
+				return
+			}
+			if tokErr != nil {
+				yield(zero, fmt.Errorf("could not parse %s XML: %v", describe(command), tokErr))
+				return
+			}
+			start, isStart := tok.(xml.StartElement)
+			if isStart && start.Name.Local == "Error" {
+				var feedErr FeedError
+				if xmlErr := d.DecodeElement(&feedErr, &start); xmlErr != nil {
+					yield(zero, fmt.Errorf("could not parse %s XML: %v", describe(command), xmlErr))
+				} else {
+					yield(zero, &feedErr)
+				}
+				return
+			}
+			if !isStart || start.Name.Local != local {
+				continue
+			}
+			var v T
+			if xmlErr := d.DecodeElement(&v, &start); xmlErr != nil {
+				yield(zero, fmt.Errorf("could not parse %s XML: %v", describe(command), xmlErr))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// openStream opens the response to a feed command for streaming. It serves
+// fresh responses from the cache and retries the request as fetch does, but
+// only until the response starts, so Errors in the body aren't retried.
+func (c *Client) openStream(ctx context.Context, command string, params []string) (io.ReadCloser, error) {
+	if c.cache != nil {
+		if data, fresh := c.cache.get(c.now(), command, c.cacheKey(command, params)); fresh {
+			if c.observe != nil {
+				c.observe(RequestInfo{Command: command, Params: append([]string(nil), params...), Bytes: len(data), Outcome: OutcomeCached})
+			}
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+	}
+
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		body, err := c.open(ctx, command, params)
+		c.observed(command, params, attempt+1, start, nil, err)
+		transient, isTransient := err.(*transientError)
+		if !isTransient {
+			return body, err
+		}
+		if attempt >= c.retries || ctx.Err() != nil {
+			return nil, transient.err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("could not fetch %s from nextbus: %v", describe(command), ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// failed returns a sequence that yields only err.
+func failed[T any](err error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		yield(zero, err)
+	}
+}
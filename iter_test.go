@@ -0,0 +1,102 @@
+//go:build go1.23
+
+package nextbus
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRouteConfigs(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	expected, err := nb.GetRouteConfig("alpha")
+	ok(t, err)
+
+	var found []RouteConfig
+	for rc, err := range nb.RouteConfigs(context.Background(), "alpha") {
+		ok(t, err)
+		found = append(found, rc)
+	}
+	equals(t, expected, found)
+}
+
+func TestStreamVehicleLocations(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	var ids []string
+	for v, err := range nb.StreamVehicleLocations(context.Background(), "alpha") {
+		ok(t, err)
+		ids = append(ids, v.ID)
+		break
+	}
+	equals(t, []string{"1111"}, ids)
+}
+
+func TestRouteConfigsRetriedObservedAndCached(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+	ok(t, err)
+	feed := cannedFeed(`<body><route tag="1" title="1-first"/></body>`)
+	attempts := 0
+	flaky := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			return statusResponse(req, http.StatusServiceUnavailable), nil
+		}
+		return feed.RoundTrip(req)
+	})}
+	var outcomes []RequestOutcome
+	nb := NewClient(flaky, WithRetries(1, time.Millisecond), WithDiskCache(cache),
+		WithRequestObserver(func(info RequestInfo) { outcomes = append(outcomes, info.Outcome) }))
+
+	// The first attempt fails and is retried.
+	var titles []string
+	for rc, err := range nb.RouteConfigs(context.Background(), "alpha") {
+		ok(t, err)
+		titles = append(titles, rc.Title)
+	}
+	equals(t, []string{"1-first"}, titles)
+	equals(t, []RequestOutcome{OutcomeError, OutcomeOK}, outcomes)
+
+	// A response cached by a buffered request is streamed from the cache.
+	_, err = nb.GetRouteConfig("alpha")
+	ok(t, err)
+	for _, err := range nb.RouteConfigs(context.Background(), "alpha") {
+		ok(t, err)
+	}
+	equals(t, 2, feed.Count())
+	equals(t, OutcomeCached, outcomes[len(outcomes)-1])
+}
+
+func BenchmarkRouteConfigs(b *testing.B) {
+	data := largeRouteConfig(benchRoutes, benchStopsPerRoute, benchPathsPerRoute)
+	nb := fixedResponseClient(data)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		n := 0
+		for _, err := range nb.RouteConfigs(context.Background(), "alpha") {
+			if err != nil {
+				b.Fatal(err)
+			}
+			n++
+		}
+		if n != benchRoutes {
+			b.Fatalf("expected %d routes, got %d", benchRoutes, n)
+		}
+	}
+}
+
+func BenchmarkStreamVehicleLocations(b *testing.B) {
+	data := largeLocationResponse(benchVehicles)
+	nb := fixedResponseClient(data)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		for _, err := range nb.StreamVehicleLocations(context.Background(), "alpha") {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
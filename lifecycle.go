@@ -0,0 +1,266 @@
+package nextbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrServiceStarted is returned by Service.Start and Service.Run when the
+// Service is already running.
+var ErrServiceStarted = errors.New("nextbus: service already started")
+
+// Component is a background component with a lifecycle, such as a Service or
+// a Group of them.
+type Component interface {
+	// Start starts the component in the background. It runs until ctx is
+	// done or Stop is called.
+	Start(ctx context.Context) error
+	// Stop tells the component to stop and waits for it to wind down, or
+	// for ctx to be done.
+	Stop(ctx context.Context) error
+}
+
+// Service runs a background loop, such as PredictionWatcher.Run, with a
+// lifecycle shared by the components of this package: Start runs it in its
+// own goroutine and Stop cancels it, which also cancels the request of a poll
+// in progress, and waits for the loop to return. A stopped Service can be
+// started again.
+type Service struct {
+	// prepare is called by Start, before it returns, and returns the loop to
+	// run in the background.
+	prepare func(ctx context.Context) (loop func())
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewService creates a Service that runs fn, which must return soon after its
+// ctx is done.
+func NewService(fn func(ctx context.Context)) *Service {
+	return &Service{prepare: func(ctx context.Context) func() {
+		return func() { fn(ctx) }
+	}}
+}
+
+// Start runs the loop in a new goroutine until ctx is done or Stop is called.
+func (s *Service) Start(ctx context.Context) error {
+	_, err := s.start(ctx)
+	return err
+}
+
+func (s *Service) start(ctx context.Context) (chan struct{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		select {
+		case <-s.done:
+		default:
+			return nil, ErrServiceStarted
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	s.cancel, s.done = cancel, done
+	loop := s.prepare(ctx)
+	go func() {
+		defer close(done)
+		defer cancel()
+		loop()
+	}()
+	return done, nil
+}
+
+// Stop cancels the loop, along with any request it has in progress, and waits
+// for it to return. If ctx is done first, Stop returns its error and the loop
+// finishes in the background. Stopping a Service that isn't running does
+// nothing.
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done returns a channel that is closed when the latest run of the loop has
+// returned, or nil if the Service has never been started.
+func (s *Service) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// Run runs the loop until ctx is done and then returns nil once it has
+// stopped. Its signature suits errgroup.Group.Go and similar supervisors.
+func (s *Service) Run(ctx context.Context) error {
+	done, err := s.start(ctx)
+	if err != nil {
+		return err
+	}
+	<-done
+	return nil
+}
+
+// Group manages several components as one, starting them in order and
+// stopping them in reverse order.
+type Group struct {
+	mu         sync.Mutex
+	components []Component
+}
+
+// NewGroup creates a Group of the given components.
+func NewGroup(components ...Component) *Group {
+	return &Group{components: components}
+}
+
+// Add adds c to the group. It isn't started until the next Start.
+func (g *Group) Add(c Component) {
+	g.mu.Lock()
+	g.components = append(g.components, c)
+	g.mu.Unlock()
+}
+
+func (g *Group) list() []Component {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Component(nil), g.components...)
+}
+
+// Start starts every component. If one fails to start, those already started
+// are stopped and its error is returned.
+func (g *Group) Start(ctx context.Context) error {
+	components := g.list()
+	for i, c := range components {
+		if err := c.Start(ctx); err != nil {
+			stopAll(context.Background(), components[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop stops every component, in reverse order, and returns the first error.
+// ctx bounds the whole shutdown.
+func (g *Group) Stop(ctx context.Context) error {
+	return stopAll(ctx, g.list())
+}
+
+func stopAll(ctx context.Context, components []Component) error {
+	var firstErr error
+	for i := len(components) - 1; i >= 0; i-- {
+		if err := components[i].Stop(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Run starts every component, waits for ctx to be done and then stops them,
+// allowing them up to timeout to finish, or without limit if timeout is zero.
+func (g *Group) Run(ctx context.Context, timeout time.Duration) error {
+	if err := g.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	stopCtx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		stopCtx, cancel = context.WithTimeout(stopCtx, timeout)
+		defer cancel()
+	}
+	return g.Stop(stopCtx)
+}
+
+// Service returns a Service that runs w.RunStrategy with strategy.
+func (w *PredictionWatcher) Service(strategy PollStrategy) *Service {
+	return NewService(func(ctx context.Context) { w.RunStrategy(ctx, strategy) })
+}
+
+// Service returns a Service that runs m.Run with interval.
+func (m *HealthMonitor) Service(interval time.Duration) *Service {
+	return NewService(func(ctx context.Context) { m.Run(ctx, interval) })
+}
+
+// Service returns a Service that runs s.Run with interval.
+func (s *RouteConfigStore) Service(interval time.Duration) *Service {
+	return NewService(func(ctx context.Context) { s.Run(ctx, interval) })
+}
+
+// Service returns a Service that runs s.Run with interval.
+func (s *VehicleLocationSession) Service(interval time.Duration) *Service {
+	return NewService(func(ctx context.Context) { s.Run(ctx, interval) })
+}
+
+// Service returns a Service that runs d.Run with interval.
+func (d *Directory) Service(interval time.Duration) *Service {
+	return NewService(func(ctx context.Context) { d.Run(ctx, interval) })
+}
+
+// Service returns a Service that feeds the Archiver every TopicPredictions
+// event published on bus once Start returns, as fetched at the event's Time.
+// Events are queued and archived in order from the Service's goroutine, so
+// slow stores don't hold up publishers; when archiverQueue events are already
+// waiting, further ones are dropped and counted by Dropped. Those queued when
+// it stops are archived before Stop returns. Errors are passed to OnError.
+func (a *Archiver) Service(bus *Bus) *Service {
+	return &Service{prepare: func(ctx context.Context) func() {
+		events := make(chan BusEvent, archiverQueue)
+		unsubscribe := bus.Subscribe(func(e BusEvent) {
+			select {
+			case events <- e:
+			default:
+				a.mu.Lock()
+				a.dropped++
+				a.mu.Unlock()
+			}
+		}, TopicPredictions)
+		return func() {
+			for {
+				select {
+				case e := <-events:
+					a.observeEvent(e)
+				case <-ctx.Done():
+					unsubscribe()
+					for {
+						select {
+						case e := <-events:
+							a.observeEvent(e)
+						default:
+							return
+						}
+					}
+				}
+			}
+		}
+	}}
+}
+
+// archiverQueue is the number of events an Archiver's Service holds while the
+// store catches up.
+const archiverQueue = 64
+
+// Dropped returns the number of events the Archiver's Services have dropped
+// because their queue was full.
+func (a *Archiver) Dropped() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.dropped
+}
+
+func (a *Archiver) observeEvent(e BusEvent) {
+	predictions, _ := e.Payload.([]PredictionData)
+	if err := a.ObservePredictions(e.Time, predictions); err != nil && a.OnError != nil {
+		a.OnError(err)
+	}
+}
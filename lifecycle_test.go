@@ -0,0 +1,137 @@
+package nextbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestServiceLifecycle(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var drained bool
+	s := NewService(func(ctx context.Context) {
+		started <- struct{}{}
+		<-ctx.Done()
+		<-release
+		drained = true
+	})
+	equals(t, (<-chan struct{})(nil), s.Done())
+	ok(t, s.Stop(context.Background()))
+
+	ok(t, s.Start(context.Background()))
+	<-started
+	equals(t, ErrServiceStarted, s.Start(context.Background()))
+
+	// The loop is still draining when the deadline passes.
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	equals(t, context.Canceled, s.Stop(expired))
+	close(release)
+	ok(t, s.Stop(context.Background()))
+	assert(t, drained, "expected Stop to wait for the loop to return")
+
+	// A stopped Service can be started again, here by Run.
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() { result <- s.Run(ctx) }()
+	<-started
+	cancel()
+	ok(t, <-result)
+}
+
+type recordingComponent struct {
+	name     string
+	startErr error
+	log      *[]string
+}
+
+func (c recordingComponent) Start(ctx context.Context) error {
+	*c.log = append(*c.log, "start "+c.name)
+	return c.startErr
+}
+
+func (c recordingComponent) Stop(ctx context.Context) error {
+	*c.log = append(*c.log, "stop "+c.name)
+	return nil
+}
+
+func TestGroup(t *testing.T) {
+	var log []string
+	g := NewGroup(recordingComponent{name: "a", log: &log}, recordingComponent{name: "b", log: &log})
+	ok(t, g.Start(context.Background()))
+	ok(t, g.Stop(context.Background()))
+	equals(t, []string{"start a", "start b", "stop b", "stop a"}, log)
+
+	log = nil
+	failed := errors.New("no such agency")
+	g.Add(recordingComponent{name: "c", startErr: failed, log: &log})
+	equals(t, failed, g.Start(context.Background()))
+	equals(t, []string{"start a", "start b", "start c", "stop b", "stop a"}, log)
+}
+
+func TestArchiverService(t *testing.T) {
+	store := &MemoryArrivalStore{}
+	a := NewArchiver(store)
+	bus := &Bus{}
+	s := a.Service(bus)
+	ok(t, s.Start(context.Background()))
+
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	bus.Publish(BusEvent{Topic: TopicPredictions, Time: now, Payload: arrivingAt("1500", now.Add(time.Minute))})
+	bus.Publish(BusEvent{Topic: TopicPredictions, Time: now.Add(90 * time.Second), Payload: arrivingAt("1700", now.Add(20*time.Minute))})
+	ok(t, s.Stop(context.Background()))
+
+	records, err := store.Arrivals(now, now.Add(time.Hour))
+	ok(t, err)
+	equals(t, 1, len(records))
+	equals(t, "1500", records[0].Vehicle)
+}
+
+// blockingArrivalStore holds each Save until release is closed.
+type blockingArrivalStore struct {
+	MemoryArrivalStore
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingArrivalStore) Save(records []ArrivalRecord) error {
+	s.saving <- struct{}{}
+	<-s.release
+	return s.MemoryArrivalStore.Save(records)
+}
+
+func TestArchiverServiceDropsWhenFull(t *testing.T) {
+	store := &blockingArrivalStore{saving: make(chan struct{}, 1), release: make(chan struct{})}
+	a := NewArchiver(store)
+	bus := &Bus{}
+	s := a.Service(bus)
+	ok(t, s.Start(context.Background()))
+
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	bus.Publish(BusEvent{Topic: TopicPredictions, Time: now, Payload: arrivingAt("1500", now.Add(time.Minute))})
+	bus.Publish(BusEvent{Topic: TopicPredictions, Time: now.Add(90 * time.Second), Payload: arrivingAt("1700", now.Add(20*time.Minute))})
+	<-store.saving
+
+	// The store is stuck, so the queue fills and publishing carries on.
+	for i := 0; i < archiverQueue+3; i++ {
+		bus.Publish(BusEvent{Topic: TopicPredictions, Time: now.Add(2 * time.Minute), Payload: arrivingAt("1700", now.Add(20*time.Minute))})
+	}
+	equals(t, 3, a.Dropped())
+	close(store.release)
+	ok(t, s.Stop(context.Background()))
+}
+
+func TestVehicleLocationSessionService(t *testing.T) {
+	bus := &Bus{}
+	vehicles := make(chan *LocationResponse, 1)
+	bus.Subscribe(func(e BusEvent) { vehicles <- e.Payload.(*LocationResponse) }, TopicVehicles)
+	session := NewVehicleLocationSession(NewClient(testingClient(t)), "alpha", "")
+	session.Bus = bus
+	s := session.Service(time.Hour)
+	ok(t, s.Start(context.Background()))
+	equals(t, 2, len((<-vehicles).VehicleList))
+	ok(t, s.Stop(context.Background()))
+	equals(t, "1234567890123", session.LastTime())
+}
@@ -0,0 +1,256 @@
+// Package lite decodes NextBus predictions and vehicle locations into compact
+// structs, for departure displays and gateways with little memory, such as
+// ESP32-class boards built with TinyGo or small ARM boards.
+//
+// Unlike the nextbus package, lite has no xml.Name fields, parses numbers
+// once instead of keeping every attribute as a string, skips rarely used
+// attributes, and decodes from a stream token by token without reflection,
+// so a response never has to be held in memory. It depends only on the
+// standard library's encoding/xml, not on net/http or the nextbus package,
+// so importing it adds little to a binary. Fetch responses with whatever HTTP
+// client the target provides:
+//
+//	resp, err := http.Get("https://webservices.nextbus.com/service/publicXMLFeed?command=predictions&a=sf-muni&r=N&s=5205")
+//	predictions, err := lite.DecodePredictions(resp.Body)
+package lite
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// FeedError is an error reported by NextBus in the body of a response.
+type FeedError struct {
+	Message string
+	// Retryable reports whether NextBus indicated that the same request may
+	// succeed if tried again later.
+	Retryable bool
+}
+
+func (e *FeedError) Error() string {
+	return "nextbus: " + e.Message
+}
+
+// Prediction is one predicted arrival or departure at a stop.
+type Prediction struct {
+	// Epoch is the predicted time in milliseconds since the Unix epoch.
+	Epoch int64
+	// Seconds is how long until the predicted time, as of the response.
+	Seconds int32
+	// Direction is the title of the direction the vehicle travels in.
+	Direction   string
+	DirTag      string
+	Vehicle     string
+	IsDeparture bool
+	// AffectedByLayover is set for predictions that depend on the vehicle
+	// leaving a layover on time, which makes them less reliable.
+	AffectedByLayover bool
+}
+
+// Minutes returns the whole minutes until the prediction.
+func (p Prediction) Minutes() int {
+	return int(p.Seconds / 60)
+}
+
+// StopPredictions are the predictions for one route at one stop.
+type StopPredictions struct {
+	RouteTag    string
+	RouteTitle  string
+	StopTag     string
+	StopTitle   string
+	Predictions []Prediction
+	// Messages are the texts of the agency's messages for the stop.
+	Messages []string
+}
+
+// Vehicle is the location of one vehicle.
+type Vehicle struct {
+	ID       string
+	RouteTag string
+	DirTag   string
+	Lat      float32
+	Lon      float32
+	// Heading is in degrees clockwise from north, or negative if unknown.
+	Heading int16
+	// SpeedKmHr is the vehicle's speed in kilometers per hour.
+	SpeedKmHr float32
+	// SecsSinceReport is the age of the location in seconds.
+	SecsSinceReport int32
+	Predictable     bool
+}
+
+// VehicleLocations is a vehicleLocations response.
+type VehicleLocations struct {
+	Vehicles []Vehicle
+	// LastTime is the time of the response in milliseconds since the Unix
+	// epoch, to pass as t in the next request.
+	LastTime int64
+}
+
+// DecodePredictions decodes a predictions or predictionsForMultiStops
+// response, which must be UTF-8 as NextBus sends them. An Error reported by
+// NextBus is returned as a *FeedError.
+func DecodePredictions(r io.Reader) ([]StopPredictions, error) {
+	d := xml.NewDecoder(r)
+	var result []StopPredictions
+	var direction string
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse predictions XML: %v", err)
+		}
+		start, isStart := tok.(xml.StartElement)
+		if !isStart {
+			continue
+		}
+		switch start.Name.Local {
+		case "Error":
+			return nil, feedError(d, start)
+		case "predictions":
+			sp := StopPredictions{}
+			for _, attr := range start.Attr {
+				switch attr.Name.Local {
+				case "routeTag":
+					sp.RouteTag = attr.Value
+				case "routeTitle":
+					sp.RouteTitle = attr.Value
+				case "stopTag":
+					sp.StopTag = attr.Value
+				case "stopTitle":
+					sp.StopTitle = attr.Value
+				}
+			}
+			result = append(result, sp)
+		case "direction":
+			direction = attrValue(start, "title")
+		case "prediction":
+			if len(result) == 0 {
+				continue
+			}
+			p := Prediction{Direction: direction}
+			for _, attr := range start.Attr {
+				switch attr.Name.Local {
+				case "epochTime":
+					p.Epoch, _ = strconv.ParseInt(attr.Value, 10, 64)
+				case "seconds":
+					seconds, _ := strconv.ParseInt(attr.Value, 10, 32)
+					p.Seconds = int32(seconds)
+				case "dirTag":
+					p.DirTag = attr.Value
+				case "vehicle":
+					p.Vehicle = attr.Value
+				case "isDeparture":
+					p.IsDeparture = attr.Value == "true"
+				case "affectedByLayover":
+					p.AffectedByLayover = attr.Value == "true"
+				}
+			}
+			sp := &result[len(result)-1]
+			sp.Predictions = append(sp.Predictions, p)
+		case "message":
+			if len(result) != 0 {
+				sp := &result[len(result)-1]
+				sp.Messages = append(sp.Messages, attrValue(start, "text"))
+			}
+		}
+	}
+}
+
+// DecodeVehicleLocations decodes a vehicleLocations response, which must be
+// UTF-8 as NextBus sends them. An Error reported by NextBus is returned as a
+// *FeedError.
+func DecodeVehicleLocations(r io.Reader) (*VehicleLocations, error) {
+	d := xml.NewDecoder(r)
+	result := &VehicleLocations{}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse vehicle locations XML: %v", err)
+		}
+		start, isStart := tok.(xml.StartElement)
+		if !isStart {
+			continue
+		}
+		switch start.Name.Local {
+		case "Error":
+			return nil, feedError(d, start)
+		case "vehicle":
+			result.Vehicles = append(result.Vehicles, vehicle(start))
+		case "lastTime":
+			result.LastTime, _ = strconv.ParseInt(attrValue(start, "time"), 10, 64)
+		}
+	}
+}
+
+func vehicle(start xml.StartElement) Vehicle {
+	v := Vehicle{Heading: -1}
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "id":
+			v.ID = attr.Value
+		case "routeTag":
+			v.RouteTag = attr.Value
+		case "dirTag":
+			v.DirTag = attr.Value
+		case "lat":
+			v.Lat = parseFloat32(attr.Value)
+		case "lon":
+			v.Lon = parseFloat32(attr.Value)
+		case "heading":
+			if heading, err := strconv.ParseInt(attr.Value, 10, 16); err == nil && heading >= 0 {
+				v.Heading = int16(heading)
+			}
+		case "speedKmHr":
+			v.SpeedKmHr = parseFloat32(attr.Value)
+		case "secsSinceReport":
+			secs, _ := strconv.ParseInt(attr.Value, 10, 32)
+			v.SecsSinceReport = int32(secs)
+		case "predictable":
+			v.Predictable = attr.Value == "true"
+		}
+	}
+	return v
+}
+
+func parseFloat32(s string) float32 {
+	f, _ := strconv.ParseFloat(s, 32)
+	return float32(f)
+}
+
+func attrValue(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// feedError reads the Error element started by start.
+func feedError(d *xml.Decoder, start xml.StartElement) error {
+	var message strings.Builder
+	for depth := 1; depth > 0; {
+		tok, err := d.Token()
+		if err != nil {
+			return fmt.Errorf("could not parse error XML: %v", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			message.Write(t)
+		}
+	}
+	return &FeedError{Message: strings.TrimSpace(message.String()), Retryable: attrValue(start, "shouldRetry") == "true"}
+}
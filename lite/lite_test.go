@@ -0,0 +1,87 @@
+package lite
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dinedal/nextbus/fixtures"
+)
+
+func TestDecodePredictions(t *testing.T) {
+	data, err := fixtures.Raw("sf-muni", "predictionsForMultiStops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	predictions, err := DecodePredictions(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := fixtures.Predictions("sf-muni")
+	if len(predictions) != len(full) {
+		t.Fatalf("got %d stops, want %d", len(predictions), len(full))
+	}
+	for i, sp := range predictions {
+		if sp.RouteTag != full[i].RouteTag || sp.StopTag != full[i].StopTag || sp.StopTitle != full[i].StopTitle {
+			t.Errorf("stop %d is %+v, want route %s stop %s", i, sp, full[i].RouteTag, full[i].StopTag)
+		}
+		var n int
+		for _, dir := range full[i].PredictionDirectionList {
+			for _, p := range dir.PredictionList {
+				got := sp.Predictions[n]
+				if got.Vehicle != p.Vehicle || got.Direction != dir.Title || p.EpochTime != strconv.FormatInt(got.Epoch, 10) {
+					t.Errorf("prediction %d of stop %d is %+v, want %+v", n, i, got, p)
+				}
+				n++
+			}
+		}
+		if n != len(sp.Predictions) {
+			t.Errorf("stop %d has %d predictions, want %d", i, len(sp.Predictions), n)
+		}
+	}
+	first := predictions[0].Predictions[0]
+	if first.Seconds != 181 || first.Minutes() != 3 || first.IsDeparture {
+		t.Errorf("unexpected first prediction %+v", first)
+	}
+	if !predictions[0].Predictions[1].AffectedByLayover {
+		t.Errorf("expected the second prediction to be affected by a layover")
+	}
+	if len(predictions[0].Messages) != 1 {
+		t.Errorf("got messages %q, want one", predictions[0].Messages)
+	}
+}
+
+func TestDecodeVehicleLocations(t *testing.T) {
+	data, err := fixtures.Raw("sf-muni", "vehicleLocations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	locations, err := DecodeVehicleLocations(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locations.Vehicles) != len(fixtures.VehicleLocations("sf-muni").VehicleList) {
+		t.Fatalf("got %d vehicles", len(locations.Vehicles))
+	}
+	want := Vehicle{ID: "1506", RouteTag: "N", DirTag: "N____O_F00", Lat: 37.7701, Lon: -122.4262, Heading: 261, SpeedKmHr: 18, SecsSinceReport: 9, Predictable: true}
+	if locations.Vehicles[0] != want {
+		t.Errorf("got %+v, want %+v", locations.Vehicles[0], want)
+	}
+	if locations.LastTime != 1487276900000 {
+		t.Errorf("got lastTime %d", locations.LastTime)
+	}
+}
+
+func TestFeedError(t *testing.T) {
+	_, err := DecodeVehicleLocations(strings.NewReader(`<body><Error shouldRetry="true">
+  Agency server cannot accept client while status is: agency name = sf-muni,status = UNINITIALIZED
+</Error></body>`))
+	feedErr, isFeedError := err.(*FeedError)
+	if !isFeedError {
+		t.Fatalf("got %v, want a *FeedError", err)
+	}
+	if !feedErr.Retryable || !strings.HasPrefix(feedErr.Message, "Agency server") {
+		t.Errorf("unexpected error %+v", feedErr)
+	}
+}
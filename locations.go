@@ -0,0 +1,101 @@
+package nextbus
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+var vehicleElement = []byte("<vehicle ")
+
+// decodeLocationResponse is a faster equivalent of xml.Unmarshal for
+// vehicleLocations responses, which pollers decode many times a minute. It
+// walks the tokens instead of using reflection and sizes VehicleList up
+// front. Like xml.Unmarshal, it requires a <body> root and reads only its
+// direct children.
+func decodeLocationResponse(data []byte, out *LocationResponse) error {
+	*out = LocationResponse{XMLName: elementName("body")}
+	if n := bytes.Count(data, vehicleElement); n != 0 {
+		out.VehicleList = make([]VehicleLocation, 0, n)
+	}
+	d := newXMLDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			// Token reports truncated documents as syntax errors, so EOF here
+			// means no root element was read.
+			return fmt.Errorf("could not parse vehicle locations XML: expected element type <body>")
+		}
+		if err != nil {
+			return fmt.Errorf("could not parse vehicle locations XML: %v", err)
+		}
+		if _, isEnd := tok.(xml.EndElement); isEnd {
+			depth--
+			if depth == 0 {
+				break
+			}
+			continue
+		}
+		start, isStart := tok.(xml.StartElement)
+		if !isStart {
+			continue
+		}
+		depth++
+		if depth == 1 {
+			if start.Name.Local != "body" {
+				return fmt.Errorf("could not parse vehicle locations XML: expected element type <body> but have <%s>", start.Name.Local)
+			}
+			continue
+		}
+		if depth != 2 {
+			continue
+		}
+		switch start.Name.Local {
+		case "vehicle":
+			out.VehicleList = append(out.VehicleList, vehicleFromAttrs(start.Attr))
+		case "lastTime":
+			out.LastTime.XMLName = elementName("lastTime")
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "time" {
+					out.LastTime.Time = attr.Value
+				}
+			}
+		}
+	}
+	return checkFeedError(data)
+}
+
+func vehicleFromAttrs(attrs []xml.Attr) VehicleLocation {
+	v := VehicleLocation{XMLName: elementName("vehicle")}
+	for _, attr := range attrs {
+		switch attr.Name.Local {
+		case "id":
+			v.ID = attr.Value
+		case "routeTag":
+			v.RouteTag = attr.Value
+		case "dirTag":
+			v.DirTag = attr.Value
+		case "lat":
+			v.Lat = attr.Value
+		case "lon":
+			v.Lon = attr.Value
+		case "secsSinceReport":
+			v.SecsSinceReport = attr.Value
+		case "predictable":
+			v.Predictable = attr.Value
+		case "heading":
+			v.Heading = attr.Value
+		case "speedKmHr":
+			v.SpeedKmHr = attr.Value
+		case "leadingVehicleId":
+			v.LeadingVehicleID = attr.Value
+		}
+	}
+	return v
+}
+
+func elementName(local string) xml.Name {
+	return xml.Name{Local: local}
+}
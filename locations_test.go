@@ -0,0 +1,80 @@
+package nextbus
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestDecodeLocationResponseMatchesUnmarshal(t *testing.T) {
+	data := []byte(fakes[makeURL("vehicleLocations", "a", "alpha", "t", "0")])
+	var expected, found LocationResponse
+	ok(t, xml.Unmarshal(data, &expected))
+	ok(t, decodeLocationResponse(data, &found))
+	equals(t, expected, found)
+}
+
+func TestDecodeLocationResponseErrors(t *testing.T) {
+	var found LocationResponse
+	err := decodeLocationResponse([]byte(`<body><Error shouldRetry="false">Agency parameter "a=gamma" is not valid.</Error></body>`), &found)
+	_, isFeedErr := err.(*FeedError)
+	assert(t, isFeedErr, "expected a *FeedError, got %v", err)
+
+	err = decodeLocationResponse([]byte(`<html>`), &found)
+	assert(t, err != nil, "expected an error for a non-feed document")
+
+	err = decodeLocationResponse([]byte(`<html><body><vehicle id="1"/></body></html>`), &found)
+	assert(t, err != nil, "expected an error for a document whose root isn't <body>")
+}
+
+func TestDecodeLocationResponseTruncated(t *testing.T) {
+	data := []byte(`<body><vehicle id="1" routeTag="N" lat="37.7" lon="-122.4"/>`)
+	var expected, found LocationResponse
+	assert(t, xml.Unmarshal(data, &expected) != nil, "expected xml.Unmarshal to reject truncated input")
+	err := decodeLocationResponse(data, &found)
+	assert(t, err != nil, "expected an error for truncated input, got %d vehicles", len(found.VehicleList))
+}
+
+func TestDecodeLocationResponseDirectChildren(t *testing.T) {
+	data := []byte(`<body><vehicle id="1"/><group><vehicle id="2"/></group><lastTime time="5"/></body>`)
+	var expected, found LocationResponse
+	ok(t, xml.Unmarshal(data, &expected))
+	ok(t, decodeLocationResponse(data, &found))
+	equals(t, expected, found)
+}
+
+func TestDecodeLocationResponseAllocs(t *testing.T) {
+	data := largeLocationResponse(200)
+	unmarshal := testing.AllocsPerRun(10, func() {
+		var result LocationResponse
+		xml.Unmarshal(data, &result)
+	})
+	fast := testing.AllocsPerRun(10, func() {
+		var result LocationResponse
+		decodeLocationResponse(data, &result)
+	})
+	assert(t, fast < unmarshal*0.75, "decodeLocationResponse made %v allocations, xml.Unmarshal %v", fast, unmarshal)
+}
+
+func BenchmarkUnmarshalLocationResponse(b *testing.B) {
+	data := largeLocationResponse(benchVehicles)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		var result LocationResponse
+		if err := xml.Unmarshal(data, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeLocationResponse(b *testing.B) {
+	data := largeLocationResponse(benchVehicles)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		var result LocationResponse
+		if err := decodeLocationResponse(data, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
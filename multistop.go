@@ -0,0 +1,113 @@
+package nextbus
+
+import (
+	"errors"
+	"regexp"
+)
+
+// RouteStop identifies a stop on a particular route.
+type RouteStop struct {
+	RouteTag string
+	StopTag  string
+}
+
+// StopError records why predictions for a stop could not be fetched.
+type StopError struct {
+	RouteStop
+	Err error
+}
+
+func (e StopError) Error() string {
+	return e.RouteTag + "|" + e.StopTag + ": " + e.Err.Error()
+}
+
+// MultiStopResult is the outcome of a GetPredictionsForMultiStopsResilient
+// call.
+type MultiStopResult struct {
+	Predictions []PredictionData
+	Failed      []StopError
+}
+
+// These match the ways NextBus names the route and stop that caused a request
+// to fail, e.g. `stop s=1234 is on none of the directions for r=N` or
+// `Could not get stop "1234" for route "N"`.
+var (
+	failedStopPattern  = regexp.MustCompile(`(?:\bs=|stop "|stop=")([^\s"&|]+)`)
+	failedRoutePattern = regexp.MustCompile(`(?:\br=|route "|route=")([^\s"&|]+)`)
+)
+
+// failingStops returns the members of stops that the NextBus error message
+// msg refers to. If the message names a route but no stop, every stop on that
+// route is returned.
+func failingStops(msg string, stops []RouteStop) []RouteStop {
+	var stopTag, routeTag string
+	if m := failedStopPattern.FindStringSubmatch(msg); m != nil {
+		stopTag = m[1]
+	}
+	if m := failedRoutePattern.FindStringSubmatch(msg); m != nil {
+		routeTag = m[1]
+	}
+	if stopTag == "" && routeTag == "" {
+		return nil
+	}
+
+	var result []RouteStop
+	for _, rs := range stops {
+		if (stopTag == "" || rs.StopTag == stopTag) && (routeTag == "" || rs.RouteTag == routeTag) {
+			result = append(result, rs)
+		}
+	}
+	return result
+}
+
+// GetPredictionsForMultiStopsResilient fetches predictions for several stops
+// like GetPredictionsForMultiStops. NextBus fails the whole request if any one
+// stop is invalid; when that happens the failing stop is identified from the
+// error message, recorded in the result's Failed list, and the request is
+// retried without it. An error is only returned if the failing stop cannot be
+// identified or the request fails for another reason.
+func (c *Client) GetPredictionsForMultiStopsResilient(agencyTag string, stops []RouteStop, params ...PredReqParam) (*MultiStopResult, error) {
+	remaining := append([]RouteStop(nil), stops...)
+	var result MultiStopResult
+	for len(remaining) != 0 {
+		reqParams := make([]PredReqParam, 0, len(remaining)+len(params))
+		for _, rs := range remaining {
+			reqParams = append(reqParams, PredReqStop(rs.RouteTag, rs.StopTag))
+		}
+		reqParams = append(reqParams, params...)
+
+		predictions, err := c.GetPredictionsForMultiStops(agencyTag, reqParams...)
+		if err == nil {
+			result.Predictions = predictions
+			return &result, nil
+		}
+
+		var feedErr *FeedError
+		if !errors.As(err, &feedErr) {
+			return nil, err
+		}
+		failed := failingStops(feedErr.Message, remaining)
+		if len(failed) == 0 {
+			return nil, err
+		}
+		for _, rs := range failed {
+			result.Failed = append(result.Failed, StopError{rs, feedErr})
+		}
+		remaining = withoutStops(remaining, failed)
+	}
+	return &result, nil
+}
+
+func withoutStops(stops, remove []RouteStop) []RouteStop {
+	drop := make(map[RouteStop]bool, len(remove))
+	for _, rs := range remove {
+		drop[rs] = true
+	}
+	var result []RouteStop
+	for _, rs := range stops {
+		if !drop[rs] {
+			result = append(result, rs)
+		}
+	}
+	return result
+}
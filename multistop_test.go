@@ -0,0 +1,34 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestGetPredictionsForMultiStopsReturnsFeedError(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	_, err := nb.GetPredictionsForMultiStops("alpha", PredReqStop("1", "1123"), PredReqStop("2", "1123"))
+	feedErr, isFeedErr := err.(*FeedError)
+	assert(t, isFeedErr, "expected a *FeedError, got %v", err)
+	assert(t, !feedErr.Retryable(), "expected error not to be retryable")
+	equals(t, `nextbus: Could not get route "2". Route not found`, err.Error())
+}
+
+func TestGetPredictionsForMultiStopsResilient(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	stops := []RouteStop{{"1", "1123"}, {"1", "9999"}, {"2", "1123"}}
+	result, err := nb.GetPredictionsForMultiStopsResilient("alpha", stops)
+	ok(t, err)
+
+	equals(t, 1, len(result.Predictions))
+	equals(t, "1123", result.Predictions[0].StopTag)
+	equals(t, 2, len(result.Failed))
+	equals(t, RouteStop{"1", "9999"}, result.Failed[0].RouteStop)
+	equals(t, RouteStop{"2", "1123"}, result.Failed[1].RouteStop)
+}
+
+func TestFailingStops(t *testing.T) {
+	stops := []RouteStop{{"N", "5205"}, {"N", "5206"}, {"J", "5205"}}
+	equals(t, []RouteStop{{"N", "5206"}}, failingStops("Invalid stop s=5206 for r=N", stops))
+	equals(t, []RouteStop{{"N", "5205"}, {"J", "5205"}}, failingStops(`Could not get stop "5205"`, stops))
+	equals(t, []RouteStop(nil), failingStops("Agency server cannot accept client while status is: Connecting", stops))
+}
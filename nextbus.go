@@ -1,25 +1,62 @@
 package nextbus
 
 import (
+	"context"
 	"encoding/xml"
-	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strings"
+	"time"
 )
 
 // DefaultClient uses the default http client to make requests
-var DefaultClient = &Client{http.DefaultClient}
+var DefaultClient = NewClient(http.DefaultClient)
 
 // Client is used to make requests
 type Client struct {
-	httpClient *http.Client
+	httpClient    *http.Client
+	limiter       *rateLimiter
+	retries       int
+	retryBackoff  time.Duration
+	userAgent     string
+	contact       string
+	transport     *transportOptions
+	stalePolicy   *StalePolicy
+	throttle      throttle
+	cache         *DiskCache
+	clock         Clock
+	observe       func(RequestInfo)
+	charsetReader CharsetReader
+	har           *HARRecorder
+	feedURL       string
+	headers       http.Header
+
+	routeConfigFallback int
 }
 
-// NewClient creates a new nextbus client.
-func NewClient(httpClient *http.Client) *Client {
-	return &Client{httpClient}
+// Version is the version of this package, reported in DefaultUserAgent.
+const Version = "0.1.0"
+
+// DefaultUserAgent is the User-Agent header sent when WithUserAgent isn't
+// used.
+const DefaultUserAgent = "nextbus-go/" + Version
+
+// NewClient creates a new nextbus client. A nil httpClient uses a client
+// with default settings.
+func NewClient(httpClient *http.Client, opts ...Option) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	c := &Client{httpClient: httpClient, userAgent: DefaultUserAgent}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.transport != nil {
+		c.httpClient = c.transport.apply(c.httpClient)
+	}
+	if c.har != nil {
+		c.httpClient = c.har.wrap(c.httpClient, c.headers)
+	}
+	return c
 }
 
 // AgencyResponse represents a list of transit agencies.
@@ -38,20 +75,14 @@ type Agency struct {
 
 // GetAgencyList fetches the list of supported transit agencies by nextbus.
 func (c *Client) GetAgencyList() ([]Agency, error) {
-	resp, httpErr := c.httpClient.Get("http://webservices.nextbus.com/service/publicXMLFeed?command=agencyList")
-	if httpErr != nil {
-		return nil, fmt.Errorf("could not fetch agencies from nextbus: %v", httpErr)
-	}
-	defer resp.Body.Close()
-
-	body, readErr := ioutil.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, fmt.Errorf("could not parse agencies response body: %v", readErr)
-	}
+	return c.GetAgencyListContext(context.Background())
+}
 
-	var a AgencyResponse
-	if xmlErr := xml.Unmarshal(body, &a); xmlErr != nil {
-		return nil, fmt.Errorf("could not parse agencies XML: %v", xmlErr)
+// GetAgencyListContext is like GetAgencyList but makes its request with ctx.
+func (c *Client) GetAgencyListContext(ctx context.Context) ([]Agency, error) {
+	a, err := fetchAndDecode[AgencyResponse](ctx, c, "agencyList", nil)
+	if err != nil {
+		return nil, err
 	}
 	return a.AgencyList, nil
 }
@@ -71,21 +102,14 @@ type Route struct {
 
 // GetRouteList fetches the list of routes within the specified agency.
 func (c *Client) GetRouteList(agencyTag string) ([]Route, error) {
-	resp, httpErr := c.httpClient.Get("http://webservices.nextbus.com/service/publicXMLFeed?command=routeList&a=" + agencyTag)
-	if httpErr != nil {
-		return nil, fmt.Errorf("could not fetch routes from nextbus: %v", httpErr)
-	}
-	defer resp.Body.Close()
-
-	body, readErr := ioutil.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, fmt.Errorf("could not parse routes response body: %v", readErr)
-	}
+	return c.GetRouteListContext(context.Background(), agencyTag)
+}
 
-	var a RouteResponse
-	xmlErr := xml.Unmarshal(body, &a)
-	if xmlErr != nil {
-		return nil, fmt.Errorf("could not parse routes XML: %v", xmlErr)
+// GetRouteListContext is like GetRouteList but makes its request with ctx.
+func (c *Client) GetRouteListContext(ctx context.Context, agencyTag string) ([]Route, error) {
+	a, err := fetchAndDecode[RouteResponse](ctx, c, "routeList", []string{"a=" + url.QueryEscape(agencyTag)})
+	if err != nil {
+		return nil, err
 	}
 	return a.RouteList, nil
 }
@@ -152,53 +176,46 @@ type Point struct {
 	Lon     string   `xml:"lon,attr"`
 }
 
-// RouteConfigParam is a configuration parameters for GetRouteConfig.
+// RouteConfigParam is a configuration parameters for GetRouteConfig. It
+// returns an escaped query fragment; see Param for the typed equivalent.
 type RouteConfigParam func() string
 
 // RouteConfigTag creates a RouteConfigParam that restricts a
 // GetRouteConfig call to a single route.
 func RouteConfigTag(tag string) RouteConfigParam {
-	return func() string {
-		return "r=" + url.QueryEscape(tag)
-	}
+	return RouteParam(tag).Encode
 }
 
 // RouteConfigTerse configures a GetRouteConfig call to avoid path results
 func RouteConfigTerse() RouteConfigParam {
-	return func() string {
-		return "terse"
-	}
+	return TerseParam().Encode
 }
 
 // RouteConfigVerbose configures a GetRouteConfig call to include directions
 // not normally shown in UIs.
 func RouteConfigVerbose() RouteConfigParam {
-	return func() string {
-		return "verbose"
-	}
+	return VerboseParam().Encode
 }
 
 // GetRouteConfig fetches the metadata for routes in a particular transit
-// agency. Use the configParams to filter the requested data.
+// agency. Use the configParams to filter the requested data. See
+// WithRouteConfigFallback for agencies too large to fetch at once.
 func (c *Client) GetRouteConfig(agencyTag string, configParams ...RouteConfigParam) ([]RouteConfig, error) {
-	params := []string{"command=routeConfig", "a=" + url.QueryEscape(agencyTag)}
-	for _, cp := range configParams {
-		params = append(params, cp())
-	}
-	resp, httpErr := c.httpClient.Get("http://webservices.nextbus.com/service/publicXMLFeed?" + strings.Join(params, "&"))
-	if httpErr != nil {
-		return nil, fmt.Errorf("could not fetch route config from nextbus: %v", httpErr)
-	}
-	defer resp.Body.Close()
+	return c.GetRouteConfigContext(context.Background(), agencyTag, configParams...)
+}
 
-	body, readErr := ioutil.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, fmt.Errorf("could not parse route config response body: %v", readErr)
+// GetRouteConfigContext is like GetRouteConfig but makes its request with ctx.
+func (c *Client) GetRouteConfigContext(ctx context.Context, agencyTag string, configParams ...RouteConfigParam) ([]RouteConfig, error) {
+	params, paramErr := buildQuery("routeConfig", agencyTag, toParams(configParams))
+	if paramErr != nil {
+		return nil, paramErr
 	}
-
-	var a RouteConfigResponse
-	if xmlErr := xml.Unmarshal(body, &a); xmlErr != nil {
-		return nil, fmt.Errorf("could not parse route config XML: %v", xmlErr)
+	a, err := fetchAndDecode[RouteConfigResponse](ctx, c, "routeConfig", params)
+	if err != nil {
+		if c.routeConfigFallback > 0 && isOversized(err) && !hasRouteTag(configParams) {
+			return c.GetRouteConfigPerRouteContext(ctx, agencyTag, c.routeConfigFallback, configParams...)
+		}
+		return nil, err
 	}
 	return a.RouteList, nil
 }
@@ -207,19 +224,22 @@ func (c *Client) GetRouteConfig(agencyTag string, configParams ...RouteConfigPar
 type PredictionResponse struct {
 	XMLName            xml.Name         `xml:"body"`
 	PredictionDataList []PredictionData `xml:"predictions"`
+	Error              *FeedError       `xml:"Error"`
 }
 
 // PredictionData represents a prediction for a particular route and stop. It
-// contains a set of predictions arranged by direction.
+// contains a set of predictions arranged by direction. When there are no
+// predictions, DirTitleBecauseNoPredictions names the stop's direction instead.
 type PredictionData struct {
-	XMLName                 xml.Name              `xml:"predictions"`
-	PredictionDirectionList []PredictionDirection `xml:"direction"`
-	MessageList             []Message             `xml:"message"`
-	AgencyTitle             string                `xml:"agencyTitle,attr"`
-	RouteTitle              string                `xml:"routeTitle,attr"`
-	RouteTag                string                `xml:"routeTag,attr"`
-	StopTitle               string                `xml:"stopTitle,attr"`
-	StopTag                 string                `xml:"stopTag,attr"`
+	XMLName                      xml.Name              `xml:"predictions"`
+	PredictionDirectionList      []PredictionDirection `xml:"direction"`
+	MessageList                  []Message             `xml:"message"`
+	AgencyTitle                  string                `xml:"agencyTitle,attr"`
+	RouteTitle                   string                `xml:"routeTitle,attr"`
+	RouteTag                     string                `xml:"routeTag,attr"`
+	StopTitle                    string                `xml:"stopTitle,attr"`
+	StopTag                      string                `xml:"stopTag,attr"`
+	DirTitleBecauseNoPredictions string                `xml:"dirTitleBecauseNoPredictions,attr"`
 }
 
 // PredictionDirection contains a list of arrival predictions for a particular
@@ -244,6 +264,8 @@ type Prediction struct {
 	VehiclesInConsist string   `xml:"vehiclesInConsist,attr"`
 	Block             string   `xml:"block,attr"`
 	TripTag           string   `xml:"tripTag,attr"`
+	IsScheduleBased   string   `xml:"isScheduleBased,attr"`
+	Delayed           string   `xml:"delayed,attr"`
 }
 
 // Message is an informational message provided by the transit agency.
@@ -257,20 +279,15 @@ type Message struct {
 // provided stop. Note that this requires the 'stopID' which is the unique
 // identifier for a stop indepenedent of a route.
 func (c *Client) GetStopPredictions(agencyTag string, stopID string) ([]PredictionData, error) {
-	resp, httpErr := c.httpClient.Get("http://webservices.nextbus.com/service/publicXMLFeed?command=predictions&a=" + agencyTag + "&stopId=" + stopID)
-	if httpErr != nil {
-		return nil, fmt.Errorf("could not fetch stop predictions from nextbus: %v", httpErr)
-	}
-	defer resp.Body.Close()
-
-	body, readErr := ioutil.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, fmt.Errorf("could not parse stop predictions response body: %v", readErr)
-	}
+	return c.GetStopPredictionsContext(context.Background(), agencyTag, stopID)
+}
 
-	var a PredictionResponse
-	if xmlErr := xml.Unmarshal(body, &a); xmlErr != nil {
-		return nil, fmt.Errorf("could not parse stop predictions XML: %v", xmlErr)
+// GetStopPredictionsContext is like GetStopPredictions but makes its request with ctx.
+func (c *Client) GetStopPredictionsContext(ctx context.Context, agencyTag string, stopID string) ([]PredictionData, error) {
+	params := []string{"a=" + url.QueryEscape(agencyTag), "stopId=" + url.QueryEscape(stopID)}
+	a, err := fetchAndDecode[PredictionResponse](ctx, c, "predictions", params)
+	if err != nil {
+		return nil, err
 	}
 	return a.PredictionDataList, nil
 }
@@ -278,66 +295,48 @@ func (c *Client) GetStopPredictions(agencyTag string, stopID string) ([]Predicti
 // GetPredictions fetches a set of predictions for a transit agency at the
 // provided route and stop.
 func (c *Client) GetPredictions(agencyTag string, routeTag string, stopTag string) ([]PredictionData, error) {
-	resp, httpErr := c.httpClient.Get("http://webservices.nextbus.com/service/publicXMLFeed?command=predictions&a=" + agencyTag + "&r=" + routeTag + "&s=" + stopTag)
-	if httpErr != nil {
-		return nil, fmt.Errorf("could not fetch predictions from nextbus: %v", httpErr)
-	}
-	defer resp.Body.Close()
-
-	body, readErr := ioutil.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, fmt.Errorf("could not parse predictions response body: %v", readErr)
-	}
+	return c.GetPredictionsContext(context.Background(), agencyTag, routeTag, stopTag)
+}
 
-	var a PredictionResponse
-	if xmlErr := xml.Unmarshal(body, &a); xmlErr != nil {
-		return nil, fmt.Errorf("could not parse predictions XML: %v", xmlErr)
+// GetPredictionsContext is like GetPredictions but makes its request with ctx.
+func (c *Client) GetPredictionsContext(ctx context.Context, agencyTag string, routeTag string, stopTag string) ([]PredictionData, error) {
+	params := []string{"a=" + url.QueryEscape(agencyTag), "r=" + url.QueryEscape(routeTag), "s=" + url.QueryEscape(stopTag)}
+	a, err := fetchAndDecode[PredictionResponse](ctx, c, "predictions", params)
+	if err != nil {
+		return nil, err
 	}
 	return a.PredictionDataList, nil
 }
 
 // PredReqParam knows how to configure a request for a multi stop prediction.
+// It returns an escaped query fragment; see Param for the typed equivalent.
 type PredReqParam func() string
 
 // PredReqStop specifies a route and stop which we want predictions for.
 func PredReqStop(routeTag, stopTag string) PredReqParam {
-	return func() string {
-		return "stops=" + url.QueryEscape(fmt.Sprintf("%s|%s", routeTag, stopTag))
-	}
+	return StopParam(routeTag, stopTag).Encode
 }
 
 // PredReqShortTitles specifies that we want short titles in our
 // predictions response.
 func PredReqShortTitles() PredReqParam {
-	return func() string {
-		return "useShortTitles=true"
-	}
+	return ShortTitlesParam().Encode
 }
 
 // GetPredictionsForMultiStops Issues a request to get predictions for multiple stops.
 func (c *Client) GetPredictionsForMultiStops(agencyTag string, params ...PredReqParam) ([]PredictionData, error) {
-	queryParams := []string{
-		"command=predictionsForMultiStops",
-		"a=" + url.QueryEscape(agencyTag),
-	}
-	for _, p := range params {
-		queryParams = append(queryParams, p())
-	}
-
-	resp, httpErr := c.httpClient.Get("http://webservices.nextbus.com/service/publicXMLFeed?" + strings.Join(queryParams, "&"))
-	if httpErr != nil {
-		return nil, fmt.Errorf("could not fetch predictions for multiple stops from nextbus: %v", httpErr)
-	}
-	defer resp.Body.Close()
+	return c.GetPredictionsForMultiStopsContext(context.Background(), agencyTag, params...)
+}
 
-	body, readErr := ioutil.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, fmt.Errorf("could not parse predictions for multiple stops response body: %v", readErr)
+// GetPredictionsForMultiStopsContext is like GetPredictionsForMultiStops but makes its request with ctx.
+func (c *Client) GetPredictionsForMultiStopsContext(ctx context.Context, agencyTag string, params ...PredReqParam) ([]PredictionData, error) {
+	queryParams, paramErr := buildQuery("predictionsForMultiStops", agencyTag, toParams(params))
+	if paramErr != nil {
+		return nil, paramErr
 	}
-
-	var a PredictionResponse
-	if xmlErr := xml.Unmarshal(body, &a); xmlErr != nil {
-		return nil, fmt.Errorf("could not parse predictions for multiple stops XML: %v", xmlErr)
+	a, err := fetchAndDecode[PredictionResponse](ctx, c, "predictionsForMultiStops", queryParams)
+	if err != nil {
+		return nil, err
 	}
 	return a.PredictionDataList, nil
 }
@@ -372,54 +371,125 @@ type LocationLastTime struct {
 }
 
 // VehicleLocationParam is used to specify options when fetching vehicle
-// locations.
+// locations. It returns an escaped query fragment; see Param for the typed
+// equivalent.
 type VehicleLocationParam func() string
 
 // VehicleLocationRoute returns a VehicleLocationParam that indicates the
 // desired route to filter vehicle locations by.
 func VehicleLocationRoute(routeTag string) VehicleLocationParam {
-	return func() string {
-		return "r=" + url.QueryEscape(routeTag)
-	}
+	return RouteParam(routeTag).Encode
 }
 
 // VehicleLocationTime returns a VehicleLocationParam that indicates the
 // desired time after which to fetch vehicle locations.
 func VehicleLocationTime(t string) VehicleLocationParam {
-	return func() string {
-		return "t=" + url.QueryEscape(t)
-	}
+	return TimeParam(t).Encode
 }
 
-// GetVehicleLocations fetches the set of vehicle locations for a transit
-// agency. Use the configParams to filter the requested data.
-func (c *Client) GetVehicleLocations(agencyTag string, configParams ...VehicleLocationParam) (*LocationResponse, error) {
-	params := []string{"command=vehicleLocations", "a=" + url.QueryEscape(agencyTag)}
+// vehicleLocationParams builds the query for a vehicleLocations request,
+// defaulting t to 0 when the caller didn't provide one.
+func vehicleLocationParams(agencyTag string, configParams []VehicleLocationParam) ([]string, error) {
+	params := toParams(configParams)
 	timeWasSet := false
-	for _, cp := range configParams {
-		paramText := cp()
-		if strings.HasPrefix(paramText, "t=") {
+	for _, p := range params {
+		if p.Key == "t" {
 			timeWasSet = true
 		}
-		params = append(params, paramText)
 	}
 	if !timeWasSet {
-		params = append(params, VehicleLocationTime("0")())
-	}
-	resp, httpErr := c.httpClient.Get("http://webservices.nextbus.com/service/publicXMLFeed?" + strings.Join(params, "&"))
-	if httpErr != nil {
-		return nil, fmt.Errorf("could not fetch vehicle locations from nextbus: %v", httpErr)
+		params = append(params, TimeParam("0"))
 	}
-	defer resp.Body.Close()
+	return buildQuery("vehicleLocations", agencyTag, params)
+}
 
-	body, readErr := ioutil.ReadAll(resp.Body)
-	if readErr != nil {
-		return nil, fmt.Errorf("could not parse vehicle locations response body: %v", readErr)
-	}
+// GetVehicleLocations fetches the set of vehicle locations for a transit
+// agency. Use the configParams to filter the requested data.
+func (c *Client) GetVehicleLocations(agencyTag string, configParams ...VehicleLocationParam) (*LocationResponse, error) {
+	return c.GetVehicleLocationsContext(context.Background(), agencyTag, configParams...)
+}
 
+// GetVehicleLocationsContext is like GetVehicleLocations but makes its request with ctx.
+func (c *Client) GetVehicleLocationsContext(ctx context.Context, agencyTag string, configParams ...VehicleLocationParam) (*LocationResponse, error) {
+	params, paramErr := vehicleLocationParams(agencyTag, configParams)
+	if paramErr != nil {
+		return nil, paramErr
+	}
 	var result LocationResponse
-	if xmlErr := xml.Unmarshal(body, &result); xmlErr != nil {
-		return nil, fmt.Errorf("could not parse vehicle locations XML: %v", xmlErr)
+	err := c.fetch(ctx, "vehicleLocations", params, func(data []byte) error {
+		return decodeLocationResponse(data, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c.stalePolicy != nil {
+		result.VehicleList = c.stalePolicy.Filter(result.VehicleList)
 	}
 	return &result, nil
 }
+
+// ScheduleResponse is the set of schedules for a route.
+type ScheduleResponse struct {
+	XMLName      xml.Name   `xml:"body"`
+	ScheduleList []Schedule `xml:"route"`
+	Error        *FeedError `xml:"Error"`
+}
+
+// Schedule is the timetable for one direction of a route on one class of
+// service day, such as weekdays.
+type Schedule struct {
+	XMLName       xml.Name        `xml:"route"`
+	Tag           string          `xml:"tag,attr"`
+	Title         string          `xml:"title,attr"`
+	ScheduleClass string          `xml:"scheduleClass,attr"`
+	ServiceClass  string          `xml:"serviceClass,attr"`
+	Direction     string          `xml:"direction,attr"`
+	Header        ScheduleHeader  `xml:"header"`
+	BlockList     []ScheduleBlock `xml:"tr"`
+}
+
+// ScheduleHeader lists the timepoint stops that appear in a schedule.
+type ScheduleHeader struct {
+	XMLName  xml.Name             `xml:"header"`
+	StopList []ScheduleHeaderStop `xml:"stop"`
+}
+
+// ScheduleHeaderStop is a timepoint stop named in a schedule header.
+type ScheduleHeaderStop struct {
+	XMLName xml.Name `xml:"stop"`
+	Tag     string   `xml:"tag,attr"`
+	Title   string   `xml:",chardata"`
+}
+
+// ScheduleBlock is one row of a schedule: the times a vehicle block is
+// scheduled to reach each timepoint on a single trip.
+type ScheduleBlock struct {
+	XMLName  xml.Name       `xml:"tr"`
+	BlockID  string         `xml:"blockID,attr"`
+	StopList []ScheduleStop `xml:"stop"`
+}
+
+// ScheduleStop is the scheduled time at a timepoint. EpochTime is the number
+// of milliseconds since midnight of the service day, or "-1" if the trip
+// doesn't serve the stop.
+type ScheduleStop struct {
+	XMLName   xml.Name `xml:"stop"`
+	Tag       string   `xml:"tag,attr"`
+	EpochTime string   `xml:"epochTime,attr"`
+	Time      string   `xml:",chardata"`
+}
+
+// GetSchedule fetches the schedules for a route.
+func (c *Client) GetSchedule(agencyTag, routeTag string) ([]Schedule, error) {
+	return c.GetScheduleContext(context.Background(), agencyTag, routeTag)
+}
+
+// GetScheduleContext is like GetSchedule but makes its request with ctx.
+func (c *Client) GetScheduleContext(ctx context.Context, agencyTag, routeTag string) ([]Schedule, error) {
+	params := []string{"a=" + url.QueryEscape(agencyTag), "r=" + url.QueryEscape(routeTag)}
+	a, err := fetchAndDecode[ScheduleResponse](ctx, c, "schedule", params)
+	if err != nil {
+		return nil, err
+	}
+	return a.ScheduleList, nil
+}
@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -57,6 +58,51 @@ var fakes = map[string]string{
 </direction>
 </route>
 </body>
+`,
+	makeURL("routeConfig", "a", "alpha", "r", "1"): `
+<body copyright="All data copyright some transit company.">
+<route tag="1" title="1-first" color="660000" oppositeColor="ffffff" latMin="12.3456789" latMax="45.6789012" lonMin="-123.4567890" lonMax="-456.78901">
+<stop tag="1123" title="First stop" lat="12.3456789" lon="-123.45789" stopId="98765"/>
+<stop tag="1234" title="Second stop" lat="23.4567890" lon="-456.78901" stopId="87654"/>
+</route>
+</body>
+`,
+	makeURL("routeConfig", "a", "alpha", "r", "9"): `
+<body copyright="All data copyright some transit company.">
+<Error shouldRetry="false">
+Could not get route "9". Route not found
+</Error>
+</body>
+`,
+	makeURL("schedule", "a", "alpha", "r", "1"): `
+<body copyright="All data copyright some transit company.">
+<route tag="1" title="1-first" scheduleClass="2017T" serviceClass="wkd" direction="Outbound">
+<header>
+<stop tag="1123">First stop</stop>
+<stop tag="1234">Second stop</stop>
+</header>
+<tr blockID="101">
+<stop tag="1123" epochTime="25200000">07:00:00</stop>
+<stop tag="1234" epochTime="25800000">07:10:00</stop>
+</tr>
+<tr blockID="102">
+<stop tag="1123" epochTime="25800000">07:10:00</stop>
+<stop tag="1234" epochTime="26400000">07:20:00</stop>
+</tr>
+<tr blockID="103">
+<stop tag="1123" epochTime="-1">--</stop>
+<stop tag="1234" epochTime="27000000">07:30:00</stop>
+</tr>
+<tr blockID="101">
+<stop tag="1123" epochTime="27000000">07:30:00</stop>
+<stop tag="1234" epochTime="27600000">07:40:00</stop>
+</tr>
+<tr blockID="102">
+<stop tag="1123" epochTime="43200000">12:00:00</stop>
+<stop tag="1234" epochTime="43800000">12:10:00</stop>
+</tr>
+</route>
+</body>
 `,
 	makeURL("vehicleLocations", "a", "alpha", "t", "0"): `
 <body copyright="All data copyright some transit company.">
@@ -64,6 +110,12 @@ var fakes = map[string]string{
 <vehicle id="2222" routeTag="2" dirTag="2_inbound" lat="37.74891" lon="-122.45848" secsSinceReport="5" predictable="true" heading="217" speedKmHr="0" leadingVehicleId="2223"/>
 <lastTime time="1234567890123"/>
 </body>
+`,
+	makeURL("vehicleLocations", "a", "alpha", "t", "1234567890123"): `
+<body copyright="All data copyright some transit company.">
+<vehicle id="2222" routeTag="2" dirTag="2_inbound" lat="37.74901" lon="-122.45858" secsSinceReport="2" predictable="true" heading="217" speedKmHr="12" leadingVehicleId="2223"/>
+<lastTime time="1234567900123"/>
+</body>
 `,
 	makeURL("predictions", "a", "alpha", "stopId", "11123"): `
 <body copyright="All data copyright some transit company.">
@@ -80,6 +132,40 @@ var fakes = map[string]string{
 </direction>
 </predictions>
 </body>
+`,
+	makeURL("predictionsForMultiStops", "a", "alpha", "stops", "1|1123", "stops", "1|9999", "stops", "2|1123"): `
+<body copyright="All data copyright some transit company.">
+<Error shouldRetry="false">
+For agency=alpha stop s=9999 is on none of the directions for r=1 so cannot determine which stop to provide data for.
+</Error>
+</body>
+`,
+	makeURL("predictionsForMultiStops", "a", "alpha", "stops", "1|1123", "stops", "2|1123"): `
+<body copyright="All data copyright some transit company.">
+<Error shouldRetry="false">
+Could not get route "2". Route not found
+</Error>
+</body>
+`,
+	makeURL("predictionsForMultiStops", "a", "alpha", "stops", "1|1123", "stops", "1|1234"): `
+<body copyright="All data copyright some transit company.">
+<predictions agencyTitle="some transit company" routeTitle="The First" routeTag="1" stopTitle="First stop" stopTag="1123">
+<direction title="Outbound">
+<prediction epochTime="1487277081162" seconds="181" minutes="3" isDeparture="false" dirTag="1out" vehicle="1111" block="9999" tripTag="7318265"/>
+</direction>
+</predictions>
+<predictions agencyTitle="some transit company" routeTitle="The First" routeTag="1" stopTitle="Second stop" stopTag="1234" dirTitleBecauseNoPredictions="Outbound to somewhere">
+</predictions>
+</body>
+`,
+	makeURL("predictionsForMultiStops", "a", "alpha", "stops", "1|1123"): `
+<body copyright="All data copyright some transit company.">
+<predictions agencyTitle="some transit company" routeTitle="The First" routeTag="1" stopTitle="Some Station Outbound" stopTag="1123">
+<direction title="Outbound">
+<prediction epochTime="1487277081162" seconds="181" minutes="3" isDeparture="false" dirTag="1____O_F00" vehicle="1111" vehiclesInConsist="2" block="9999" tripTag="7318265"/>
+</direction>
+</predictions>
+</body>
 `,
 	makeURL("predictionsForMultiStops", "a", "alpha", "stops", "1|1123", "stops", "1|1124"): `
 <body copyright="All data copyright some transit company.">
@@ -126,6 +212,73 @@ func (f fakeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	return &res, nil
 }
 
+// fakeFeed is a feed server for tests that answers each command with a
+// canned body and records the requests it gets.
+type fakeFeed struct {
+	// Bodies are the responses by command. The "" entry answers the commands
+	// without one.
+	Bodies map[string]string
+	// Status is the status of every response; zero is 200 OK.
+	Status int
+	// Respond, if set, gives the body of each request instead of Bodies.
+	Respond func(req *http.Request) string
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+// cannedFeed returns a fakeFeed answering every command with body.
+func cannedFeed(body string) *fakeFeed {
+	return &fakeFeed{Bodies: map[string]string{"": body}}
+}
+
+func (f *fakeFeed) RoundTrip(req *http.Request) (*http.Response, error) {
+	command := req.URL.Query().Get("command")
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	body, found := f.Bodies[command]
+	if !found {
+		body = f.Bodies[""]
+	}
+	status := f.Status
+	f.mu.Unlock()
+	if f.Respond != nil {
+		body = f.Respond(req)
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	res := statusResponse(req, status)
+	res.Body = ioutil.NopCloser(strings.NewReader(body))
+	return res, nil
+}
+
+// Client returns an HTTP client whose requests f answers.
+func (f *fakeFeed) Client() *http.Client {
+	return &http.Client{Transport: f}
+}
+
+// Set changes the response to a command.
+func (f *fakeFeed) Set(command, body string) {
+	f.mu.Lock()
+	f.Bodies[command] = body
+	f.mu.Unlock()
+}
+
+// Count returns the number of requests f has answered.
+func (f *fakeFeed) Count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// Requests returns the requests f has answered, in order.
+func (f *fakeFeed) Requests() []*http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*http.Request(nil), f.requests...)
+}
+
 func testingClient(t *testing.T) *http.Client {
 	httpClient := http.Client{}
 	httpClient.Transport = fakeRoundTripper{t}
@@ -276,6 +429,8 @@ func TestGetStopPredictions(t *testing.T) {
 							"",
 							"0712",
 							"7447642",
+							"",
+							"",
 						},
 						Prediction{
 							xmlName("prediction"),
@@ -289,6 +444,8 @@ func TestGetStopPredictions(t *testing.T) {
 							"",
 							"0705",
 							"7447643",
+							"",
+							"",
 						},
 					},
 					"Outbound",
@@ -300,6 +457,7 @@ func TestGetStopPredictions(t *testing.T) {
 			"1",
 			"Some Station Outbound",
 			"1123",
+			"",
 		},
 		PredictionData{
 			xmlName("predictions"),
@@ -319,6 +477,8 @@ func TestGetStopPredictions(t *testing.T) {
 							"",
 							"0609",
 							"7447028",
+							"",
+							"",
 						},
 						Prediction{
 							xmlName("prediction"),
@@ -332,6 +492,8 @@ func TestGetStopPredictions(t *testing.T) {
 							"",
 							"0602",
 							"7447029",
+							"",
+							"",
 						},
 					},
 					"Outbound",
@@ -343,6 +505,7 @@ func TestGetStopPredictions(t *testing.T) {
 			"2",
 			"Some Station Outbound",
 			"1123",
+			"",
 		},
 	}
 	equals(t, expected, found)
@@ -372,6 +535,8 @@ func TestGetPredictionsForMultiStops(t *testing.T) {
 							"2",
 							"9999",
 							"7318265",
+							"",
+							"",
 						},
 						Prediction{
 							xmlName("prediction"),
@@ -385,6 +550,8 @@ func TestGetPredictionsForMultiStops(t *testing.T) {
 							"2",
 							"8888",
 							"7318264",
+							"",
+							"",
 						},
 					},
 					"Outbound",
@@ -396,6 +563,7 @@ func TestGetPredictionsForMultiStops(t *testing.T) {
 			"1",
 			"Some Station Outbound",
 			"1123",
+			"",
 		},
 		PredictionData{
 			xmlName("predictions"),
@@ -415,6 +583,8 @@ func TestGetPredictionsForMultiStops(t *testing.T) {
 							"2",
 							"6666",
 							"7318264",
+							"",
+							"",
 						},
 					},
 					"Outbound",
@@ -432,6 +602,7 @@ func TestGetPredictionsForMultiStops(t *testing.T) {
 			"1",
 			"Some Other Station Outbound",
 			"1124",
+			"",
 		},
 	}
 	equals(t, expected, found)
@@ -0,0 +1,128 @@
+package nextbustest
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dinedal/nextbus"
+)
+
+// FaultKind is a way for the fake feed to misbehave.
+type FaultKind int
+
+// The kinds of fault a Server can inject.
+const (
+	// FaultNone answers normally, after the Fault's Delay.
+	FaultNone FaultKind = iota
+	// FaultTruncate cuts the XML response off halfway through.
+	FaultTruncate
+	// FaultRetryableError answers with an Error body marked
+	// shouldRetry="true", as NextBus does when an agency's server is
+	// unavailable.
+	FaultRetryableError
+	// FaultError answers with an Error body marked shouldRetry="false".
+	FaultError
+	// FaultServerError answers with an HTTP 500.
+	FaultServerError
+	// FaultRateLimit answers with an HTTP 429 and a Retry-After header.
+	FaultRateLimit
+)
+
+// Fault makes the Server misbehave for some requests.
+type Fault struct {
+	Kind FaultKind
+	// Delay holds the response back for this long, whatever its Kind.
+	Delay time.Duration
+	// Command limits the fault to one feed command. If empty, every command
+	// is affected.
+	Command string
+	// Times is how many requests the fault affects before it is used up. If
+	// zero, it affects every matching request.
+	Times int
+	// Message is the text of an Error body. A default is used if empty.
+	Message string
+	// RetryAfter is sent with FaultRateLimit responses.
+	RetryAfter time.Duration
+}
+
+// Inject adds a fault. Faults are tried in the order they were injected and
+// the first that matches a request is applied to it.
+func (s *Server) Inject(f Fault) {
+	s.mu.Lock()
+	s.faults = append(s.faults, &f)
+	s.mu.Unlock()
+}
+
+// ClearFaults removes every injected fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	s.faults = nil
+	s.mu.Unlock()
+}
+
+// fault returns the fault to apply to a request for command, using up one of
+// its Times, or nil.
+func (s *Server) fault(command string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if f.Command != "" && f.Command != command {
+			continue
+		}
+		applied := *f
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return &applied
+	}
+	return nil
+}
+
+// serveFault writes the response for f and reports whether the normal
+// response should be written instead. A truncated response is written by
+// ServeHTTP itself.
+func serveFault(w http.ResponseWriter, req *http.Request, f *Fault) bool {
+	if f.Delay > 0 {
+		timer := time.NewTimer(f.Delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+
+	switch f.Kind {
+	case FaultRetryableError, FaultError:
+		message, retry := f.Message, "false"
+		if f.Kind == FaultRetryableError {
+			retry = "true"
+			if message == "" {
+				message = "Agency server cannot accept client while status is: UNINITIALIZED"
+			}
+		} else if message == "" {
+			message = "Injected fault"
+		}
+		data, _ := xml.Marshal(struct {
+			XMLName xml.Name `xml:"body"`
+			Error   nextbus.FeedError
+		}{Error: nextbus.FeedError{ShouldRetry: retry, Message: message}})
+		w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+		w.Write([]byte(xml.Header))
+		w.Write(data)
+		return false
+	case FaultServerError:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return false
+	case FaultRateLimit:
+		w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter/time.Second)))
+		http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package nextbustest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dinedal/nextbus"
+)
+
+func TestFaults(t *testing.T) {
+	s := NewServer(Generate(Config{Routes: 1}))
+	defer s.Close()
+	nb := nextbus.NewClient(s.Client())
+
+	s.Inject(Fault{Kind: FaultServerError, Command: "routeList", Times: 1})
+	if _, err := nb.GetRouteList("sim"); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected a 500, got %v", err)
+	}
+	if _, err := nb.GetRouteList("sim"); err != nil {
+		t.Errorf("expected the fault to be used up, got %v", err)
+	}
+
+	s.Inject(Fault{Kind: FaultTruncate, Times: 1})
+	if _, err := nb.GetAgencyList(); err == nil || !strings.Contains(err.Error(), "could not parse") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+
+	s.Inject(Fault{Kind: FaultRetryableError, Times: 1})
+	_, err := nb.GetAgencyList()
+	var feedErr *nextbus.FeedError
+	if !errors.As(err, &feedErr) || !feedErr.Retryable() {
+		t.Errorf("expected a retryable *FeedError, got %v", err)
+	}
+
+	s.Inject(Fault{Kind: FaultRateLimit, Times: 1})
+	if _, err := nb.GetAgencyList(); !errors.Is(err, nextbus.ErrRateLimited) {
+		t.Errorf("expected a rate limit error, got %v", err)
+	}
+
+	s.Inject(Fault{Kind: FaultError, Message: "nope"})
+	if _, err := nb.GetAgencyList(); err == nil || err.Error() != "nextbus: nope" {
+		t.Errorf("expected the injected error, got %v", err)
+	}
+	s.ClearFaults()
+	if _, err := nb.GetAgencyList(); err != nil {
+		t.Errorf("expected no faults, got %v", err)
+	}
+}
+
+func TestFaultRetries(t *testing.T) {
+	s := NewServer(Generate(Config{Routes: 1}))
+	defer s.Close()
+	s.Inject(Fault{Kind: FaultServerError, Times: 2})
+	nb := nextbus.NewClient(s.Client(), nextbus.WithRetries(2, 0))
+	if _, err := nb.GetAgencyList(); err != nil {
+		t.Errorf("expected the client to retry past the faults, got %v", err)
+	}
+}
+
+func TestFaultDelay(t *testing.T) {
+	s := NewServer(Generate(Config{Routes: 1}))
+	defer s.Close()
+	s.Inject(Fault{Delay: time.Second})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := nextbus.NewClient(s.Client()).Do(ctx, "agencyList"); err == nil {
+		t.Error("expected the delayed request to time out")
+	}
+}
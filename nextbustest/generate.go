@@ -0,0 +1,232 @@
+package nextbustest
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dinedal/nextbus"
+	"github.com/dinedal/nextbus/geo"
+)
+
+// The defaults for the zero values of Config.
+const (
+	DefaultRoutes           = 5
+	DefaultStopsPerRoute    = 20
+	DefaultVehiclesPerRoute = 4
+	DefaultStopSpacing      = 400
+	DefaultSpeedKmHr        = 20
+)
+
+// DefaultCenter is where generated agencies are placed when Config.Center is
+// zero.
+var DefaultCenter = nextbus.LatLon{Lat: 37.7749, Lon: -122.4194}
+
+// Config describes an agency for Generate to synthesize.
+type Config struct {
+	// Tag is the agency tag. It defaults to "sim".
+	Tag              string
+	Routes           int
+	StopsPerRoute    int
+	VehiclesPerRoute int
+	// StopSpacing is the distance between consecutive stops in meters.
+	StopSpacing float64
+	SpeedKmHr   float64
+	Center      nextbus.LatLon
+}
+
+func (c Config) withDefaults() Config {
+	if c.Tag == "" {
+		c.Tag = "sim"
+	}
+	if c.Routes <= 0 {
+		c.Routes = DefaultRoutes
+	}
+	if c.StopsPerRoute < 2 {
+		c.StopsPerRoute = DefaultStopsPerRoute
+	}
+	if c.VehiclesPerRoute < 0 {
+		c.VehiclesPerRoute = 0
+	} else if c.VehiclesPerRoute == 0 {
+		c.VehiclesPerRoute = DefaultVehiclesPerRoute
+	}
+	if c.StopSpacing <= 0 {
+		c.StopSpacing = DefaultStopSpacing
+	}
+	if c.SpeedKmHr <= 0 {
+		c.SpeedKmHr = DefaultSpeedKmHr
+	}
+	if c.Center == (nextbus.LatLon{}) {
+		c.Center = DefaultCenter
+	}
+	return c
+}
+
+// simRoute is a generated route: a straight line of stops leading away from
+// the center, which vehicles run back and forth along.
+type simRoute struct {
+	tag      string
+	start    nextbus.LatLon
+	bearing  float64
+	length   float64
+	stopTags []string
+}
+
+// cycle is the distance a vehicle covers on a round trip.
+func (r simRoute) cycle() float64 {
+	return 2 * r.length
+}
+
+// position returns how far around its round trip a vehicle is at t.
+func (r simRoute) position(cfg Config, vehicle int, t time.Time) float64 {
+	speed := cfg.SpeedKmHr / 3.6
+	phase := r.cycle() * float64(vehicle) / float64(cfg.VehiclesPerRoute)
+	return mod(t.Sub(time.Unix(0, 0)).Seconds()*speed+phase, r.cycle())
+}
+
+func mod(x, m float64) float64 {
+	x = math.Mod(x, m)
+	if x < 0 {
+		x += m
+	}
+	return x
+}
+
+func formatCoord(f float64) string {
+	return strconv.FormatFloat(f, 'f', 7, 64)
+}
+
+func outbound(routeTag string) string { return routeTag + "_O" }
+func inbound(routeTag string) string  { return routeTag + "_I" }
+
+func vehicleID(route, vehicle int) string {
+	return strconv.Itoa(1000 + 100*route + vehicle)
+}
+
+// Generate synthesizes an agency whose routes lead away from a center and
+// whose vehicles run back and forth along them at a constant speed. The
+// vehicles' positions and predictions depend only on the time, so any number
+// of servers and clients see the same data.
+func Generate(cfg Config) *Agency {
+	cfg = cfg.withDefaults()
+	agency := &Agency{Agency: nextbus.Agency{Tag: cfg.Tag, Title: "Simulated " + cfg.Tag, RegionTitle: "Simulation"}}
+
+	var routes []simRoute
+	for i := 0; i < cfg.Routes; i++ {
+		r := simRoute{
+			tag:     strconv.Itoa(i + 1),
+			bearing: 360 * float64(i) / float64(cfg.Routes),
+			length:  cfg.StopSpacing * float64(cfg.StopsPerRoute-1),
+		}
+		r.start = geo.Destination(cfg.Center, r.bearing, cfg.StopSpacing/2)
+		rc := nextbus.RouteConfig{Tag: r.tag, Title: r.tag + "-Simulated", Color: "336699", OppositeColor: "ffffff"}
+		out := nextbus.Direction{Tag: outbound(r.tag), Title: "Outbound", Name: "Outbound", UseForUI: "true"}
+		in := nextbus.Direction{Tag: inbound(r.tag), Title: "Inbound", Name: "Inbound", UseForUI: "true"}
+		var path nextbus.Path
+		latMin, latMax, lonMin, lonMax := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+		for j := 0; j < cfg.StopsPerRoute; j++ {
+			p := geo.Destination(r.start, r.bearing, cfg.StopSpacing*float64(j))
+			tag := r.tag + "_" + strconv.Itoa(j+1)
+			r.stopTags = append(r.stopTags, tag)
+			rc.StopList = append(rc.StopList, nextbus.Stop{
+				Tag:    tag,
+				Title:  "Route " + r.tag + " Stop " + strconv.Itoa(j+1),
+				Lat:    formatCoord(p.Lat),
+				Lon:    formatCoord(p.Lon),
+				StopID: strconv.Itoa(10000*(i+1) + j + 1),
+			})
+			out.StopMarkerList = append(out.StopMarkerList, nextbus.StopMarker{Tag: tag})
+			path.PointList = append(path.PointList, nextbus.Point{Lat: formatCoord(p.Lat), Lon: formatCoord(p.Lon)})
+			latMin, latMax = math.Min(latMin, p.Lat), math.Max(latMax, p.Lat)
+			lonMin, lonMax = math.Min(lonMin, p.Lon), math.Max(lonMax, p.Lon)
+		}
+		for j := len(out.StopMarkerList) - 1; j >= 0; j-- {
+			in.StopMarkerList = append(in.StopMarkerList, out.StopMarkerList[j])
+		}
+		rc.LatMin, rc.LatMax = formatCoord(latMin), formatCoord(latMax)
+		rc.LonMin, rc.LonMax = formatCoord(lonMin), formatCoord(lonMax)
+		rc.DirList = []nextbus.Direction{out, in}
+		rc.PathList = []nextbus.Path{path}
+		agency.Routes = append(agency.Routes, rc)
+		routes = append(routes, r)
+	}
+
+	agency.Vehicles = func(t time.Time) []nextbus.VehicleLocation {
+		var result []nextbus.VehicleLocation
+		for i, r := range routes {
+			for k := 0; k < cfg.VehiclesPerRoute; k++ {
+				c := r.position(cfg, k, t)
+				dirTag, heading, d := outbound(r.tag), r.bearing, c
+				if c >= r.length {
+					dirTag, heading, d = inbound(r.tag), mod(r.bearing+180, 360), r.cycle()-c
+				}
+				p := geo.Destination(r.start, r.bearing, d)
+				result = append(result, nextbus.VehicleLocation{
+					ID:              vehicleID(i, k),
+					RouteTag:        r.tag,
+					DirTag:          dirTag,
+					Lat:             formatCoord(p.Lat),
+					Lon:             formatCoord(p.Lon),
+					SecsSinceReport: "0",
+					Predictable:     "true",
+					Heading:         strconv.Itoa(int(heading)),
+					SpeedKmHr:       strconv.FormatFloat(cfg.SpeedKmHr, 'f', -1, 64),
+				})
+			}
+		}
+		return result
+	}
+
+	agency.Predictions = func(t time.Time, routeTag, stopTag string) nextbus.PredictionData {
+		pd := nextbus.PredictionData{}
+		for i, r := range routes {
+			if r.tag != routeTag {
+				continue
+			}
+			for j, tag := range r.stopTags {
+				if tag != stopTag {
+					continue
+				}
+				d := cfg.StopSpacing * float64(j)
+				out := nextbus.PredictionDirection{Title: "Outbound"}
+				in := nextbus.PredictionDirection{Title: "Inbound"}
+				for k := 0; k < cfg.VehiclesPerRoute; k++ {
+					c := r.position(cfg, k, t)
+					out.PredictionList = append(out.PredictionList, prediction(cfg, t, mod(d-c, r.cycle()), outbound(r.tag), vehicleID(i, k)))
+					in.PredictionList = append(in.PredictionList, prediction(cfg, t, mod(r.cycle()-d-c, r.cycle()), inbound(r.tag), vehicleID(i, k)))
+				}
+				for _, dir := range []nextbus.PredictionDirection{out, in} {
+					sort.Slice(dir.PredictionList, func(a, b int) bool {
+						return dir.PredictionList[a].ArrivalTime().Before(dir.PredictionList[b].ArrivalTime())
+					})
+					if len(dir.PredictionList) > 5 {
+						dir.PredictionList = dir.PredictionList[:5]
+					}
+					if len(dir.PredictionList) != 0 {
+						pd.PredictionDirectionList = append(pd.PredictionDirectionList, dir)
+					}
+				}
+			}
+		}
+		return pd
+	}
+	return agency
+}
+
+// prediction predicts the arrival of a vehicle that is distance meters away
+// from a stop at t.
+func prediction(cfg Config, t time.Time, distance float64, dirTag, vehicle string) nextbus.Prediction {
+	seconds := int(distance / (cfg.SpeedKmHr / 3.6))
+	arrival := t.Add(time.Duration(seconds) * time.Second)
+	return nextbus.Prediction{
+		EpochTime:   strconv.FormatInt(arrival.UnixNano()/int64(time.Millisecond), 10),
+		Seconds:     strconv.Itoa(seconds),
+		Minutes:     strconv.Itoa(seconds / 60),
+		IsDeparture: "false",
+		DirTag:      dirTag,
+		Vehicle:     vehicle,
+		Block:       vehicle,
+		TripTag:     vehicle + "_" + dirTag,
+	}
+}
@@ -0,0 +1,254 @@
+// Package nextbustest provides a fake NextBus feed for testing code that uses
+// the nextbus package without reaching the real service.
+package nextbustest
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dinedal/nextbus"
+)
+
+// Agency is the data the fake feed serves for one agency. Vehicles and
+// Predictions are computed on each request from the server's clock, so the
+// data can change over time; either may be nil if the agency has none.
+type Agency struct {
+	Agency    nextbus.Agency
+	Routes    []nextbus.RouteConfig
+	Schedules []nextbus.Schedule
+	// Vehicles returns the vehicles of the agency at t.
+	Vehicles func(t time.Time) []nextbus.VehicleLocation
+	// Predictions returns the predictions for a stop of a route at t.
+	Predictions func(t time.Time, routeTag, stopTag string) nextbus.PredictionData
+}
+
+// Server is a fake NextBus feed. It answers the commands the nextbus package
+// uses from the Agencies it was given.
+type Server struct {
+	// Clock is the time the feed reports. It defaults to
+	// nextbus.SystemClock.
+	Clock nextbus.Clock
+
+	server *httptest.Server
+
+	mu       sync.RWMutex
+	agencies map[string]*Agency
+	faults   []*Fault
+}
+
+// NewServer starts a fake feed serving agencies. It should be closed when the
+// test is done.
+func NewServer(agencies ...*Agency) *Server {
+	s := &Server{agencies: map[string]*Agency{}}
+	for _, a := range agencies {
+		s.agencies[a.Agency.Tag] = a
+	}
+	s.server = httptest.NewServer(s)
+	return s
+}
+
+// URL returns the base URL of the server.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// AddAgency adds or replaces an agency in the feed.
+func (s *Server) AddAgency(a *Agency) {
+	s.mu.Lock()
+	s.agencies[a.Agency.Tag] = a
+	s.mu.Unlock()
+}
+
+// Client returns an HTTP client that sends the requests meant for NextBus to
+// the server instead, for use with nextbus.NewClient.
+func (s *Server) Client() *http.Client {
+	target, _ := url.Parse(s.server.URL)
+	base := s.server.Client().Transport
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		redirected := req.Clone(req.Context())
+		redirected.URL.Scheme = target.Scheme
+		redirected.URL.Host = target.Host
+		redirected.Host = target.Host
+		return base.RoundTrip(redirected)
+	})}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func (s *Server) now() time.Time {
+	if s.Clock == nil {
+		return nextbus.SystemClock.Now()
+	}
+	return s.Clock.Now()
+}
+
+// ServeHTTP answers a feed request, applying any injected Fault. Unknown
+// commands, agencies, routes and stops are reported with an Error body, as
+// NextBus does.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	f := s.fault(q.Get("command"))
+	if f != nil && !serveFault(w, req, f) {
+		return
+	}
+	body, feedErr := s.respond(q.Get("command"), q)
+	if feedErr != "" {
+		body = struct {
+			XMLName xml.Name `xml:"body"`
+			Error   nextbus.FeedError
+		}{Error: nextbus.FeedError{ShouldRetry: "false", Message: feedErr}}
+	}
+	data, err := xml.Marshal(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if f != nil && f.Kind == FaultTruncate {
+		data = data[:len(data)/2]
+	}
+	w.Header().Set("Content-Type", "text/xml;charset=UTF-8")
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+// respond returns the response body for a command, or the message of the
+// Error to report instead.
+func (s *Server) respond(command string, q url.Values) (interface{}, string) {
+	if command == "agencyList" {
+		s.mu.RLock()
+		resp := nextbus.AgencyResponse{}
+		for _, a := range s.agencies {
+			resp.AgencyList = append(resp.AgencyList, a.Agency)
+		}
+		s.mu.RUnlock()
+		sort.Slice(resp.AgencyList, func(i, j int) bool { return resp.AgencyList[i].Tag < resp.AgencyList[j].Tag })
+		return resp, ""
+	}
+
+	s.mu.RLock()
+	a := s.agencies[q.Get("a")]
+	s.mu.RUnlock()
+	if a == nil {
+		return nil, "Agency parameter \"a=" + q.Get("a") + "\" is not valid."
+	}
+	now := s.now()
+	routeTag := q.Get("r")
+	route, found := findRoute(a, routeTag)
+	if routeTag != "" && !found {
+		return nil, "Could not get route \"" + routeTag + "\". Route not found"
+	}
+
+	switch command {
+	case "routeList":
+		resp := nextbus.RouteResponse{}
+		for _, rc := range a.Routes {
+			resp.RouteList = append(resp.RouteList, nextbus.Route{Tag: rc.Tag, Title: rc.Title})
+		}
+		return resp, ""
+
+	case "routeConfig":
+		if routeTag != "" {
+			return nextbus.RouteConfigResponse{RouteList: []nextbus.RouteConfig{route}}, ""
+		}
+		return nextbus.RouteConfigResponse{RouteList: a.Routes}, ""
+
+	case "schedule":
+		if routeTag == "" {
+			return nil, "route r parameter not specified"
+		}
+		resp := nextbus.ScheduleResponse{}
+		for _, sc := range a.Schedules {
+			if sc.Tag == routeTag {
+				resp.ScheduleList = append(resp.ScheduleList, sc)
+			}
+		}
+		return resp, ""
+
+	case "vehicleLocations":
+		resp := nextbus.LocationResponse{LastTime: nextbus.LocationLastTime{Time: epochMillis(now)}}
+		if a.Vehicles != nil {
+			for _, v := range a.Vehicles(now) {
+				if routeTag == "" || v.RouteTag == routeTag {
+					resp.VehicleList = append(resp.VehicleList, v)
+				}
+			}
+		}
+		return resp, ""
+
+	case "predictions", "predictionsForMultiStops":
+		var stops [][2]string
+		if command == "predictions" {
+			stops = append(stops, [2]string{routeTag, q.Get("s")})
+		}
+		for _, rs := range q["stops"] {
+			parts := strings.SplitN(rs, "|", 2)
+			if len(parts) != 2 {
+				return nil, "stops parameter \"" + rs + "\" is not valid"
+			}
+			stops = append(stops, [2]string{parts[0], parts[1]})
+		}
+		resp := nextbus.PredictionResponse{}
+		for _, rs := range stops {
+			rc, found := findRoute(a, rs[0])
+			if !found {
+				return nil, "Could not get route \"" + rs[0] + "\". Route not found"
+			}
+			stop, found := findStop(rc, rs[1])
+			if !found {
+				return nil, "For agency=" + a.Agency.Tag + " stop s=" + rs[1] + " is on none of the directions for r=" + rs[0] + " so cannot determine which stop to provide data for."
+			}
+			pd := nextbus.PredictionData{}
+			if a.Predictions != nil {
+				pd = a.Predictions(now, rc.Tag, stop.Tag)
+			}
+			pd.AgencyTitle = a.Agency.Title
+			pd.RouteTag, pd.RouteTitle = rc.Tag, rc.Title
+			pd.StopTag, pd.StopTitle = stop.Tag, stop.Title
+			if pd.Empty() && pd.DirTitleBecauseNoPredictions == "" && len(rc.DirList) != 0 {
+				pd.PredictionDirectionList = nil
+				pd.DirTitleBecauseNoPredictions = rc.DirList[0].Title
+			}
+			resp.PredictionDataList = append(resp.PredictionDataList, pd)
+		}
+		return resp, ""
+	}
+	return nil, "Command \"" + command + "\" is not valid."
+}
+
+func findRoute(a *Agency, routeTag string) (nextbus.RouteConfig, bool) {
+	for _, rc := range a.Routes {
+		if rc.Tag == routeTag {
+			return rc, true
+		}
+	}
+	return nextbus.RouteConfig{}, false
+}
+
+func findStop(rc nextbus.RouteConfig, stopTag string) (nextbus.Stop, bool) {
+	for _, stop := range rc.StopList {
+		if stop.Tag == stopTag {
+			return stop, true
+		}
+	}
+	return nextbus.Stop{}, false
+}
+
+func epochMillis(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+}
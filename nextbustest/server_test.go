@@ -0,0 +1,77 @@
+package nextbustest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dinedal/nextbus"
+)
+
+func TestServer(t *testing.T) {
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	s := NewServer(Generate(Config{Routes: 3, StopsPerRoute: 10, VehiclesPerRoute: 2}))
+	defer s.Close()
+	s.Clock = nextbus.ClockFunc(func() time.Time { return now })
+	nb := nextbus.NewClient(s.Client())
+
+	agencies, err := nb.GetAgencyList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agencies) != 1 || agencies[0].Tag != "sim" {
+		t.Fatalf("agencies: got %+v", agencies)
+	}
+
+	configs, err := nb.GetRouteConfig("sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 3 || len(configs[0].StopList) != 10 || len(configs[0].DirList) != 2 {
+		t.Fatalf("unexpected route configs %+v", configs)
+	}
+
+	vehicles, err := nb.GetVehicleLocations("sim", nextbus.VehicleLocationRoute("2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vehicles.VehicleList) != 2 || vehicles.VehicleList[0].RouteTag != "2" {
+		t.Fatalf("unexpected vehicles %+v", vehicles.VehicleList)
+	}
+
+	predictions, err := nb.GetPredictionsForMultiStops("sim", nextbus.PredReqStop("1", "1_5"), nextbus.PredReqStop("3", "3_1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(predictions) != 2 || predictions[0].StopTitle != "Route 1 Stop 5" || predictions[0].Empty() {
+		t.Fatalf("unexpected predictions %+v", predictions)
+	}
+
+	_, err = nb.GetRouteConfig("sim", nextbus.RouteConfigTag("9"))
+	if _, isFeedErr := err.(*nextbus.FeedError); !isFeedErr {
+		t.Errorf("expected a *FeedError for a missing route, got %v", err)
+	}
+	_, err = nb.GetRouteList("nope")
+	if _, isFeedErr := err.(*nextbus.FeedError); !isFeedErr {
+		t.Errorf("expected a *FeedError for a missing agency, got %v", err)
+	}
+}
+
+func TestGeneratedVehiclesMove(t *testing.T) {
+	a := Generate(Config{Routes: 1, VehiclesPerRoute: 1, SpeedKmHr: 36})
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	before := a.Vehicles(now)[0]
+	after := a.Vehicles(now.Add(10 * time.Second))[0]
+	if before.Lat == after.Lat && before.Lon == after.Lon {
+		t.Errorf("vehicle did not move: %+v", after)
+	}
+
+	// At a constant speed, the predicted arrival stays put as the vehicle
+	// approaches.
+	pd := a.Predictions(now, "1", "1_10")
+	later := a.Predictions(now.Add(10*time.Second), "1", "1_10")
+	first := pd.PredictionDirectionList[0].PredictionList[0]
+	next := later.PredictionDirectionList[0].PredictionList[0]
+	if d := next.ArrivalTime().Sub(first.ArrivalTime()); d < -time.Second || d > time.Second {
+		t.Errorf("arrival moved from %v to %v at constant speed", first.ArrivalTime(), next.ArrivalTime())
+	}
+}
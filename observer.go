@@ -0,0 +1,89 @@
+package nextbus
+
+import (
+	"errors"
+	"time"
+)
+
+// RequestOutcome classifies how a request to NextBus ended.
+type RequestOutcome string
+
+// The outcomes reported in RequestInfo.
+const (
+	// OutcomeOK is a successful response.
+	OutcomeOK RequestOutcome = "ok"
+	// OutcomeCached is a response answered from the DiskCache without a
+	// request.
+	OutcomeCached RequestOutcome = "cached"
+	// OutcomeFeedError is a response whose body holds an Error.
+	OutcomeFeedError RequestOutcome = "feed_error"
+	// OutcomeRateLimited is a request NextBus throttled or blocked.
+	OutcomeRateLimited RequestOutcome = "rate_limited"
+	// OutcomeError is any other failure, such as a network error or an
+	// unexpected status.
+	OutcomeError RequestOutcome = "error"
+)
+
+// RequestInfo describes one attempt at a feed request, for metrics and
+// logging.
+type RequestInfo struct {
+	Command string
+	// Params are the escaped query fragments sent with the command.
+	Params []string
+	// Attempt is 1 for the first try of a request and increases with each
+	// retry.
+	Attempt int
+	// Duration is how long the attempt took, including any wait for the
+	// rate limiter or a throttle.
+	Duration time.Duration
+	// Bytes is the size of the response body.
+	Bytes   int
+	Outcome RequestOutcome
+	// Err is the error the attempt failed with, if any.
+	Err error
+}
+
+// WithRequestObserver makes a Client call observe after every attempt at a
+// request, including retries and cache hits. Streaming requests made by
+// RouteConfigs and StreamVehicleLocations are not reported. observe is called
+// synchronously and should return quickly.
+func WithRequestObserver(observe func(RequestInfo)) Option {
+	return func(c *Client) {
+		c.observe = observe
+	}
+}
+
+// observed reports an attempt that returned data and err to the Client's
+// observer, if it has one. A FeedError in data is reported as the attempt's
+// error.
+func (c *Client) observed(command string, params []string, attempt int, start time.Time, data []byte, err error) {
+	if c.observe == nil {
+		return
+	}
+	if transient, isTransient := err.(*transientError); isTransient {
+		err = transient.err
+	}
+	if err == nil {
+		err = checkFeedError(data)
+	}
+	info := RequestInfo{
+		Command:  command,
+		Params:   append([]string(nil), params...),
+		Attempt:  attempt,
+		Duration: time.Since(start),
+		Bytes:    len(data),
+		Err:      err,
+	}
+	var feedErr *FeedError
+	switch {
+	case err == nil:
+		info.Outcome = OutcomeOK
+	case errors.Is(err, ErrRateLimited):
+		info.Outcome = OutcomeRateLimited
+	case errors.As(err, &feedErr):
+		info.Outcome = OutcomeFeedError
+	default:
+		info.Outcome = OutcomeError
+	}
+	c.observe(info)
+}
@@ -0,0 +1,63 @@
+package nextbus
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRequestObserver(t *testing.T) {
+	responses := []string{
+		"",
+		`<body><Error shouldRetry="true">Agency server cannot accept client while status is: Connecting</Error></body>`,
+		`<body><route tag="1" title="1-first"/></body>`,
+	}
+	var attempts int
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := responses[attempts]
+		attempts++
+		if body == "" {
+			return statusResponse(req, http.StatusBadGateway), nil
+		}
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(body))
+		return res, nil
+	})}
+	cache, err := NewDiskCache(t.TempDir())
+	ok(t, err)
+
+	var infos []RequestInfo
+	nb := NewClient(httpClient, WithRetries(2, 0), WithDiskCache(cache), WithRequestObserver(func(info RequestInfo) {
+		infos = append(infos, info)
+	}))
+	_, err = nb.GetRouteList("alpha")
+	ok(t, err)
+	_, err = nb.GetRouteList("alpha")
+	ok(t, err)
+
+	equals(t, 4, len(infos))
+	for i, outcome := range []RequestOutcome{OutcomeError, OutcomeFeedError, OutcomeOK, OutcomeCached} {
+		equals(t, outcome, infos[i].Outcome)
+		equals(t, "routeList", infos[i].Command)
+		equals(t, []string{"a=alpha"}, infos[i].Params)
+	}
+	equals(t, 1, infos[0].Attempt)
+	equals(t, 3, infos[2].Attempt)
+	equals(t, len(responses[2]), infos[2].Bytes)
+	assert(t, infos[0].Err != nil, "expected the 502 to be reported")
+	assert(t, infos[2].Err == nil, "unexpected error %v", infos[2].Err)
+}
+
+func TestRequestObserverRateLimited(t *testing.T) {
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		res := statusResponse(req, http.StatusTooManyRequests)
+		res.Header = http.Header{"Retry-After": {"0"}}
+		return res, nil
+	})}
+	var outcome RequestOutcome
+	nb := NewClient(httpClient, WithRequestObserver(func(info RequestInfo) { outcome = info.Outcome }))
+	_, err := nb.GetAgencyList()
+	assert(t, err != nil, "expected a rate limit error")
+	equals(t, OutcomeRateLimited, outcome)
+}
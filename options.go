@@ -0,0 +1,65 @@
+package nextbus
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithRetries makes a Client retry failed requests up to n more times. Network
+// errors, 5xx responses and errors NextBus marks with shouldRetry="true" are
+// retried; the delay before the first retry is backoff and doubles after each
+// attempt. A retry after a *RateLimitError also waits out its RetryAfter.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = n
+		c.retryBackoff = backoff
+	}
+}
+
+// WithRateLimit spaces the requests made by a Client at least interval apart,
+// including retries. NextBus limits how much data each client may download,
+// so long-running pollers should set this.
+func WithRateLimit(interval time.Duration) Option {
+	return func(c *Client) {
+		c.limiter = &rateLimiter{interval: interval}
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request, replacing
+// DefaultUserAgent. NextBus asks API consumers to identify their application.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithContact sets a contact address, usually an email address, sent in the
+// From header of every request so NextBus can reach the operator of a
+// misbehaving client instead of blocking it.
+func WithContact(contact string) Option {
+	return func(c *Client) {
+		c.contact = contact
+	}
+}
+
+// WithFeedURL sends requests to the feed at url, such as SecureFeedURL or a
+// mirror, instead of the default NextBus endpoint.
+func WithFeedURL(url string) Option {
+	return func(c *Client) {
+		c.feedURL = url
+	}
+}
+
+// WithHeader adds a header sent with every request, such as the credentials
+// an agency's own feed server requires. It can be given more than once.
+func WithHeader(name, value string) Option {
+	return func(c *Client) {
+		if c.headers == nil {
+			c.headers = http.Header{}
+		}
+		c.headers.Add(name, value)
+	}
+}
@@ -0,0 +1,131 @@
+package nextbus
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Param is a typed query parameter of a feed request. Flag parameters, such
+// as terse, are sent as a bare key and have no value.
+type Param struct {
+	Key   string
+	Value string
+	Flag  bool
+}
+
+// Encode returns the parameter as an escaped query fragment, such as "r=N".
+func (p Param) Encode() string {
+	if p.Flag {
+		return url.QueryEscape(p.Key)
+	}
+	return url.QueryEscape(p.Key) + "=" + url.QueryEscape(p.Value)
+}
+
+// RouteParam restricts a request to one route.
+func RouteParam(routeTag string) Param {
+	return Param{Key: "r", Value: routeTag}
+}
+
+// StopParam adds a route's stop to a predictionsForMultiStops request.
+func StopParam(routeTag, stopTag string) Param {
+	return Param{Key: "stops", Value: routeTag + "|" + stopTag}
+}
+
+// TimeParam requests vehicle locations reported after t, in milliseconds
+// since the epoch as returned in a response's lastTime.
+func TimeParam(t string) Param {
+	return Param{Key: "t", Value: t}
+}
+
+// TerseParam leaves paths out of a routeConfig response.
+func TerseParam() Param {
+	return Param{Key: "terse", Flag: true}
+}
+
+// VerboseParam includes directions not normally shown in UIs in a
+// routeConfig response.
+func VerboseParam() Param {
+	return Param{Key: "verbose", Flag: true}
+}
+
+// ShortTitlesParam asks for short titles in a predictions response.
+func ShortTitlesParam() Param {
+	return Param{Key: "useShortTitles", Value: "true"}
+}
+
+// parseParam is the inverse of Param.Encode, for query fragments made by
+// callers' own RouteConfigParam, PredReqParam and VehicleLocationParam
+// functions.
+func parseParam(fragment string) Param {
+	key, value, hasValue := strings.Cut(fragment, "=")
+	if k, err := url.QueryUnescape(key); err == nil {
+		key = k
+	}
+	if !hasValue {
+		return Param{Key: key, Flag: true}
+	}
+	if v, err := url.QueryUnescape(value); err == nil {
+		value = v
+	}
+	return Param{Key: key, Value: value}
+}
+
+// Param returns the typed parameter p stands for.
+func (p RouteConfigParam) Param() Param { return parseParam(p()) }
+
+// Param returns the typed parameter p stands for.
+func (p PredReqParam) Param() Param { return parseParam(p()) }
+
+// Param returns the typed parameter p stands for.
+func (p VehicleLocationParam) Param() Param { return parseParam(p()) }
+
+// toParams converts the legacy parameter functions to typed parameters.
+func toParams[P interface{ Param() Param }](ps []P) []Param {
+	result := make([]Param, len(ps))
+	for i, p := range ps {
+		result[i] = p.Param()
+	}
+	return result
+}
+
+// ParamError reports parameters that can't be sent together.
+type ParamError struct {
+	Command string
+	Message string
+}
+
+func (e *ParamError) Error() string {
+	return "nextbus: invalid " + e.Command + " parameters: " + e.Message
+}
+
+// singleValued are the parameters NextBus accepts only once per request.
+var singleValued = map[string]bool{"a": true, "r": true, "t": true, "useShortTitles": true}
+
+// buildQuery validates the parameters of a request for an agency and encodes
+// them as query fragments, starting with the agency. Repeated parameters, such
+// as the same stop given twice, are sent once; different values for a
+// parameter that takes only one, or both terse and verbose, are rejected.
+func buildQuery(command, agencyTag string, params []Param) ([]string, error) {
+	all := append([]Param{{Key: "a", Value: agencyTag}}, params...)
+	seen := map[Param]bool{}
+	values := map[string]string{}
+	query := make([]string, 0, len(all))
+	for _, p := range all {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if singleValued[p.Key] {
+			if previous, set := values[p.Key]; set {
+				return nil, &ParamError{command, fmt.Sprintf("conflicting values %q and %q for %s", previous, p.Value, p.Key)}
+			}
+			values[p.Key] = p.Value
+		}
+		query = append(query, p.Encode())
+	}
+	if seen[TerseParam()] && seen[VerboseParam()] {
+		return nil, &ParamError{command, "terse and verbose are mutually exclusive"}
+	}
+	return query, nil
+}
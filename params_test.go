@@ -0,0 +1,48 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestParamEncode(t *testing.T) {
+	equals(t, "stops=N%7C5205", StopParam("N", "5205").Encode())
+	equals(t, "r=J+K", RouteParam("J K").Encode())
+	equals(t, "terse", TerseParam().Encode())
+
+	// The legacy constructors are built on the typed parameters.
+	equals(t, "stops=N%7C5205", PredReqStop("N", "5205")())
+	equals(t, StopParam("N", "5205"), PredReqStop("N", "5205").Param())
+	equals(t, VerboseParam(), RouteConfigVerbose().Param())
+	equals(t, TimeParam("1234"), VehicleLocationParam(func() string { return "t=1234" }).Param())
+}
+
+func TestConflictingRouteConfigParams(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	_, err := nb.GetRouteConfig("alpha", RouteConfigTerse(), RouteConfigVerbose())
+	paramErr, isParamErr := err.(*ParamError)
+	assert(t, isParamErr, "expected a *ParamError, got %v", err)
+	equals(t, "nextbus: invalid routeConfig parameters: terse and verbose are mutually exclusive", paramErr.Error())
+}
+
+func TestBuildQuery(t *testing.T) {
+	query, err := buildQuery("predictionsForMultiStops", "alpha", []Param{StopParam("N", "5205"), StopParam("J", "5205"), StopParam("N", "5205")})
+	ok(t, err)
+	equals(t, []string{"a=alpha", "stops=N%7C5205", "stops=J%7C5205"}, query)
+
+	query, err = buildQuery("vehicleLocations", "alpha", []Param{TimeParam("10"), TimeParam("10")})
+	ok(t, err)
+	equals(t, []string{"a=alpha", "t=10"}, query)
+
+	_, err = buildQuery("vehicleLocations", "alpha", []Param{TimeParam("10"), TimeParam("20")})
+	equals(t, `nextbus: invalid vehicleLocations parameters: conflicting values "10" and "20" for t`, err.Error())
+
+	_, err = buildQuery("routeConfig", "alpha", []Param{{Key: "a", Value: "beta"}})
+	assert(t, err != nil, "expected a second agency to be rejected")
+}
+
+func TestConflictingVehicleLocationParams(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	_, err := nb.GetVehicleLocations("alpha", VehicleLocationRoute("N"), VehicleLocationRoute("J"))
+	_, isParamErr := err.(*ParamError)
+	assert(t, isParamErr, "expected a *ParamError, got %v", err)
+}
@@ -0,0 +1,178 @@
+package nextbus
+
+import (
+	"sort"
+	"strings"
+)
+
+// pointKey identifies a point by its coordinates as NextBus formats them.
+// Segments that meet share exactly the same coordinate strings.
+func pointKey(p Point) string {
+	return p.Lat + "," + p.Lon
+}
+
+// pathKey identifies a path by its sequence of points.
+func pathKey(points []Point) string {
+	keys := make([]string, len(points))
+	for i, p := range points {
+		keys[i] = pointKey(p)
+	}
+	return strings.Join(keys, " ")
+}
+
+// cleanPoints drops points that can't be parsed and consecutive repeats of the
+// same point.
+func cleanPoints(points []Point) []Point {
+	var result []Point
+	for _, p := range points {
+		if _, _, located := parseLatLon(p.Lat, p.Lon); !located {
+			continue
+		}
+		if n := len(result); n != 0 && pointKey(result[n-1]) == pointKey(p) {
+			continue
+		}
+		result = append(result, Point{Lat: p.Lat, Lon: p.Lon})
+	}
+	return result
+}
+
+func reversed(points []Point) []Point {
+	result := make([]Point, len(points))
+	for i, p := range points {
+		result[len(points)-1-i] = p
+	}
+	return result
+}
+
+// MergePaths removes duplicate segments from a route's paths and stitches
+// together segments where one ends exactly where the next begins, producing
+// fewer, longer paths. A segment is a duplicate of another if it has the same
+// points in the same or the reverse order. Segments are only joined where the
+// join is unambiguous: the end of one is the start of exactly one other, and
+// nothing else ends there. Points that can't be parsed are dropped.
+func MergePaths(paths []Path) []Path {
+	seen := map[string]bool{}
+	var chains [][]Point
+	for _, path := range paths {
+		points := cleanPoints(path.PointList)
+		if len(points) < 2 {
+			continue
+		}
+		key := pathKey(points)
+		if seen[key] || seen[pathKey(reversed(points))] {
+			continue
+		}
+		seen[key] = true
+		chains = append(chains, points)
+	}
+
+	starts := map[string][]int{}
+	ends := map[string]int{}
+	for i, chain := range chains {
+		starts[pointKey(chain[0])] = append(starts[pointKey(chain[0])], i)
+		ends[pointKey(chain[len(chain)-1])]++
+	}
+	// next[i] is the chain that continues chain i, or -1.
+	next := make([]int, len(chains))
+	hasPrev := make([]bool, len(chains))
+	for i, chain := range chains {
+		next[i] = -1
+		end := pointKey(chain[len(chain)-1])
+		if candidates := starts[end]; len(candidates) == 1 && ends[end] == 1 && candidates[0] != i {
+			next[i] = candidates[0]
+			hasPrev[candidates[0]] = true
+		}
+	}
+
+	var result []Path
+	used := make([]bool, len(chains))
+	follow := func(i int) {
+		var points []Point
+		for ; i >= 0 && !used[i]; i = next[i] {
+			used[i] = true
+			if points == nil {
+				points = append(points, chains[i]...)
+			} else {
+				points = append(points, chains[i][1:]...)
+			}
+		}
+		result = append(result, Path{PointList: points})
+	}
+	for i := range chains {
+		if !hasPrev[i] && !used[i] {
+			follow(i)
+		}
+	}
+	// Whatever is left forms loops, which have no natural start.
+	for i := range chains {
+		if !used[i] {
+			follow(i)
+		}
+	}
+	return result
+}
+
+// DirectionPaths returns the route's merged paths that a direction travels
+// along, in the order the direction reaches them. A path is considered part
+// of a direction if at least two of the direction's stops lie on it and it
+// visits them in the direction's order, which excludes paths for the opposite
+// direction running along the same streets.
+func (rc RouteConfig) DirectionPaths(dirTag string) []Path {
+	seq := rc.StopSequence(dirTag)
+	var proj planar
+	var projected bool
+	var stops []xy
+	for _, pos := range seq {
+		lat, lon, located := parseLatLon(pos.Stop.Lat, pos.Stop.Lon)
+		if !located {
+			continue
+		}
+		if !projected {
+			proj = newPlanar(lat, lon)
+			projected = true
+		}
+		stops = append(stops, proj.toXY(lat, lon))
+	}
+	if len(stops) < 2 {
+		return nil
+	}
+
+	type match struct {
+		path  Path
+		first int
+	}
+	var matches []match
+	for _, path := range MergePaths(rc.PathList) {
+		lines := polylinesFromPaths(proj, []Path{path})
+		if len(lines) == 0 {
+			continue
+		}
+		first, count := -1, 0
+		lastOffset := -1.0
+		forward := true
+		for i, q := range stops {
+			offset, dist := lines[0].project(q)
+			if dist > maxSnapDistance {
+				continue
+			}
+			if first < 0 {
+				first = i
+			}
+			if offset < lastOffset {
+				forward = false
+			}
+			lastOffset = offset
+			count++
+		}
+		if count >= 2 && forward {
+			matches = append(matches, match{path, first})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].first < matches[j].first })
+	result := make([]Path, len(matches))
+	for i, m := range matches {
+		result[i] = m.path
+	}
+	return result
+}
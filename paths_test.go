@@ -0,0 +1,67 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func pathOf(coords ...string) Path {
+	var path Path
+	for i := 0; i+1 < len(coords); i += 2 {
+		path.PointList = append(path.PointList, Point{Lat: coords[i], Lon: coords[i+1]})
+	}
+	return path
+}
+
+func TestMergePaths(t *testing.T) {
+	paths := []Path{
+		pathOf("37.71", "-122.40", "37.72", "-122.40"),
+		pathOf("37.70", "-122.40", "37.71", "-122.40"),
+		// A duplicate of the first segment, in reverse.
+		pathOf("37.72", "-122.40", "37.71", "-122.40"),
+		// A repeated point and one that can't be parsed.
+		pathOf("37.72", "-122.40", "37.72", "-122.40", "x", "y", "37.73", "-122.40"),
+		pathOf("37.80", "-122.30"),
+	}
+
+	merged := MergePaths(paths)
+	equals(t, 1, len(merged))
+	equals(t, pathOf("37.70", "-122.40", "37.71", "-122.40", "37.72", "-122.40", "37.73", "-122.40"), merged[0])
+}
+
+func TestMergePathsLeavesBranchesApart(t *testing.T) {
+	paths := []Path{
+		pathOf("37.70", "-122.40", "37.71", "-122.40"),
+		pathOf("37.71", "-122.40", "37.72", "-122.40"),
+		pathOf("37.71", "-122.40", "37.71", "-122.41"),
+	}
+	equals(t, 3, len(MergePaths(paths)))
+}
+
+func TestDirectionPaths(t *testing.T) {
+	rc := RouteConfig{
+		StopList: []Stop{
+			{Tag: "a", Lat: "37.7000", Lon: "-122.4000"},
+			{Tag: "b", Lat: "37.7050", Lon: "-122.4000"},
+			{Tag: "c", Lat: "37.7100", Lon: "-122.4000"},
+			{Tag: "d", Lat: "37.7150", Lon: "-122.4000"},
+		},
+		DirList: []Direction{
+			{Tag: "north", StopMarkerList: stopMarkers("a", "b", "c", "d")},
+			{Tag: "south", StopMarkerList: stopMarkers("d", "c", "b", "a")},
+		},
+		PathList: []Path{
+			pathOf("37.7100", "-122.4000", "37.7150", "-122.4000"),
+			pathOf("37.7150", "-122.4001", "37.7000", "-122.4001"),
+			pathOf("37.7000", "-122.4000", "37.7050", "-122.4000", "37.7100", "-122.4000"),
+		},
+	}
+
+	north := rc.DirectionPaths("north")
+	equals(t, 1, len(north))
+	equals(t, pathOf("37.7000", "-122.4000", "37.7050", "-122.4000", "37.7100", "-122.4000", "37.7150", "-122.4000"), north[0])
+
+	south := rc.DirectionPaths("south")
+	equals(t, []Path{pathOf("37.7150", "-122.4001", "37.7000", "-122.4001")}, south)
+
+	equals(t, []Path(nil), rc.DirectionPaths("east"))
+}
@@ -0,0 +1,184 @@
+package nextbus
+
+import (
+	"sort"
+)
+
+// Leg is a ride on one route direction between two stops.
+type Leg struct {
+	RouteTag string
+	DirTag   string
+	From     Stop
+	To       Stop
+	// Stops is the number of stops traveled.
+	Stops int
+	// Distance is the distance traveled along the route in meters.
+	Distance float64
+	// Predictions are the upcoming departures in this direction from From,
+	// soonest first. They are only filled in by Client.PlanTrip.
+	Predictions []Prediction
+}
+
+// Itinerary is a way of getting from one stop to another, as a sequence of
+// legs. Riders transfer between legs at the stop where one ends and the next
+// begins.
+type Itinerary struct {
+	Legs []Leg
+}
+
+// Transfers returns the number of times a rider changes vehicles.
+func (it Itinerary) Transfers() int {
+	return len(it.Legs) - 1
+}
+
+// Distance returns the total distance traveled in meters.
+func (it Itinerary) Distance() float64 {
+	total := 0.0
+	for _, leg := range it.Legs {
+		total += leg.Distance
+	}
+	return total
+}
+
+// run is the sequence of stops of one route direction.
+type run struct {
+	routeTag, dirTag string
+	stops            []string
+	cum              []float64
+}
+
+// runs rebuilds the route directions from the edges of the graph, which
+// NewGraph adds one direction at a time.
+func (g *Graph) runs() []run {
+	var result []run
+	for _, e := range g.Edges {
+		n := len(result)
+		if n == 0 || result[n-1].routeTag != e.RouteTag || result[n-1].dirTag != e.DirTag || result[n-1].stops[len(result[n-1].stops)-1] != e.From {
+			result = append(result, run{e.RouteTag, e.DirTag, []string{e.From}, []float64{0}})
+			n++
+		}
+		r := &result[n-1]
+		r.stops = append(r.stops, e.To)
+		r.cum = append(r.cum, r.cum[len(r.cum)-1]+e.Distance)
+	}
+	return result
+}
+
+func (r run) index(stopTag string, from int) int {
+	for i := from; i < len(r.stops); i++ {
+		if r.stops[i] == stopTag {
+			return i
+		}
+	}
+	return -1
+}
+
+func (g *Graph) leg(r run, from, to int) Leg {
+	return Leg{
+		RouteTag: r.routeTag,
+		DirTag:   r.dirTag,
+		From:     g.Stops[r.stops[from]],
+		To:       g.Stops[r.stops[to]],
+		Stops:    to - from,
+		Distance: r.cum[to] - r.cum[from],
+	}
+}
+
+// Plan suggests ways of getting from one stop to another, either directly or
+// with a single transfer at a stop shared by two routes. For each pair of
+// route directions, only the transfer with the shortest total distance is
+// suggested. Direct itineraries come first, then the rest by distance.
+func (g *Graph) Plan(fromStopTag, toStopTag string) []Itinerary {
+	runs := g.runs()
+	var result []Itinerary
+	for _, first := range runs {
+		i := first.index(fromStopTag, 0)
+		if i < 0 {
+			continue
+		}
+		if j := first.index(toStopTag, i+1); j >= 0 {
+			result = append(result, Itinerary{[]Leg{g.leg(first, i, j)}})
+			continue
+		}
+		for _, second := range runs {
+			if second.routeTag == first.routeTag {
+				continue
+			}
+			best, bestP, bestQ, bestK := -1.0, 0, 0, 0
+			for p := i + 1; p < len(first.stops); p++ {
+				q := second.index(first.stops[p], 0)
+				if q < 0 {
+					continue
+				}
+				k := second.index(toStopTag, q+1)
+				if k < 0 {
+					continue
+				}
+				if d := first.cum[p] - first.cum[i] + second.cum[k] - second.cum[q]; best < 0 || d < best {
+					best, bestP, bestQ, bestK = d, p, q, k
+				}
+			}
+			if best >= 0 {
+				result = append(result, Itinerary{[]Leg{g.leg(first, i, bestP), g.leg(second, bestQ, bestK)}})
+			}
+		}
+	}
+	sort.SliceStable(result, func(a, b int) bool {
+		if result[a].Transfers() != result[b].Transfers() {
+			return result[a].Transfers() < result[b].Transfers()
+		}
+		return result[a].Distance() < result[b].Distance()
+	})
+	return result
+}
+
+// PlanTrip suggests ways of getting from one stop of the snapshot's agency to
+// another, as Graph.Plan does, and fills in the current predictions for each
+// leg.
+func (c *Client) PlanTrip(snapshot *AgencySnapshot, fromStopTag, toStopTag string) ([]Itinerary, error) {
+	itineraries := snapshot.Graph().Plan(fromStopTag, toStopTag)
+
+	seen := map[RouteStop]bool{}
+	var params []PredReqParam
+	for _, it := range itineraries {
+		for _, leg := range it.Legs {
+			rs := RouteStop{leg.RouteTag, leg.From.Tag}
+			if !seen[rs] {
+				seen[rs] = true
+				params = append(params, PredReqStop(rs.RouteTag, rs.StopTag))
+			}
+		}
+	}
+	predictions := map[RouteStop][]Prediction{}
+	for start := 0; start < len(params); start += maxStopsPerRequest {
+		end := start + maxStopsPerRequest
+		if end > len(params) {
+			end = len(params)
+		}
+		data, err := c.GetPredictionsForMultiStops(snapshot.Agency.Tag, params[start:end]...)
+		if err != nil {
+			return nil, err
+		}
+		for _, pd := range data {
+			rs := RouteStop{pd.RouteTag, pd.StopTag}
+			for _, dir := range pd.PredictionDirectionList {
+				predictions[rs] = append(predictions[rs], dir.PredictionList...)
+			}
+		}
+	}
+
+	for _, it := range itineraries {
+		for i := range it.Legs {
+			leg := &it.Legs[i]
+			for _, p := range predictions[RouteStop{leg.RouteTag, leg.From.Tag}] {
+				if p.DirTag == "" || p.DirTag == leg.DirTag {
+					leg.Predictions = append(leg.Predictions, p)
+				}
+			}
+			sort.SliceStable(leg.Predictions, func(a, b int) bool {
+				return leg.Predictions[a].ArrivalTime().Before(leg.Predictions[b].ArrivalTime())
+			})
+		}
+	}
+	return itineraries, nil
+}
@@ -0,0 +1,46 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	g := NewGraph(testNetwork())
+
+	direct := g.Plan("a", "c")
+	equals(t, 1, len(direct))
+	equals(t, 0, direct[0].Transfers())
+	equals(t, "1_out", direct[0].Legs[0].DirTag)
+	equals(t, 2, direct[0].Legs[0].Stops)
+
+	transfer := g.Plan("a", "d")
+	equals(t, 1, len(transfer))
+	equals(t, 1, transfer[0].Transfers())
+	legs := transfer[0].Legs
+	equals(t, []string{"1", "a", "b"}, []string{legs[0].RouteTag, legs[0].From.Tag, legs[0].To.Tag})
+	equals(t, []string{"2", "b", "d"}, []string{legs[1].RouteTag, legs[1].From.Tag, legs[1].To.Tag})
+	assert(t, transfer[0].Distance() > 1400 && transfer[0].Distance() < 1500, "unexpected distance %v", transfer[0].Distance())
+
+	equals(t, []Itinerary(nil), g.Plan("c", "a"))
+}
+
+func TestPlanTrip(t *testing.T) {
+	snapshot := &AgencySnapshot{
+		Agency: Agency{Tag: "alpha"},
+		Routes: []RouteConfig{{
+			Tag: "1",
+			StopList: []Stop{
+				{Tag: "1123", Lat: "37.7000", Lon: "-122.4000"},
+				{Tag: "1124", Lat: "37.7050", Lon: "-122.4000"},
+			},
+			DirList: []Direction{{Tag: "1____O_F00", StopMarkerList: stopMarkers("1123", "1124")}},
+		}},
+	}
+
+	nb := NewClient(testingClient(t))
+	itineraries, err := nb.PlanTrip(snapshot, "1123", "1124")
+	ok(t, err)
+	equals(t, 1, len(itineraries))
+	equals(t, 1, len(itineraries[0].Legs[0].Predictions))
+	equals(t, "1111", itineraries[0].Legs[0].Predictions[0].Vehicle)
+}
@@ -0,0 +1,7 @@
+//go:build postgres
+
+package postgresstore
+
+import (
+	_ "github.com/lib/pq"
+)
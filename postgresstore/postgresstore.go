@@ -0,0 +1,249 @@
+// Package postgresstore keeps the arrivals recorded by nextbus.Archiver in a
+// PostgreSQL database, for archives of several agencies kept for longer than
+// suits memory or a single file. Every agency has its own nextbus.ArrivalStore
+// over a shared table:
+//
+//	db, err := sql.Open("pgx", "postgres://localhost/transit")
+//	store, err := postgresstore.New(ctx, db)
+//	archiver := nextbus.NewArchiver(store.Arrivals("sf-muni"))
+//
+// The package uses database/sql and doesn't import a driver; use any
+// PostgreSQL driver, such as github.com/jackc/pgx/v5/stdlib or
+// github.com/lib/pq. New brings the tables up to date with the migrations of
+// this version of the package, recording those applied in
+// nextbus_schema_migrations.
+package postgresstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dinedal/nextbus"
+)
+
+// DefaultBatchSize is the number of records a Store inserts per statement
+// when its BatchSize is zero.
+const DefaultBatchSize = 500
+
+// migrations are the changes to the schema, in order. The version of each is
+// its index plus one; applied migrations must never change.
+var migrations = []string{
+	`CREATE TABLE nextbus_arrivals (
+		agency TEXT NOT NULL,
+		route TEXT NOT NULL,
+		stop TEXT NOT NULL,
+		direction TEXT NOT NULL,
+		vehicle TEXT NOT NULL,
+		trip TEXT NOT NULL,
+		arrived_at TIMESTAMPTZ NOT NULL,
+		predicted_at TIMESTAMPTZ
+	)`,
+	`CREATE UNIQUE INDEX nextbus_arrivals_key
+		ON nextbus_arrivals (agency, route, stop, vehicle, trip, COALESCE(predicted_at, arrived_at))`,
+	`CREATE INDEX nextbus_arrivals_time ON nextbus_arrivals (agency, arrived_at)`,
+}
+
+// arrivalColumns are the columns of nextbus_arrivals set by Save, in the
+// order Save passes their values.
+const arrivalColumns = "agency, route, stop, direction, vehicle, trip, arrived_at, predicted_at"
+
+// columnCount is the number of arrivalColumns.
+const columnCount = 8
+
+// Store holds the arrivals of any number of agencies in a PostgreSQL
+// database.
+type Store struct {
+	db *sql.DB
+
+	// BatchSize is the largest number of records inserted by one statement;
+	// larger saves use several statements in one transaction.
+	BatchSize int
+}
+
+// New creates a Store using db, applying any migrations the database hasn't
+// had yet. Arrivals already in the database are kept.
+func New(ctx context.Context, db *sql.DB) (*Store, error) {
+	if err := migrate(ctx, db); err != nil {
+		return nil, fmt.Errorf("could not migrate arrival tables: %v", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// migrate applies the migrations newer than the database's version, each in
+// its own transaction. The version table is locked meanwhile, so that
+// processes starting together don't apply a migration twice.
+func migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS nextbus_schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`); err != nil {
+		return err
+	}
+	for {
+		applied, err := migrateOnce(ctx, db)
+		if err != nil || !applied {
+			return err
+		}
+	}
+}
+
+// migrateOnce applies the next pending migration, if there is one.
+func migrateOnce(ctx context.Context, db *sql.DB) (applied bool, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE nextbus_schema_migrations IN EXCLUSIVE MODE`); err != nil {
+		return false, err
+	}
+	var version int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM nextbus_schema_migrations`).Scan(&version); err != nil {
+		return false, err
+	}
+	if version > len(migrations) {
+		return false, fmt.Errorf("database is at version %d, newer than the %d known to this package", version, len(migrations))
+	}
+	if version == len(migrations) {
+		return false, tx.Commit()
+	}
+	if _, err := tx.ExecContext(ctx, migrations[version]); err != nil {
+		return false, fmt.Errorf("migration %d: %v", version+1, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO nextbus_schema_migrations (version, applied_at) VALUES ($1, $2)`,
+		version+1, time.Now().UTC()); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// Arrivals returns the nextbus.ArrivalStore of an agency.
+func (s *Store) Arrivals(agencyTag string) *Arrivals {
+	return &Arrivals{store: s, agencyTag: agencyTag}
+}
+
+// Agencies returns the tags of the agencies with recorded arrivals.
+func (s *Store) Agencies(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT agency FROM nextbus_arrivals ORDER BY agency`)
+	if err != nil {
+		return nil, fmt.Errorf("could not list agencies: %v", err)
+	}
+	defer rows.Close()
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("could not list agencies: %v", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not list agencies: %v", err)
+	}
+	return tags, nil
+}
+
+// Arrivals is the nextbus.ArrivalStore of one agency in a Store.
+type Arrivals struct {
+	store     *Store
+	agencyTag string
+}
+
+// Save adds records to the store in a single transaction, in batches of the
+// Store's BatchSize. Records already stored, with the same route, stop,
+// vehicle, trip and Predicted time, are skipped, as when an archiver replays
+// predictions after a restart. Records without a Predicted time are keyed by
+// their Time.
+func (a *Arrivals) Save(records []nextbus.ArrivalRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	size := a.store.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	ctx := context.Background()
+	tx, err := a.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not save arrivals: %v", err)
+	}
+	for start := 0; start < len(records); start += size {
+		end := start + size
+		if end > len(records) {
+			end = len(records)
+		}
+		batch := records[start:end]
+		args := make([]interface{}, 0, len(batch)*columnCount)
+		for _, r := range batch {
+			var predicted sql.NullTime
+			if !r.Predicted.IsZero() {
+				predicted = sql.NullTime{Time: r.Predicted.UTC(), Valid: true}
+			}
+			args = append(args, a.agencyTag, r.RouteTag, r.StopTag, r.DirTag, r.Vehicle, r.TripTag, r.Time.UTC(), predicted)
+		}
+		if _, err := tx.ExecContext(ctx, insertStatement(len(batch)), args...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not save arrivals: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not save arrivals: %v", err)
+	}
+	return nil
+}
+
+// insertStatement returns the statement inserting n records.
+func insertStatement(n int) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO nextbus_arrivals (" + arrivalColumns + ") VALUES ")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j := 0; j < columnCount; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString("$" + strconv.Itoa(i*columnCount+j+1))
+		}
+		b.WriteString(")")
+	}
+	b.WriteString(" ON CONFLICT DO NOTHING")
+	return b.String()
+}
+
+// Arrivals returns the records with a Time in [from, to), in order.
+func (a *Arrivals) Arrivals(from, to time.Time) ([]nextbus.ArrivalRecord, error) {
+	rows, err := a.store.db.QueryContext(context.Background(), `SELECT route, stop, direction, vehicle, trip, arrived_at, predicted_at
+		FROM nextbus_arrivals WHERE agency = $1 AND arrived_at >= $2 AND arrived_at < $3
+		ORDER BY arrived_at`, a.agencyTag, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("could not query arrivals: %v", err)
+	}
+	defer rows.Close()
+	var records []nextbus.ArrivalRecord
+	for rows.Next() {
+		var r nextbus.ArrivalRecord
+		var predicted sql.NullTime
+		if err := rows.Scan(&r.RouteTag, &r.StopTag, &r.DirTag, &r.Vehicle, &r.TripTag, &r.Time, &predicted); err != nil {
+			return nil, fmt.Errorf("could not query arrivals: %v", err)
+		}
+		if predicted.Valid {
+			r.Predicted = predicted.Time
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not query arrivals: %v", err)
+	}
+	return records, nil
+}
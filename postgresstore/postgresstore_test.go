@@ -0,0 +1,106 @@
+package postgresstore
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dinedal/nextbus"
+)
+
+func TestInsertStatement(t *testing.T) {
+	want := "INSERT INTO nextbus_arrivals (agency, route, stop, direction, vehicle, trip, arrived_at, predicted_at) VALUES " +
+		"($1, $2, $3, $4, $5, $6, $7, $8), ($9, $10, $11, $12, $13, $14, $15, $16) ON CONFLICT DO NOTHING"
+	if got := insertStatement(2); got != want {
+		t.Errorf("insertStatement(2) = %q, want %q", got, want)
+	}
+}
+
+// openStore opens a Store in the database named by NEXTBUS_POSTGRES_DSN,
+// skipping the test if it isn't set or no PostgreSQL driver is linked in.
+// The records of the test's agency are deleted when it ends.
+func openStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	dsn := os.Getenv("NEXTBUS_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("NEXTBUS_POSTGRES_DSN is not set")
+	}
+	var driver string
+	for _, name := range sql.Drivers() {
+		if name == "postgres" || name == "pgx" {
+			driver = name
+		}
+	}
+	if driver == "" {
+		t.Skip("no PostgreSQL driver is registered; run the tests with -tags postgres")
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := New(context.Background(), db)
+	if err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+	agencyTag := "test-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM nextbus_arrivals WHERE agency = $1`, agencyTag)
+		db.Close()
+	})
+	return store, agencyTag
+}
+
+func TestArrivalsSkipReplays(t *testing.T) {
+	store, agencyTag := openStore(t)
+	store.BatchSize = 2
+	arrivals := store.Arrivals(agencyTag)
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	records := []nextbus.ArrivalRecord{
+		{RouteTag: "N", StopTag: "5205", Vehicle: "1500", TripTag: "7001", Time: now, Predicted: now.Add(time.Minute)},
+		{RouteTag: "N", StopTag: "5206", Vehicle: "1500", TripTag: "7001", Time: now.Add(2 * time.Minute), Predicted: now.Add(2 * time.Minute)},
+		{RouteTag: "N", StopTag: "5205", Vehicle: "1501", Time: now.Add(5 * time.Minute)},
+	}
+	if err := arrivals.Save(records); err != nil {
+		t.Fatal(err)
+	}
+
+	// A replay polled at other times reports the same arrivals at other
+	// times, but with the same predictions.
+	replayed := append([]nextbus.ArrivalRecord(nil), records...)
+	replayed[0].Time = now.Add(20 * time.Second)
+	later := records[1]
+	later.Time, later.Predicted = now.Add(time.Hour), now.Add(time.Hour)
+	if err := arrivals.Save(append(replayed, later)); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := arrivals.Arrivals(now, now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 4 {
+		t.Fatalf("got %d arrivals, want 4: %+v", len(found), found)
+	}
+	if !found[0].Time.Equal(now) || !found[0].Predicted.Equal(now.Add(time.Minute)) || found[0].TripTag != "7001" {
+		t.Errorf("first arrival: got %+v", found[0])
+	}
+	if !found[2].Predicted.IsZero() {
+		t.Errorf("an arrival without a predicted time: got %+v", found[2])
+	}
+
+	tags, err := store.Agencies(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := false
+	for _, tag := range tags {
+		listed = listed || tag == agencyTag
+	}
+	if !listed {
+		t.Errorf("agencies: %s missing from %v", agencyTag, tags)
+	}
+}
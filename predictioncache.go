@@ -0,0 +1,164 @@
+package nextbus
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPredictionTTL is the PredictionCache TTL used when none is set.
+// NextBus updates predictions about every 10 seconds and asks clients not to
+// poll faster than that.
+const DefaultPredictionTTL = 30 * time.Second
+
+// PredictionSet is a set of predictions with the time it was fetched, so that
+// countdowns from an old response can be recognized and flagged.
+type PredictionSet struct {
+	Predictions []PredictionData
+	FetchedAt   time.Time
+
+	clock Clock
+}
+
+// Age returns how long ago the set was fetched.
+func (s *PredictionSet) Age() time.Duration {
+	clock := s.clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	return clock.Now().Sub(s.FetchedAt)
+}
+
+// IsStale reports whether the set is older than ttl.
+func (s *PredictionSet) IsStale(ttl time.Duration) bool {
+	return s.Age() > ttl
+}
+
+// predictionFlight is a fetch that concurrent Get calls for the same stops
+// wait on together.
+type predictionFlight struct {
+	done chan struct{}
+	set  *PredictionSet
+	err  error
+}
+
+// PredictionCache serves predictions for sets of stops of an agency,
+// refetching them once they are older than TTL. Concurrent requests for the
+// same stops while a fetch is in progress share it instead of each making a
+// request.
+type PredictionCache struct {
+	// TTL is how long a set is served before it is refetched. Zero uses
+	// DefaultPredictionTTL.
+	TTL time.Duration
+
+	client    *Client
+	agencyTag string
+
+	mu      sync.Mutex
+	sets    map[string]*PredictionSet
+	flights map[string]*predictionFlight
+}
+
+// NewPredictionCache creates a cache for predictions of an agency's stops.
+func NewPredictionCache(client *Client, agencyTag string) *PredictionCache {
+	return &PredictionCache{client: client, agencyTag: agencyTag}
+}
+
+func (c *PredictionCache) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return DefaultPredictionTTL
+	}
+	return c.TTL
+}
+
+// stopsKey identifies a set of stops regardless of their order.
+func stopsKey(stops []RouteStop) string {
+	keys := make([]string, len(stops))
+	for i, rs := range stops {
+		keys[i] = rs.RouteTag + "|" + rs.StopTag
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "&")
+}
+
+// Get returns the predictions for stops, fetching them if there are none
+// younger than TTL. If the fetch fails, the last set fetched is returned
+// along with the error, if there is one, so that callers can keep showing it
+// marked as stale rather than nothing.
+func (c *PredictionCache) Get(ctx context.Context, stops ...RouteStop) (*PredictionSet, error) {
+	key := stopsKey(stops)
+	c.mu.Lock()
+	if set, cached := c.sets[key]; cached && !set.IsStale(c.ttl()) {
+		c.mu.Unlock()
+		return set, nil
+	}
+	flight, inFlight := c.flights[key]
+	if !inFlight {
+		flight = &predictionFlight{done: make(chan struct{})}
+		if c.flights == nil {
+			c.flights = map[string]*predictionFlight{}
+		}
+		c.flights[key] = flight
+	}
+	c.mu.Unlock()
+
+	if !inFlight {
+		go c.fetch(key, flight, append([]RouteStop(nil), stops...))
+	}
+	select {
+	case <-flight.done:
+	case <-ctx.Done():
+		return c.Cached(stops...), ctx.Err()
+	}
+	if flight.err != nil {
+		return c.Cached(stops...), flight.err
+	}
+	return flight.set, nil
+}
+
+// fetch makes the request of a flight and stores its result. It runs apart
+// from the Get call that started it and doesn't use its context, so that
+// canceling that caller returns it promptly without failing the others
+// waiting on the same fetch.
+func (c *PredictionCache) fetch(key string, flight *predictionFlight, stops []RouteStop) {
+	defer close(flight.done)
+	var all []PredictionData
+	for start := 0; start < len(stops); start += maxStopsPerRequest {
+		end := start + maxStopsPerRequest
+		if end > len(stops) {
+			end = len(stops)
+		}
+		params := make([]PredReqParam, 0, end-start)
+		for _, rs := range stops[start:end] {
+			params = append(params, PredReqStop(rs.RouteTag, rs.StopTag))
+		}
+		predictions, err := c.client.GetPredictionsForMultiStopsContext(context.Background(), c.agencyTag, params...)
+		if err != nil {
+			flight.err = err
+			break
+		}
+		all = append(all, predictions...)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.flights, key)
+	if flight.err != nil {
+		return
+	}
+	flight.set = &PredictionSet{Predictions: all, FetchedAt: c.client.now(), clock: c.client.clock}
+	if c.sets == nil {
+		c.sets = map[string]*PredictionSet{}
+	}
+	c.sets[key] = flight.set
+}
+
+// Cached returns the last set fetched for stops without fetching, or nil if
+// there is none.
+func (c *PredictionCache) Cached(stops ...RouteStop) *PredictionSet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sets[stopsKey(stops)]
+}
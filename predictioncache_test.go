@@ -0,0 +1,121 @@
+package nextbus
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const cachedPredictionsBody = `<body><predictions routeTag="N" stopTag="5205"><direction title="Inbound"><prediction epochTime="1487246460000" minutes="1"/></direction></predictions></body>`
+
+func TestPredictionCache(t *testing.T) {
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	feed := cannedFeed(cachedPredictionsBody)
+	nb := NewClient(feed.Client(), WithClock(fixedClock(&now)))
+	c := NewPredictionCache(nb, "sf-muni")
+	stop := RouteStop{"N", "5205"}
+
+	set, err := c.Get(context.Background(), stop)
+	ok(t, err)
+	equals(t, 1, len(set.Predictions))
+	equals(t, now, set.FetchedAt)
+
+	now = now.Add(20 * time.Second)
+	set, err = c.Get(context.Background(), stop)
+	ok(t, err)
+	equals(t, 1, feed.Count())
+	equals(t, 20*time.Second, set.Age())
+	assert(t, set.IsStale(10*time.Second), "expected a 20s old set to be stale after 10s")
+	assert(t, !set.IsStale(DefaultPredictionTTL), "did not expect a 20s old set to be stale")
+
+	now = now.Add(time.Minute)
+	set, err = c.Get(context.Background(), stop)
+	ok(t, err)
+	equals(t, 2, feed.Count())
+	equals(t, time.Duration(0), set.Age())
+}
+
+func TestPredictionCacheSharesFetches(t *testing.T) {
+	var attempts int
+	var mu sync.Mutex
+	release := make(chan struct{})
+	blocking := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		<-release
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(cachedPredictionsBody))
+		return res, nil
+	})}
+	c := NewPredictionCache(NewClient(blocking), "sf-muni")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Get(context.Background(), RouteStop{"N", "5205"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	equals(t, 1, attempts)
+}
+
+func TestPredictionCacheServesStaleOnError(t *testing.T) {
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	failing := false
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if failing {
+			return nil, errors.New("network down")
+		}
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(cachedPredictionsBody))
+		return res, nil
+	})}
+	c := NewPredictionCache(NewClient(client, WithClock(fixedClock(&now))), "sf-muni")
+	_, err := c.Get(context.Background(), RouteStop{"N", "5205"})
+	ok(t, err)
+
+	failing = true
+	now = now.Add(time.Minute)
+	set, err := c.Get(context.Background(), RouteStop{"N", "5205"})
+	assert(t, err != nil, "expected the refetch to fail")
+	assert(t, set != nil && set.IsStale(c.TTL+DefaultPredictionTTL), "expected the stale set, got %+v", set)
+}
+
+func TestPredictionCacheLeaderDeadline(t *testing.T) {
+	release := make(chan struct{})
+	blocking := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-release
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(cachedPredictionsBody))
+		return res, nil
+	})}
+	c := NewPredictionCache(NewClient(blocking), "sf-muni")
+	stop := RouteStop{"N", "5205"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	set, err := c.Get(ctx, stop)
+	elapsed := time.Since(start)
+	equals(t, context.DeadlineExceeded, err)
+	assert(t, set == nil, "expected no set before the first fetch finishes, got %+v", set)
+	assert(t, elapsed < time.Second, "expected Get to return at its deadline, took %v", elapsed)
+
+	// The fetch carries on for the callers that wait for it.
+	close(release)
+	set, err = c.Get(context.Background(), stop)
+	ok(t, err)
+	equals(t, 1, len(set.Predictions))
+}
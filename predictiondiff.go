@@ -0,0 +1,147 @@
+package nextbus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PredictionChangeKind identifies the kind of a PredictionChange.
+type PredictionChangeKind string
+
+// The kinds of PredictionChange reported by DiffPredictions.
+const (
+	// VehicleDropped is a vehicle no longer predicted at a stop although it
+	// hadn't arrived yet.
+	VehicleDropped PredictionChangeKind = "vehicle dropped"
+	// ArrivalSlipped is a vehicle predicted to arrive later than before by
+	// more than the threshold.
+	ArrivalSlipped PredictionChangeKind = "arrival slipped"
+	// EarlierArrival is a newly predicted vehicle arriving before every
+	// vehicle that was predicted before and hasn't arrived yet.
+	EarlierArrival PredictionChangeKind = "new earlier arrival"
+)
+
+// DefaultSlipThreshold is the slip PredictionWatcher reports when its
+// SlipThreshold isn't set.
+const DefaultSlipThreshold = 2 * time.Minute
+
+// PredictionChange is one difference between two fetches of predictions for
+// the same stops.
+type PredictionChange struct {
+	Kind     PredictionChangeKind
+	RouteTag string
+	StopTag  string
+	// Vehicle is the vehicle, or failing that the trip, the change is about.
+	Vehicle string
+	// Old and New are the prediction before and after. Old is zero for an
+	// EarlierArrival and New for a VehicleDropped.
+	Old Prediction
+	New Prediction
+	// Slip is how much later the vehicle is predicted to arrive, for an
+	// ArrivalSlipped.
+	Slip time.Duration
+}
+
+// String formats the change as a log line.
+func (c PredictionChange) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "route %s stop %s vehicle %s: %s", c.RouteTag, c.StopTag, c.Vehicle, c.Kind)
+	if c.Slip != 0 {
+		fmt.Fprintf(&b, " by %s", c.Slip)
+	}
+	return b.String()
+}
+
+// predictionKey identifies a vehicle's prediction at a stop across fetches.
+func predictionKey(pd PredictionData, p Prediction) (string, string) {
+	id := p.Vehicle
+	if id == "" {
+		id = p.TripTag
+	}
+	return pd.RouteTag + "|" + pd.StopTag, id
+}
+
+type keyedPrediction struct {
+	routeTag, stopTag string
+	prediction        Prediction
+}
+
+// indexPredictions maps the predictions of vehicles at each stop by stop and
+// vehicle. Predictions without a vehicle or trip can't be followed and are
+// left out.
+func indexPredictions(predictions []PredictionData) map[string]map[string]keyedPrediction {
+	index := map[string]map[string]keyedPrediction{}
+	for _, pd := range predictions {
+		for _, dir := range pd.PredictionDirectionList {
+			for _, p := range dir.PredictionList {
+				stop, id := predictionKey(pd, p)
+				if id == "" {
+					continue
+				}
+				if index[stop] == nil {
+					index[stop] = map[string]keyedPrediction{}
+				}
+				if _, seen := index[stop][id]; !seen {
+					index[stop][id] = keyedPrediction{pd.RouteTag, pd.StopTag, p}
+				}
+			}
+		}
+	}
+	return index
+}
+
+// DiffPredictions compares two fetches of predictions for the same stops, the
+// second made at now, and reports vehicles dropped before arriving, arrivals
+// that slipped by more than slip, and new vehicles arriving before every
+// vehicle predicted before. Stops missing from either fetch are skipped.
+// Changes are ordered by route, stop and vehicle.
+func DiffPredictions(old, new []PredictionData, now time.Time, slip time.Duration) []PredictionChange {
+	oldIndex, newIndex := indexPredictions(old), indexPredictions(new)
+	var changes []PredictionChange
+	for stop, before := range oldIndex {
+		after, fetched := newIndex[stop]
+		if !fetched {
+			continue
+		}
+		var earliest time.Time
+		for id, o := range before {
+			oldArrival := o.prediction.ArrivalTime()
+			if oldArrival.After(now) && (earliest.IsZero() || oldArrival.Before(earliest)) {
+				earliest = oldArrival
+			}
+			n, still := after[id]
+			if !still {
+				if oldArrival.After(now) {
+					changes = append(changes, PredictionChange{Kind: VehicleDropped, RouteTag: o.routeTag, StopTag: o.stopTag, Vehicle: id, Old: o.prediction})
+				}
+				continue
+			}
+			// A vehicle that was due by now has arrived, and a later
+			// prediction for it is its next trip rather than a slip.
+			if !oldArrival.After(now) {
+				continue
+			}
+			if delta := n.prediction.ArrivalTime().Sub(oldArrival); delta > slip {
+				changes = append(changes, PredictionChange{Kind: ArrivalSlipped, RouteTag: o.routeTag, StopTag: o.stopTag, Vehicle: id, Old: o.prediction, New: n.prediction, Slip: delta})
+			}
+		}
+		for id, n := range after {
+			if _, known := before[id]; !known && !earliest.IsZero() && n.prediction.ArrivalTime().Before(earliest) {
+				changes = append(changes, PredictionChange{Kind: EarlierArrival, RouteTag: n.routeTag, StopTag: n.stopTag, Vehicle: id, New: n.prediction})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.RouteTag != b.RouteTag {
+			return a.RouteTag < b.RouteTag
+		}
+		if a.StopTag != b.StopTag {
+			return a.StopTag < b.StopTag
+		}
+		return a.Vehicle < b.Vehicle
+	})
+	return changes
+}
@@ -0,0 +1,75 @@
+package nextbus
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func atStop(predictions ...Prediction) []PredictionData {
+	return []PredictionData{{RouteTag: "N", StopTag: "5205", PredictionDirectionList: []PredictionDirection{{PredictionList: predictions}}}}
+}
+
+func TestDiffPredictions(t *testing.T) {
+	fetched := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	now := fetched.Add(30 * time.Second)
+	old := atStop(
+		withArrival(Prediction{Vehicle: "1"}, fetched, fetched.Add(20*time.Second)),
+		withArrival(Prediction{Vehicle: "2"}, fetched, fetched.Add(4*time.Minute)),
+		withArrival(Prediction{Vehicle: "3"}, fetched, fetched.Add(10*time.Minute)),
+		withArrival(Prediction{Vehicle: "4"}, fetched, fetched.Add(15*time.Minute)),
+	)
+	new := atStop(
+		withArrival(Prediction{Vehicle: "5"}, now, fetched.Add(2*time.Minute)),
+		withArrival(Prediction{Vehicle: "3"}, now, fetched.Add(11*time.Minute)),
+		withArrival(Prediction{Vehicle: "4"}, now, fetched.Add(20*time.Minute)),
+		withArrival(Prediction{Vehicle: "6"}, now, fetched.Add(30*time.Minute)),
+		withArrival(Prediction{Vehicle: "1"}, now, fetched.Add(time.Hour+time.Minute)),
+	)
+
+	changes := DiffPredictions(old, new, now, 2*time.Minute)
+	equals(t, 3, len(changes))
+	// Vehicle 1 arrived and is predicted again for its next loop, 3 slipped
+	// less than the threshold and 6 is later than everything predicted
+	// before.
+	equals(t, VehicleDropped, changes[0].Kind)
+	equals(t, "2", changes[0].Vehicle)
+	equals(t, ArrivalSlipped, changes[1].Kind)
+	equals(t, "4", changes[1].Vehicle)
+	equals(t, 5*time.Minute, changes[1].Slip)
+	equals(t, EarlierArrival, changes[2].Kind)
+	equals(t, "5", changes[2].Vehicle)
+	equals(t, "route N stop 5205 vehicle 4: arrival slipped by 5m0s", changes[1].String())
+
+	equals(t, 0, len(DiffPredictions(old, nil, now, time.Minute)))
+}
+
+func TestWatcherOnChange(t *testing.T) {
+	bodies := []string{
+		`<body><predictions routeTag="N" stopTag="5205"><direction title="Inbound"><prediction epochTime="1487246580000" vehicle="1"/></direction></predictions></body>`,
+		`<body><predictions routeTag="N" stopTag="5205"><direction title="Inbound"><prediction epochTime="1487246880000" vehicle="1"/></direction></predictions></body>`,
+	}
+	calls := 0
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(bodies[calls]))
+		calls++
+		return res, nil
+	})}
+	now := time.Unix(1487246400, 0)
+	w := NewPredictionWatcher(NewClient(client, WithClock(fixedClock(&now))), "sf-muni", RouteStop{"N", "5205"})
+	var changes []PredictionChange
+	w.OnChange = func(c []PredictionChange) { changes = append(changes, c...) }
+	var published int
+	w.Bus = &Bus{}
+	w.Bus.Subscribe(func(BusEvent) { published++ }, TopicPredictionChanges)
+
+	ok(t, w.Poll())
+	equals(t, 0, len(changes))
+	ok(t, w.Poll())
+	equals(t, 1, len(changes))
+	equals(t, ArrivalSlipped, changes[0].Kind)
+	equals(t, 1, published)
+}
@@ -0,0 +1,125 @@
+package nextbus
+
+import (
+	"context"
+)
+
+// probeStops is how many stops of the probed route are asked for
+// predictions.
+const probeStops = 5
+
+// Capabilities are the commands and attributes an agency's feed was seen to
+// support by Probe. Attributes are judged from the data returned at the time
+// of the probe, so optional ones, such as trip tags, may be reported missing
+// when no vehicles are in service.
+type Capabilities struct {
+	AgencyTag string
+	// RouteTag is the route whose config, predictions and schedule were
+	// probed.
+	RouteTag string
+
+	// StopIDs reports whether stops have a stopId, which GetStopPredictions
+	// needs.
+	StopIDs bool
+	// Paths reports whether route configs include path points.
+	Paths bool
+
+	// Predictions reports whether predictions for the route's stops could be
+	// fetched.
+	Predictions bool
+	// TripTags and Blocks report whether predictions identify the trip and
+	// block the vehicle is serving.
+	TripTags bool
+	Blocks   bool
+
+	// Vehicles reports whether vehicle locations were returned.
+	Vehicles bool
+	// Speed and Heading report whether vehicles report their speed and a
+	// heading.
+	Speed   bool
+	Heading bool
+
+	// Schedules reports whether the schedule command is supported.
+	Schedules bool
+}
+
+// Probe makes a few small requests to find out which features an agency's
+// feed supports: the config of its first route, predictions for some of that
+// route's stops, the agency's vehicle locations and the route's schedule. A
+// command the feed rejects with an Error is reported as unsupported; any
+// other failure is returned.
+func (c *Client) Probe(ctx context.Context, agencyTag string) (*Capabilities, error) {
+	routes, err := c.GetRouteListContext(ctx, agencyTag)
+	if err != nil {
+		return nil, err
+	}
+	caps := &Capabilities{AgencyTag: agencyTag}
+	if len(routes) == 0 {
+		return caps, nil
+	}
+	caps.RouteTag = routes[0].Tag
+
+	configs, err := c.GetRouteConfigContext(ctx, agencyTag, RouteConfigTag(caps.RouteTag))
+	if err != nil {
+		return nil, err
+	}
+	var stops []RouteStop
+	for _, rc := range configs {
+		caps.Paths = caps.Paths || len(rc.PathList) != 0
+		for _, stop := range rc.StopList {
+			caps.StopIDs = caps.StopIDs || stop.StopID != ""
+			if len(stops) < probeStops {
+				stops = append(stops, RouteStop{rc.Tag, stop.Tag})
+			}
+		}
+	}
+
+	if len(stops) != 0 {
+		params := make([]PredReqParam, len(stops))
+		for i, rs := range stops {
+			params[i] = PredReqStop(rs.RouteTag, rs.StopTag)
+		}
+		predictions, err := c.GetPredictionsForMultiStopsContext(ctx, agencyTag, params...)
+		if caps.Predictions, err = supported(err); err != nil {
+			return nil, err
+		}
+		for _, pd := range predictions {
+			for _, dir := range pd.PredictionDirectionList {
+				for _, p := range dir.PredictionList {
+					caps.TripTags = caps.TripTags || p.TripTag != ""
+					caps.Blocks = caps.Blocks || p.Block != ""
+				}
+			}
+		}
+	}
+
+	vehicles, err := c.GetVehicleLocationsContext(ctx, agencyTag)
+	if _, err = supported(err); err != nil {
+		return nil, err
+	}
+	if vehicles != nil {
+		caps.Vehicles = len(vehicles.VehicleList) != 0
+		for _, v := range vehicles.VehicleList {
+			caps.Speed = caps.Speed || v.SpeedKmHr != ""
+			caps.Heading = caps.Heading || v.Heading != "" && v.Heading != "-1"
+		}
+	}
+
+	_, err = c.GetScheduleContext(ctx, agencyTag, caps.RouteTag)
+	if caps.Schedules, err = supported(err); err != nil {
+		return nil, err
+	}
+	return caps, nil
+}
+
+// supported interprets the error of a probing request: a *FeedError means
+// the command isn't supported, and other errors are returned.
+func supported(err error) (bool, error) {
+	if err == nil {
+		return true, nil
+	}
+	if _, isFeedErr := err.(*FeedError); isFeedErr {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,37 @@
+package nextbus
+
+import (
+	"context"
+	"testing"
+)
+
+func TestProbe(t *testing.T) {
+	responses := map[string]string{
+		"routeList":   `<body><route tag="N" title="N-Judah"/></body>`,
+		"routeConfig": `<body><route tag="N"><stop tag="5205" stopId="15205"/><stop tag="4006"/><path><point lat="37.7" lon="-122.4"/></path></route></body>`,
+		"predictionsForMultiStops": `<body><predictions routeTag="N" stopTag="5205"><direction title="Inbound">` +
+			`<prediction epochTime="1" block="9702" vehicle="1500"/></direction></predictions></body>`,
+		"vehicleLocations": `<body><vehicle id="1500" routeTag="N" heading="-1"/><lastTime time="1"/></body>`,
+		"schedule":         `<body><Error shouldRetry="false">Command not supported for this agency</Error></body>`,
+	}
+	caps, err := NewClient((&fakeFeed{Bodies: responses}).Client()).Probe(context.Background(), "alpha")
+	ok(t, err)
+	equals(t, &Capabilities{
+		AgencyTag:   "alpha",
+		RouteTag:    "N",
+		StopIDs:     true,
+		Paths:       true,
+		Predictions: true,
+		Blocks:      true,
+		Vehicles:    true,
+	}, caps)
+}
+
+func TestProbeUnknownAgency(t *testing.T) {
+	feed := &fakeFeed{Bodies: map[string]string{
+		"routeList": `<body><Error shouldRetry="false">Agency parameter "a=gamma" is not valid.</Error></body>`,
+	}}
+	_, err := NewClient(feed.Client()).Probe(context.Background(), "gamma")
+	_, isFeedErr := err.(*FeedError)
+	assert(t, isFeedErr, "expected a *FeedError, got %v", err)
+}
@@ -0,0 +1,56 @@
+package nextbus
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SecureFeedURL is the HTTPS endpoint of the NextBus public XML feed.
+const SecureFeedURL = "https://webservices.nextbus.com/service/publicXMLFeed"
+
+// NewRecommendedClient creates a Client configured the way most programs
+// should use the feed: over HTTPS, with a request timeout, three retries and
+// requests spaced a second apart, followed by any opts. Responses are
+// compressed with gzip, which the http package requests and decodes
+// transparently.
+func NewRecommendedClient(opts ...Option) *Client {
+	return NewClient(&http.Client{Timeout: 30 * time.Second}, append([]Option{
+		WithFeedURL(SecureFeedURL),
+		WithRetries(3, time.Second),
+		WithRateLimit(time.Second),
+	}, opts...)...)
+}
+
+var quickstart struct {
+	once   sync.Once
+	client *Client
+}
+
+// quickstartClient returns the Client shared by the package-level helpers,
+// creating it on first use.
+func quickstartClient() *Client {
+	quickstart.once.Do(func() {
+		quickstart.client = NewRecommendedClient()
+	})
+	return quickstart.client
+}
+
+// Predictions fetches the predictions for a stop on a route, such as
+// Predictions(ctx, "sf-muni", "N", "5205"), with a Client created by
+// NewRecommendedClient and shared by the package-level helpers.
+func Predictions(ctx context.Context, agencyTag, routeTag, stopTag string) ([]PredictionData, error) {
+	return quickstartClient().GetPredictionsContext(ctx, agencyTag, routeTag, stopTag)
+}
+
+// Vehicles fetches the locations of the vehicles on a route, such as
+// Vehicles(ctx, "sf-muni", "N"), with the Client shared by the package-level
+// helpers.
+func Vehicles(ctx context.Context, agencyTag, routeTag string) ([]VehicleLocation, error) {
+	locations, err := quickstartClient().GetVehicleLocationsContext(ctx, agencyTag, VehicleLocationRoute(routeTag))
+	if err != nil {
+		return nil, err
+	}
+	return locations.VehicleList, nil
+}
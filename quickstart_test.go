@@ -0,0 +1,40 @@
+package nextbus
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestQuickstart(t *testing.T) {
+	nb := NewRecommendedClient()
+	equals(t, SecureFeedURL, nb.feedURL)
+	equals(t, 3, nb.retries)
+	assert(t, nb.limiter != nil, "expected a rate limit")
+
+	var requested []string
+	quickstart.once.Do(func() {})
+	quickstart.client = NewClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requested = append(requested, req.URL.String())
+		body := `<body><predictions routeTag="N" stopTag="5205"/></body>`
+		if req.URL.Query().Get("command") == "vehicleLocations" {
+			body = `<body><vehicle id="1500" routeTag="N"/><lastTime time="1000"/></body>`
+		}
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(body))
+		return res, nil
+	})}, WithFeedURL("https://mirror.example/feed"))
+
+	predictions, err := Predictions(context.Background(), "sf-muni", "N", "5205")
+	ok(t, err)
+	equals(t, 1, len(predictions))
+	vehicles, err := Vehicles(context.Background(), "sf-muni", "N")
+	ok(t, err)
+	equals(t, "1500", vehicles[0].ID)
+	equals(t, []string{
+		"https://mirror.example/feed?command=predictions&a=sf-muni&r=N&s=5205",
+		"https://mirror.example/feed?command=vehicleLocations&a=sf-muni&r=N&t=0",
+	}, requested)
+}
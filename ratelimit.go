@@ -0,0 +1,115 @@
+package nextbus
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter hands out request slots at most one per interval.
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait blocks until the caller may make a request or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// defaultRetryAfter is assumed when NextBus throttles a client without saying
+// for how long.
+const defaultRetryAfter = 30 * time.Second
+
+var (
+	// throttleText matches the messages NextBus uses when a client exceeds its
+	// request or bandwidth limits or has been blocked.
+	throttleText = regexp.MustCompile(`(?i)rate limit|bandwidth|throttl|banned|blocked|too many requests|exceeded the maximum number of requests|more than \S+ of data`)
+	// waitText finds a wait such as "wait 20 seconds" in a throttling message.
+	waitText = regexp.MustCompile(`(?i)(\d+)\s*(seconds?|secs?|minutes?|mins?)\b`)
+)
+
+// rateLimitError returns the RateLimitError described by a throttling message
+// and the value of a Retry-After header, either of which may be empty.
+func rateLimitError(message, retryAfter string) *RateLimitError {
+	message = strings.TrimSpace(message)
+	e := &RateLimitError{RetryAfter: defaultRetryAfter, Message: message}
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs >= 0 {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	} else if when, err := http.ParseTime(retryAfter); err == nil {
+		e.RetryAfter = time.Until(when)
+	} else if m := waitText.FindStringSubmatch(message); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		unit := time.Second
+		if strings.HasPrefix(strings.ToLower(m[2]), "min") {
+			unit = time.Minute
+		}
+		e.RetryAfter = time.Duration(n) * unit
+	}
+	if e.RetryAfter < 0 {
+		e.RetryAfter = 0
+	}
+	return e
+}
+
+// throttle holds back every request of a Client once NextBus has reported it
+// as rate limited, so that concurrent and retried requests all back off
+// rather than each discovering the limit on its own.
+type throttle struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// hold blocks requests for d from now, unless they are already blocked for
+// longer.
+func (t *throttle) hold(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := time.Now().Add(d); until.After(t.until) {
+		t.until = until
+	}
+}
+
+// wait blocks until requests are no longer held back or ctx is done.
+func (t *throttle) wait(ctx context.Context) error {
+	t.mu.Lock()
+	delay := time.Until(t.until)
+	t.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
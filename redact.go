@@ -0,0 +1,249 @@
+package nextbus
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// RedactAction is what a Redactor does with one kind of identifier.
+type RedactAction int
+
+// The actions a Redactor can take.
+const (
+	// RedactKeep leaves identifiers unchanged.
+	RedactKeep RedactAction = iota
+	// RedactStrip replaces identifiers with the empty string.
+	RedactStrip
+	// RedactHash replaces identifiers with a hash, so that records of the
+	// same vehicle or block can still be matched with each other.
+	RedactHash
+)
+
+// Redactor removes vehicle and block IDs from the data leaving a deployment
+// whose data-sharing agreement restricts them. The same Redactor can filter
+// events sent to a Notifier or republished on a Bus, arrivals saved by an
+// Archiver and the responses recorded by a HARRecorder, so that every output
+// applies the same rules. The zero value keeps everything.
+type Redactor struct {
+	// Vehicles is the action for vehicle IDs, including those of leading
+	// vehicles and vehicles in a consist.
+	Vehicles RedactAction
+	// Blocks is the action for block IDs.
+	Blocks RedactAction
+	// Key keys the hashes of RedactHash. Without a key, anyone can recover
+	// the IDs of a fleet by hashing each possible ID.
+	Key []byte
+}
+
+func (r *Redactor) apply(action RedactAction, id string) string {
+	if id == "" {
+		return id
+	}
+	switch action {
+	case RedactStrip:
+		return ""
+	case RedactHash:
+		mac := hmac.New(sha256.New, r.Key)
+		mac.Write([]byte(id))
+		return hex.EncodeToString(mac.Sum(nil)[:8])
+	}
+	return id
+}
+
+// Vehicle returns the redacted form of a vehicle ID.
+func (r *Redactor) Vehicle(id string) string {
+	return r.apply(r.action(true), id)
+}
+
+// Block returns the redacted form of a block ID.
+func (r *Redactor) Block(id string) string {
+	return r.apply(r.action(false), id)
+}
+
+func (r *Redactor) action(vehicles bool) RedactAction {
+	if r == nil {
+		return RedactKeep
+	}
+	if vehicles {
+		return r.Vehicles
+	}
+	return r.Blocks
+}
+
+// consist redacts each ID of a comma-separated list of vehicles.
+func (r *Redactor) consist(ids string) string {
+	if ids == "" || r.action(true) == RedactKeep {
+		return ids
+	}
+	if r.Vehicles == RedactStrip {
+		return ""
+	}
+	parts := strings.Split(ids, ",")
+	for i, id := range parts {
+		parts[i] = r.Vehicle(strings.TrimSpace(id))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Predictions returns a copy of predictions with their vehicle and block IDs
+// redacted.
+func (r *Redactor) Predictions(predictions []PredictionData) []PredictionData {
+	result := make([]PredictionData, len(predictions))
+	for i, pd := range predictions {
+		result[i] = pd
+		result[i].PredictionDirectionList = make([]PredictionDirection, len(pd.PredictionDirectionList))
+		for j, dir := range pd.PredictionDirectionList {
+			redacted := dir
+			redacted.PredictionList = make([]Prediction, len(dir.PredictionList))
+			for k, p := range dir.PredictionList {
+				p.Vehicle = r.Vehicle(p.Vehicle)
+				p.VehiclesInConsist = r.consist(p.VehiclesInConsist)
+				p.Block = r.Block(p.Block)
+				redacted.PredictionList[k] = p
+			}
+			result[i].PredictionDirectionList[j] = redacted
+		}
+	}
+	return result
+}
+
+// VehicleLocations returns a copy of locations with their vehicle IDs
+// redacted.
+func (r *Redactor) VehicleLocations(locations *LocationResponse) *LocationResponse {
+	result := *locations
+	result.VehicleList = make([]VehicleLocation, len(locations.VehicleList))
+	for i, v := range locations.VehicleList {
+		v.ID = r.Vehicle(v.ID)
+		v.LeadingVehicleID = r.Vehicle(v.LeadingVehicleID)
+		result.VehicleList[i] = v
+	}
+	return &result
+}
+
+// Event returns e with its vehicle ID redacted.
+func (r *Redactor) Event(e Event) Event {
+	e.Vehicle = r.Vehicle(e.Vehicle)
+	return e
+}
+
+// Arrivals returns a copy of records with their vehicle IDs redacted.
+func (r *Redactor) Arrivals(records []ArrivalRecord) []ArrivalRecord {
+	result := make([]ArrivalRecord, len(records))
+	for i, rec := range records {
+		rec.Vehicle = r.Vehicle(rec.Vehicle)
+		result[i] = rec
+	}
+	return result
+}
+
+// PredictionChanges returns a copy of changes with their vehicle and block
+// IDs redacted.
+func (r *Redactor) PredictionChanges(changes []PredictionChange) []PredictionChange {
+	result := make([]PredictionChange, len(changes))
+	for i, c := range changes {
+		c.Vehicle = r.Vehicle(c.Vehicle)
+		for _, p := range []*Prediction{&c.Old, &c.New} {
+			p.Vehicle = r.Vehicle(p.Vehicle)
+			p.VehiclesInConsist = r.consist(p.VehiclesInConsist)
+			p.Block = r.Block(p.Block)
+		}
+		result[i] = c
+	}
+	return result
+}
+
+// Payload returns the redacted form of the payload of a BusEvent published by
+// this package. Other payloads are returned unchanged.
+func (r *Redactor) Payload(payload interface{}) interface{} {
+	switch p := payload.(type) {
+	case Event:
+		return r.Event(p)
+	case []PredictionData:
+		return r.Predictions(p)
+	case *LocationResponse:
+		return r.VehicleLocations(p)
+	case []PredictionChange:
+		return r.PredictionChanges(p)
+	case []ArrivalRecord:
+		return r.Arrivals(p)
+	}
+	return payload
+}
+
+// Forward republishes every event published on from to to, with its payload
+// redacted, until unsubscribe is called. Subscribers that share data outside
+// the deployment, such as NotifyFrom, subscribe to to.
+func (r *Redactor) Forward(from, to *Bus) (unsubscribe func()) {
+	return from.Subscribe(func(e BusEvent) {
+		e.Payload = r.Payload(e.Payload)
+		to.Publish(e)
+	})
+}
+
+// Notifier returns a Notifier that redacts events before passing them to n.
+func (r *Redactor) Notifier(n Notifier) Notifier {
+	return redactingNotifier{r, n}
+}
+
+type redactingNotifier struct {
+	redactor *Redactor
+	next     Notifier
+}
+
+func (n redactingNotifier) Notify(ctx context.Context, e Event) error {
+	return n.next.Notify(ctx, n.redactor.Event(e))
+}
+
+// ArrivalStore returns an ArrivalStore that redacts records before saving them
+// to s.
+func (r *Redactor) ArrivalStore(s ArrivalStore) ArrivalStore {
+	return redactingStore{r, s}
+}
+
+type redactingStore struct {
+	redactor *Redactor
+	ArrivalStore
+}
+
+func (s redactingStore) Save(records []ArrivalRecord) error {
+	return s.ArrivalStore.Save(s.redactor.Arrivals(records))
+}
+
+// The attributes of feed responses holding vehicle and block IDs. The id
+// attribute holds a vehicle ID only on vehicle elements.
+var (
+	vehicleStartTag  = regexp.MustCompile(`<vehicle\s[^>]*>`)
+	vehicleIDAttr    = regexp.MustCompile(`\b(id|leadingVehicleId)="([^"]*)"`)
+	predictionIDAttr = regexp.MustCompile(`\b(vehicle|vehiclesInConsist|block|blockID)="([^"]*)"`)
+)
+
+// XML returns a copy of a raw feed response with its vehicle and block IDs
+// redacted.
+func (r *Redactor) XML(data []byte) []byte {
+	if r.action(true) == RedactKeep && r.action(false) == RedactKeep {
+		return data
+	}
+	replace := func(attr *regexp.Regexp) func([]byte) []byte {
+		return func(match []byte) []byte {
+			parts := attr.FindSubmatch(match)
+			value := string(parts[2])
+			switch string(parts[1]) {
+			case "block", "blockID":
+				value = r.Block(value)
+			case "vehiclesInConsist":
+				value = r.consist(value)
+			default:
+				value = r.Vehicle(value)
+			}
+			return []byte(string(parts[1]) + `="` + value + `"`)
+		}
+	}
+	result := predictionIDAttr.ReplaceAllFunc(data, replace(predictionIDAttr))
+	return vehicleStartTag.ReplaceAllFunc(result, func(element []byte) []byte {
+		return vehicleIDAttr.ReplaceAllFunc(element, replace(vehicleIDAttr))
+	})
+}
@@ -0,0 +1,71 @@
+package nextbus
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactor(t *testing.T) {
+	r := &Redactor{Vehicles: RedactHash, Blocks: RedactStrip, Key: []byte("secret")}
+	hashed := r.Vehicle("1500")
+	equals(t, 16, len(hashed))
+	assert(t, hashed != "1500", "expected the vehicle ID to be hashed")
+	equals(t, hashed, r.Vehicle("1500"))
+	assert(t, hashed != (&Redactor{Vehicles: RedactHash}).Vehicle("1500"), "expected the key to change the hash")
+
+	predictions := []PredictionData{{RouteTag: "N", StopTag: "5205", PredictionDirectionList: []PredictionDirection{{
+		PredictionList: []Prediction{{Vehicle: "1500", VehiclesInConsist: "1500,1501", Block: "9701"}},
+	}}}}
+	p := r.Predictions(predictions)[0].PredictionDirectionList[0].PredictionList[0]
+	equals(t, hashed, p.Vehicle)
+	equals(t, hashed+","+r.Vehicle("1501"), p.VehiclesInConsist)
+	equals(t, "", p.Block)
+	equals(t, "1500", predictions[0].PredictionDirectionList[0].PredictionList[0].Vehicle)
+
+	data := []byte(`<body><vehicle id="1500" routeTag="N" leadingVehicleId="1501"/><predictions><direction>` +
+		`<prediction vehicle="1500" block="9701" tripTag="7"/></direction></predictions></body>`)
+	equals(t, `<body><vehicle id="`+hashed+`" routeTag="N" leadingVehicleId="`+r.Vehicle("1501")+`"/><predictions><direction>`+
+		`<prediction vehicle="`+hashed+`" block="" tripTag="7"/></direction></predictions></body>`, string(r.XML(data)))
+
+	var kept *Redactor
+	equals(t, "1500", kept.Vehicle("1500"))
+	equals(t, data, kept.XML(data))
+}
+
+func TestRedactorOutputs(t *testing.T) {
+	r := &Redactor{Vehicles: RedactStrip}
+	var notified []Event
+	n := notifierFunc(func(ctx context.Context, e Event) error {
+		notified = append(notified, e)
+		return nil
+	})
+	ok(t, r.Notifier(n).Notify(context.Background(), Event{Type: EventArrivalAlert, Vehicle: "1500"}))
+	equals(t, "", notified[0].Vehicle)
+
+	from, to := &Bus{}, &Bus{}
+	var forwarded []BusEvent
+	to.Subscribe(func(e BusEvent) { forwarded = append(forwarded, e) })
+	unsubscribe := r.Forward(from, to)
+	from.Publish(BusEvent{Topic: TopicVehicles, Payload: &LocationResponse{VehicleList: []VehicleLocation{{ID: "1500"}}}})
+	unsubscribe()
+	from.Publish(BusEvent{Topic: TopicVehicles, Payload: &LocationResponse{}})
+	equals(t, 1, len(forwarded))
+	equals(t, "", forwarded[0].Payload.(*LocationResponse).VehicleList[0].ID)
+
+	store := &MemoryArrivalStore{}
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	ok(t, r.ArrivalStore(store).Save([]ArrivalRecord{{Vehicle: "1500", Time: now}}))
+	records, err := store.Arrivals(now, now.Add(time.Minute))
+	ok(t, err)
+	equals(t, "", records[0].Vehicle)
+
+	rec := &HARRecorder{Redactor: r}
+	nb := NewClient(cannedFeed(`<body><vehicle id="1500"/><lastTime time="1"/></body>`).Client(), WithHARRecorder(rec))
+	_, err = nb.GetVehicleLocations("alpha")
+	ok(t, err)
+	var har strings.Builder
+	ok(t, rec.Write(&har))
+	assert(t, !strings.Contains(har.String(), "1500"), "expected the recorded response to be redacted")
+}
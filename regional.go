@@ -0,0 +1,154 @@
+package nextbus
+
+import (
+	"sort"
+)
+
+// AgencyStop is a route-scoped stop of a particular agency.
+type AgencyStop struct {
+	AgencyTag string
+	RouteTag  string
+	Stop      Stop
+}
+
+// RegionalStop is one physical location served by stops of more than one
+// agency, such as a transit center shared by a city and a regional operator.
+type RegionalStop struct {
+	// Title is the most common title among the stops.
+	Title string
+	// Lat and Lon are the centroid of the stops.
+	Lat   float64
+	Lon   float64
+	Stops []AgencyStop
+}
+
+// AgencyTags returns the distinct agencies serving the stop, in order.
+func (r RegionalStop) AgencyTags() []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, as := range r.Stops {
+		if !seen[as.AgencyTag] {
+			seen[as.AgencyTag] = true
+			result = append(result, as.AgencyTag)
+		}
+	}
+	return result
+}
+
+// MatchAgencyStops links the stops of several agencies, keyed by agency tag,
+// that are within maxMeters of each other; as with ClusterStops, stops of one
+// agency sharing a StopID are kept together and matching is transitive. Only
+// locations served by at least two agencies are returned, ordered by agency
+// tag and then by the order their first stop appears in that agency's
+// routes.
+func MatchAgencyStops(agencies map[string][]RouteConfig, maxMeters float64) []RegionalStop {
+	tags := make([]string, 0, len(agencies))
+	for tag := range agencies {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	var stops []AgencyStop
+	var entries []clusterEntry
+	for _, tag := range tags {
+		for _, rc := range agencies[tag] {
+			for _, stop := range rc.StopList {
+				id := ""
+				if stop.StopID != "" {
+					id = tag + "|" + stop.StopID
+				}
+				stops = append(stops, AgencyStop{tag, rc.Tag, stop})
+				entries = append(entries, newClusterEntry(stop, id))
+			}
+		}
+	}
+
+	var result []RegionalStop
+	for _, c := range clusterEntries(entries, maxMeters) {
+		rs := RegionalStop{Title: c.title, Lat: c.lat, Lon: c.lon}
+		for _, i := range c.members {
+			rs.Stops = append(rs.Stops, stops[i])
+		}
+		if len(rs.AgencyTags()) > 1 {
+			result = append(result, rs)
+		}
+	}
+	return result
+}
+
+// RegionalArrival is one predicted arrival at a RegionalStop.
+type RegionalArrival struct {
+	AgencyTag string
+	StationArrival
+}
+
+// RegionalPredictions are the combined predictions of every agency's stops at
+// a RegionalStop.
+type RegionalPredictions struct {
+	Stop RegionalStop
+	// Arrivals are ordered by predicted time.
+	Arrivals []RegionalArrival
+	// Messages holds each distinct message of the stops once.
+	Messages []Message
+}
+
+// GroupByRegionalStop merges the prediction data of several agencies, keyed
+// by agency tag, by the RegionalStop its stop belongs to. Stops are returned
+// in the order given, skipping those without prediction data.
+func GroupByRegionalStop(stops []RegionalStop, predictions map[string][]PredictionData) []RegionalPredictions {
+	type agencyRouteStop struct {
+		agencyTag string
+		RouteStop
+	}
+	index := map[agencyRouteStop]int{}
+	for i, rs := range stops {
+		for _, as := range rs.Stops {
+			index[agencyRouteStop{as.AgencyTag, RouteStop{as.RouteTag, as.Stop.Tag}}] = i
+		}
+	}
+
+	grouped := make([]*RegionalPredictions, len(stops))
+	seenMessages := make([]map[string]bool, len(stops))
+	tags := make([]string, 0, len(predictions))
+	for tag := range predictions {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		for _, pd := range predictions[tag] {
+			i, found := index[agencyRouteStop{tag, RouteStop{pd.RouteTag, pd.StopTag}}]
+			if !found {
+				continue
+			}
+			if grouped[i] == nil {
+				grouped[i] = &RegionalPredictions{Stop: stops[i]}
+				seenMessages[i] = map[string]bool{}
+			}
+			rp := grouped[i]
+			for _, dir := range pd.PredictionDirectionList {
+				for _, p := range dir.PredictionList {
+					rp.Arrivals = append(rp.Arrivals, RegionalArrival{tag, StationArrival{pd.RouteTag, pd.RouteTitle, pd.StopTag, dir.Title, p}})
+				}
+			}
+			for _, m := range pd.MessageList {
+				if !seenMessages[i][m.Text] {
+					seenMessages[i][m.Text] = true
+					rp.Messages = append(rp.Messages, m)
+				}
+			}
+		}
+	}
+
+	var result []RegionalPredictions
+	for _, rp := range grouped {
+		if rp == nil {
+			continue
+		}
+		arrivals := rp.Arrivals
+		sort.SliceStable(arrivals, func(a, b int) bool {
+			return arrivals[a].Prediction.ArrivalTime().Before(arrivals[b].Prediction.ArrivalTime())
+		})
+		result = append(result, *rp)
+	}
+	return result
+}
@@ -0,0 +1,46 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestMatchAgencyStops(t *testing.T) {
+	agencies := map[string][]RouteConfig{
+		"sf-muni": {{Tag: "14", StopList: []Stop{
+			{Tag: "m1", Title: "Mission St & 16th St", Lat: "37.7650", Lon: "-122.4195", StopID: "100"},
+			{Tag: "m2", Title: "Mission St & 24th St", Lat: "37.7522", Lon: "-122.4184"},
+		}}},
+		"bart": {{Tag: "RED", StopList: []Stop{
+			{Tag: "b1", Title: "16th St Mission", Lat: "37.7651", Lon: "-122.4196", StopID: "100"},
+			{Tag: "b2", Title: "Glen Park", Lat: "37.7330", Lon: "-122.4337"},
+		}}},
+	}
+
+	matched := MatchAgencyStops(agencies, 40)
+	equals(t, 1, len(matched))
+	equals(t, []string{"bart", "sf-muni"}, matched[0].AgencyTags())
+	equals(t, "b1", matched[0].Stops[0].Stop.Tag)
+	equals(t, "m1", matched[0].Stops[1].Stop.Tag)
+
+	// The same StopID in different agencies doesn't link distant stops.
+	equals(t, 0, len(MatchAgencyStops(agencies, 0)))
+}
+
+func TestGroupByRegionalStop(t *testing.T) {
+	stops := []RegionalStop{{Title: "16th St Mission", Stops: []AgencyStop{
+		{"bart", "RED", Stop{Tag: "b1"}},
+		{"sf-muni", "14", Stop{Tag: "m1"}},
+	}}}
+	grouped := GroupByRegionalStop(stops, map[string][]PredictionData{
+		"sf-muni": {{RouteTag: "14", StopTag: "m1", PredictionDirectionList: []PredictionDirection{{Title: "Outbound", PredictionList: []Prediction{{EpochTime: "2000"}}}}}},
+		"bart": {
+			{RouteTag: "RED", StopTag: "b1", PredictionDirectionList: []PredictionDirection{{Title: "Richmond", PredictionList: []Prediction{{EpochTime: "1000"}, {EpochTime: "3000"}}}}},
+			{RouteTag: "RED", StopTag: "b2"},
+		},
+	})
+	equals(t, 1, len(grouped))
+	equals(t, 3, len(grouped[0].Arrivals))
+	equals(t, "bart", grouped[0].Arrivals[0].AgencyTag)
+	equals(t, "sf-muni", grouped[0].Arrivals[1].AgencyTag)
+	equals(t, "Outbound", grouped[0].Arrivals[1].Direction)
+}
@@ -0,0 +1,278 @@
+// Package report measures on-time performance by comparing the arrivals
+// recorded by a nextbus.Archiver with the scheduled times of a
+// nextbus.Timetable.
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dinedal/nextbus"
+)
+
+// The defaults for the zero values of Options.
+const (
+	DefaultEarly      = time.Minute
+	DefaultLate       = 5 * time.Minute
+	DefaultMaxMatch   = 30 * time.Minute
+	DefaultWorstStops = 5
+)
+
+// Options control how arrivals are judged.
+type Options struct {
+	// Early and Late bound the on-time window: an arrival up to Early before
+	// or Late after its scheduled time is on time.
+	Early, Late time.Duration
+	// MaxMatch is how far an arrival may be from the nearest scheduled time
+	// and still be matched to it. Arrivals further away are left out.
+	MaxMatch time.Duration
+	// ServiceClass returns the timetable service class that runs on the day
+	// of t. It defaults to nextbus.DefaultServiceClass.
+	ServiceClass func(t time.Time) string
+	// WorstStops is how many stops each RouteReport lists.
+	WorstStops int
+	// Location is the agency's time zone, in which service days start at
+	// midnight. Nil uses the location of the report's from time, so a range
+	// given in the agency's time zone needs no Location. Arrival times are
+	// converted to it whatever zone they were recorded in.
+	Location *time.Location
+}
+
+func (o Options) withDefaults() Options {
+	if o.Early <= 0 {
+		o.Early = DefaultEarly
+	}
+	if o.Late <= 0 {
+		o.Late = DefaultLate
+	}
+	if o.MaxMatch <= 0 {
+		o.MaxMatch = DefaultMaxMatch
+	}
+	if o.ServiceClass == nil {
+		o.ServiceClass = nextbus.DefaultServiceClass
+	}
+	if o.WorstStops <= 0 {
+		o.WorstStops = DefaultWorstStops
+	}
+	return o
+}
+
+// Performance summarizes a set of matched arrivals.
+type Performance struct {
+	Arrivals int
+	Early    int
+	OnTime   int
+	Late     int
+	// AverageDelay is the mean difference between the arrivals and their
+	// scheduled times; early arrivals count against it.
+	AverageDelay time.Duration
+
+	totalDelay time.Duration
+}
+
+// OnTimePercent returns the percentage of arrivals that were on time.
+func (p Performance) OnTimePercent() float64 {
+	if p.Arrivals == 0 {
+		return 0
+	}
+	return 100 * float64(p.OnTime) / float64(p.Arrivals)
+}
+
+func (p *Performance) add(delay time.Duration, o Options) {
+	p.Arrivals++
+	switch {
+	case delay < -o.Early:
+		p.Early++
+	case delay > o.Late:
+		p.Late++
+	default:
+		p.OnTime++
+	}
+	p.totalDelay += delay
+	p.AverageDelay = p.totalDelay / time.Duration(p.Arrivals)
+}
+
+// StopReport is the performance of a route at one stop.
+type StopReport struct {
+	StopTag string
+	Performance
+}
+
+// RouteReport is the performance of one route.
+type RouteReport struct {
+	RouteTag string
+	Performance
+	// WorstStops are the stops with the greatest average delay, worst
+	// first.
+	WorstStops []StopReport
+}
+
+// Report is the on-time performance of an agency's routes over a period.
+type Report struct {
+	From, To time.Time
+	Routes   []RouteReport
+	// Unmatched counts the arrivals with no scheduled time to compare with.
+	Unmatched int
+}
+
+// Generate compares the arrivals in [from, to) with the timetables. Only
+// timepoints, the stops listed in a timetable, can be judged.
+func Generate(arrivals []nextbus.ArrivalRecord, timetables []nextbus.Timetable, from, to time.Time, opts Options) Report {
+	opts = opts.withDefaults()
+	loc := opts.Location
+	if loc == nil {
+		loc = from.Location()
+	}
+
+	type routeClass struct{ routeTag, class string }
+	byRoute := map[routeClass][]nextbus.Timetable{}
+	for _, tt := range timetables {
+		key := routeClass{tt.RouteTag, tt.ServiceClass}
+		byRoute[key] = append(byRoute[key], tt)
+	}
+
+	report := Report{From: from, To: to}
+	routes := map[string]*Performance{}
+	stops := map[string]map[string]*Performance{}
+	for _, a := range arrivals {
+		if a.Time.Before(from) || !a.Time.Before(to) {
+			continue
+		}
+		delay, matched := time.Duration(0), false
+		at := a.Time.In(loc)
+		// Trips after midnight are scheduled on the previous service day.
+		for _, day := range []time.Time{at, at.AddDate(0, 0, -1)} {
+			for _, tt := range byRoute[routeClass{a.RouteTag, opts.ServiceClass(day)}] {
+				for _, offset := range tt.Departures(a.StopTag) {
+					d := at.Sub(nextbus.StopTime{Offset: offset}.On(day))
+					if abs(d) <= opts.MaxMatch && (!matched || abs(d) < abs(delay)) {
+						delay, matched = d, true
+					}
+				}
+			}
+		}
+		if !matched {
+			report.Unmatched++
+			continue
+		}
+		if routes[a.RouteTag] == nil {
+			routes[a.RouteTag] = &Performance{}
+			stops[a.RouteTag] = map[string]*Performance{}
+		}
+		routes[a.RouteTag].add(delay, opts)
+		if stops[a.RouteTag][a.StopTag] == nil {
+			stops[a.RouteTag][a.StopTag] = &Performance{}
+		}
+		stops[a.RouteTag][a.StopTag].add(delay, opts)
+	}
+
+	for routeTag, p := range routes {
+		rr := RouteReport{RouteTag: routeTag, Performance: *p}
+		for stopTag, sp := range stops[routeTag] {
+			rr.WorstStops = append(rr.WorstStops, StopReport{StopTag: stopTag, Performance: *sp})
+		}
+		sort.Slice(rr.WorstStops, func(i, j int) bool {
+			a, b := rr.WorstStops[i], rr.WorstStops[j]
+			if a.AverageDelay != b.AverageDelay {
+				return a.AverageDelay > b.AverageDelay
+			}
+			return a.StopTag < b.StopTag
+		})
+		if len(rr.WorstStops) > opts.WorstStops {
+			rr.WorstStops = rr.WorstStops[:opts.WorstStops]
+		}
+		report.Routes = append(report.Routes, rr)
+	}
+	sort.Slice(report.Routes, func(i, j int) bool { return report.Routes[i].RouteTag < report.Routes[j].RouteTag })
+	return report
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// csvHeader is the first row written by WriteCSV.
+var csvHeader = []string{"route", "stop", "arrivals", "early", "on_time", "late", "on_time_percent", "average_delay_seconds"}
+
+// WriteCSV writes one row per route, with an empty stop, followed by a row
+// for each of its worst stops.
+func (r Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	rows := [][]string{csvHeader}
+	for _, rr := range r.Routes {
+		rows = append(rows, csvRow(rr.RouteTag, "", rr.Performance))
+		for _, sr := range rr.WorstStops {
+			rows = append(rows, csvRow(rr.RouteTag, sr.StopTag, sr.Performance))
+		}
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("could not write report CSV: %v", err)
+	}
+	return nil
+}
+
+func csvRow(routeTag, stopTag string, p Performance) []string {
+	return []string{
+		routeTag,
+		stopTag,
+		strconv.Itoa(p.Arrivals),
+		strconv.Itoa(p.Early),
+		strconv.Itoa(p.OnTime),
+		strconv.Itoa(p.Late),
+		strconv.FormatFloat(p.OnTimePercent(), 'f', 1, 64),
+		strconv.FormatFloat(p.AverageDelay.Seconds(), 'f', 0, 64),
+	}
+}
+
+// jsonPerformance is the JSON form of a Performance, with the delay in
+// seconds.
+type jsonPerformance struct {
+	Arrivals            int     `json:"arrivals"`
+	Early               int     `json:"early"`
+	OnTime              int     `json:"onTime"`
+	Late                int     `json:"late"`
+	OnTimePercent       float64 `json:"onTimePercent"`
+	AverageDelaySeconds float64 `json:"averageDelaySeconds"`
+}
+
+func toJSON(p Performance) jsonPerformance {
+	return jsonPerformance{p.Arrivals, p.Early, p.OnTime, p.Late, p.OnTimePercent(), p.AverageDelay.Seconds()}
+}
+
+// WriteJSON writes the report as a JSON document.
+func (r Report) WriteJSON(w io.Writer) error {
+	type jsonStop struct {
+		Stop string `json:"stop"`
+		jsonPerformance
+	}
+	type jsonRoute struct {
+		Route string `json:"route"`
+		jsonPerformance
+		WorstStops []jsonStop `json:"worstStops"`
+	}
+	doc := struct {
+		From      time.Time   `json:"from"`
+		To        time.Time   `json:"to"`
+		Routes    []jsonRoute `json:"routes"`
+		Unmatched int         `json:"unmatched"`
+	}{From: r.From, To: r.To, Routes: []jsonRoute{}, Unmatched: r.Unmatched}
+	for _, rr := range r.Routes {
+		jr := jsonRoute{Route: rr.RouteTag, jsonPerformance: toJSON(rr.Performance), WorstStops: []jsonStop{}}
+		for _, sr := range rr.WorstStops {
+			jr.WorstStops = append(jr.WorstStops, jsonStop{sr.StopTag, toJSON(sr.Performance)})
+		}
+		doc.Routes = append(doc.Routes, jr)
+	}
+	if err := json.NewEncoder(w).Encode(doc); err != nil {
+		return fmt.Errorf("could not write report JSON: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dinedal/nextbus"
+)
+
+// thursday is a weekday, so the "wkd" timetable applies.
+var thursday = time.Date(2017, 2, 16, 0, 0, 0, 0, time.UTC)
+
+func testTimetable() nextbus.Timetable {
+	return nextbus.NewTimetable(nextbus.Schedule{
+		Tag:          "N",
+		ServiceClass: "wkd",
+		Header:       nextbus.ScheduleHeader{StopList: []nextbus.ScheduleHeaderStop{{Tag: "a"}, {Tag: "b"}}},
+		BlockList: []nextbus.ScheduleBlock{
+			{BlockID: "1", StopList: []nextbus.ScheduleStop{{Tag: "a", EpochTime: "25200000"}, {Tag: "b", EpochTime: "25800000"}}},
+			{BlockID: "2", StopList: []nextbus.ScheduleStop{{Tag: "a", EpochTime: "27000000"}, {Tag: "b", EpochTime: "27600000"}}},
+		},
+	})
+}
+
+func arrival(stopTag string, at time.Duration) nextbus.ArrivalRecord {
+	return nextbus.ArrivalRecord{RouteTag: "N", StopTag: stopTag, Time: thursday.Add(at)}
+}
+
+func testReport() Report {
+	arrivals := []nextbus.ArrivalRecord{
+		arrival("a", 7*time.Hour+30*time.Second), // on time
+		arrival("b", 7*time.Hour+20*time.Minute), // 10 minutes late
+		arrival("a", 7*time.Hour+28*time.Minute), // 2 minutes early
+		arrival("b", 7*time.Hour+42*time.Minute), // 2 minutes late
+		arrival("a", 12*time.Hour),               // nothing scheduled
+		arrival("a", 7*time.Hour+24*time.Hour),   // outside the range
+	}
+	return Generate(arrivals, []nextbus.Timetable{testTimetable()}, thursday, thursday.Add(24*time.Hour), Options{})
+}
+
+func TestGenerate(t *testing.T) {
+	r := testReport()
+	if r.Unmatched != 1 {
+		t.Errorf("unmatched: got %d", r.Unmatched)
+	}
+	if len(r.Routes) != 1 {
+		t.Fatalf("routes: got %d", len(r.Routes))
+	}
+	n := r.Routes[0]
+	if n.Arrivals != 4 || n.OnTime != 2 || n.Early != 1 || n.Late != 1 {
+		t.Errorf("route N: got %+v", n.Performance)
+	}
+	if n.OnTimePercent() != 50 {
+		t.Errorf("on time percent: got %v", n.OnTimePercent())
+	}
+	if want := 30*time.Second/4 + 10*time.Minute/4; n.AverageDelay != want {
+		t.Errorf("average delay: got %v, want %v", n.AverageDelay, want)
+	}
+	if len(n.WorstStops) != 2 || n.WorstStops[0].StopTag != "b" || n.WorstStops[0].AverageDelay != 6*time.Minute {
+		t.Errorf("worst stops: got %+v", n.WorstStops)
+	}
+}
+
+func TestGenerateAgencyTimeZone(t *testing.T) {
+	// The agency is in San Francisco, but its arrivals were archived on a
+	// host whose local time zone is UTC.
+	pacific := time.FixedZone("PST", -8*60*60)
+	day := time.Date(2017, 2, 16, 0, 0, 0, 0, pacific)
+	arrivals := []nextbus.ArrivalRecord{
+		{RouteTag: "N", StopTag: "a", Time: day.Add(7*time.Hour + 30*time.Second).UTC()},
+		{RouteTag: "N", StopTag: "b", Time: day.Add(7*time.Hour + 12*time.Minute).UTC()},
+	}
+	timetables := []nextbus.Timetable{testTimetable()}
+	for _, tc := range []struct {
+		name     string
+		from     time.Time
+		location *time.Location
+	}{
+		{"from in the agency's zone", day, nil},
+		{"explicit location", day.UTC(), pacific},
+	} {
+		r := Generate(arrivals, timetables, tc.from, tc.from.Add(24*time.Hour), Options{Location: tc.location})
+		if r.Unmatched != 0 || len(r.Routes) != 1 {
+			t.Fatalf("%s: got %d unmatched, %d routes", tc.name, r.Unmatched, len(r.Routes))
+		}
+		if n := r.Routes[0]; n.OnTime != 2 || n.AverageDelay != (30*time.Second+2*time.Minute)/2 {
+			t.Errorf("%s: got %+v", tc.name, n.Performance)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	if lines[1] != "N,,4,1,2,1,50.0,158" {
+		t.Errorf("route row: got %q", lines[1])
+	}
+	if lines[2] != "N,b,2,0,1,1,50.0,360" {
+		t.Errorf("stop row: got %q", lines[2])
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Routes []struct {
+			Route               string  `json:"route"`
+			OnTimePercent       float64 `json:"onTimePercent"`
+			AverageDelaySeconds float64 `json:"averageDelaySeconds"`
+			WorstStops          []struct {
+				Stop string `json:"stop"`
+			} `json:"worstStops"`
+		} `json:"routes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Routes) != 1 || doc.Routes[0].Route != "N" || doc.Routes[0].OnTimePercent != 50 || doc.Routes[0].WorstStops[1].Stop != "a" {
+		t.Errorf("unexpected JSON %s", buf.String())
+	}
+}
@@ -0,0 +1,293 @@
+package nextbus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// feedURL is the endpoint of the NextBus public XML feed used when
+// WithFeedURL isn't.
+const feedURL = "http://webservices.nextbus.com/service/publicXMLFeed"
+
+// commandDescriptions name the data returned by each feed command, for use in
+// error messages.
+var commandDescriptions = map[string]string{
+	"agencyList":               "agencies",
+	"routeList":                "routes",
+	"routeConfig":              "route config",
+	"predictions":              "predictions",
+	"predictionsForMultiStops": "predictions for multiple stops",
+	"vehicleLocations":         "vehicle locations",
+	"schedule":                 "schedule",
+}
+
+func describe(command string) string {
+	if what, found := commandDescriptions[command]; found {
+		return what
+	}
+	return command
+}
+
+// requestURL builds the URL for a command at the feed endpoint. Each param is
+// an already escaped query fragment such as "r=N".
+func requestURL(endpoint, command string, params []string) string {
+	return endpoint + "?" + strings.Join(append([]string{"command=" + url.QueryEscape(command)}, params...), "&")
+}
+
+// requestURL returns the URL for a command at the Client's feed.
+func (c *Client) requestURL(command string, params []string) string {
+	if c.feedURL == "" {
+		return requestURL(feedURL, command, params)
+	}
+	return requestURL(c.feedURL, command, params)
+}
+
+// maxThrottleBody is how much of a 403 or 429 response is read to look for a
+// throttling message.
+const maxThrottleBody = 4096
+
+// open issues a request for a feed command and returns the response body,
+// which the caller must close. It waits for the rate limiter and any throttle
+// NextBus has imposed but makes only a single attempt.
+func (c *Client) open(ctx context.Context, command string, params []string) (io.ReadCloser, error) {
+	if err := c.throttle.wait(ctx); err != nil {
+		return nil, fmt.Errorf("could not fetch %s from nextbus: %v", describe(command), err)
+	}
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, fmt.Errorf("could not fetch %s from nextbus: %v", describe(command), err)
+		}
+	}
+	meta := responseMetaFrom(ctx)
+	u := c.requestURL(command, params)
+	req, reqErr := http.NewRequest(http.MethodGet, u, nil)
+	if reqErr != nil {
+		return nil, fmt.Errorf("could not fetch %s from nextbus: %v", describe(command), reqErr)
+	}
+	for name, values := range c.headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.contact != "" {
+		req.Header.Set("From", c.contact)
+	}
+	resp, httpErr := c.httpClient.Do(req.WithContext(ctx))
+	if httpErr != nil {
+		meta.requested(u, 0)
+		return nil, &transientError{fmt.Errorf("could not fetch %s from nextbus: %v", describe(command), httpErr)}
+	}
+	meta.requested(u, resp.StatusCode)
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxThrottleBody))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusTooManyRequests || throttleText.Match(body) {
+			return nil, c.rateLimited(rateLimitError(string(body), resp.Header.Get("Retry-After")))
+		}
+		return nil, fmt.Errorf("could not fetch %s from nextbus: unexpected status %d", describe(command), resp.StatusCode)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		err := fmt.Errorf("could not fetch %s from nextbus: unexpected status %d", describe(command), resp.StatusCode)
+		if resp.StatusCode >= 500 {
+			return nil, &transientError{err}
+		}
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// transientError marks a failure that may not happen again if the request is
+// retried.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+
+// rateLimited holds back the Client's requests as e asks and returns it as a
+// transientError, so that a retry waits out the throttle.
+func (c *Client) rateLimited(e *RateLimitError) error {
+	c.throttle.hold(e.RetryAfter)
+	return &transientError{e}
+}
+
+// maxPooledBuffer is the capacity above which response buffers are dropped
+// rather than returned to bufferPool, so that one huge routeConfig doesn't pin
+// memory for the life of the process.
+const maxPooledBuffer = 8 << 20
+
+// bufferPool holds buffers for reading response bodies.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// attempt makes a single request for a feed command and reads the response
+// into buf.
+func (c *Client) attempt(ctx context.Context, command string, params []string, buf *bytes.Buffer) error {
+	body, openErr := c.open(ctx, command, params)
+	if openErr != nil {
+		return openErr
+	}
+	defer body.Close()
+
+	buf.Reset()
+	if _, readErr := buf.ReadFrom(body); readErr != nil {
+		return &transientError{fmt.Errorf("could not parse %s response body: %v", describe(command), readErr)}
+	}
+	if converted, charsetErr := c.toUTF8(command, buf.Bytes()); charsetErr != nil {
+		return charsetErr
+	} else if converted != nil {
+		buf.Reset()
+		buf.Write(converted)
+	}
+	if feedErr, isFeedErr := checkFeedError(buf.Bytes()).(*FeedError); isFeedErr {
+		if throttleText.MatchString(feedErr.Message) {
+			return c.rateLimited(rateLimitError(feedErr.Message, ""))
+		}
+		if feedErr.Retryable() {
+			return &transientError{feedErr}
+		}
+	}
+	return nil
+}
+
+// fetch issues a request for a feed command, applying the Client's cache, rate
+// limit and retry options, and calls use with the response. The data passed to
+// use is only valid until it returns.
+func (c *Client) fetch(ctx context.Context, command string, params []string, use func(data []byte) error) error {
+	if c.cache != nil {
+		if data, fresh := c.cache.get(c.now(), command, c.cacheKey(command, params)); fresh {
+			if c.observe != nil {
+				c.observe(RequestInfo{Command: command, Params: append([]string(nil), params...), Bytes: len(data), Outcome: OutcomeCached})
+			}
+			responseMetaFrom(ctx).received(c.requestURL(command, params), data, true)
+			return use(data)
+		}
+	}
+
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		buf.Reset()
+		start := time.Now()
+		err := c.attempt(ctx, command, params, buf)
+		c.observed(command, params, attempt+1, start, buf.Bytes(), err)
+		transient, isTransient := err.(*transientError)
+		if !isTransient {
+			if err != nil {
+				return err
+			}
+			responseMetaFrom(ctx).received(c.requestURL(command, params), buf.Bytes(), false)
+			if useErr := use(buf.Bytes()); useErr != nil || c.cache == nil {
+				return useErr
+			}
+			if checkFeedError(buf.Bytes()) == nil {
+				c.cache.put(c.now(), command, c.cacheKey(command, params), buf.Bytes())
+			}
+			return nil
+		}
+		if attempt >= c.retries || ctx.Err() != nil {
+			if _, isFeedErr := transient.err.(*FeedError); isFeedErr {
+				// The body holds a retryable Error; let the caller decode it.
+				responseMetaFrom(ctx).received(c.requestURL(command, params), buf.Bytes(), false)
+				return use(buf.Bytes())
+			}
+			return transient.err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("could not fetch %s from nextbus: %v", describe(command), ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// Do issues a request for any feed command, including ones this package
+// doesn't wrap, and returns the raw XML response. Each param is an already
+// escaped query fragment such as "r=N", like those produced by the various
+// Param types. The Client's rate limit and retry options are applied.
+func (c *Client) Do(ctx context.Context, command string, params ...string) ([]byte, error) {
+	var result []byte
+	err := c.fetch(ctx, command, params, func(data []byte) error {
+		result = append([]byte(nil), data...)
+		return nil
+	})
+	return result, err
+}
+
+// errorBody is used to find an Error element in any response.
+type errorBody struct {
+	Error *FeedError `xml:"Error"`
+}
+
+// checkFeedError returns the error NextBus reported in data, if any.
+func checkFeedError(data []byte) error {
+	if !bytes.Contains(data, []byte("<Error")) {
+		return nil
+	}
+	var e errorBody
+	if newXMLDecoder(bytes.NewReader(data)).Decode(&e) == nil && e.Error != nil {
+		return e.Error
+	}
+	return nil
+}
+
+// decode unmarshals a response into out. An Error reported by NextBus is
+// returned as a *FeedError.
+func decode(command string, data []byte, out interface{}) error {
+	if xmlErr := newXMLDecoder(bytes.NewReader(data)).Decode(out); xmlErr != nil {
+		return fmt.Errorf("could not parse %s XML: %v", describe(command), xmlErr)
+	}
+	return checkFeedError(data)
+}
+
+// fetchAndDecode issues a request for a feed command and decodes the response
+// into a T.
+func fetchAndDecode[T any](ctx context.Context, c *Client, command string, params []string) (T, error) {
+	var result T
+	err := c.fetch(ctx, command, params, func(data []byte) error {
+		return decode(command, data, &result)
+	})
+	return result, err
+}
+
+// Command issues a request for any feed command and unmarshals the XML
+// response into out, which should be a pointer to a struct whose XMLName is
+// "body". This is useful for commands this package doesn't wrap yet. An Error
+// reported by NextBus is returned as a *FeedError.
+func (c *Client) Command(ctx context.Context, name string, params url.Values, out interface{}) error {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var fragments []string
+	for _, k := range keys {
+		for _, v := range params[k] {
+			fragments = append(fragments, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+
+	return c.fetch(ctx, name, fragments, func(data []byte) error {
+		return decode(name, data, out)
+	})
+}
@@ -0,0 +1,185 @@
+package nextbus
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	data, err := nb.Do(context.Background(), "routeList", "a=alpha")
+	ok(t, err)
+	assert(t, strings.Contains(string(data), `<route tag="2" title="2-second"/>`), "unexpected response %q", data)
+}
+
+func TestFetchAndDecodeFeedError(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	_, err := fetchAndDecode[RouteConfigResponse](context.Background(), nb, "routeConfig", []string{"a=alpha", "r=9"})
+	_, isFeedErr := err.(*FeedError)
+	assert(t, isFeedErr, "expected a *FeedError, got %v", err)
+}
+
+func TestCommand(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	var out struct {
+		XMLName xml.Name `xml:"body"`
+		Routes  []struct {
+			Tag string `xml:"tag,attr"`
+		} `xml:"route"`
+	}
+	ok(t, nb.Command(context.Background(), "routeList", url.Values{"a": {"alpha"}}, &out))
+	equals(t, 2, len(out.Routes))
+	equals(t, "2", out.Routes[1].Tag)
+}
+
+func TestDoRetries(t *testing.T) {
+	responses := []string{
+		"",
+		`<body><Error shouldRetry="true">Agency server cannot accept client while status is: Connecting</Error></body>`,
+		`<body><route tag="1" title="1-first"/></body>`,
+	}
+	var attempts int
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := responses[attempts]
+		attempts++
+		if body == "" {
+			return statusResponse(req, http.StatusBadGateway), nil
+		}
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(body))
+		return res, nil
+	})}
+
+	nb := NewClient(httpClient, WithRetries(2, 0))
+	routes, err := nb.GetRouteList("alpha")
+	ok(t, err)
+	equals(t, 3, attempts)
+	equals(t, "1-first", routes[0].Title)
+}
+
+func TestDoRetriesExhausted(t *testing.T) {
+	var attempts int
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(`<body><Error shouldRetry="true">try later</Error></body>`))
+		return res, nil
+	})}
+
+	nb := NewClient(httpClient, WithRetries(1, 0))
+	_, err := nb.GetRouteList("alpha")
+	equals(t, 2, attempts)
+	equals(t, "nextbus: try later", err.Error())
+}
+
+func TestDoDoesNotRetryClientErrors(t *testing.T) {
+	var attempts int
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return statusResponse(req, http.StatusNotFound), nil
+	})}
+
+	nb := NewClient(httpClient, WithRetries(3, 0))
+	_, err := nb.GetAgencyList()
+	assert(t, err != nil, "expected an error for a 404 response")
+	equals(t, 1, attempts)
+}
+
+func TestRateLimit(t *testing.T) {
+	nb := NewClient(testingClient(t), WithRateLimit(20*time.Millisecond))
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := nb.GetAgencyList()
+		ok(t, err)
+	}
+	elapsed := time.Since(start)
+	assert(t, elapsed >= 40*time.Millisecond, "expected requests to be spaced out, took %v", elapsed)
+}
+
+func TestUserAgent(t *testing.T) {
+	var headers []http.Header
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		headers = append(headers, req.Header)
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(`<body></body>`))
+		return res, nil
+	})}
+
+	_, err := NewClient(httpClient).GetAgencyList()
+	ok(t, err)
+	_, err = NewClient(httpClient, WithUserAgent("departures/2.0"), WithContact("ops@example.com")).GetAgencyList()
+	ok(t, err)
+
+	equals(t, "nextbus-go/"+Version, headers[0].Get("User-Agent"))
+	equals(t, "", headers[0].Get("From"))
+	equals(t, "departures/2.0", headers[1].Get("User-Agent"))
+	equals(t, "ops@example.com", headers[1].Get("From"))
+}
+
+func TestRateLimitedFeedError(t *testing.T) {
+	var attempts int
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(`<body><Error shouldRetry="true">Rate limit exceeded. Please wait 20 seconds.</Error></body>`))
+		return res, nil
+	})}
+
+	nb := NewClient(httpClient)
+	_, err := nb.GetRouteList("alpha")
+	rateErr, isRateErr := err.(*RateLimitError)
+	assert(t, isRateErr, "expected a *RateLimitError, got %v", err)
+	equals(t, 20*time.Second, rateErr.RetryAfter)
+	assert(t, errors.Is(err, ErrRateLimited), "expected the error to match ErrRateLimited")
+	equals(t, 1, attempts)
+
+	// Every later request waits for the throttle to pass.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = nb.Do(ctx, "routeList", "a=alpha")
+	assert(t, err != nil, "expected the request to wait out the throttle")
+	equals(t, 1, attempts)
+}
+
+func TestRateLimitedStatus(t *testing.T) {
+	statuses := []int{http.StatusTooManyRequests, http.StatusOK}
+	var attempts int
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		res := statusResponse(req, statuses[attempts])
+		attempts++
+		if res.StatusCode == http.StatusTooManyRequests {
+			res.Header = http.Header{"Retry-After": {"0"}}
+		} else {
+			res.Body = ioutil.NopCloser(strings.NewReader(`<body><route tag="1" title="1-first"/></body>`))
+		}
+		return res, nil
+	})}
+
+	nb := NewClient(httpClient, WithRetries(1, 0))
+	routes, err := nb.GetRouteList("alpha")
+	ok(t, err)
+	equals(t, 2, attempts)
+	equals(t, "1-first", routes[0].Title)
+}
+
+func TestForbiddenWithoutThrottleText(t *testing.T) {
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return statusResponse(req, http.StatusForbidden), nil
+	})}
+
+	_, err := NewClient(httpClient).GetRouteList("alpha")
+	assert(t, err != nil && !errors.Is(err, ErrRateLimited), "expected a plain error, got %v", err)
+}
+
+func TestRateLimitErrorRetryAfter(t *testing.T) {
+	equals(t, defaultRetryAfter, rateLimitError("You have been blocked", "").RetryAfter)
+	equals(t, 2*time.Minute, rateLimitError("Bandwidth exceeded, wait 2 minutes", "").RetryAfter)
+	equals(t, 5*time.Second, rateLimitError("wait 2 minutes", "5").RetryAfter)
+}
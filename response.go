@@ -0,0 +1,118 @@
+package nextbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"sync"
+	"time"
+)
+
+// Response is a decoded feed response with metadata about how it was
+// obtained, for proxies and debugging tools that need more than the payload.
+type Response[T any] struct {
+	Payload T
+	// Copyright is the copyright attribute of the response's body element.
+	Copyright string
+	// Status is the HTTP status of the last attempt, or zero if no request
+	// was made.
+	Status int
+	// URL is the feed URL requested.
+	URL string
+	// Attempts is how many requests were made, including retries.
+	Attempts int
+	// Duration is how long the call took, including retries and waits for
+	// the rate limiter.
+	Duration time.Duration
+	// FromCache is set when the response was answered from the DiskCache.
+	FromCache bool
+}
+
+// responseMeta collects the metadata of the requests made during a call to
+// WithResponse.
+type responseMeta struct {
+	mu        sync.Mutex
+	url       string
+	status    int
+	attempts  int
+	copyright string
+	fromCache bool
+}
+
+type responseMetaKey struct{}
+
+func responseMetaFrom(ctx context.Context) *responseMeta {
+	meta, _ := ctx.Value(responseMetaKey{}).(*responseMeta)
+	return meta
+}
+
+// WithResponse calls call, typically one of the Client's Context methods,
+// and returns its result along with the metadata of the request it made. If
+// call makes several requests, as GetRouteConfig does with
+// WithRouteConfigFallback, Attempts counts every request made and the other
+// metadata is that of the last one to finish. The metadata is returned even
+// when call fails.
+//
+//	resp, err := nextbus.WithResponse(ctx, func(ctx context.Context) ([]nextbus.Route, error) {
+//		return nb.GetRouteListContext(ctx, "sf-muni")
+//	})
+func WithResponse[T any](ctx context.Context, call func(ctx context.Context) (T, error)) (Response[T], error) {
+	meta := &responseMeta{}
+	start := time.Now()
+	payload, err := call(context.WithValue(ctx, responseMetaKey{}, meta))
+	meta.mu.Lock()
+	defer meta.mu.Unlock()
+	return Response[T]{
+		Payload:   payload,
+		Copyright: meta.copyright,
+		Status:    meta.status,
+		URL:       meta.url,
+		Attempts:  meta.attempts,
+		Duration:  time.Since(start),
+		FromCache: meta.fromCache,
+	}, err
+}
+
+// requested records an attempt at url that got status, which is zero if no
+// response was received.
+func (m *responseMeta) requested(url string, status int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.url = url
+	m.status = status
+	m.attempts++
+	m.mu.Unlock()
+}
+
+// received records the response data answered for url.
+func (m *responseMeta) received(url string, data []byte, fromCache bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.url = url
+	m.copyright = bodyCopyright(data)
+	m.fromCache = fromCache
+	m.mu.Unlock()
+}
+
+// bodyCopyright returns the copyright attribute of data's body element.
+func bodyCopyright(data []byte) string {
+	d := newXMLDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.RawToken()
+		if err != nil {
+			return ""
+		}
+		if start, isStart := tok.(xml.StartElement); isStart {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "copyright" {
+					return attr.Value
+				}
+			}
+			return ""
+		}
+	}
+}
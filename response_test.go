@@ -0,0 +1,49 @@
+package nextbus
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithResponse(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	resp, err := WithResponse(context.Background(), func(ctx context.Context) ([]Route, error) {
+		return nb.GetRouteListContext(ctx, "alpha")
+	})
+	ok(t, err)
+	equals(t, 2, len(resp.Payload))
+	equals(t, "All data copyright some transit company.", resp.Copyright)
+	equals(t, http.StatusOK, resp.Status)
+	equals(t, makeURL("routeList", "a", "alpha"), resp.URL)
+	equals(t, 1, resp.Attempts)
+	assert(t, !resp.FromCache, "did not expect a cached response")
+}
+
+func TestWithResponseCachedAndRetried(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+	ok(t, err)
+	var attempts int
+	failing := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			return statusResponse(req, http.StatusServiceUnavailable), nil
+		}
+		return cannedFeed(`<body copyright="c"><route tag="1" title="1-first"/></body>`).RoundTrip(req)
+	})}
+	nb := NewClient(failing, WithRetries(1, time.Millisecond), WithDiskCache(cache))
+	routeList := func(ctx context.Context) ([]Route, error) { return nb.GetRouteListContext(ctx, "alpha") }
+
+	resp, err := WithResponse(context.Background(), routeList)
+	ok(t, err)
+	equals(t, 2, resp.Attempts)
+	equals(t, http.StatusOK, resp.Status)
+
+	resp, err = WithResponse(context.Background(), routeList)
+	ok(t, err)
+	assert(t, resp.FromCache, "expected a cached response")
+	equals(t, 0, resp.Attempts)
+	equals(t, "c", resp.Copyright)
+	equals(t, "1-first", resp.Payload[0].Title)
+}
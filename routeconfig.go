@@ -0,0 +1,119 @@
+package nextbus
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// oversizedText matches the Error NextBus returns when a routeConfig request
+// for every route of an agency would exceed its size cap.
+var oversizedText = regexp.MustCompile(`(?i)too (much|large|many)|maximum|exceed`)
+
+// WithRouteConfigFallback makes GetRouteConfig fall back to fetching each
+// route's config separately, up to concurrency at a time, when NextBus
+// refuses to return every route of an agency at once. Requests still go
+// through the Client's rate limit.
+func WithRouteConfigFallback(concurrency int) Option {
+	return func(c *Client) {
+		if concurrency < 1 {
+			concurrency = 1
+		}
+		c.routeConfigFallback = concurrency
+	}
+}
+
+// isOversized reports whether err is NextBus refusing a request because the
+// response would be too large.
+func isOversized(err error) bool {
+	feedErr, isFeedErr := err.(*FeedError)
+	return isFeedErr && !feedErr.Retryable() && oversizedText.MatchString(feedErr.Message)
+}
+
+// GetRouteConfigPerRoute fetches the config of every route of an agency with
+// one request per route, up to concurrency at a time, and returns them in the
+// order of the agency's route list. configParams apply to every request and
+// should not include a RouteConfigTag.
+func (c *Client) GetRouteConfigPerRoute(agencyTag string, concurrency int, configParams ...RouteConfigParam) ([]RouteConfig, error) {
+	return c.GetRouteConfigPerRouteContext(context.Background(), agencyTag, concurrency, configParams...)
+}
+
+// GetRouteConfigPerRouteContext is like GetRouteConfigPerRoute but makes its
+// requests with ctx, stopping at the first error or when ctx is done.
+func (c *Client) GetRouteConfigPerRouteContext(ctx context.Context, agencyTag string, concurrency int, configParams ...RouteConfigParam) ([]RouteConfig, error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	extra := toParams(configParams)
+	if _, paramErr := buildQuery("routeConfig", agencyTag, extra); paramErr != nil {
+		return nil, paramErr
+	}
+	routes, listErr := c.GetRouteListContext(ctx, agencyTag)
+	if listErr != nil {
+		return nil, listErr
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	result := make([]RouteConfig, len(routes))
+	found := make([]bool, len(routes))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i, route := range routes {
+		// The parameters were validated above.
+		params, _ := buildQuery("routeConfig", agencyTag, append([]Param{RouteParam(route.Tag)}, extra...))
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, params []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			resp, err := fetchAndDecode[RouteConfigResponse](ctx, c, "routeConfig", params)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			if len(resp.RouteList) != 0 {
+				result[i] = resp.RouteList[0]
+				found[i] = true
+			}
+		}(i, params)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("could not fetch route config from nextbus: %v", err)
+	}
+
+	configs := result[:0]
+	for i, rc := range result {
+		if found[i] {
+			configs = append(configs, rc)
+		}
+	}
+	return configs, nil
+}
+
+// hasRouteTag reports whether params restrict a routeConfig request to one
+// route.
+func hasRouteTag(params []RouteConfigParam) bool {
+	for _, p := range params {
+		if p.Param().Key == "r" {
+			return true
+		}
+	}
+	return false
+}
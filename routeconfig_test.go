@@ -0,0 +1,92 @@
+package nextbus
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// oversizedAgencyFeed answers like an agency with too many routes for one
+// routeConfig request.
+func oversizedAgencyFeed() *fakeFeed {
+	return &fakeFeed{Respond: func(req *http.Request) string {
+		q := req.URL.Query()
+		switch {
+		case q.Get("command") == "agencyList":
+			return `<body><agency tag="alpha" title="Alpha"/></body>`
+		case q.Get("command") == "routeList":
+			return `<body><route tag="1" title="1-first"/><route tag="2" title="2-second"/></body>`
+		case q.Get("r") == "":
+			return `<body><Error shouldRetry="false">Command would return more routes than the maximum: 100. Try specifying batches of routes from "routeList".</Error></body>`
+		default:
+			return `<body><route tag="` + q.Get("r") + `" title="route ` + q.Get("r") + `"/></body>`
+		}
+	}}
+}
+
+// commands lists the command and route of each request feed answered.
+func commands(feed *fakeFeed) []string {
+	var got []string
+	for _, req := range feed.Requests() {
+		q := req.URL.Query()
+		got = append(got, q.Get("command")+" "+q.Get("r"))
+	}
+	return got
+}
+
+func TestRouteConfigFallback(t *testing.T) {
+	feed := oversizedAgencyFeed()
+	nb := NewClient(feed.Client(), WithRouteConfigFallback(2))
+	configs, err := nb.GetRouteConfig("alpha")
+	ok(t, err)
+	equals(t, 2, len(configs))
+	equals(t, "1", configs[0].Tag)
+	equals(t, "route 2", configs[1].Title)
+	equals(t, 4, feed.Count())
+}
+
+func TestRouteConfigWithoutFallback(t *testing.T) {
+	feed := oversizedAgencyFeed()
+	nb := NewClient(feed.Client())
+	_, err := nb.GetRouteConfig("alpha")
+	_, isFeedErr := err.(*FeedError)
+	assert(t, isFeedErr, "expected a *FeedError, got %v", err)
+	equals(t, []string{"routeConfig "}, commands(feed))
+}
+
+func TestRouteConfigFallbackContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	feed := oversizedAgencyFeed()
+	oversized := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// Like a real transport, fail the requests made once the caller gave
+		// up, which it does as soon as NextBus refuses the whole agency.
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		defer cancel()
+		return feed.RoundTrip(req)
+	})}
+	nb := NewClient(oversized, WithRouteConfigFallback(2))
+	_, err := nb.GetRouteConfigContext(ctx, "alpha")
+	assert(t, err != nil, "expected the canceled fallback to fail")
+	equals(t, []string{"routeConfig "}, commands(feed))
+}
+
+func TestRouteConfigFallbackResponse(t *testing.T) {
+	nb := NewClient(oversizedAgencyFeed().Client(), WithRouteConfigFallback(1))
+	resp, err := WithResponse(context.Background(), func(ctx context.Context) ([]RouteConfig, error) {
+		return nb.GetRouteConfigContext(ctx, "alpha")
+	})
+	ok(t, err)
+	equals(t, 2, len(resp.Payload))
+	equals(t, 4, resp.Attempts)
+	equals(t, makeURL("routeConfig", "a", "alpha", "r", "2"), resp.URL)
+}
+
+func TestRouteConfigFallbackSnapshot(t *testing.T) {
+	snapshot, err := NewClient(oversizedAgencyFeed().Client(), WithRouteConfigFallback(2)).GetAgencySnapshot("alpha")
+	ok(t, err)
+	equals(t, 2, len(snapshot.Routes))
+	equals(t, "route 2", snapshot.Routes[1].Title)
+}
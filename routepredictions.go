@@ -0,0 +1,52 @@
+package nextbus
+
+// maxStopsPerRequest is the most stops NextBus accepts in a single
+// predictionsForMultiStops request.
+const maxStopsPerRequest = 150
+
+// GetRoutePredictions fetches predictions for every stop on a route. The
+// route's stops are looked up with a routeConfig request and then fetched in
+// as few predictionsForMultiStops requests as possible. The result maps each
+// stop tag to its predictions.
+func (c *Client) GetRoutePredictions(agencyTag, routeTag string, params ...PredReqParam) (map[string]PredictionData, error) {
+	configs, configErr := c.getSingleRouteConfig(agencyTag, routeTag)
+	if configErr != nil {
+		return nil, configErr
+	}
+	if err := validateRoutes(configs, agencyTag, routeTag, ""); err != nil {
+		return nil, err
+	}
+
+	var stopTags []string
+	for _, rc := range configs {
+		if rc.Tag != routeTag {
+			continue
+		}
+		for _, stop := range rc.StopList {
+			stopTags = append(stopTags, stop.Tag)
+		}
+	}
+
+	result := make(map[string]PredictionData, len(stopTags))
+	for len(stopTags) != 0 {
+		n := len(stopTags)
+		if n > maxStopsPerRequest {
+			n = maxStopsPerRequest
+		}
+		reqParams := make([]PredReqParam, 0, n+len(params))
+		for _, stopTag := range stopTags[:n] {
+			reqParams = append(reqParams, PredReqStop(routeTag, stopTag))
+		}
+		reqParams = append(reqParams, params...)
+
+		predictions, err := c.GetPredictionsForMultiStops(agencyTag, reqParams...)
+		if err != nil {
+			return nil, err
+		}
+		for _, pd := range predictions {
+			result[pd.StopTag] = pd
+		}
+		stopTags = stopTags[n:]
+	}
+	return result, nil
+}
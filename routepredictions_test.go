@@ -0,0 +1,22 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestGetRoutePredictions(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	found, err := nb.GetRoutePredictions("alpha", "1")
+	ok(t, err)
+
+	equals(t, 2, len(found))
+	equals(t, "First stop", found["1123"].StopTitle)
+	equals(t, "1111", found["1123"].PredictionDirectionList[0].PredictionList[0].Vehicle)
+	equals(t, 0, len(found["1234"].PredictionDirectionList))
+}
+
+func TestGetRoutePredictionsUnknownRoute(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	_, err := nb.GetRoutePredictions("alpha", "9")
+	equals(t, &NotFoundError{Kind: "route", AgencyTag: "alpha", RouteTag: "9"}, err)
+}
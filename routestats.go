@@ -0,0 +1,141 @@
+package nextbus
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TerminalStats summarizes the predictions at the first stop of one of a
+// route's directions.
+type TerminalStats struct {
+	DirTag    string
+	DirTitle  string
+	StopTag   string
+	StopTitle string
+	// Predictions is the number of upcoming departures predicted.
+	Predictions int
+	// Next is the time until the first of them.
+	Next time.Duration
+	// MinGap and MaxGap are the shortest and longest intervals between
+	// successive predictions, or zero with fewer than two.
+	MinGap time.Duration
+	MaxGap time.Duration
+}
+
+// RouteStats is an overview of a route's current service, for status pages.
+type RouteStats struct {
+	AgencyTag string
+	RouteTag  string
+	FetchedAt time.Time
+	// ActiveVehicles is the number of vehicles reporting on the route.
+	ActiveVehicles int
+	// AverageReportAge is the mean time since the vehicles last reported.
+	AverageReportAge time.Duration
+	// Terminals has an entry for each direction shown in UIs.
+	Terminals []TerminalStats
+	// Messages are the distinct messages shown at the terminals.
+	Messages []Message
+}
+
+// RouteStats computes a RouteStats from a route's config, its vehicle
+// locations and the predictions at its terminals, making three requests.
+func (c *Client) RouteStats(ctx context.Context, agencyTag, routeTag string) (*RouteStats, error) {
+	configs, err := c.GetRouteConfigContext(ctx, agencyTag, RouteConfigTag(routeTag), RouteConfigTerse())
+	if err != nil {
+		return nil, err
+	}
+	if len(configs) == 0 {
+		return nil, &NotFoundError{Kind: "route", AgencyTag: agencyTag, RouteTag: routeTag}
+	}
+	rc := configs[0]
+
+	stats := &RouteStats{AgencyTag: agencyTag, RouteTag: routeTag}
+	vehicles, err := c.GetVehicleLocationsContext(ctx, agencyTag, VehicleLocationRoute(routeTag))
+	if err != nil {
+		return nil, err
+	}
+	var totalAge time.Duration
+	for _, v := range vehicles.VehicleList {
+		stats.ActiveVehicles++
+		if secs, err := strconv.Atoi(v.SecsSinceReport); err == nil {
+			totalAge += time.Duration(secs) * time.Second
+		}
+	}
+	if stats.ActiveVehicles != 0 {
+		stats.AverageReportAge = totalAge / time.Duration(stats.ActiveVehicles)
+	}
+
+	titles := map[string]string{}
+	for _, stop := range rc.StopList {
+		titles[stop.Tag] = stop.Title
+	}
+	var params []PredReqParam
+	for _, dir := range rc.DirList {
+		if dir.UseForUI == "false" || len(dir.StopMarkerList) == 0 {
+			continue
+		}
+		stopTag := dir.StopMarkerList[0].Tag
+		stats.Terminals = append(stats.Terminals, TerminalStats{DirTag: dir.Tag, DirTitle: dir.Title, StopTag: stopTag, StopTitle: titles[stopTag]})
+		params = append(params, PredReqStop(routeTag, stopTag))
+	}
+	if len(params) == 0 {
+		stats.FetchedAt = c.now()
+		return stats, nil
+	}
+	predictions, err := c.GetPredictionsForMultiStopsContext(ctx, agencyTag, params...)
+	if err != nil {
+		return nil, err
+	}
+	stats.FetchedAt = c.now()
+
+	seenMessages := map[string]bool{}
+	for i := range stats.Terminals {
+		ts := &stats.Terminals[i]
+		var arrivals []time.Time
+		for _, pd := range predictions {
+			if pd.StopTag != ts.StopTag {
+				continue
+			}
+			for _, dir := range pd.PredictionDirectionList {
+				for _, p := range dir.PredictionList {
+					if p.DirTag != "" && p.DirTag != ts.DirTag {
+						continue
+					}
+					if arrival := p.ArrivalTime(); !arrival.IsZero() {
+						arrivals = append(arrivals, arrival)
+					}
+				}
+			}
+			for _, m := range pd.MessageList {
+				if !seenMessages[m.Text] {
+					seenMessages[m.Text] = true
+					stats.Messages = append(stats.Messages, m)
+				}
+			}
+		}
+		terminalStats(ts, stats.FetchedAt, arrivals)
+	}
+	return stats, nil
+}
+
+// terminalStats fills in the prediction statistics of ts from the predicted
+// arrivals at its stop.
+func terminalStats(ts *TerminalStats, now time.Time, arrivals []time.Time) {
+	ts.Predictions = len(arrivals)
+	if len(arrivals) == 0 {
+		return
+	}
+	sort.Slice(arrivals, func(i, j int) bool { return arrivals[i].Before(arrivals[j]) })
+	ts.Next = arrivals[0].Sub(now)
+	for i := 1; i < len(arrivals); i++ {
+		gap := arrivals[i].Sub(arrivals[i-1])
+		if i == 1 || gap < ts.MinGap {
+			ts.MinGap = gap
+		}
+		if gap > ts.MaxGap {
+			ts.MaxGap = gap
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package nextbus
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRouteStats(t *testing.T) {
+	now := time.Unix(1487246400, 0)
+	feed := &fakeFeed{Bodies: map[string]string{
+		"routeConfig": `<body><route tag="N">
+<stop tag="1" title="Ocean Beach"/><stop tag="2" title="Caltrain"/>
+<direction tag="N_O" title="Outbound" useForUI="true"><stop tag="2"/><stop tag="1"/></direction>
+<direction tag="N_I" title="Inbound" useForUI="true"><stop tag="1"/><stop tag="2"/></direction>
+<direction tag="N_X" title="Short turn" useForUI="false"><stop tag="1"/></direction>
+</route></body>`,
+		"vehicleLocations": `<body><vehicle id="1" secsSinceReport="10"/><vehicle id="2" secsSinceReport="30"/><lastTime time="1"/></body>`,
+		"predictionsForMultiStops": `<body>
+<predictions routeTag="N" stopTag="2"><direction title="Outbound">
+<prediction epochTime="1487246700000" dirTag="N_O"/><prediction epochTime="1487247300000" dirTag="N_O"/><prediction epochTime="1487247600000" dirTag="N_O"/>
+</direction><message text="Delays" priority="Normal"/></predictions>
+<predictions routeTag="N" stopTag="1"><message text="Delays" priority="Normal"/></predictions>
+</body>`,
+	}}
+	stats, err := NewClient(feed.Client(), WithClock(fixedClock(&now))).RouteStats(context.Background(), "sf-muni", "N")
+	ok(t, err)
+	equals(t, 2, stats.ActiveVehicles)
+	equals(t, 20*time.Second, stats.AverageReportAge)
+	equals(t, now, stats.FetchedAt)
+	equals(t, 1, len(stats.Messages))
+	equals(t, []TerminalStats{
+		{DirTag: "N_O", DirTitle: "Outbound", StopTag: "2", StopTitle: "Caltrain", Predictions: 3, Next: 5 * time.Minute, MinGap: 5 * time.Minute, MaxGap: 10 * time.Minute},
+		{DirTag: "N_I", DirTitle: "Inbound", StopTag: "1", StopTitle: "Ocean Beach"},
+	}, stats.Terminals)
+}
+
+func TestRouteStatsUnknownRoute(t *testing.T) {
+	_, err := NewClient((&fakeFeed{Bodies: map[string]string{"routeConfig": `<body/>`}}).Client()).RouteStats(context.Background(), "sf-muni", "X")
+	_, notFound := err.(*NotFoundError)
+	assert(t, notFound, "expected a *NotFoundError, got %v", err)
+}
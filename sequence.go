@@ -0,0 +1,87 @@
+package nextbus
+
+import (
+	"math"
+)
+
+// maxSnapDistance is how far in meters a point may be from a path and still
+// be considered on it.
+const maxSnapDistance = 40
+
+// StopPosition is a stop's place in the sequence of a route direction.
+type StopPosition struct {
+	// Sequence is the 1-based position of the stop in the direction.
+	Sequence int
+	Stop     Stop
+	// Distance is how far along the route, in meters, the stop is from the
+	// first stop of the direction. It follows the route's paths where
+	// possible and falls back to a straight line where they have gaps.
+	Distance float64
+}
+
+// StopSequence returns the stops of a direction in order with their distance
+// along the route. It returns nil if the direction doesn't exist. Stops of the
+// direction missing from the route's stop list are skipped.
+func (rc RouteConfig) StopSequence(dirTag string) []StopPosition {
+	dir, found := rc.Direction(dirTag)
+	if !found {
+		return nil
+	}
+	stops := make(map[string]Stop, len(rc.StopList))
+	for _, s := range rc.StopList {
+		stops[s.Tag] = s
+	}
+
+	var result []StopPosition
+	var lines []polyline
+	var proj planar
+	var projected bool
+	var prev xy
+	for _, marker := range dir.StopMarkerList {
+		stop, known := stops[marker.Tag]
+		if !known {
+			continue
+		}
+		lat, lon, located := parseLatLon(stop.Lat, stop.Lon)
+		pos := StopPosition{Sequence: len(result) + 1, Stop: stop}
+		if located {
+			if !projected {
+				proj = newPlanar(lat, lon)
+				lines = polylinesFromPaths(proj, MergePaths(rc.PathList))
+				projected = true
+			}
+			q := proj.toXY(lat, lon)
+			if len(result) != 0 {
+				pos.Distance = result[len(result)-1].Distance + alongPath(lines, prev, q)
+			}
+			prev = q
+		} else if len(result) != 0 {
+			pos.Distance = result[len(result)-1].Distance
+		}
+		result = append(result, pos)
+	}
+	return result
+}
+
+// alongPath returns the distance from a to b following whichever line passes
+// near both in that order, or the straight-line distance if none does.
+func alongPath(lines []polyline, a, b xy) float64 {
+	straight := math.Hypot(b.x-a.x, b.y-a.y)
+	best := math.Inf(1)
+	for _, line := range lines {
+		offA, distA := line.project(a)
+		offB, distB := line.project(b)
+		if distA > maxSnapDistance || distB > maxSnapDistance || offB < offA {
+			continue
+		}
+		// The path can't be shorter than the straight line between the two
+		// projected points, minus the distance each stop is off the path.
+		if along := offB - offA; along >= straight-distA-distB && along < best {
+			best = along
+		}
+	}
+	if math.IsInf(best, 1) {
+		return straight
+	}
+	return best
+}
@@ -0,0 +1,42 @@
+package nextbus
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStopSequence(t *testing.T) {
+	rc := RouteConfig{
+		StopList: []Stop{
+			{Tag: "a", Lat: "37.7000", Lon: "-122.4000"},
+			{Tag: "b", Lat: "37.7000", Lon: "-122.3900"},
+			{Tag: "c", Lat: "37.7100", Lon: "-122.3900"},
+			{Tag: "d", Lat: "37.8000", Lon: "-122.3000"},
+		},
+		DirList: []Direction{
+			{Tag: "out", StopMarkerList: stopMarkers("a", "b", "missing", "c", "d")},
+		},
+		PathList: []Path{
+			// An L shaped path from a through b to c.
+			{PointList: []Point{
+				{Lat: "37.7000", Lon: "-122.4000"},
+				{Lat: "37.7000", Lon: "-122.3900"},
+				{Lat: "37.7100", Lon: "-122.3900"},
+			}},
+		},
+	}
+
+	seq := rc.StopSequence("out")
+	equals(t, 4, len(seq))
+	equals(t, []int{1, 2, 3, 4}, []int{seq[0].Sequence, seq[1].Sequence, seq[2].Sequence, seq[3].Sequence})
+	equals(t, "c", seq[2].Stop.Tag)
+	equals(t, 0.0, seq[0].Distance)
+	assert(t, math.Abs(seq[1].Distance-880) < 5, "unexpected distance to b %v", seq[1].Distance)
+	assert(t, math.Abs(seq[2].Distance-880-1112) < 5, "unexpected distance to c %v", seq[2].Distance)
+
+	// d isn't on any path so the straight line from c is used.
+	straight := haversine(37.71, -122.39, 37.80, -122.30)
+	assert(t, math.Abs(seq[3].Distance-seq[2].Distance-straight) < 50, "unexpected distance to d %v", seq[3].Distance)
+
+	equals(t, []StopPosition(nil), rc.StopSequence("in"))
+}
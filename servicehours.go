@@ -0,0 +1,242 @@
+package nextbus
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ServiceSpan is the part of the day a route runs on days of one service
+// class.
+type ServiceSpan struct {
+	RouteTag     string
+	ServiceClass string
+	// First and Last are the earliest and latest scheduled times, as offsets
+	// from midnight of the service day. Last exceeds 24 hours when service
+	// runs past midnight.
+	First time.Duration
+	Last  time.Duration
+}
+
+// ServiceHours tells when a set of routes is in service, based on their
+// schedules. Times passed to its methods should be in the agency's time zone.
+type ServiceHours struct {
+	Spans []ServiceSpan
+	// ServiceClass returns the service class that runs on the day of t. It
+	// defaults to DefaultServiceClass.
+	ServiceClass func(t time.Time) string
+	// Preroll is how long before the first trip of the day service is
+	// considered to start, so that pollers resume before vehicles appear.
+	Preroll time.Duration
+}
+
+// DefaultServiceClass returns "sat" on Saturdays, "sun" on Sundays and "wkd"
+// otherwise, the service classes most agencies use.
+func DefaultServiceClass(t time.Time) string {
+	switch t.Weekday() {
+	case time.Saturday:
+		return "sat"
+	case time.Sunday:
+		return "sun"
+	}
+	return "wkd"
+}
+
+// NewServiceHours infers the service spans of each route and service class
+// from schedules.
+func NewServiceHours(schedules []Schedule) *ServiceHours {
+	type key struct{ route, serviceClass string }
+	spans := map[key]int{}
+	var h ServiceHours
+	for _, s := range schedules {
+		for _, block := range s.BlockList {
+			for _, stop := range block.StopList {
+				ms, err := strconv.ParseInt(stop.EpochTime, 10, 64)
+				if err != nil || ms < 0 {
+					continue
+				}
+				t := time.Duration(ms) * time.Millisecond
+				k := key{s.Tag, s.ServiceClass}
+				i, found := spans[k]
+				if !found {
+					spans[k] = len(h.Spans)
+					h.Spans = append(h.Spans, ServiceSpan{s.Tag, s.ServiceClass, t, t})
+					continue
+				}
+				if t < h.Spans[i].First {
+					h.Spans[i].First = t
+				}
+				if t > h.Spans[i].Last {
+					h.Spans[i].Last = t
+				}
+			}
+		}
+	}
+	return &h
+}
+
+func (h *ServiceHours) serviceClass(t time.Time) string {
+	if h.ServiceClass != nil {
+		return h.ServiceClass(t)
+	}
+	return DefaultServiceClass(t)
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// RouteInService reports whether a route is in service at t, including the
+// Preroll before its first trip. An empty routeTag matches every route.
+func (h *ServiceHours) RouteInService(routeTag string, t time.Time) bool {
+	today := midnight(t)
+	// Service that runs past midnight belongs to the previous service day.
+	for _, day := range []time.Time{today, today.AddDate(0, 0, -1)} {
+		class := h.serviceClass(day)
+		offset := t.Sub(day)
+		for _, span := range h.Spans {
+			if (routeTag == "" || span.RouteTag == routeTag) && span.ServiceClass == class &&
+				offset >= span.First-h.Preroll && offset <= span.Last {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InService reports whether any route is in service at t. It can be used as
+// HealthMonitor.InService or ServiceHoursStrategy.InService.
+func (h *ServiceHours) InService(t time.Time) bool {
+	return h.RouteInService("", t)
+}
+
+// Resume returns how long after t service next starts, including the Preroll:
+// zero if it is in service at t, or a negative duration if no service is
+// scheduled in the following week.
+func (h *ServiceHours) Resume(t time.Time) time.Duration {
+	if h.InService(t) {
+		return 0
+	}
+	best := time.Duration(-1)
+	today := midnight(t)
+	for d := 0; d <= 7; d++ {
+		day := today.AddDate(0, 0, d)
+		class := h.serviceClass(day)
+		for _, span := range h.Spans {
+			if span.ServiceClass != class {
+				continue
+			}
+			if wait := day.Add(span.First - h.Preroll).Sub(t); wait > 0 && (best < 0 || wait < best) {
+				best = wait
+			}
+		}
+	}
+	return best
+}
+
+// Strategy returns a PollStrategy that polls with inService during service
+// hours and pauses outside them until the next service day starts, or for a
+// day if none is scheduled.
+func (h *ServiceHours) Strategy(inService PollStrategy) PollStrategy {
+	return pausingStrategy{h, inService}
+}
+
+type pausingStrategy struct {
+	hours     *ServiceHours
+	inService PollStrategy
+}
+
+func (p pausingStrategy) Interval(now time.Time, latest []PredictionData) time.Duration {
+	switch resume := p.hours.Resume(now); {
+	case resume == 0:
+		return p.inService.Interval(now, latest)
+	case resume < 0:
+		return 24 * time.Hour
+	default:
+		return resume
+	}
+}
+
+// serviceSlot is the granularity at which ServiceObserver learns service
+// hours.
+const serviceSlot = 15 * time.Minute
+
+const slotsPerDay = int(24 * time.Hour / serviceSlot)
+
+// ServiceObserver learns when an agency is in service from what it observes
+// rather than from schedules: times of day at which predictions or vehicles
+// were seen are considered in service on days of the same service class.
+// Times of day it has never observed are assumed to be in service, so that it
+// errs towards polling until it has learned otherwise.
+type ServiceObserver struct {
+	// ServiceClass returns the service class that runs on the day of t. It
+	// defaults to DefaultServiceClass.
+	ServiceClass func(t time.Time) string
+	// Preroll is how far ahead InService looks for service.
+	Preroll time.Duration
+
+	mu       sync.Mutex
+	observed map[string]*[slotsPerDay]bool
+	active   map[string]*[slotsPerDay]bool
+}
+
+func (o *ServiceObserver) slot(t time.Time) (string, int) {
+	class := DefaultServiceClass(t)
+	if o.ServiceClass != nil {
+		class = o.ServiceClass(t)
+	}
+	return class, int(t.Sub(midnight(t)) / serviceSlot)
+}
+
+// Observe records whether there was service at t.
+func (o *ServiceObserver) Observe(t time.Time, active bool) {
+	class, slot := o.slot(t)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.observed == nil {
+		o.observed = map[string]*[slotsPerDay]bool{}
+		o.active = map[string]*[slotsPerDay]bool{}
+	}
+	if o.observed[class] == nil {
+		o.observed[class] = new([slotsPerDay]bool)
+		o.active[class] = new([slotsPerDay]bool)
+	}
+	o.observed[class][slot] = true
+	if active {
+		o.active[class][slot] = true
+	}
+}
+
+// ObservePredictions records service at t if any of predictions has an
+// arrival predicted.
+func (o *ServiceObserver) ObservePredictions(t time.Time, predictions []PredictionData) {
+	active := false
+	for _, pd := range predictions {
+		for _, dir := range pd.PredictionDirectionList {
+			if len(dir.PredictionList) != 0 {
+				active = true
+			}
+		}
+	}
+	o.Observe(t, active)
+}
+
+// ObserveVehicles records service at t if resp lists any vehicles.
+func (o *ServiceObserver) ObserveVehicles(t time.Time, resp *LocationResponse) {
+	o.Observe(t, resp != nil && len(resp.VehicleList) != 0)
+}
+
+// InService reports whether service was observed at the time of day of t, or
+// within Preroll after it, on days of the same service class, or if that
+// time has never been observed.
+func (o *ServiceObserver) InService(t time.Time) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for ahead := time.Duration(0); ahead <= o.Preroll; ahead += serviceSlot {
+		class, slot := o.slot(t.Add(ahead))
+		if o.observed[class] == nil || !o.observed[class][slot] || o.active[class][slot] {
+			return true
+		}
+	}
+	return false
+}
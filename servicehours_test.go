@@ -0,0 +1,63 @@
+package nextbus
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func scheduleTimes(routeTag, serviceClass string, times ...time.Duration) Schedule {
+	s := Schedule{Tag: routeTag, ServiceClass: serviceClass}
+	for _, t := range times {
+		s.BlockList = append(s.BlockList, ScheduleBlock{StopList: []ScheduleStop{
+			{Tag: "a", EpochTime: strconv.FormatInt(int64(t/time.Millisecond), 10)},
+			{Tag: "b", EpochTime: "-1"},
+		}})
+	}
+	return s
+}
+
+func TestServiceHours(t *testing.T) {
+	h := NewServiceHours([]Schedule{
+		scheduleTimes("1", "wkd", 6*time.Hour, 12*time.Hour, 25*time.Hour),
+		scheduleTimes("1", "sat", 8*time.Hour, 20*time.Hour),
+		scheduleTimes("2", "wkd", 7*time.Hour, 9*time.Hour),
+	})
+	equals(t, ServiceSpan{"1", "wkd", 6 * time.Hour, 25 * time.Hour}, h.Spans[0])
+	equals(t, 3, len(h.Spans))
+
+	// Thursday 16 February 2017.
+	thursday := func(hour, min int) time.Time { return time.Date(2017, 2, 16, hour, min, 0, 0, time.UTC) }
+	assert(t, h.InService(thursday(6, 0)), "expected service at the first trip")
+	assert(t, !h.InService(thursday(5, 50)), "expected no service before the first trip")
+	assert(t, h.InService(thursday(0, 30)), "expected service after midnight from Wednesday")
+	assert(t, !h.RouteInService("2", thursday(10, 0)), "expected route 2 to be done")
+	assert(t, h.RouteInService("1", thursday(10, 0)), "expected route 1 to be running")
+
+	h.Preroll = 15 * time.Minute
+	assert(t, h.InService(thursday(5, 50)), "expected service within the preroll")
+	equals(t, 5*time.Minute, h.Resume(thursday(5, 40)))
+	equals(t, time.Duration(0), h.Resume(thursday(12, 0)))
+
+	// Friday service ends at 1:00 on Saturday, which starts at 8:00.
+	saturday := time.Date(2017, 2, 18, 2, 0, 0, 0, time.UTC)
+	equals(t, 5*time.Hour+45*time.Minute, h.Resume(saturday))
+	equals(t, 5*time.Hour+45*time.Minute, h.Strategy(FixedInterval(time.Minute)).Interval(saturday, nil))
+	equals(t, time.Minute, h.Strategy(FixedInterval(time.Minute)).Interval(thursday(12, 0), nil))
+
+	equals(t, time.Duration(-1), NewServiceHours(nil).Resume(saturday))
+}
+
+func TestServiceObserver(t *testing.T) {
+	var o ServiceObserver
+	night := time.Date(2017, 2, 16, 3, 0, 0, 0, time.UTC)
+	assert(t, o.InService(night), "expected unobserved times to be in service")
+
+	o.ObservePredictions(night, []PredictionData{{}})
+	assert(t, !o.InService(night.AddDate(0, 0, 7).Add(5*time.Minute)), "expected no service in the same slot a week later")
+	assert(t, o.InService(night.Add(time.Hour)), "expected unobserved times to be in service")
+
+	o.ObserveVehicles(night.Add(30*time.Minute), &LocationResponse{VehicleList: []VehicleLocation{{ID: "1"}}})
+	o.Preroll = 30 * time.Minute
+	assert(t, o.InService(night), "expected service within the preroll")
+}
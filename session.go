@@ -0,0 +1,99 @@
+package nextbus
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// VehicleLocationSession fetches vehicle locations for an agency, optionally
+// restricted to one route, and remembers the lastTime of each response so the
+// next call only returns vehicles that have reported since.
+type VehicleLocationSession struct {
+	client    *Client
+	agencyTag string
+	routeTag  string
+
+	// Bus, if set, receives a TopicVehicles event for each response.
+	Bus *Bus
+	// OnError, if set, is called with any error from a fetch made by Run.
+	OnError func(error)
+
+	mu       sync.Mutex
+	lastTime string
+}
+
+// NewVehicleLocationSession creates a session for an agency's vehicles. An
+// empty routeTag includes every route.
+func NewVehicleLocationSession(client *Client, agencyTag, routeTag string) *VehicleLocationSession {
+	return &VehicleLocationSession{client: client, agencyTag: agencyTag, routeTag: routeTag}
+}
+
+// Next fetches the vehicles that have reported since the previous call, or all
+// recent vehicles on the first call.
+func (s *VehicleLocationSession) Next() (*LocationResponse, error) {
+	return s.NextContext(context.Background())
+}
+
+// NextContext is like Next but uses ctx for the request.
+func (s *VehicleLocationSession) NextContext(ctx context.Context) (*LocationResponse, error) {
+	resp, err := s.next(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.Bus.Publish(BusEvent{Topic: TopicVehicles, Time: s.client.now(), Payload: resp})
+	return resp, nil
+}
+
+func (s *VehicleLocationSession) next(ctx context.Context) (*LocationResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var params []VehicleLocationParam
+	if s.routeTag != "" {
+		params = append(params, VehicleLocationRoute(s.routeTag))
+	}
+	if s.lastTime != "" {
+		params = append(params, VehicleLocationTime(s.lastTime))
+	}
+	resp, err := s.client.GetVehicleLocationsContext(ctx, s.agencyTag, params...)
+	if err != nil {
+		return nil, err
+	}
+	if resp.LastTime.Time != "" {
+		s.lastTime = resp.LastTime.Time
+	}
+	return resp, nil
+}
+
+// LastTime returns the lastTime that will be sent with the next request, or
+// the empty string before the first successful call.
+func (s *VehicleLocationSession) LastTime() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastTime
+}
+
+// Reset makes the next call fetch all recent vehicles again.
+func (s *VehicleLocationSession) Reset() {
+	s.mu.Lock()
+	s.lastTime = ""
+	s.mu.Unlock()
+}
+
+// Run fetches immediately and then every interval until ctx is done. The
+// responses are published on Bus.
+func (s *VehicleLocationSession) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.NextContext(ctx); err != nil && s.OnError != nil {
+			s.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestVehicleLocationSession(t *testing.T) {
+	s := NewVehicleLocationSession(NewClient(testingClient(t)), "alpha", "")
+	equals(t, "", s.LastTime())
+
+	first, err := s.Next()
+	ok(t, err)
+	equals(t, 2, len(first.VehicleList))
+	equals(t, "1234567890123", s.LastTime())
+
+	second, err := s.Next()
+	ok(t, err)
+	equals(t, 1, len(second.VehicleList))
+	equals(t, "2222", second.VehicleList[0].ID)
+	equals(t, "1234567900123", s.LastTime())
+
+	s.Reset()
+	equals(t, "", s.LastTime())
+}
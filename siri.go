@@ -0,0 +1,143 @@
+package nextbus
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SiriVersion is the version of SIRI produced by NewSiriStopMonitoring.
+const SiriVersion = "2.0"
+
+// Siri is a SIRI StopMonitoring (SIRI-SM) response, the format many transit
+// aggregation platforms consume. It renders as SIRI XML with WriteXML and as
+// SIRI JSON with WriteJSON.
+type Siri struct {
+	XMLName         xml.Name            `xml:"http://www.siri.org.uk/siri Siri" json:"-"`
+	Version         string              `xml:"version,attr" json:"-"`
+	ServiceDelivery SiriServiceDelivery `xml:"ServiceDelivery"`
+}
+
+// SiriServiceDelivery holds the deliveries of a SIRI response.
+type SiriServiceDelivery struct {
+	ResponseTimestamp      time.Time                    `xml:"ResponseTimestamp"`
+	ProducerRef            string                       `xml:"ProducerRef"`
+	StopMonitoringDelivery []SiriStopMonitoringDelivery `xml:"StopMonitoringDelivery"`
+}
+
+// SiriStopMonitoringDelivery lists the upcoming visits to the monitored stops.
+type SiriStopMonitoringDelivery struct {
+	Version            string                   `xml:"version,attr" json:"-"`
+	ResponseTimestamp  time.Time                `xml:"ResponseTimestamp"`
+	MonitoredStopVisit []SiriMonitoredStopVisit `xml:"MonitoredStopVisit"`
+}
+
+// SiriMonitoredStopVisit is one vehicle's upcoming visit to a stop.
+type SiriMonitoredStopVisit struct {
+	RecordedAtTime          time.Time                   `xml:"RecordedAtTime"`
+	MonitoringRef           string                      `xml:"MonitoringRef"`
+	MonitoredVehicleJourney SiriMonitoredVehicleJourney `xml:"MonitoredVehicleJourney"`
+}
+
+// SiriMonitoredVehicleJourney describes the vehicle making a visit.
+type SiriMonitoredVehicleJourney struct {
+	LineRef                 string                      `xml:"LineRef"`
+	DirectionRef            string                      `xml:"DirectionRef"`
+	FramedVehicleJourneyRef SiriFramedVehicleJourneyRef `xml:"FramedVehicleJourneyRef"`
+	PublishedLineName       string                      `xml:"PublishedLineName"`
+	OperatorRef             string                      `xml:"OperatorRef"`
+	DestinationName         string                      `xml:"DestinationName,omitempty" json:",omitempty"`
+	// Monitored is false for predictions based on the schedule rather than
+	// on the vehicle's position.
+	Monitored     bool              `xml:"Monitored"`
+	BlockRef      string            `xml:"BlockRef,omitempty" json:",omitempty"`
+	VehicleRef    string            `xml:"VehicleRef,omitempty" json:",omitempty"`
+	MonitoredCall SiriMonitoredCall `xml:"MonitoredCall"`
+}
+
+// SiriFramedVehicleJourneyRef identifies a trip on a service day.
+type SiriFramedVehicleJourneyRef struct {
+	DataFrameRef           string `xml:"DataFrameRef"`
+	DatedVehicleJourneyRef string `xml:"DatedVehicleJourneyRef"`
+}
+
+// SiriMonitoredCall is the predicted call at the monitored stop.
+type SiriMonitoredCall struct {
+	StopPointRef          string     `xml:"StopPointRef"`
+	StopPointName         string     `xml:"StopPointName"`
+	ExpectedArrivalTime   *time.Time `xml:"ExpectedArrivalTime,omitempty" json:",omitempty"`
+	ExpectedDepartureTime *time.Time `xml:"ExpectedDepartureTime,omitempty" json:",omitempty"`
+}
+
+// NewSiriStopMonitoring converts predictions into a SIRI StopMonitoring
+// response produced at now. Each prediction becomes a MonitoredStopVisit,
+// with the route as the line, the stop tag as the monitoring and stop point
+// reference, and the trip tag as the journey. Predictions NextBus flags as
+// departures set ExpectedDepartureTime instead of ExpectedArrivalTime.
+func NewSiriStopMonitoring(agencyTag string, predictions []PredictionData, now time.Time) *Siri {
+	delivery := SiriStopMonitoringDelivery{Version: SiriVersion, ResponseTimestamp: now}
+	for _, pd := range predictions {
+		for _, dir := range pd.PredictionDirectionList {
+			for _, p := range dir.PredictionList {
+				expected := p.ArrivalTime()
+				call := SiriMonitoredCall{StopPointRef: pd.StopTag, StopPointName: pd.StopTitle}
+				if p.IsDeparture == "true" {
+					call.ExpectedDepartureTime = &expected
+				} else {
+					call.ExpectedArrivalTime = &expected
+				}
+				delivery.MonitoredStopVisit = append(delivery.MonitoredStopVisit, SiriMonitoredStopVisit{
+					RecordedAtTime: now,
+					MonitoringRef:  pd.StopTag,
+					MonitoredVehicleJourney: SiriMonitoredVehicleJourney{
+						LineRef:      pd.RouteTag,
+						DirectionRef: p.DirTag,
+						FramedVehicleJourneyRef: SiriFramedVehicleJourneyRef{
+							DataFrameRef:           expected.Format("2006-01-02"),
+							DatedVehicleJourneyRef: p.TripTag,
+						},
+						PublishedLineName: pd.RouteTitle,
+						OperatorRef:       agencyTag,
+						DestinationName:   dir.Title,
+						Monitored:         p.IsScheduleBased != "true",
+						BlockRef:          p.Block,
+						VehicleRef:        p.Vehicle,
+						MonitoredCall:     call,
+					},
+				})
+			}
+		}
+	}
+	return &Siri{
+		Version: SiriVersion,
+		ServiceDelivery: SiriServiceDelivery{
+			ResponseTimestamp:      now,
+			ProducerRef:            agencyTag,
+			StopMonitoringDelivery: []SiriStopMonitoringDelivery{delivery},
+		},
+	}
+}
+
+// WriteXML writes the response as a SIRI XML document.
+func (s *Siri) WriteXML(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("could not write SIRI XML: %v", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("could not write SIRI XML: %v", err)
+	}
+	return nil
+}
+
+// WriteJSON writes the response in the SIRI JSON encoding, an object with a
+// single Siri member.
+func (s *Siri) WriteJSON(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(struct{ Siri *Siri }{s}); err != nil {
+		return fmt.Errorf("could not write SIRI JSON: %v", err)
+	}
+	return nil
+}
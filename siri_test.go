@@ -0,0 +1,55 @@
+package nextbus
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSiriStopMonitoring(t *testing.T) {
+	predictions, err := NewClient(testingClient(t)).GetPredictionsForMultiStops("alpha", PredReqStop("1", "1123"))
+	ok(t, err)
+	now := time.Unix(1487277000, 0).UTC()
+	siri := NewSiriStopMonitoring("alpha", predictions, now)
+
+	visits := siri.ServiceDelivery.StopMonitoringDelivery[0].MonitoredStopVisit
+	equals(t, 1, len(visits))
+	journey := visits[0].MonitoredVehicleJourney
+	equals(t, "1", journey.LineRef)
+	equals(t, "7318265", journey.FramedVehicleJourneyRef.DatedVehicleJourneyRef)
+	equals(t, "1111", journey.VehicleRef)
+	equals(t, true, journey.Monitored)
+	equals(t, int64(1487277081), journey.MonitoredCall.ExpectedArrivalTime.Unix())
+	assert(t, journey.MonitoredCall.ExpectedDepartureTime == nil, "expected no departure time")
+
+	var xmlOut bytes.Buffer
+	ok(t, siri.WriteXML(&xmlOut))
+	for _, want := range []string{
+		`<Siri xmlns="http://www.siri.org.uk/siri" version="2.0">`,
+		`<MonitoringRef>1123</MonitoringRef>`,
+		`<StopPointName>Some Station Outbound</StopPointName>`,
+	} {
+		assert(t, strings.Contains(xmlOut.String(), want), "expected %q in %s", want, xmlOut.String())
+	}
+	assert(t, !strings.Contains(xmlOut.String(), "ExpectedDepartureTime"), "unexpected departure time in %s", xmlOut.String())
+
+	var jsonOut bytes.Buffer
+	ok(t, siri.WriteJSON(&jsonOut))
+	var decoded struct {
+		Siri struct {
+			ServiceDelivery struct {
+				ProducerRef            string
+				StopMonitoringDelivery []struct {
+					MonitoredStopVisit []struct {
+						MonitoringRef string
+					}
+				}
+			}
+		}
+	}
+	ok(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	equals(t, "alpha", decoded.Siri.ServiceDelivery.ProducerRef)
+	equals(t, "1123", decoded.Siri.ServiceDelivery.StopMonitoringDelivery[0].MonitoredStopVisit[0].MonitoringRef)
+}
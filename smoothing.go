@@ -0,0 +1,104 @@
+package nextbus
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultSmoothingFactor is the weight given to the newest prediction when a
+// Smoother's Alpha is zero.
+const DefaultSmoothingFactor = 0.5
+
+// DefaultSmoothingReset is how far a new prediction can move from the smoothed
+// one before a Smoother starts over from it when Reset is zero.
+const DefaultSmoothingReset = 10 * time.Minute
+
+// Smoother damps the jitter in successive predictions for the same vehicle,
+// such as a countdown that goes from 4 to 6 to 3 minutes between polls, using
+// exponential smoothing of the predicted arrival time. The zero value is ready
+// to use.
+type Smoother struct {
+	// Alpha is the weight, between 0 and 1, of the newest prediction. Lower
+	// values give steadier countdowns that are slower to follow real changes.
+	Alpha float64
+	// Reset is the change in predicted arrival beyond which the history for a
+	// vehicle is discarded, as when it has been reassigned or has passed the
+	// stop and is predicted on its next trip.
+	Reset time.Duration
+
+	mu       sync.Mutex
+	arrivals map[string]time.Time
+}
+
+// Smooth returns a copy of predictions with the arrival time, seconds and
+// minutes of each prediction stabilized against previous calls. Predictions
+// without a vehicle or trip to follow them by are returned unchanged. History
+// is kept only for the predictions in the latest call.
+func (s *Smoother) Smooth(now time.Time, predictions []PredictionData) []PredictionData {
+	alpha := s.Alpha
+	if alpha <= 0 || alpha > 1 {
+		alpha = DefaultSmoothingFactor
+	}
+	reset := s.Reset
+	if reset <= 0 {
+		reset = DefaultSmoothingReset
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous := s.arrivals
+	s.arrivals = map[string]time.Time{}
+
+	result := make([]PredictionData, len(predictions))
+	for i, pd := range predictions {
+		result[i] = pd
+		result[i].PredictionDirectionList = make([]PredictionDirection, len(pd.PredictionDirectionList))
+		for j, dir := range pd.PredictionDirectionList {
+			smoothed := dir
+			smoothed.PredictionList = make([]Prediction, len(dir.PredictionList))
+			for k, p := range dir.PredictionList {
+				smoothed.PredictionList[k] = p
+				key := smoothingKey(pd, p)
+				arrival := p.ArrivalTime()
+				if key == "" || arrival.IsZero() {
+					continue
+				}
+				if last, ok := previous[key]; ok {
+					if delta := arrival.Sub(last); delta < reset && delta > -reset {
+						arrival = last.Add(time.Duration(alpha * float64(delta)))
+					}
+				}
+				s.arrivals[key] = arrival
+				smoothed.PredictionList[k] = withArrival(p, now, arrival)
+			}
+			result[i].PredictionDirectionList[j] = smoothed
+		}
+	}
+	return result
+}
+
+// smoothingKey identifies the vehicle or trip a prediction follows at its
+// stop, or is empty if it has neither.
+func smoothingKey(pd PredictionData, p Prediction) string {
+	id := p.Vehicle
+	if id == "" {
+		id = p.TripTag
+	}
+	if id == "" {
+		return ""
+	}
+	return pd.RouteTag + "|" + pd.StopTag + "|" + id
+}
+
+// withArrival returns p predicting arrival at the given time, as seen at now.
+func withArrival(p Prediction, now, arrival time.Time) Prediction {
+	seconds := int(arrival.Sub(now) / time.Second)
+	if seconds < 0 {
+		seconds = 0
+	}
+	p.EpochTime = strconv.FormatInt(arrival.UnixNano()/int64(time.Millisecond), 10)
+	p.Seconds = strconv.Itoa(seconds)
+	p.Minutes = strconv.Itoa(seconds / 60)
+	return p
+}
@@ -0,0 +1,55 @@
+package nextbus
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func arrivingAt(vehicle string, arrival time.Time) []PredictionData {
+	epoch := strconv.FormatInt(arrival.UnixNano()/int64(time.Millisecond), 10)
+	return []PredictionData{{
+		RouteTag: "N",
+		StopTag:  "5205",
+		PredictionDirectionList: []PredictionDirection{{
+			PredictionList: []Prediction{{EpochTime: epoch, Vehicle: vehicle}},
+		}},
+	}}
+}
+
+func TestSmoother(t *testing.T) {
+	var s Smoother
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+
+	first := s.Smooth(now, arrivingAt("1500", now.Add(4*time.Minute)))
+	p := first[0].PredictionDirectionList[0].PredictionList[0]
+	equals(t, "240", p.Seconds)
+	equals(t, "4", p.Minutes)
+
+	// A jump to 6 minutes is halved.
+	second := s.Smooth(now, arrivingAt("1500", now.Add(6*time.Minute)))
+	p = second[0].PredictionDirectionList[0].PredictionList[0]
+	equals(t, "300", p.Seconds)
+	equals(t, "5", p.Minutes)
+	assert(t, p.ArrivalTime().Equal(now.Add(5*time.Minute)), "unexpected arrival %v", p.ArrivalTime())
+
+	// Another vehicle has no history.
+	other := s.Smooth(now, arrivingAt("1501", now.Add(6*time.Minute)))
+	equals(t, "360", other[0].PredictionDirectionList[0].PredictionList[0].Seconds)
+
+	// Vehicle 1500 was forgotten when it was missing from the last call.
+	again := s.Smooth(now, arrivingAt("1500", now.Add(2*time.Minute)))
+	equals(t, "120", again[0].PredictionDirectionList[0].PredictionList[0].Seconds)
+
+	// A change beyond Reset starts over.
+	far := s.Smooth(now, arrivingAt("1500", now.Add(30*time.Minute)))
+	equals(t, "1800", far[0].PredictionDirectionList[0].PredictionList[0].Seconds)
+}
+
+func TestSmootherLeavesUnfollowedPredictions(t *testing.T) {
+	var s Smoother
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	in := predictionsIn("3")
+	out := s.Smooth(now, in)
+	equals(t, in, out)
+}
@@ -0,0 +1,72 @@
+package nextbus
+
+import (
+	"context"
+	"time"
+)
+
+// AgencySnapshot is the static configuration of a transit agency, its routes
+// and their stops, as fetched at a point in time.
+type AgencySnapshot struct {
+	Agency    Agency
+	Routes    []RouteConfig
+	FetchedAt time.Time
+}
+
+// GetAgencySnapshot fetches the agency's metadata and the config of all of its
+// routes.
+func (c *Client) GetAgencySnapshot(agencyTag string) (*AgencySnapshot, error) {
+	agencies, agencyErr := c.GetAgencyList()
+	if agencyErr != nil {
+		return nil, agencyErr
+	}
+	return c.agencySnapshot(context.Background(), agencies, agencyTag)
+}
+
+// agencySnapshot fetches the route configs of an agency found in an already
+// fetched agency list.
+func (c *Client) agencySnapshot(ctx context.Context, agencies []Agency, agencyTag string) (*AgencySnapshot, error) {
+	snapshot := AgencySnapshot{FetchedAt: c.now()}
+	found := false
+	for _, a := range agencies {
+		if a.Tag == agencyTag {
+			snapshot.Agency = a
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, &NotFoundError{Kind: "agency", AgencyTag: agencyTag}
+	}
+
+	routes, routeErr := c.GetRouteConfigContext(ctx, agencyTag)
+	if routeErr != nil {
+		return nil, routeErr
+	}
+	snapshot.Routes = routes
+	return &snapshot, nil
+}
+
+// Route returns the config of the route with the given tag.
+func (s *AgencySnapshot) Route(routeTag string) (*RouteConfig, bool) {
+	for i := range s.Routes {
+		if s.Routes[i].Tag == routeTag {
+			return &s.Routes[i], true
+		}
+	}
+	return nil, false
+}
+
+// Stop returns the stop with the given tag on the given route.
+func (s *AgencySnapshot) Stop(routeTag, stopTag string) (*Stop, bool) {
+	rc, found := s.Route(routeTag)
+	if !found {
+		return nil, false
+	}
+	for i := range rc.StopList {
+		if rc.StopList[i].Tag == stopTag {
+			return &rc.StopList[i], true
+		}
+	}
+	return nil, false
+}
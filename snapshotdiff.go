@@ -0,0 +1,193 @@
+package nextbus
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChangeKind identifies the kind of a Change between two snapshots.
+type ChangeKind string
+
+// The kinds of Change reported by DiffSnapshots.
+const (
+	RouteAdded       ChangeKind = "route added"
+	RouteRemoved     ChangeKind = "route removed"
+	RouteRenamed     ChangeKind = "route renamed"
+	StopAdded        ChangeKind = "stop added"
+	StopRemoved      ChangeKind = "stop removed"
+	StopMoved        ChangeKind = "stop moved"
+	StopRenamed      ChangeKind = "stop renamed"
+	DirectionAdded   ChangeKind = "direction added"
+	DirectionRemoved ChangeKind = "direction removed"
+	DirectionChanged ChangeKind = "direction stops changed"
+	PathChanged      ChangeKind = "path changed"
+)
+
+// minStopMove is how far in meters a stop must move to be reported, so that
+// rounding of coordinates doesn't show up as a change.
+const minStopMove = 1
+
+// Change is one difference between two snapshots of an agency.
+type Change struct {
+	Kind     ChangeKind
+	RouteTag string
+	// StopTag is set for stop changes and DirTag for direction changes.
+	StopTag string
+	DirTag  string
+	// Old and New describe the value before and after, such as a title or
+	// coordinates, where the kind of change has one.
+	Old string
+	New string
+	// Distance is how far a stop moved in meters.
+	Distance float64
+}
+
+// String formats the change as a changelog line.
+func (c Change) String() string {
+	var b strings.Builder
+	b.WriteString("route " + c.RouteTag)
+	if c.StopTag != "" {
+		b.WriteString(" stop " + c.StopTag)
+	}
+	if c.DirTag != "" {
+		b.WriteString(" direction " + c.DirTag)
+	}
+	b.WriteString(": " + string(c.Kind))
+	switch {
+	case c.Old != "" && c.New != "":
+		fmt.Fprintf(&b, " from %q to %q", c.Old, c.New)
+	case c.New != "":
+		fmt.Fprintf(&b, " %q", c.New)
+	case c.Old != "":
+		fmt.Fprintf(&b, " %q", c.Old)
+	}
+	if c.Distance != 0 {
+		fmt.Fprintf(&b, " (%.0fm)", c.Distance)
+	}
+	return b.String()
+}
+
+// DiffSnapshots compares two snapshots of the same agency and returns what
+// changed from old to new, ordered by route and then by the kind of change.
+func DiffSnapshots(old, new *AgencySnapshot) []Change {
+	oldRoutes := map[string]RouteConfig{}
+	for _, rc := range old.Routes {
+		oldRoutes[rc.Tag] = rc
+	}
+	newRoutes := map[string]RouteConfig{}
+	for _, rc := range new.Routes {
+		newRoutes[rc.Tag] = rc
+	}
+
+	var changes []Change
+	for _, rc := range old.Routes {
+		if _, kept := newRoutes[rc.Tag]; !kept {
+			changes = append(changes, Change{Kind: RouteRemoved, RouteTag: rc.Tag, Old: rc.Title})
+		}
+	}
+	for _, rc := range new.Routes {
+		before, existed := oldRoutes[rc.Tag]
+		if !existed {
+			changes = append(changes, Change{Kind: RouteAdded, RouteTag: rc.Tag, New: rc.Title})
+			continue
+		}
+		changes = append(changes, diffRoute(before, rc)...)
+	}
+
+	order := map[string]int{}
+	for i, rc := range new.Routes {
+		order[rc.Tag] = i
+	}
+	for i, rc := range old.Routes {
+		if _, found := order[rc.Tag]; !found {
+			order[rc.Tag] = len(new.Routes) + i
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return order[changes[i].RouteTag] < order[changes[j].RouteTag] })
+	return changes
+}
+
+func diffRoute(old, new RouteConfig) []Change {
+	var changes []Change
+	if old.Title != new.Title {
+		changes = append(changes, Change{Kind: RouteRenamed, RouteTag: new.Tag, Old: old.Title, New: new.Title})
+	}
+
+	oldStops := map[string]Stop{}
+	for _, s := range old.StopList {
+		oldStops[s.Tag] = s
+	}
+	newStops := map[string]bool{}
+	for _, s := range new.StopList {
+		newStops[s.Tag] = true
+		before, existed := oldStops[s.Tag]
+		if !existed {
+			changes = append(changes, Change{Kind: StopAdded, RouteTag: new.Tag, StopTag: s.Tag, New: s.Title})
+			continue
+		}
+		if before.Title != s.Title {
+			changes = append(changes, Change{Kind: StopRenamed, RouteTag: new.Tag, StopTag: s.Tag, Old: before.Title, New: s.Title})
+		}
+		if d, located := stopDistance(before, s); located && d >= minStopMove {
+			changes = append(changes, Change{
+				Kind:     StopMoved,
+				RouteTag: new.Tag,
+				StopTag:  s.Tag,
+				Old:      before.Lat + "," + before.Lon,
+				New:      s.Lat + "," + s.Lon,
+				Distance: d,
+			})
+		}
+	}
+	for _, s := range old.StopList {
+		if !newStops[s.Tag] {
+			changes = append(changes, Change{Kind: StopRemoved, RouteTag: new.Tag, StopTag: s.Tag, Old: s.Title})
+		}
+	}
+
+	oldDirs := map[string]Direction{}
+	for _, d := range old.DirList {
+		oldDirs[d.Tag] = d
+	}
+	newDirs := map[string]bool{}
+	for _, d := range new.DirList {
+		newDirs[d.Tag] = true
+		before, existed := oldDirs[d.Tag]
+		if !existed {
+			changes = append(changes, Change{Kind: DirectionAdded, RouteTag: new.Tag, DirTag: d.Tag, New: d.Title})
+		} else if markerTags(before) != markerTags(d) {
+			changes = append(changes, Change{Kind: DirectionChanged, RouteTag: new.Tag, DirTag: d.Tag})
+		}
+	}
+	for _, d := range old.DirList {
+		if !newDirs[d.Tag] {
+			changes = append(changes, Change{Kind: DirectionRemoved, RouteTag: new.Tag, DirTag: d.Tag, Old: d.Title})
+		}
+	}
+
+	if pathSet(old.PathList) != pathSet(new.PathList) {
+		changes = append(changes, Change{Kind: PathChanged, RouteTag: new.Tag})
+	}
+	return changes
+}
+
+func markerTags(d Direction) string {
+	tags := make([]string, len(d.StopMarkerList))
+	for i, m := range d.StopMarkerList {
+		tags[i] = m.Tag
+	}
+	return strings.Join(tags, " ")
+}
+
+// pathSet identifies a route's geometry regardless of how NextBus split it
+// into segments or ordered them.
+func pathSet(paths []Path) string {
+	merged := MergePaths(paths)
+	keys := make([]string, len(merged))
+	for i, p := range merged {
+		keys[i] = pathKey(p.PointList)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "|")
+}
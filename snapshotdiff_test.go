@@ -0,0 +1,57 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	old := &AgencySnapshot{Routes: []RouteConfig{
+		{
+			Tag: "1", Title: "1-First",
+			StopList: []Stop{
+				{Tag: "a", Title: "A St", Lat: "37.7000", Lon: "-122.4000"},
+				{Tag: "b", Title: "B St", Lat: "37.7050", Lon: "-122.4000"},
+				{Tag: "c", Title: "C St", Lat: "37.7100", Lon: "-122.4000"},
+			},
+			DirList:  []Direction{{Tag: "out", StopMarkerList: stopMarkers("a", "b", "c")}},
+			PathList: []Path{pathOf("37.7000", "-122.4000", "37.7050", "-122.4000", "37.7100", "-122.4000")},
+		},
+		{Tag: "2", Title: "2-Second"},
+	}}
+	new := &AgencySnapshot{Routes: []RouteConfig{
+		{
+			Tag: "1", Title: "1-First",
+			StopList: []Stop{
+				{Tag: "a", Title: "A St", Lat: "37.7000", Lon: "-122.4000"},
+				{Tag: "b", Title: "B Street", Lat: "37.7060", Lon: "-122.4000"},
+				{Tag: "d", Title: "D St", Lat: "37.7200", Lon: "-122.4000"},
+			},
+			DirList: []Direction{
+				{Tag: "out", StopMarkerList: stopMarkers("a", "b", "d")},
+				{Tag: "in", Title: "Inbound"},
+			},
+			// The same geometry split into two segments.
+			PathList: []Path{
+				pathOf("37.7000", "-122.4000", "37.7050", "-122.4000"),
+				pathOf("37.7050", "-122.4000", "37.7100", "-122.4000"),
+			},
+		},
+		{Tag: "3", Title: "3-Third"},
+	}}
+
+	changes := DiffSnapshots(old, new)
+	var kinds []ChangeKind
+	for _, c := range changes {
+		kinds = append(kinds, c.Kind)
+	}
+	equals(t, []ChangeKind{StopRenamed, StopMoved, StopAdded, StopRemoved, DirectionChanged, DirectionAdded, RouteAdded, RouteRemoved}, kinds)
+	equals(t, `route 1 stop b: stop renamed from "B St" to "B Street"`, changes[0].String())
+	equals(t, `route 1 stop b: stop moved from "37.7050,-122.4000" to "37.7060,-122.4000" (111m)`, changes[1].String())
+	equals(t, `route 2: route removed "2-Second"`, changes[7].String())
+
+	equals(t, []Change(nil), DiffSnapshots(new, new))
+
+	rerouted := &AgencySnapshot{Routes: []RouteConfig{new.Routes[0], new.Routes[1]}}
+	rerouted.Routes[0].PathList = []Path{pathOf("37.7000", "-122.4000", "37.7100", "-122.4100")}
+	equals(t, []Change{{Kind: PathChanged, RouteTag: "1"}}, DiffSnapshots(new, rerouted))
+}
@@ -0,0 +1,397 @@
+// Package sqlitestore keeps the snapshots of a nextbus.Directory in an SQLite
+// database, so that services and tools working with many agencies load only
+// the routes they use into memory, and find stops by location through an
+// R-tree index instead of scanning every stop. The same database can hold
+// the arrivals recorded by a nextbus.Archiver, as postgresstore does for
+// larger archives.
+//
+// The package uses database/sql and doesn't import a driver. Open the
+// database with any SQLite driver built with the R*Tree module, such as
+// github.com/mattn/go-sqlite3 or modernc.org/sqlite:
+//
+//	db, err := sql.Open("sqlite3", "nextbus.db")
+//	store, err := sqlitestore.New(ctx, db)
+//	dir := nextbus.NewDirectoryWithStore(client, store)
+//	rc, err := dir.Route(ctx, "sf-muni", "N")
+//	archiver := nextbus.NewArchiver(store.Arrivals("sf-muni"))
+//
+// Store is a nextbus.RouteStore, so Directory.Route loads only the route asked
+// for rather than the whole snapshot, as Directory.Snapshot does.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/dinedal/nextbus"
+	"github.com/dinedal/nextbus/geo"
+)
+
+// schema creates the tables New needs if they don't exist yet.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS nextbus_agencies (
+		tag TEXT PRIMARY KEY,
+		agency BLOB NOT NULL,
+		fetched_at INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS nextbus_routes (
+		agency TEXT NOT NULL,
+		tag TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		config BLOB NOT NULL,
+		PRIMARY KEY (agency, tag)
+	)`,
+	`CREATE TABLE IF NOT EXISTS nextbus_stops (
+		id INTEGER PRIMARY KEY,
+		agency TEXT NOT NULL,
+		route TEXT NOT NULL,
+		stop BLOB NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS nextbus_stops_agency ON nextbus_stops (agency)`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS nextbus_stops_rtree USING rtree (id, min_lat, max_lat, min_lon, max_lon)`,
+	`CREATE TABLE IF NOT EXISTS nextbus_arrivals (
+		agency TEXT NOT NULL,
+		route TEXT NOT NULL,
+		stop TEXT NOT NULL,
+		direction TEXT NOT NULL,
+		vehicle TEXT NOT NULL,
+		trip TEXT NOT NULL,
+		arrived_at INTEGER NOT NULL,
+		predicted_at INTEGER
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS nextbus_arrivals_key
+		ON nextbus_arrivals (agency, route, stop, vehicle, trip, COALESCE(predicted_at, arrived_at))`,
+	`CREATE INDEX IF NOT EXISTS nextbus_arrivals_time ON nextbus_arrivals (agency, arrived_at)`,
+}
+
+// Store is a nextbus.DirectoryStore backed by an SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+var (
+	_ nextbus.DirectoryStore = (*Store)(nil)
+	_ nextbus.RouteStore     = (*Store)(nil)
+	_ nextbus.ArrivalStore   = (*Arrivals)(nil)
+)
+
+// New creates a Store using db, creating its tables if needed. Snapshots and
+// arrivals already in the database are kept.
+func New(ctx context.Context, db *sql.DB) (*Store, error) {
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("could not create snapshot tables: %v", err)
+		}
+	}
+	return &Store{db}, nil
+}
+
+// Put replaces the snapshot of an agency in a single transaction, so readers
+// see either the old snapshot or the new one.
+func (s *Store) Put(ctx context.Context, snapshot *nextbus.AgencySnapshot) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not store snapshot: %v", err)
+	}
+	if err := put(ctx, tx, snapshot); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("could not store snapshot: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not store snapshot: %v", err)
+	}
+	return nil
+}
+
+func put(ctx context.Context, tx *sql.Tx, snapshot *nextbus.AgencySnapshot) error {
+	tag := snapshot.Agency.Tag
+	for _, stmt := range []string{
+		`DELETE FROM nextbus_stops_rtree WHERE id IN (SELECT id FROM nextbus_stops WHERE agency = ?)`,
+		`DELETE FROM nextbus_stops WHERE agency = ?`,
+		`DELETE FROM nextbus_routes WHERE agency = ?`,
+		`DELETE FROM nextbus_agencies WHERE tag = ?`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt, tag); err != nil {
+			return err
+		}
+	}
+
+	agency, err := xml.Marshal(snapshot.Agency)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO nextbus_agencies (tag, agency, fetched_at) VALUES (?, ?, ?)`,
+		tag, agency, snapshot.FetchedAt.UnixNano()); err != nil {
+		return err
+	}
+	for i, rc := range snapshot.Routes {
+		config, err := xml.Marshal(rc)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO nextbus_routes (agency, tag, position, config) VALUES (?, ?, ?, ?)`,
+			tag, rc.Tag, i, config); err != nil {
+			return err
+		}
+		for _, stop := range rc.StopList {
+			if err := putStop(ctx, tx, tag, rc.Tag, stop); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func putStop(ctx context.Context, tx *sql.Tx, agencyTag, routeTag string, stop nextbus.Stop) error {
+	data, err := xml.Marshal(stop)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `INSERT INTO nextbus_stops (agency, route, stop) VALUES (?, ?, ?)`, agencyTag, routeTag, data)
+	if err != nil {
+		return err
+	}
+	p, located := stop.LatLon()
+	if !located {
+		return nil
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO nextbus_stops_rtree (id, min_lat, max_lat, min_lon, max_lon) VALUES (?, ?, ?, ?, ?)`,
+		id, p.Lat, p.Lat, p.Lon, p.Lon)
+	return err
+}
+
+// Get loads the snapshot of an agency, or returns nil if there is none.
+func (s *Store) Get(ctx context.Context, agencyTag string) (*nextbus.AgencySnapshot, error) {
+	var agency []byte
+	var fetchedAt int64
+	err := s.db.QueryRowContext(ctx, `SELECT agency, fetched_at FROM nextbus_agencies WHERE tag = ?`, agencyTag).Scan(&agency, &fetchedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not load snapshot: %v", err)
+	}
+	snapshot := &nextbus.AgencySnapshot{FetchedAt: time.Unix(0, fetchedAt)}
+	if err := xml.Unmarshal(agency, &snapshot.Agency); err != nil {
+		return nil, fmt.Errorf("could not load snapshot: %v", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT config FROM nextbus_routes WHERE agency = ? ORDER BY position`, agencyTag)
+	if err != nil {
+		return nil, fmt.Errorf("could not load snapshot: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rc, err := scanRoute(rows)
+		if err != nil {
+			return nil, fmt.Errorf("could not load snapshot: %v", err)
+		}
+		snapshot.Routes = append(snapshot.Routes, rc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not load snapshot: %v", err)
+	}
+	return snapshot, nil
+}
+
+func scanRoute(row interface{ Scan(...interface{}) error }) (nextbus.RouteConfig, error) {
+	var config []byte
+	var rc nextbus.RouteConfig
+	if err := row.Scan(&config); err != nil {
+		return rc, err
+	}
+	err := xml.Unmarshal(config, &rc)
+	return rc, err
+}
+
+// Agencies returns the tags of the agencies with a snapshot.
+func (s *Store) Agencies(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT tag FROM nextbus_agencies ORDER BY tag`)
+	if err != nil {
+		return nil, fmt.Errorf("could not list agencies: %v", err)
+	}
+	defer rows.Close()
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("could not list agencies: %v", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not list agencies: %v", err)
+	}
+	return tags, nil
+}
+
+// Route loads the config of one route without the rest of its agency's
+// snapshot. A route that isn't stored is reported as a *nextbus.NotFoundError.
+func (s *Store) Route(ctx context.Context, agencyTag, routeTag string) (nextbus.RouteConfig, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT config FROM nextbus_routes WHERE agency = ? AND tag = ?`, agencyTag, routeTag)
+	rc, err := scanRoute(row)
+	if err == sql.ErrNoRows {
+		return rc, &nextbus.NotFoundError{Kind: "route", AgencyTag: agencyTag, RouteTag: routeTag}
+	}
+	if err != nil {
+		return rc, fmt.Errorf("could not load route: %v", err)
+	}
+	return rc, nil
+}
+
+// StopsIn returns the stops of every stored agency inside box, ordered by
+// agency, route and stop tag.
+func (s *Store) StopsIn(ctx context.Context, box nextbus.BoundingBox) ([]nextbus.AgencyStop, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT s.agency, s.route, s.stop
+		FROM nextbus_stops_rtree r JOIN nextbus_stops s ON s.id = r.id
+		WHERE r.max_lat >= ? AND r.min_lat <= ? AND r.max_lon >= ? AND r.min_lon <= ?`,
+		box.MinLat, box.MaxLat, box.MinLon, box.MaxLon)
+	if err != nil {
+		return nil, fmt.Errorf("could not query stops: %v", err)
+	}
+	defer rows.Close()
+	var stops []nextbus.AgencyStop
+	for rows.Next() {
+		var as nextbus.AgencyStop
+		var data []byte
+		if err := rows.Scan(&as.AgencyTag, &as.RouteTag, &data); err != nil {
+			return nil, fmt.Errorf("could not query stops: %v", err)
+		}
+		if err := xml.Unmarshal(data, &as.Stop); err != nil {
+			return nil, fmt.Errorf("could not query stops: %v", err)
+		}
+		stops = append(stops, as)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not query stops: %v", err)
+	}
+	sort.Slice(stops, func(i, j int) bool {
+		a, b := stops[i], stops[j]
+		if a.AgencyTag != b.AgencyTag {
+			return a.AgencyTag < b.AgencyTag
+		}
+		if a.RouteTag != b.RouteTag {
+			return a.RouteTag < b.RouteTag
+		}
+		return a.Stop.Tag < b.Stop.Tag
+	})
+	return stops, nil
+}
+
+// StopsNear returns the stops of every stored agency within meters of
+// center, nearest first.
+func (s *Store) StopsNear(ctx context.Context, center nextbus.LatLon, meters float64) ([]nextbus.AgencyStop, error) {
+	dLat := meters / (geo.EarthRadius * math.Pi / 180)
+	dLon := dLat / math.Max(0.01, math.Cos(center.Lat*math.Pi/180))
+	candidates, err := s.StopsIn(ctx, nextbus.BoundingBox{
+		MinLat: center.Lat - dLat, MaxLat: center.Lat + dLat,
+		MinLon: center.Lon - dLon, MaxLon: center.Lon + dLon,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var near []nextbus.AgencyStop
+	var distances []float64
+	for _, as := range candidates {
+		p, _ := as.Stop.LatLon()
+		if d := geo.Distance(center, p); d <= meters {
+			near = append(near, as)
+			distances = append(distances, d)
+		}
+	}
+	sort.Stable(byDistance{near, distances})
+	return near, nil
+}
+
+// byDistance sorts stops by their distance from a point.
+type byDistance struct {
+	stops     []nextbus.AgencyStop
+	distances []float64
+}
+
+func (b byDistance) Len() int           { return len(b.stops) }
+func (b byDistance) Less(i, j int) bool { return b.distances[i] < b.distances[j] }
+func (b byDistance) Swap(i, j int) {
+	b.stops[i], b.stops[j] = b.stops[j], b.stops[i]
+	b.distances[i], b.distances[j] = b.distances[j], b.distances[i]
+}
+
+// Arrivals returns the nextbus.ArrivalStore of an agency.
+func (s *Store) Arrivals(agencyTag string) *Arrivals {
+	return &Arrivals{store: s, agencyTag: agencyTag}
+}
+
+// Arrivals is the nextbus.ArrivalStore of one agency in a Store.
+type Arrivals struct {
+	store     *Store
+	agencyTag string
+}
+
+// Save adds records to the store in a single transaction. Records already
+// stored, with the same route, stop, vehicle, trip and Predicted time, are
+// skipped, as when an archiver replays predictions after a restart. Records
+// without a Predicted time are keyed by their Time.
+func (a *Arrivals) Save(records []nextbus.ArrivalRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	tx, err := a.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not save arrivals: %v", err)
+	}
+	for _, r := range records {
+		var predicted sql.NullInt64
+		if !r.Predicted.IsZero() {
+			predicted = sql.NullInt64{Int64: r.Predicted.UnixNano(), Valid: true}
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO nextbus_arrivals
+			(agency, route, stop, direction, vehicle, trip, arrived_at, predicted_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			a.agencyTag, r.RouteTag, r.StopTag, r.DirTag, r.Vehicle, r.TripTag, r.Time.UnixNano(), predicted); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not save arrivals: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not save arrivals: %v", err)
+	}
+	return nil
+}
+
+// Arrivals returns the records with a Time in [from, to), in order.
+func (a *Arrivals) Arrivals(from, to time.Time) ([]nextbus.ArrivalRecord, error) {
+	rows, err := a.store.db.QueryContext(context.Background(), `SELECT route, stop, direction, vehicle, trip, arrived_at, predicted_at
+		FROM nextbus_arrivals WHERE agency = ? AND arrived_at >= ? AND arrived_at < ?
+		ORDER BY arrived_at`, a.agencyTag, from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("could not query arrivals: %v", err)
+	}
+	defer rows.Close()
+	var records []nextbus.ArrivalRecord
+	for rows.Next() {
+		var r nextbus.ArrivalRecord
+		var arrivedAt int64
+		var predictedAt sql.NullInt64
+		if err := rows.Scan(&r.RouteTag, &r.StopTag, &r.DirTag, &r.Vehicle, &r.TripTag, &arrivedAt, &predictedAt); err != nil {
+			return nil, fmt.Errorf("could not query arrivals: %v", err)
+		}
+		r.Time = time.Unix(0, arrivedAt)
+		if predictedAt.Valid {
+			r.Predicted = time.Unix(0, predictedAt.Int64)
+		}
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not query arrivals: %v", err)
+	}
+	return records, nil
+}
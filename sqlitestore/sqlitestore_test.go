@@ -0,0 +1,175 @@
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dinedal/nextbus"
+)
+
+// openStore opens a Store in a new database file, skipping the test if no
+// SQLite driver is linked in.
+func openStore(t *testing.T) *Store {
+	t.Helper()
+	var driver string
+	for _, name := range sql.Drivers() {
+		if name == "sqlite3" || name == "sqlite" {
+			driver = name
+		}
+	}
+	if driver == "" {
+		t.Skip("no SQLite driver is registered; run the tests with -tags sqlite")
+	}
+	db, err := sql.Open(driver, filepath.Join(t.TempDir(), "nextbus.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store, err := New(context.Background(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func testSnapshot(agencyTag string, fetchedAt time.Time) *nextbus.AgencySnapshot {
+	return &nextbus.AgencySnapshot{
+		Agency:    nextbus.Agency{Tag: agencyTag, Title: "Agency " + agencyTag},
+		FetchedAt: fetchedAt,
+		Routes: []nextbus.RouteConfig{
+			{Tag: "N", Title: "N-Judah", StopList: []nextbus.Stop{
+				{Tag: "5205", Title: "Duboce & Church", Lat: "37.7695", Lon: "-122.4290"},
+				{Tag: "5206", Title: "Carl & Cole", Lat: "37.7656", Lon: "-122.4500"},
+			}},
+			{Tag: "J", Title: "J-Church", StopList: []nextbus.Stop{
+				{Tag: "5205", Title: "Duboce & Church", Lat: "37.7695", Lon: "-122.4290"},
+				{Tag: "9999", Title: "Unlocated"},
+			}},
+		},
+	}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t)
+	fetched := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	if err := store.Put(ctx, testSnapshot("sf-muni", fetched)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := store.Get(ctx, "sf-muni")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Agency.Title != "Agency sf-muni" || !s.FetchedAt.Equal(fetched) {
+		t.Errorf("agency: got %+v fetched at %v", s.Agency, s.FetchedAt)
+	}
+	if len(s.Routes) != 2 || s.Routes[0].Tag != "N" || s.Routes[1].Tag != "J" || len(s.Routes[0].StopList) != 2 {
+		t.Errorf("routes: got %+v", s.Routes)
+	}
+	if missing, err := store.Get(ctx, "ac-transit"); missing != nil || err != nil {
+		t.Errorf("missing agency: got %+v, %v", missing, err)
+	}
+	tags, err := store.Agencies(ctx)
+	if err != nil || len(tags) != 1 || tags[0] != "sf-muni" {
+		t.Errorf("agencies: got %v, %v", tags, err)
+	}
+}
+
+func TestStoreRoute(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t)
+	if err := store.Put(ctx, testSnapshot("sf-muni", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+	dir := nextbus.NewDirectoryWithStore(nextbus.NewClient(nil), store)
+	rc, err := dir.Route(ctx, "sf-muni", "J")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.Title != "J-Church" || len(rc.StopList) != 2 {
+		t.Errorf("route J: got %+v", rc)
+	}
+	if _, err := dir.Route(ctx, "sf-muni", "K"); err == nil {
+		t.Error("expected an error for a route that isn't stored")
+	} else if _, notFound := err.(*nextbus.NotFoundError); !notFound {
+		t.Errorf("expected a *nextbus.NotFoundError, got %v", err)
+	}
+}
+
+func TestStoreStops(t *testing.T) {
+	ctx := context.Background()
+	store := openStore(t)
+	if err := store.Put(ctx, testSnapshot("sf-muni", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+
+	stops, err := store.StopsIn(ctx, nextbus.BoundingBox{MinLat: 37.76, MaxLat: 37.77, MinLon: -122.44, MaxLon: -122.42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stops) != 2 || stops[0].RouteTag != "J" || stops[1].RouteTag != "N" || stops[0].Stop.Tag != "5205" {
+		t.Errorf("stops in box: got %+v", stops)
+	}
+
+	near, err := store.StopsNear(ctx, nextbus.LatLon{Lat: 37.7656, Lon: -122.4501}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(near) != 1 || near[0].Stop.Tag != "5206" {
+		t.Errorf("stops near: got %+v", near)
+	}
+
+	// Replacing the snapshot replaces its stops.
+	smaller := testSnapshot("sf-muni", time.Now())
+	smaller.Routes = smaller.Routes[:1]
+	if err := store.Put(ctx, smaller); err != nil {
+		t.Fatal(err)
+	}
+	stops, err = store.StopsIn(ctx, nextbus.BoundingBox{MinLat: 37, MaxLat: 38, MinLon: -123, MaxLon: -122})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stops) != 2 {
+		t.Errorf("stops after replacing the snapshot: got %+v", stops)
+	}
+}
+
+func TestArrivalsSkipReplays(t *testing.T) {
+	store := openStore(t)
+	arrivals := store.Arrivals("sf-muni")
+	now := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	records := []nextbus.ArrivalRecord{
+		{RouteTag: "N", StopTag: "5205", Vehicle: "1500", TripTag: "7001", Time: now, Predicted: now.Add(time.Minute)},
+		{RouteTag: "N", StopTag: "5205", Vehicle: "1501", Time: now.Add(5 * time.Minute)},
+	}
+	if err := arrivals.Save(records); err != nil {
+		t.Fatal(err)
+	}
+	// A replay polled at another time reports the same arrival.
+	replayed := records[0]
+	replayed.Time = now.Add(20 * time.Second)
+	if err := arrivals.Save([]nextbus.ArrivalRecord{replayed, records[1]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Arrivals("ac-transit").Save(records); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := arrivals.Arrivals(now, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("got %d arrivals, want 2: %+v", len(found), found)
+	}
+	if !found[0].Time.Equal(now) || !found[0].Predicted.Equal(now.Add(time.Minute)) || found[0].TripTag != "7001" {
+		t.Errorf("first arrival: got %+v", found[0])
+	}
+	if !found[1].Predicted.IsZero() {
+		t.Errorf("an arrival without a predicted time: got %+v", found[1])
+	}
+}
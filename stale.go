@@ -0,0 +1,49 @@
+package nextbus
+
+import (
+	"strconv"
+	"time"
+)
+
+// StalePolicy describes which vehicle reports are too unreliable to show.
+type StalePolicy struct {
+	// MaxAge drops vehicles whose last report is older than it. Zero keeps
+	// vehicles of any age.
+	MaxAge time.Duration
+	// DropUnpredictable drops vehicles NextBus reports as not predictable,
+	// which are usually out of service or off route.
+	DropUnpredictable bool
+}
+
+// Keep reports whether v passes the policy. Vehicles whose age can't be parsed
+// are kept.
+func (p StalePolicy) Keep(v VehicleLocation) bool {
+	if p.DropUnpredictable && v.Predictable == "false" {
+		return false
+	}
+	if p.MaxAge > 0 {
+		if secs, err := strconv.Atoi(v.SecsSinceReport); err == nil && time.Duration(secs)*time.Second > p.MaxAge {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the vehicles that pass the policy.
+func (p StalePolicy) Filter(vehicles []VehicleLocation) []VehicleLocation {
+	result := make([]VehicleLocation, 0, len(vehicles))
+	for _, v := range vehicles {
+		if p.Keep(v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// WithStaleVehicleFilter makes the Client drop vehicles failing policy from
+// every vehicle locations response.
+func WithStaleVehicleFilter(policy StalePolicy) Option {
+	return func(c *Client) {
+		c.stalePolicy = &policy
+	}
+}
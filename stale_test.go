@@ -0,0 +1,34 @@
+package nextbus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStalePolicy(t *testing.T) {
+	vehicles := []VehicleLocation{
+		{ID: "fresh", SecsSinceReport: "10", Predictable: "true"},
+		{ID: "old", SecsSinceReport: "600", Predictable: "true"},
+		{ID: "ghost", SecsSinceReport: "5", Predictable: "false"},
+		{ID: "unknown"},
+	}
+	ids := func(vs []VehicleLocation) []string {
+		var result []string
+		for _, v := range vs {
+			result = append(result, v.ID)
+		}
+		return result
+	}
+
+	equals(t, []string{"fresh", "old", "ghost", "unknown"}, ids(StalePolicy{}.Filter(vehicles)))
+	equals(t, []string{"fresh", "ghost", "unknown"}, ids(StalePolicy{MaxAge: 2 * time.Minute}.Filter(vehicles)))
+	equals(t, []string{"fresh", "unknown"}, ids(StalePolicy{MaxAge: 2 * time.Minute, DropUnpredictable: true}.Filter(vehicles)))
+}
+
+func TestWithStaleVehicleFilter(t *testing.T) {
+	nb := NewClient(testingClient(t), WithStaleVehicleFilter(StalePolicy{MaxAge: 4 * time.Second}))
+	found, err := nb.GetVehicleLocations("alpha")
+	ok(t, err)
+	equals(t, 1, len(found.VehicleList))
+	equals(t, "1111", found.VehicleList[0].ID)
+}
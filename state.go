@@ -0,0 +1,190 @@
+package nextbus
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// StateVersion is the version of the format written by the Save methods.
+// Restore rejects state written by a newer version.
+const StateVersion = 1
+
+// Persistent is implemented by the long-running types whose state can be
+// saved and restored across restarts of a service: VehicleLocationSession,
+// PredictionWatcher and VehicleDiffer. The state is written as JSON, so it can
+// be kept in a file, a database row or a key-value store alike.
+type Persistent interface {
+	Save(w io.Writer) error
+	Restore(r io.Reader) error
+}
+
+// SaveFile saves p's state to path, replacing any previous state atomically.
+func SaveFile(path string, p Persistent) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not save state: %v", err)
+	}
+	writeErr := p.Save(tmp)
+	closeErr := tmp.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil {
+		writeErr = os.Rename(tmp.Name(), path)
+	}
+	if writeErr != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not save state: %v", writeErr)
+	}
+	return nil
+}
+
+// RestoreFile restores p's state from path. A missing file, as on a service's
+// first start, leaves p unchanged and isn't an error.
+func RestoreFile(path string, p Persistent) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not restore state: %v", err)
+	}
+	defer f.Close()
+	return p.Restore(f)
+}
+
+// saveState writes v, the state of a kind of value, as JSON.
+func saveState(w io.Writer, kind string, v interface{}) error {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return fmt.Errorf("could not save %s state: %v", kind, err)
+	}
+	return nil
+}
+
+// restoreState reads state written by saveState into v, whose Version field
+// is returned by version.
+func restoreState(r io.Reader, kind string, v interface{}, version func() int) error {
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("could not restore %s state: %v", kind, err)
+	}
+	if version() > StateVersion {
+		return fmt.Errorf("could not restore %s state: unsupported version %d", kind, version())
+	}
+	return nil
+}
+
+type sessionState struct {
+	Version   int
+	AgencyTag string
+	RouteTag  string
+	LastTime  string
+}
+
+// Save writes the lastTime the session will send with its next request.
+func (s *VehicleLocationSession) Save(w io.Writer) error {
+	return saveState(w, "vehicle location session", sessionState{StateVersion, s.agencyTag, s.routeTag, s.LastTime()})
+}
+
+// Restore resumes a session from state written by Save, so its next request
+// only returns vehicles that have reported since the saved one. The state must
+// be for the same agency and route.
+func (s *VehicleLocationSession) Restore(r io.Reader) error {
+	var state sessionState
+	if err := restoreState(r, "vehicle location session", &state, func() int { return state.Version }); err != nil {
+		return err
+	}
+	if state.AgencyTag != s.agencyTag || state.RouteTag != s.routeTag {
+		return fmt.Errorf("could not restore vehicle location session state: saved for agency %q route %q", state.AgencyTag, state.RouteTag)
+	}
+	s.mu.Lock()
+	s.lastTime = state.LastTime
+	s.mu.Unlock()
+	return nil
+}
+
+type watcherState struct {
+	Version     int
+	AgencyTag   string
+	Stops       []RouteStop
+	Predictions []PredictionData
+	Updated     time.Time
+	Messages    []string
+}
+
+// Save writes the watched stops, the latest predictions and the service
+// messages already published.
+func (w *PredictionWatcher) Save(out io.Writer) error {
+	w.mu.RLock()
+	state := watcherState{StateVersion, w.agencyTag, w.Stops(), w.predictions, w.updated, nil}
+	for key := range w.messages {
+		state.Messages = append(state.Messages, key)
+	}
+	w.mu.RUnlock()
+	sort.Strings(state.Messages)
+	return saveState(out, "prediction watcher", state)
+}
+
+// Restore resumes a watcher from state written by Save: it watches the saved
+// stops, reports the saved predictions until its next poll and doesn't publish
+// the saved messages again. The state must be for the same agency. Restore
+// must not be called while the watcher is running.
+func (w *PredictionWatcher) Restore(in io.Reader) error {
+	var state watcherState
+	if err := restoreState(in, "prediction watcher", &state, func() int { return state.Version }); err != nil {
+		return err
+	}
+	if state.AgencyTag != w.agencyTag {
+		return fmt.Errorf("could not restore prediction watcher state: saved for agency %q", state.AgencyTag)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stops = state.Stops
+	w.predictions = state.Predictions
+	w.updated = state.Updated
+	w.messages = map[string]bool{}
+	for _, key := range state.Messages {
+		w.messages[key] = true
+	}
+	return nil
+}
+
+type differState struct {
+	Version  int
+	Vehicles []trackedVehicleState
+}
+
+type trackedVehicleState struct {
+	Location VehicleLocation
+	Reported time.Time
+	Stale    bool
+}
+
+// Save writes the vehicles being tracked with their last reports.
+func (d *VehicleDiffer) Save(w io.Writer) error {
+	state := differState{Version: StateVersion}
+	for _, tracked := range d.vehicles {
+		state.Vehicles = append(state.Vehicles, trackedVehicleState{tracked.location, tracked.reported, tracked.stale})
+	}
+	sort.Slice(state.Vehicles, func(i, j int) bool { return state.Vehicles[i].Location.ID < state.Vehicles[j].Location.ID })
+	return saveState(w, "vehicle differ", state)
+}
+
+// Restore replaces the tracked vehicles with state written by Save, so
+// vehicles tracked before a restart aren't reported as added again.
+func (d *VehicleDiffer) Restore(r io.Reader) error {
+	var state differState
+	if err := restoreState(r, "vehicle differ", &state, func() int { return state.Version }); err != nil {
+		return err
+	}
+	d.vehicles = make(map[string]*trackedVehicle, len(state.Vehicles))
+	for _, v := range state.Vehicles {
+		d.vehicles[v.Location.ID] = &trackedVehicle{v.Location, v.Reported, v.Stale}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package nextbus
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionSaveRestore(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	s := NewVehicleLocationSession(nb, "alpha", "")
+	_, err := s.Next()
+	ok(t, err)
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	ok(t, SaveFile(path, s))
+	restarted := NewVehicleLocationSession(nb, "alpha", "")
+	ok(t, RestoreFile(path, restarted))
+	equals(t, "1234567890123", restarted.LastTime())
+	second, err := restarted.Next()
+	ok(t, err)
+	equals(t, 1, len(second.VehicleList))
+
+	other := NewVehicleLocationSession(nb, "alpha", "1")
+	assert(t, RestoreFile(path, other) != nil, "expected an error restoring another route's session")
+
+	fresh := NewVehicleLocationSession(nb, "alpha", "")
+	ok(t, RestoreFile(filepath.Join(t.TempDir(), "missing.json"), fresh))
+	equals(t, "", fresh.LastTime())
+}
+
+func TestWatcherSaveRestore(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	w := NewPredictionWatcher(nb, "alpha", RouteStop{"1", "1123"}, RouteStop{"1", "1124"})
+	ok(t, w.Poll())
+	var state bytes.Buffer
+	ok(t, w.Save(&state))
+
+	restarted := NewPredictionWatcher(nb, "alpha")
+	ok(t, restarted.Restore(&state))
+	equals(t, w.Stops(), restarted.Stops())
+	predictions, updated := w.Predictions()
+	restoredPredictions, restoredUpdated := restarted.Predictions()
+	equals(t, predictions, restoredPredictions)
+	assert(t, updated.Equal(restoredUpdated), "expected update time %v, got %v", updated, restoredUpdated)
+
+	// The message published before the restart isn't published again.
+	var messages int
+	restarted.Bus = &Bus{}
+	restarted.Bus.Subscribe(func(BusEvent) { messages++ }, TopicMessages)
+	ok(t, restarted.Poll())
+	equals(t, 0, messages)
+}
+
+func TestDifferSaveRestore(t *testing.T) {
+	d := NewVehicleDiffer()
+	start := time.Unix(1490564600, 0)
+	vehicles := &LocationResponse{VehicleList: []VehicleLocation{
+		{ID: "1", Lat: "37.7000", Lon: "-122.4000", Heading: "350", SecsSinceReport: "0"},
+	}}
+	d.Apply(vehicles, start)
+	var state bytes.Buffer
+	ok(t, d.Save(&state))
+
+	restarted := NewVehicleDiffer()
+	ok(t, restarted.Restore(&state))
+	changes := restarted.Apply(vehicles, start.Add(30*time.Second))
+	assert(t, changes.Empty(), "expected no changes after restoring, got %+v", changes)
+}
+
+func TestRestoreNewerVersion(t *testing.T) {
+	err := NewVehicleDiffer().Restore(strings.NewReader(`{"Version": 99}`))
+	assert(t, err != nil && strings.Contains(err.Error(), "unsupported version"), "expected a version error, got %v", err)
+}
@@ -0,0 +1,198 @@
+package nextbus
+
+import (
+	"math"
+)
+
+// StationStop is a route-scoped stop belonging to a Station.
+type StationStop struct {
+	RouteTag string
+	Stop     Stop
+}
+
+// Station is one physical location served by one or more route-scoped stops.
+type Station struct {
+	// Title is the most common title among the station's stops.
+	Title string
+	// Lat and Lon are the centroid of the station's stops.
+	Lat   float64
+	Lon   float64
+	Stops []StationStop
+}
+
+// RouteTags returns the distinct routes serving the station, in order.
+func (s Station) RouteTags() []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, ss := range s.Stops {
+		if !seen[ss.RouteTag] {
+			seen[ss.RouteTag] = true
+			result = append(result, ss.RouteTag)
+		}
+	}
+	return result
+}
+
+// Stations clusters the snapshot's stops; see ClusterStops.
+func (s *AgencySnapshot) Stations(maxMeters float64) []Station {
+	return ClusterStops(s.Routes, maxMeters)
+}
+
+// ClusterStops groups the stops of routes into stations. Stops sharing a
+// StopID, or within maxMeters of each other, belong to the same station;
+// clustering is transitive. Stations are returned in the order their first
+// stop appears in routes.
+func ClusterStops(routes []RouteConfig, maxMeters float64) []Station {
+	var stops []StationStop
+	var entries []clusterEntry
+	for _, rc := range routes {
+		for _, stop := range rc.StopList {
+			stops = append(stops, StationStop{rc.Tag, stop})
+			entries = append(entries, newClusterEntry(stop, stop.StopID))
+		}
+	}
+
+	var stations []Station
+	for _, c := range clusterEntries(entries, maxMeters) {
+		station := Station{Title: c.title, Lat: c.lat, Lon: c.lon}
+		for _, i := range c.members {
+			station.Stops = append(station.Stops, stops[i])
+		}
+		stations = append(stations, station)
+	}
+	return stations
+}
+
+// clusterEntry is a stop being clustered. Entries with the same non-empty id
+// always belong to the same cluster.
+type clusterEntry struct {
+	id       string
+	title    string
+	lat, lon float64
+	located  bool
+}
+
+func newClusterEntry(stop Stop, id string) clusterEntry {
+	lat, lon, ok := parseLatLon(stop.Lat, stop.Lon)
+	return clusterEntry{id, stop.Title, lat, lon, ok}
+}
+
+// cluster is a group of clusterEntries, identified by their indices.
+type cluster struct {
+	// title is the most common title among the members.
+	title string
+	// lat and lon are the centroid of the located members.
+	lat, lon float64
+	members  []int
+}
+
+// clusterEntries groups entries sharing an id or within maxMeters of each
+// other, transitively. Clusters are returned in the order of their first
+// member.
+func clusterEntries(entries []clusterEntry, maxMeters float64) []cluster {
+	sumLat, located := 0.0, 0
+	for _, e := range entries {
+		if e.located {
+			sumLat += e.lat
+			located++
+		}
+	}
+
+	parent := make([]int, len(entries))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		ri, rj := find(i), find(j)
+		if ri == rj {
+			return
+		}
+		if ri < rj {
+			parent[rj] = ri
+		} else {
+			parent[ri] = rj
+		}
+	}
+
+	byID := map[string]int{}
+	for i, e := range entries {
+		if e.id == "" {
+			continue
+		}
+		if j, seen := byID[e.id]; seen {
+			union(i, j)
+		} else {
+			byID[e.id] = i
+		}
+	}
+
+	if maxMeters > 0 && located != 0 {
+		// Bucket stops into a grid of cells at least maxMeters wide so only
+		// neighbouring cells need to be compared.
+		cellLat := maxMeters / (earthRadius * math.Pi / 180)
+		cellLon := cellLat / math.Max(0.01, math.Cos(sumLat/float64(located)*math.Pi/180))
+		type cell struct{ x, y int }
+		grid := map[cell][]int{}
+		for i, e := range entries {
+			if !e.located {
+				continue
+			}
+			c := cell{int(math.Floor(e.lat / cellLat)), int(math.Floor(e.lon / cellLon))}
+			for dx := -1; dx <= 1; dx++ {
+				for dy := -1; dy <= 1; dy++ {
+					for _, j := range grid[cell{c.x + dx, c.y + dy}] {
+						if haversine(e.lat, e.lon, entries[j].lat, entries[j].lon) <= maxMeters {
+							union(i, j)
+						}
+					}
+				}
+			}
+			grid[c] = append(grid[c], i)
+		}
+	}
+
+	index := map[int]int{}
+	var clusters []cluster
+	var counts []map[string]int
+	var sums [][3]float64
+	for i, e := range entries {
+		root := find(i)
+		ci, seen := index[root]
+		if !seen {
+			ci = len(clusters)
+			index[root] = ci
+			clusters = append(clusters, cluster{})
+			counts = append(counts, map[string]int{})
+			sums = append(sums, [3]float64{})
+		}
+		clusters[ci].members = append(clusters[ci].members, i)
+		counts[ci][e.title]++
+		if e.located {
+			sums[ci][0] += e.lat
+			sums[ci][1] += e.lon
+			sums[ci][2]++
+		}
+	}
+
+	for ci := range clusters {
+		best := 0
+		for _, i := range clusters[ci].members {
+			if n := counts[ci][entries[i].title]; n > best {
+				best = n
+				clusters[ci].title = entries[i].title
+			}
+		}
+		if n := sums[ci][2]; n != 0 {
+			clusters[ci].lat = sums[ci][0] / n
+			clusters[ci].lon = sums[ci][1] / n
+		}
+	}
+	return clusters
+}
@@ -0,0 +1,36 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestClusterStops(t *testing.T) {
+	routes := []RouteConfig{
+		{Tag: "F", StopList: []Stop{
+			{Tag: "f1", Title: "Market St & Castro St", Lat: "37.7625", Lon: "-122.4350", StopID: "1"},
+			{Tag: "f2", Title: "Market St & Noe St", Lat: "37.7645", Lon: "-122.4330"},
+		}},
+		{Tag: "K", StopList: []Stop{
+			{Tag: "k1", Title: "Castro Station", Lat: "37.7626", Lon: "-122.4351"},
+			{Tag: "k2", Title: "Market St & Castro St", Lat: "37.7700", Lon: "-122.4400", StopID: "1"},
+		}},
+		{Tag: "L", StopList: []Stop{
+			{Tag: "l1", Title: "Market St & Castro St", Lat: "37.7627", Lon: "-122.4352"},
+		}},
+	}
+
+	stations := ClusterStops(routes, 30)
+	equals(t, 2, len(stations))
+	equals(t, "Market St & Castro St", stations[0].Title)
+	equals(t, 4, len(stations[0].Stops))
+	equals(t, []string{"F", "K", "L"}, stations[0].RouteTags())
+	equals(t, "f2", stations[1].Stops[0].Stop.Tag)
+}
+
+func TestClusterStopsWithoutDistance(t *testing.T) {
+	routes := []RouteConfig{
+		{Tag: "F", StopList: []Stop{{Tag: "f1", Lat: "37.7625", Lon: "-122.4350"}}},
+		{Tag: "K", StopList: []Stop{{Tag: "k1", Lat: "37.7625", Lon: "-122.4350"}}},
+	}
+	equals(t, 2, len(ClusterStops(routes, 0)))
+}
@@ -0,0 +1,75 @@
+package nextbus
+
+import (
+	"sort"
+)
+
+// StationArrival is one predicted arrival at a station.
+type StationArrival struct {
+	RouteTag   string
+	RouteTitle string
+	StopTag    string
+	// Direction is the title of the direction the vehicle is traveling in.
+	Direction  string
+	Prediction Prediction
+}
+
+// StationPredictions are the combined predictions of every stop of a station.
+type StationPredictions struct {
+	Station Station
+	// Arrivals are ordered by predicted time.
+	Arrivals []StationArrival
+	// Messages holds each distinct message of the station's stops once.
+	Messages []Message
+}
+
+// GroupByStation merges prediction data by the station its stop belongs to,
+// giving one arrivals list per physical location. Stations are returned in
+// the order given, skipping those without prediction data; prediction data
+// for stops outside every station is ignored.
+func GroupByStation(stations []Station, predictions []PredictionData) []StationPredictions {
+	index := map[RouteStop]int{}
+	for i, s := range stations {
+		for _, ss := range s.Stops {
+			index[RouteStop{ss.RouteTag, ss.Stop.Tag}] = i
+		}
+	}
+
+	grouped := make([]*StationPredictions, len(stations))
+	seenMessages := make([]map[string]bool, len(stations))
+	for _, pd := range predictions {
+		i, found := index[RouteStop{pd.RouteTag, pd.StopTag}]
+		if !found {
+			continue
+		}
+		if grouped[i] == nil {
+			grouped[i] = &StationPredictions{Station: stations[i]}
+			seenMessages[i] = map[string]bool{}
+		}
+		sp := grouped[i]
+		for _, dir := range pd.PredictionDirectionList {
+			for _, p := range dir.PredictionList {
+				sp.Arrivals = append(sp.Arrivals, StationArrival{pd.RouteTag, pd.RouteTitle, pd.StopTag, dir.Title, p})
+			}
+		}
+		for _, m := range pd.MessageList {
+			if !seenMessages[i][m.Text] {
+				seenMessages[i][m.Text] = true
+				sp.Messages = append(sp.Messages, m)
+			}
+		}
+	}
+
+	var result []StationPredictions
+	for _, sp := range grouped {
+		if sp == nil {
+			continue
+		}
+		arrivals := sp.Arrivals
+		sort.SliceStable(arrivals, func(a, b int) bool {
+			return arrivals[a].Prediction.ArrivalTime().Before(arrivals[b].Prediction.ArrivalTime())
+		})
+		result = append(result, *sp)
+	}
+	return result
+}
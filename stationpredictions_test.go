@@ -0,0 +1,38 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestGroupByStation(t *testing.T) {
+	stations := ClusterStops([]RouteConfig{
+		{Tag: "N", StopList: []Stop{{Tag: "6992", Title: "Embarcadero", Lat: "37.7929", Lon: "-122.3969"}}},
+		{Tag: "J", StopList: []Stop{{Tag: "7217", Title: "Embarcadero", Lat: "37.7930", Lon: "-122.3968"}}},
+		{Tag: "F", StopList: []Stop{{Tag: "5000", Title: "Castro", Lat: "37.7625", Lon: "-122.4350"}}},
+	}, 50)
+	equals(t, 2, len(stations))
+
+	message := Message{Text: "Elevator out of service"}
+	predictions := []PredictionData{
+		{RouteTag: "N", StopTag: "6992", MessageList: []Message{message}, PredictionDirectionList: []PredictionDirection{{
+			Title:          "Inbound",
+			PredictionList: []Prediction{{EpochTime: "3000", Vehicle: "n1"}},
+		}}},
+		{RouteTag: "J", StopTag: "7217", MessageList: []Message{message}, PredictionDirectionList: []PredictionDirection{{
+			Title:          "Inbound",
+			PredictionList: []Prediction{{EpochTime: "1000", Vehicle: "j1"}, {EpochTime: "5000", Vehicle: "j2"}},
+		}}},
+		{RouteTag: "K", StopTag: "9999"},
+	}
+
+	grouped := GroupByStation(stations, predictions)
+	equals(t, 1, len(grouped))
+	equals(t, "Embarcadero", grouped[0].Station.Title)
+	var vehicles []string
+	for _, a := range grouped[0].Arrivals {
+		vehicles = append(vehicles, a.Prediction.Vehicle)
+	}
+	equals(t, []string{"j1", "n1", "j2"}, vehicles)
+	equals(t, "N", grouped[0].Arrivals[1].RouteTag)
+	equals(t, []Message{message}, grouped[0].Messages)
+}
@@ -0,0 +1,99 @@
+package nextbus
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// StopRecord is one stop of an agency as a flat document for a search
+// engine, with the routes serving it merged into one record.
+type StopRecord struct {
+	// ID is unique across agencies: the agency tag and the stop tag joined by
+	// a colon.
+	ID        string   `json:"id"`
+	AgencyTag string   `json:"agency"`
+	StopTag   string   `json:"stopTag"`
+	StopID    string   `json:"stopId,omitempty"`
+	Title     string   `json:"title"`
+	Lat       float64  `json:"lat"`
+	Lon       float64  `json:"lon"`
+	RouteTags []string `json:"routes"`
+}
+
+// StopRecords returns a record for every distinct stop of the snapshot, in
+// the order the stops first appear in its routes. Stops without a valid
+// location are left out.
+func (s *AgencySnapshot) StopRecords() []StopRecord {
+	index := map[string]int{}
+	var records []StopRecord
+	for _, rc := range s.Routes {
+		for _, stop := range rc.StopList {
+			if i, seen := index[stop.Tag]; seen {
+				if !contains(records[i].RouteTags, rc.Tag) {
+					records[i].RouteTags = append(records[i].RouteTags, rc.Tag)
+				}
+				continue
+			}
+			lat, lon, located := parseLatLon(stop.Lat, stop.Lon)
+			if !located {
+				continue
+			}
+			index[stop.Tag] = len(records)
+			records = append(records, StopRecord{
+				ID:        s.Agency.Tag + ":" + stop.Tag,
+				AgencyTag: s.Agency.Tag,
+				StopTag:   stop.Tag,
+				StopID:    stop.StopID,
+				Title:     stop.Title,
+				Lat:       lat,
+				Lon:       lon,
+				RouteTags: []string{rc.Tag},
+			})
+		}
+	}
+	return records
+}
+
+// WriteStopsJSON writes the snapshot's StopRecords as JSON Lines, one
+// document per line, the format accepted by Typesense's import endpoint and,
+// after pairing each line with an action, Elasticsearch's bulk API.
+func (s *AgencySnapshot) WriteStopsJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, r := range s.StopRecords() {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("could not write stops JSON: %v", err)
+		}
+	}
+	return nil
+}
+
+// stopsCSVHeader names the columns written by WriteStopsCSV.
+var stopsCSVHeader = []string{"id", "agency", "stop_tag", "stop_id", "title", "lat", "lon", "routes"}
+
+// WriteStopsCSV writes the snapshot's StopRecords as CSV with a header row,
+// suitable for loading into an SQLite FTS table with .import. Route tags are
+// joined by spaces so they are tokenized as separate terms.
+func (s *AgencySnapshot) WriteStopsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	rows := [][]string{stopsCSVHeader}
+	for _, r := range s.StopRecords() {
+		rows = append(rows, []string{
+			r.ID,
+			r.AgencyTag,
+			r.StopTag,
+			r.StopID,
+			r.Title,
+			strconv.FormatFloat(r.Lat, 'f', -1, 64),
+			strconv.FormatFloat(r.Lon, 'f', -1, 64),
+			strings.Join(r.RouteTags, " "),
+		})
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("could not write stops CSV: %v", err)
+	}
+	return nil
+}
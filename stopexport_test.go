@@ -0,0 +1,49 @@
+package nextbus
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func exportSnapshot() *AgencySnapshot {
+	return &AgencySnapshot{
+		Agency: Agency{Tag: "sf-muni"},
+		Routes: []RouteConfig{
+			{Tag: "N", StopList: []Stop{
+				{Tag: "5205", Title: "Duboce Ave & Church St", Lat: "37.7693", Lon: "-122.4293", StopID: "15205"},
+				{Tag: "4006", Title: "Market St, Station", Lat: "37.7752", Lon: "-122.4192"},
+			}},
+			{Tag: "J", StopList: []Stop{
+				{Tag: "5205", Title: "Duboce Ave & Church St", Lat: "37.7693", Lon: "-122.4293", StopID: "15205"},
+				{Tag: "9999", Title: "Nowhere"},
+			}},
+		},
+	}
+}
+
+func TestStopRecords(t *testing.T) {
+	records := exportSnapshot().StopRecords()
+	equals(t, 2, len(records))
+	equals(t, StopRecord{ID: "sf-muni:5205", AgencyTag: "sf-muni", StopTag: "5205", StopID: "15205", Title: "Duboce Ave & Church St", Lat: 37.7693, Lon: -122.4293, RouteTags: []string{"N", "J"}}, records[0])
+	equals(t, []string{"N"}, records[1].RouteTags)
+}
+
+func TestWriteStops(t *testing.T) {
+	var buf bytes.Buffer
+	ok(t, exportSnapshot().WriteStopsJSON(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	equals(t, 2, len(lines))
+	var doc map[string]interface{}
+	ok(t, json.Unmarshal([]byte(lines[1]), &doc))
+	equals(t, "sf-muni:4006", doc["id"])
+	_, hasStopID := doc["stopId"]
+	assert(t, !hasStopID, "expected an empty stopId to be left out of %s", lines[1])
+
+	buf.Reset()
+	ok(t, exportSnapshot().WriteStopsCSV(&buf))
+	equals(t, "id,agency,stop_tag,stop_id,title,lat,lon,routes\n"+
+		"sf-muni:5205,sf-muni,5205,15205,Duboce Ave & Church St,37.7693,-122.4293,N J\n"+
+		"sf-muni:4006,sf-muni,4006,,\"Market St, Station\",37.7752,-122.4192,N\n", buf.String())
+}
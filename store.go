@@ -0,0 +1,173 @@
+package nextbus
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RouteConfigStore holds the route configs of a single agency in memory so that
+// lookups never wait on a routeConfig download. The configs are refreshed in
+// the background by Run.
+type RouteConfigStore struct {
+	client    *Client
+	agencyTag string
+	refresh   chan struct{}
+
+	// OnError, if set, is called with any error from a background refresh.
+	OnError func(error)
+	// MinObserveInterval is the least time Observe leaves between the last
+	// refresh and the one it requests, so that a stop missing from every
+	// refresh isn't downloaded again on each poll. Zero uses
+	// DefaultMinObserveInterval.
+	MinObserveInterval time.Duration
+
+	mu        sync.RWMutex
+	routes    map[string]RouteConfig
+	order     []string
+	stops     map[string]map[string]bool
+	refreshed time.Time
+}
+
+// DefaultMinObserveInterval is the MinObserveInterval of a RouteConfigStore
+// that doesn't set one.
+const DefaultMinObserveInterval = 5 * time.Minute
+
+// DefaultRouteConfigInterval is the interval Run uses when given none.
+const DefaultRouteConfigInterval = 24 * time.Hour
+
+// NewRouteConfigStore creates an empty RouteConfigStore for an agency. Call
+// Refresh or Run to populate it.
+func NewRouteConfigStore(client *Client, agencyTag string) *RouteConfigStore {
+	return &RouteConfigStore{
+		client:    client,
+		agencyTag: agencyTag,
+		refresh:   make(chan struct{}, 1),
+		routes:    map[string]RouteConfig{},
+		stops:     map[string]map[string]bool{},
+	}
+}
+
+// Refresh downloads the agency's route configs and replaces the stored ones.
+// On error the previously stored configs are kept.
+func (s *RouteConfigStore) Refresh() error {
+	return s.RefreshContext(context.Background())
+}
+
+// RefreshContext is like Refresh but uses ctx for the requests.
+func (s *RouteConfigStore) RefreshContext(ctx context.Context) error {
+	configs, err := s.client.GetRouteConfigContext(ctx, s.agencyTag)
+	if err != nil {
+		return err
+	}
+	s.Set(configs)
+	return nil
+}
+
+// Set replaces the stored configs.
+func (s *RouteConfigStore) Set(configs []RouteConfig) {
+	routes := make(map[string]RouteConfig, len(configs))
+	stops := make(map[string]map[string]bool, len(configs))
+	order := make([]string, 0, len(configs))
+	for _, rc := range configs {
+		routes[rc.Tag] = rc
+		order = append(order, rc.Tag)
+		tags := make(map[string]bool, len(rc.StopList))
+		for _, stop := range rc.StopList {
+			tags[stop.Tag] = true
+		}
+		stops[rc.Tag] = tags
+	}
+
+	s.mu.Lock()
+	s.routes = routes
+	s.stops = stops
+	s.order = order
+	s.refreshed = s.client.now()
+	s.mu.Unlock()
+}
+
+// Route returns the stored config for routeTag.
+func (s *RouteConfigStore) Route(routeTag string) (RouteConfig, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rc, found := s.routes[routeTag]
+	return rc, found
+}
+
+// Routes returns every stored config in the order NextBus returned them.
+func (s *RouteConfigStore) Routes() []RouteConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]RouteConfig, 0, len(s.order))
+	for _, tag := range s.order {
+		result = append(result, s.routes[tag])
+	}
+	return result
+}
+
+// HasStop reports whether stopTag is a known stop on routeTag.
+func (s *RouteConfigStore) HasStop(routeTag, stopTag string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stops[routeTag][stopTag]
+}
+
+// LastRefresh returns the time the stored configs were last replaced, or the
+// zero time if the store has never been populated.
+func (s *RouteConfigStore) LastRefresh() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.refreshed
+}
+
+// RequestRefresh asks Run to refresh the store as soon as possible. Requests
+// made while a refresh is already pending are merged into it.
+func (s *RouteConfigStore) RequestRefresh() {
+	select {
+	case s.refresh <- struct{}{}:
+	default:
+	}
+}
+
+// Observe checks predictions against the stored configs and requests a
+// refresh if any of them reference a route or stop the store doesn't know
+// about, which usually means the agency has changed its configuration. No
+// refresh is requested within MinObserveInterval of the last one.
+func (s *RouteConfigStore) Observe(predictions []PredictionData) {
+	gap := s.MinObserveInterval
+	if gap <= 0 {
+		gap = DefaultMinObserveInterval
+	}
+	if last := s.LastRefresh(); !last.IsZero() && s.client.now().Sub(last) < gap {
+		return
+	}
+	for _, pd := range predictions {
+		if !s.HasStop(pd.RouteTag, pd.StopTag) {
+			s.RequestRefresh()
+			return
+		}
+	}
+}
+
+// Run refreshes the store immediately, then again every interval and whenever
+// a refresh is requested, until ctx is done. An interval of zero or less uses
+// DefaultRouteConfigInterval.
+func (s *RouteConfigStore) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRouteConfigInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.RefreshContext(ctx); err != nil && s.OnError != nil {
+			s.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.refresh:
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package nextbus
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRouteConfigStore(t *testing.T) {
+	s := NewRouteConfigStore(NewClient(testingClient(t)), "alpha")
+	_, found := s.Route("1")
+	assert(t, !found, "expected an empty store before the first refresh")
+
+	ok(t, s.Refresh())
+	rc, found := s.Route("1")
+	assert(t, found, "expected route 1 after refresh")
+	equals(t, "1-first", rc.Title)
+	equals(t, 1, len(s.Routes()))
+	assert(t, s.HasStop("1", "1234"), "expected stop 1234 on route 1")
+	assert(t, !s.HasStop("1", "9999"), "did not expect stop 9999 on route 1")
+	assert(t, !s.LastRefresh().IsZero(), "expected LastRefresh to be set")
+}
+
+func TestRouteConfigStoreObserveUnknownStop(t *testing.T) {
+	now := time.Unix(1487246400, 0)
+	s := NewRouteConfigStore(NewClient(testingClient(t), WithClock(fixedClock(&now))), "alpha")
+	ok(t, s.Refresh())
+
+	// The refresh just made is too recent to repeat.
+	s.Observe([]PredictionData{{RouteTag: "1", StopTag: "9999"}})
+	equals(t, 0, len(s.refresh))
+
+	now = now.Add(DefaultMinObserveInterval)
+	s.Observe([]PredictionData{{RouteTag: "1", StopTag: "1123"}})
+	equals(t, 0, len(s.refresh))
+
+	s.Observe([]PredictionData{{RouteTag: "1", StopTag: "9999"}})
+	equals(t, 1, len(s.refresh))
+}
+
+func TestRouteConfigStoreRun(t *testing.T) {
+	s := NewRouteConfigStore(NewClient(testingClient(t)), "alpha")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, 0)
+		close(done)
+	}()
+
+	for s.LastRefresh().IsZero() {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+	_, found := s.Route("1")
+	assert(t, found, "expected route 1 after Run")
+}
+
+func TestRouteConfigStoreStopCancelsRefresh(t *testing.T) {
+	started := make(chan struct{}, 1)
+	s := NewRouteConfigStore(NewClient(hangingClient(started)), "alpha").Service(time.Hour)
+	ok(t, s.Start(context.Background()))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ok(t, s.Stop(ctx))
+}
@@ -0,0 +1,111 @@
+package nextbus
+
+import (
+	"strconv"
+	"time"
+)
+
+// PollStrategy decides how long a PredictionWatcher waits between polls.
+type PollStrategy interface {
+	// Interval returns the delay before the next poll, given the current
+	// time and the predictions from the latest successful poll.
+	Interval(now time.Time, latest []PredictionData) time.Duration
+}
+
+// FixedInterval polls at the same interval at all times.
+type FixedInterval time.Duration
+
+// Interval returns the fixed interval.
+func (f FixedInterval) Interval(now time.Time, latest []PredictionData) time.Duration {
+	return time.Duration(f)
+}
+
+// AdaptiveInterval polls often while a vehicle is about to reach a watched
+// stop, less often otherwise, and rarely overnight. Zero fields take the
+// defaults noted on them.
+type AdaptiveInterval struct {
+	// Near is the interval while any prediction is at most NearMinutes away.
+	// It defaults to 20 seconds.
+	Near time.Duration
+	// NearMinutes defaults to 5.
+	NearMinutes int
+	// Far is the interval otherwise. It defaults to one minute.
+	Far time.Duration
+	// Night is the interval between NightStart and NightEnd, as hours of the
+	// day in the time's location, unless a vehicle is near. Night is only
+	// used if it is set.
+	Night      time.Duration
+	NightStart int
+	NightEnd   int
+}
+
+// Interval returns Near, Far or Night depending on the latest predictions
+// and the time of day.
+func (a AdaptiveInterval) Interval(now time.Time, latest []PredictionData) time.Duration {
+	near, nearMinutes, far := a.Near, a.NearMinutes, a.Far
+	if near == 0 {
+		near = 20 * time.Second
+	}
+	if nearMinutes == 0 {
+		nearMinutes = 5
+	}
+	if far == 0 {
+		far = time.Minute
+	}
+
+	for _, pd := range latest {
+		for _, dir := range pd.PredictionDirectionList {
+			for _, p := range dir.PredictionList {
+				if minutes, err := strconv.Atoi(p.Minutes); err == nil && minutes <= nearMinutes {
+					return near
+				}
+			}
+		}
+	}
+	if a.Night != 0 && inHours(now.Hour(), a.NightStart, a.NightEnd) {
+		return a.Night
+	}
+	return far
+}
+
+// inHours reports whether hour is in [start, end), which may wrap past
+// midnight.
+func inHours(hour, start, end int) bool {
+	if start <= end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// MinPollInterval is the shortest interval RunStrategy waits between polls,
+// whatever its PollStrategy returns.
+const MinPollInterval = 5 * time.Second
+
+// DefaultOutOfService is the interval a ServiceHoursStrategy without an
+// OutOfService interval polls at outside service hours.
+const DefaultOutOfService = 15 * time.Minute
+
+// ServiceHoursStrategy polls with Strategy while the agency is in service and
+// at OutOfService intervals otherwise, so that always-on displays don't poll
+// at full rate while nothing runs. A nil Strategy uses the zero
+// AdaptiveInterval and a zero OutOfService uses DefaultOutOfService.
+type ServiceHoursStrategy struct {
+	InService    func(t time.Time) bool
+	Strategy     PollStrategy
+	OutOfService time.Duration
+}
+
+// Interval returns the OutOfService interval outside service hours and defers
+// to Strategy within them.
+func (s ServiceHoursStrategy) Interval(now time.Time, latest []PredictionData) time.Duration {
+	if s.InService != nil && !s.InService(now) {
+		if s.OutOfService <= 0 {
+			return DefaultOutOfService
+		}
+		return s.OutOfService
+	}
+	if s.Strategy == nil {
+		return AdaptiveInterval{}.Interval(now, latest)
+	}
+	return s.Strategy.Interval(now, latest)
+}
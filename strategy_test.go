@@ -0,0 +1,71 @@
+package nextbus
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func predictionsIn(minutes string) []PredictionData {
+	return []PredictionData{{PredictionDirectionList: []PredictionDirection{{PredictionList: []Prediction{{Minutes: minutes}}}}}}
+}
+
+func TestAdaptiveInterval(t *testing.T) {
+	a := AdaptiveInterval{Night: 10 * time.Minute, NightStart: 23, NightEnd: 5}
+	noon := time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC)
+	night := time.Date(2017, 2, 16, 2, 0, 0, 0, time.UTC)
+
+	equals(t, 20*time.Second, a.Interval(noon, predictionsIn("3")))
+	equals(t, time.Minute, a.Interval(noon, predictionsIn("12")))
+	equals(t, time.Minute, a.Interval(noon, nil))
+	equals(t, 10*time.Minute, a.Interval(night, nil))
+	equals(t, 20*time.Second, a.Interval(night, predictionsIn("0")))
+}
+
+func TestServiceHoursStrategy(t *testing.T) {
+	s := ServiceHoursStrategy{
+		InService:    func(t time.Time) bool { return t.Hour() >= 5 },
+		Strategy:     FixedInterval(30 * time.Second),
+		OutOfService: 15 * time.Minute,
+	}
+	equals(t, 30*time.Second, s.Interval(time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC), nil))
+	equals(t, 15*time.Minute, s.Interval(time.Date(2017, 2, 16, 3, 0, 0, 0, time.UTC), nil))
+
+	defaults := ServiceHoursStrategy{InService: s.InService}
+	equals(t, time.Minute, defaults.Interval(time.Date(2017, 2, 16, 12, 0, 0, 0, time.UTC), nil))
+	equals(t, DefaultOutOfService, defaults.Interval(time.Date(2017, 2, 16, 3, 0, 0, 0, time.UTC), nil))
+}
+
+func TestRunStrategyMinInterval(t *testing.T) {
+	feed := cannedFeed(`<body><predictions routeTag="1" stopTag="1123"></predictions></body>`)
+	w := NewPredictionWatcher(NewClient(feed.Client()), "alpha", RouteStop{"1", "1123"})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	w.RunStrategy(ctx, FixedInterval(0))
+	equals(t, 1, feed.Count())
+}
+
+type recordingStrategy struct {
+	latest chan []PredictionData
+}
+
+func (r recordingStrategy) Interval(now time.Time, latest []PredictionData) time.Duration {
+	r.latest <- latest
+	return time.Hour
+}
+
+func TestRunStrategy(t *testing.T) {
+	w := NewPredictionWatcher(NewClient(testingClient(t)), "alpha", RouteStop{"1", "1123"})
+	strategy := recordingStrategy{make(chan []PredictionData, 1)}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.RunStrategy(ctx, strategy)
+		close(done)
+	}()
+
+	latest := <-strategy.latest
+	equals(t, 1, len(latest))
+	cancel()
+	<-done
+}
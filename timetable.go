@@ -0,0 +1,146 @@
+package nextbus
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// GetSchedules fetches the schedules for several routes of an agency, one
+// request per route since the feed accepts a single route. With no routeTags,
+// the schedules of every route in the agency's route list are fetched.
+func (c *Client) GetSchedules(agencyTag string, routeTags ...string) ([]Schedule, error) {
+	if len(routeTags) == 0 {
+		routes, listErr := c.GetRouteList(agencyTag)
+		if listErr != nil {
+			return nil, listErr
+		}
+		for _, route := range routes {
+			routeTags = append(routeTags, route.Tag)
+		}
+	}
+
+	var result []Schedule
+	for _, routeTag := range routeTags {
+		params, paramErr := buildQuery("schedule", agencyTag, []Param{RouteParam(routeTag)})
+		if paramErr != nil {
+			return nil, paramErr
+		}
+		resp, err := fetchAndDecode[ScheduleResponse](context.Background(), c, "schedule", params)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, resp.ScheduleList...)
+	}
+	return result, nil
+}
+
+// TimetableStop is a timepoint stop in a Timetable.
+type TimetableStop struct {
+	Tag   string
+	Title string
+}
+
+// StopTime is when a trip is scheduled at a timepoint, as an offset from
+// midnight of the service day. Offsets past 24 hours belong to trips that run
+// after midnight.
+type StopTime struct {
+	StopTag string
+	Offset  time.Duration
+}
+
+// On returns the time of the stop on the service day that starts on day.
+func (st StopTime) On(day time.Time) time.Time {
+	return midnight(day).Add(st.Offset)
+}
+
+// TimetableTrip is one scheduled trip of a vehicle block, with the timepoints
+// it serves in order.
+type TimetableTrip struct {
+	BlockID   string
+	StopTimes []StopTime
+}
+
+// First returns the trip's first stop time.
+func (t TimetableTrip) First() StopTime {
+	return t.StopTimes[0]
+}
+
+// At returns the trip's time at a stop, or false if it doesn't serve it.
+func (t TimetableTrip) At(stopTag string) (StopTime, bool) {
+	for _, st := range t.StopTimes {
+		if st.StopTag == stopTag {
+			return st, true
+		}
+	}
+	return StopTime{}, false
+}
+
+// Timetable is a Schedule normalized for use: its times are durations since
+// midnight, timepoints a trip skips are left out, and trips are ordered by
+// their first stop time.
+type Timetable struct {
+	RouteTag   string
+	RouteTitle string
+	Direction  string
+	// ServiceClass is the class of service day, such as "wkd", "sat" or
+	// "sun".
+	ServiceClass  string
+	ScheduleClass string
+	Stops         []TimetableStop
+	Trips         []TimetableTrip
+}
+
+// NewTimetable normalizes a schedule. Rows that serve no timepoint are
+// dropped.
+func NewTimetable(s Schedule) Timetable {
+	tt := Timetable{
+		RouteTag:      s.Tag,
+		RouteTitle:    s.Title,
+		Direction:     s.Direction,
+		ServiceClass:  s.ServiceClass,
+		ScheduleClass: s.ScheduleClass,
+	}
+	for _, h := range s.Header.StopList {
+		tt.Stops = append(tt.Stops, TimetableStop{Tag: h.Tag, Title: h.Title})
+	}
+	for _, block := range s.BlockList {
+		trip := TimetableTrip{BlockID: block.BlockID}
+		for _, stop := range block.StopList {
+			ms, err := strconv.ParseInt(stop.EpochTime, 10, 64)
+			if err != nil || ms < 0 {
+				continue
+			}
+			trip.StopTimes = append(trip.StopTimes, StopTime{StopTag: stop.Tag, Offset: time.Duration(ms) * time.Millisecond})
+		}
+		if len(trip.StopTimes) != 0 {
+			tt.Trips = append(tt.Trips, trip)
+		}
+	}
+	sort.SliceStable(tt.Trips, func(i, j int) bool {
+		return tt.Trips[i].First().Offset < tt.Trips[j].First().Offset
+	})
+	return tt
+}
+
+// NewTimetables normalizes each of schedules.
+func NewTimetables(schedules []Schedule) []Timetable {
+	result := make([]Timetable, len(schedules))
+	for i, s := range schedules {
+		result[i] = NewTimetable(s)
+	}
+	return result
+}
+
+// Departures returns the times trips are scheduled at a stop, in order.
+func (tt Timetable) Departures(stopTag string) []time.Duration {
+	var result []time.Duration
+	for _, trip := range tt.Trips {
+		if st, found := trip.At(stopTag); found {
+			result = append(result, st.Offset)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
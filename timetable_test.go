@@ -0,0 +1,40 @@
+package nextbus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetSchedules(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	schedules, err := nb.GetSchedules("alpha", "1")
+	ok(t, err)
+	equals(t, 1, len(schedules))
+	equals(t, "Outbound", schedules[0].Direction)
+}
+
+func TestTimetable(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	schedules, err := nb.GetSchedule("alpha", "1")
+	ok(t, err)
+
+	tt := NewTimetable(schedules[0])
+	equals(t, "1", tt.RouteTag)
+	equals(t, "wkd", tt.ServiceClass)
+	equals(t, []TimetableStop{{"1123", "First stop"}, {"1234", "Second stop"}}, tt.Stops)
+	equals(t, "101", tt.Trips[0].BlockID)
+	equals(t, 7*time.Hour, tt.Trips[0].First().Offset)
+
+	// Block 103 skips the first timepoint.
+	equals(t, "103", tt.Trips[2].BlockID)
+	equals(t, []StopTime{{"1234", 7*time.Hour + 30*time.Minute}}, tt.Trips[2].StopTimes)
+	_, serves := tt.Trips[2].At("1123")
+	assert(t, !serves, "expected block 103 to skip stop 1123")
+
+	departures := tt.Departures("1123")
+	equals(t, 7*time.Hour, departures[0])
+	equals(t, 7*time.Hour+10*time.Minute, departures[1])
+
+	day := time.Date(2017, 2, 16, 15, 4, 5, 0, time.UTC)
+	equals(t, time.Date(2017, 2, 16, 7, 0, 0, 0, time.UTC), tt.Trips[0].First().On(day))
+}
@@ -0,0 +1,218 @@
+package nextbus
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TitleRule rewrites a stop title as one step of a TitleCleaner.
+type TitleRule func(title string) string
+
+// TitleCleaner normalizes the inconsistent stop titles agencies publish, so
+// that "MARKET/3RD" and "Market St & 3rd St" display alike and can be searched
+// for the same way. The zero value applies DefaultTitleRules.
+type TitleCleaner struct {
+	// Rules are applied in order. Use append(DefaultTitleRules(), ...) to add
+	// agency-specific rules to the defaults.
+	Rules []TitleRule
+}
+
+// DefaultAbbreviations are the street abbreviations expanded by
+// DefaultTitleRules, keyed in lower case.
+var DefaultAbbreviations = map[string]string{
+	"av":   "Avenue",
+	"ave":  "Avenue",
+	"blvd": "Boulevard",
+	"ct":   "Court",
+	"ctr":  "Center",
+	"dr":   "Drive",
+	"hwy":  "Highway",
+	"ln":   "Lane",
+	"pkwy": "Parkway",
+	"pl":   "Place",
+	"rd":   "Road",
+	"sq":   "Square",
+	"st":   "Street",
+	"sta":  "Station",
+	"ter":  "Terrace",
+	"wy":   "Way",
+}
+
+// DefaultTitleRules returns the rules used by the zero TitleCleaner: unified
+// separators, then case normalization, then DefaultAbbreviations.
+func DefaultTitleRules() []TitleRule {
+	return []TitleRule{UnifySeparators, NormalizeCase, ExpandAbbreviations(DefaultAbbreviations)}
+}
+
+// Clean applies the cleaner's rules to title.
+func (c TitleCleaner) Clean(title string) string {
+	rules := c.Rules
+	if rules == nil {
+		rules = DefaultTitleRules()
+	}
+	for _, rule := range rules {
+		title = rule(title)
+	}
+	return title
+}
+
+// separators matches the ways agencies join the streets of an intersection.
+var separators = regexp.MustCompile(`\s*(?:/|&|@|\+)\s*|\s+(?i:and|at)\s+`)
+
+// UnifySeparators joins the streets of an intersection with " & ", whether
+// the title uses "/", "@", "+", "and" or "at", and collapses runs of spaces.
+func UnifySeparators(title string) string {
+	title = separators.ReplaceAllString(strings.TrimSpace(title), " & ")
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// NormalizeCase title-cases titles written entirely in upper case, such as
+// "MARKET & 3RD", leaving ordinals like "3rd" in lower case. Titles with any
+// lower case letter are assumed to be cased deliberately and are unchanged.
+func NormalizeCase(title string) string {
+	if strings.ToUpper(title) != title {
+		return title
+	}
+	lower := []rune(strings.ToLower(title))
+	for i, r := range lower {
+		if unicode.IsLetter(r) && (i == 0 || !unicode.IsLetter(lower[i-1]) && !unicode.IsDigit(lower[i-1]) && lower[i-1] != '\'') {
+			lower[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(lower)
+}
+
+// ExpandAbbreviations returns a rule replacing each word found in
+// abbreviations, compared in lower case and with any trailing period, by its
+// expansion. Only words ending a street name are expanded: the last word, or
+// one followed by a comma, "&", "-", a parenthesis or a direction such as
+// "NB". So "St Francis Wood" keeps its "St" while "Dr Carlton B Goodlett Pl"
+// becomes "Dr Carlton B Goodlett Place".
+func ExpandAbbreviations(abbreviations map[string]string) TitleRule {
+	return func(title string) string {
+		words := strings.Fields(title)
+		for i, w := range words {
+			if !endsStreet(words, i) {
+				continue
+			}
+			word := strings.TrimSuffix(w, ",")
+			if expansion, found := abbreviations[strings.ToLower(strings.TrimSuffix(word, "."))]; found {
+				words[i] = expansion + w[len(word):]
+			}
+		}
+		return strings.Join(words, " ")
+	}
+}
+
+// streetEnds are the words that can follow the last word of a street name.
+var streetEnds = map[string]bool{
+	"&": true, "-": true,
+	"n": true, "s": true, "e": true, "w": true,
+	"north": true, "south": true, "east": true, "west": true,
+	"nb": true, "sb": true, "eb": true, "wb": true,
+	"inbound": true, "outbound": true,
+}
+
+// endsStreet reports whether words[i] is the last word of a street name.
+func endsStreet(words []string, i int) bool {
+	if i == len(words)-1 || strings.HasSuffix(words[i], ",") {
+		return true
+	}
+	next := strings.ToLower(strings.TrimRight(words[i+1], ".,"))
+	return strings.HasPrefix(next, "(") || streetEnds[next]
+}
+
+// streetTypes are the words Key leaves out, so that "Market Street" and
+// "Market" match.
+var streetTypes = func() map[string]bool {
+	types := map[string]bool{}
+	for _, expansion := range DefaultAbbreviations {
+		types[strings.ToLower(expansion)] = true
+	}
+	return types
+}()
+
+// Key returns a search key for title: its cleaned words in lower case without
+// street types or separators, so that titles naming the same place share a
+// key.
+func (c TitleCleaner) Key(title string) string {
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(c.Clean(title)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	}) {
+		if !streetTypes[w] && w != "and" && w != "at" {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// StopIndex finds an agency's stops by title.
+type StopIndex struct {
+	cleaner TitleCleaner
+	keys    []string
+	stops   []StationStop
+}
+
+// NewStopIndex indexes the stops of routes with the titles cleaned by
+// cleaner.
+func NewStopIndex(routes []RouteConfig, cleaner TitleCleaner) *StopIndex {
+	idx := &StopIndex{cleaner: cleaner}
+	for _, rc := range routes {
+		for _, stop := range rc.StopList {
+			idx.keys = append(idx.keys, cleaner.Key(stop.Title))
+			idx.stops = append(idx.stops, StationStop{rc.Tag, stop})
+		}
+	}
+	return idx
+}
+
+// Search returns the stops whose title has a word starting with each word of
+// query, ordered by title and then route. "market 3rd" and "MARKET/3RD ST"
+// both find "Market St & 3rd St".
+func (idx *StopIndex) Search(query string) []StationStop {
+	terms := strings.Fields(idx.cleaner.Key(query))
+	if len(terms) == 0 {
+		return nil
+	}
+	type match struct {
+		key  string
+		stop StationStop
+	}
+	var matches []match
+	for i, key := range idx.keys {
+		if matchesAll(strings.Fields(key), terms) {
+			matches = append(matches, match{key, idx.stops[i]})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].key != matches[j].key {
+			return matches[i].key < matches[j].key
+		}
+		return matches[i].stop.RouteTag < matches[j].stop.RouteTag
+	})
+	result := make([]StationStop, len(matches))
+	for i, m := range matches {
+		result[i] = m.stop
+	}
+	return result
+}
+
+// matchesAll reports whether every term is a prefix of one of words.
+func matchesAll(words, terms []string) bool {
+	for _, term := range terms {
+		found := false
+		for _, w := range words {
+			if strings.HasPrefix(w, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package nextbus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTitleCleaner(t *testing.T) {
+	var c TitleCleaner
+	for _, tc := range []struct{ title, clean string }{
+		{"Market St & 3rd St", "Market Street & 3rd Street"},
+		{"MARKET/3RD", "Market & 3rd"},
+		{"  Geary Blvd.  @ Masonic Ave", "Geary Boulevard & Masonic Avenue"},
+		{"Church St and Duboce Ave", "Church Street & Duboce Avenue"},
+		{"Embarcadero Station", "Embarcadero Station"},
+		{"St Francis Wood", "St Francis Wood"},
+		{"Dr Carlton B Goodlett Pl", "Dr Carlton B Goodlett Place"},
+		{"ST FRANCIS BLVD/JUNIPERO SERRA BLVD", "St Francis Boulevard & Junipero Serra Boulevard"},
+		{"Mission St NB", "Mission Street NB"},
+		{"Ocean Ave, Outbound", "Ocean Avenue, Outbound"},
+		{"Market St (Inbound)", "Market Street (Inbound)"},
+	} {
+		equals(t, tc.clean, c.Clean(tc.title))
+	}
+	equals(t, c.Key("Market St & 3rd St"), c.Key("MARKET/3RD"))
+	equals(t, "market 3rd", c.Key("MARKET/3RD"))
+}
+
+func TestTitleCleanerRules(t *testing.T) {
+	c := TitleCleaner{Rules: append(DefaultTitleRules(), func(title string) string {
+		return strings.Replace(title, "Mission Bay", "UCSF Mission Bay", 1)
+	})}
+	equals(t, "UCSF Mission Bay & 4th Street", c.Clean("MISSION BAY / 4TH ST"))
+
+	none := TitleCleaner{Rules: []TitleRule{}}
+	equals(t, "MARKET/3RD", none.Clean("MARKET/3RD"))
+}
+
+func TestStopIndex(t *testing.T) {
+	idx := NewStopIndex([]RouteConfig{
+		{Tag: "N", StopList: []Stop{{Tag: "1", Title: "Market St & 3rd St"}, {Tag: "2", Title: "Duboce Ave & Church St"}}},
+		{Tag: "J", StopList: []Stop{{Tag: "3", Title: "MARKET/3RD"}, {Tag: "4", Title: "Church St & 16th St"}}},
+	}, TitleCleaner{})
+
+	found := idx.Search("market 3rd st")
+	equals(t, 2, len(found))
+	equals(t, "J", found[0].RouteTag)
+	equals(t, "N", found[1].RouteTag)
+
+	found = idx.Search("chur")
+	equals(t, 2, len(found))
+	equals(t, "4", found[0].Stop.Tag)
+	equals(t, 0, len(idx.Search("castro")))
+	equals(t, 0, len(idx.Search("")))
+}
+
+func TestFormatterStopTitle(t *testing.T) {
+	equals(t, "MARKET/3RD", Formatter{}.StopTitle("MARKET/3RD"))
+	equals(t, "Market & 3rd", Formatter{Titles: &TitleCleaner{}}.StopTitle("MARKET/3RD"))
+}
@@ -0,0 +1,50 @@
+package nextbus
+
+import (
+	"sort"
+)
+
+// TransferPoint is a place where a rider can change from one route to another.
+type TransferPoint struct {
+	// From is the stop on the first route and To the stop on the second.
+	From Stop
+	To   Stop
+	// Distance is the walking distance between the stops in meters, as the
+	// crow flies. It is zero when both routes use the same stop.
+	Distance float64
+}
+
+// TransferPoints finds the stops of routeTagA that are shared with, or within
+// maxMeters of, a stop of routeTagB. Each stop of routeTagA is paired with its
+// closest stop on routeTagB. The result is ordered by distance.
+func (s *AgencySnapshot) TransferPoints(routeTagA, routeTagB string, maxMeters float64) []TransferPoint {
+	a, foundA := s.Route(routeTagA)
+	b, foundB := s.Route(routeTagB)
+	if !foundA || !foundB {
+		return nil
+	}
+
+	var result []TransferPoint
+	for _, from := range a.StopList {
+		best := TransferPoint{Distance: -1}
+		for _, to := range b.StopList {
+			d, known := stopDistance(from, to)
+			if from.Tag == to.Tag || (from.StopID != "" && from.StopID == to.StopID) {
+				d, known = 0, true
+			}
+			if !known || d > maxMeters {
+				continue
+			}
+			if best.Distance < 0 || d < best.Distance {
+				best = TransferPoint{from, to, d}
+			}
+		}
+		if best.Distance >= 0 {
+			result = append(result, best)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Distance < result[j].Distance
+	})
+	return result
+}
@@ -0,0 +1,39 @@
+package nextbus
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHaversine(t *testing.T) {
+	// One degree of latitude is roughly 111.2km.
+	d := haversine(37, -122, 38, -122)
+	assert(t, math.Abs(d-111195) < 10, "unexpected distance %v", d)
+}
+
+func TestTransferPoints(t *testing.T) {
+	snapshot := &AgencySnapshot{
+		Agency: Agency{Tag: "alpha"},
+		Routes: []RouteConfig{
+			{Tag: "A", StopList: []Stop{
+				{Tag: "a1", Lat: "37.7000", Lon: "-122.4000", StopID: "100"},
+				{Tag: "a2", Lat: "37.7100", Lon: "-122.4000"},
+				{Tag: "a3", Lat: "37.8000", Lon: "-122.4000"},
+			}},
+			{Tag: "B", StopList: []Stop{
+				{Tag: "b1", Lat: "37.7001", Lon: "-122.4001", StopID: "100"},
+				{Tag: "b2", Lat: "37.7103", Lon: "-122.4000"},
+			}},
+		},
+	}
+
+	found := snapshot.TransferPoints("A", "B", 50)
+	equals(t, 2, len(found))
+	equals(t, "a1", found[0].From.Tag)
+	equals(t, "b1", found[0].To.Tag)
+	equals(t, 0.0, found[0].Distance)
+	equals(t, "b2", found[1].To.Tag)
+	assert(t, math.Abs(found[1].Distance-33.4) < 1, "unexpected distance %v", found[1].Distance)
+
+	equals(t, []TransferPoint(nil), snapshot.TransferPoints("A", "C", 50))
+}
@@ -0,0 +1,71 @@
+package nextbus
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// transportOptions collects the options that change how connections to
+// NextBus are made.
+type transportOptions struct {
+	proxy       func(*http.Request) (*url.URL, error)
+	dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	tlsConfig   *tls.Config
+}
+
+func (c *Client) transportOptions() *transportOptions {
+	if c.transport == nil {
+		c.transport = &transportOptions{}
+	}
+	return c.transport
+}
+
+// apply returns a copy of httpClient whose transport uses the options. The
+// client's own transport is cloned if it is an *http.Transport; otherwise the
+// default transport is used as the base. httpClient itself isn't modified.
+func (o *transportOptions) apply(httpClient *http.Client) *http.Client {
+	base, isTransport := httpClient.Transport.(*http.Transport)
+	if !isTransport {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	t := base.Clone()
+	if o.proxy != nil {
+		t.Proxy = o.proxy
+	}
+	if o.dialContext != nil {
+		t.DialContext = o.dialContext
+	}
+	if o.tlsConfig != nil {
+		t.TLSClientConfig = o.tlsConfig
+	}
+
+	result := *httpClient
+	result.Transport = t
+	return &result
+}
+
+// WithProxy sends every request through the proxy at proxyURL, such as
+// "http://proxy.corp.example:3128".
+func WithProxy(proxyURL *url.URL) Option {
+	return func(c *Client) {
+		c.transportOptions().proxy = http.ProxyURL(proxyURL)
+	}
+}
+
+// WithDialContext makes connections with dial instead of the default dialer.
+func WithDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(c *Client) {
+		c.transportOptions().dialContext = dial
+	}
+}
+
+// WithTLSConfig uses config for HTTPS connections, for example to pin
+// certificates or trust a corporate root CA.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		c.transportOptions().tlsConfig = config
+	}
+}
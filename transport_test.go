@@ -0,0 +1,44 @@
+package nextbus
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestTransportOptions(t *testing.T) {
+	proxyURL, err := url.Parse("http://proxy.example:3128")
+	ok(t, err)
+	tlsConfig := &tls.Config{ServerName: "webservices.nextbus.com"}
+	original := &http.Client{}
+	nb := NewClient(original, WithProxy(proxyURL), WithTLSConfig(tlsConfig))
+
+	assert(t, original.Transport == nil, "expected the caller's client to be left alone")
+	transport := nb.httpClient.Transport.(*http.Transport)
+	equals(t, tlsConfig, transport.TLSClientConfig)
+	found, err := transport.Proxy(&http.Request{URL: &url.URL{Scheme: "http", Host: "webservices.nextbus.com"}})
+	ok(t, err)
+	equals(t, proxyURL, found)
+}
+
+func TestWithDialContext(t *testing.T) {
+	dialErr := errors.New("no network here")
+	var dialed string
+	nb := NewClient(nil, WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = addr
+		return nil, dialErr
+	}))
+
+	_, err := nb.GetAgencyList()
+	assert(t, err != nil, "expected the dial error to be returned")
+	equals(t, "webservices.nextbus.com:80", dialed)
+}
+
+func TestNoTransportOptions(t *testing.T) {
+	httpClient := testingClient(t)
+	equals(t, httpClient, NewClient(httpClient).httpClient)
+}
@@ -0,0 +1,69 @@
+package nextbus
+
+import (
+	"time"
+)
+
+// TravelOption is one vehicle run that serves two stops, in order.
+type TravelOption struct {
+	TripTag string
+	Vehicle string
+	Depart  time.Time
+	Arrive  time.Time
+}
+
+// Duration is the predicted in-vehicle travel time.
+func (o TravelOption) Duration() time.Duration {
+	return o.Arrive.Sub(o.Depart)
+}
+
+// TravelEstimate describes how to get between two stops on a route.
+type TravelEstimate struct {
+	// Options are ordered by departure time.
+	Options []TravelOption
+	// TravelTime is the average duration of the options, or zero if there are
+	// none.
+	TravelTime time.Duration
+}
+
+// EstimateTravel estimates the in-vehicle travel time from one stop of a route
+// to another, using predictions at both stops for the same trip.
+func (c *Client) EstimateTravel(agencyTag, routeTag, fromStopTag, toStopTag string) (*TravelEstimate, error) {
+	predictions, err := c.GetPredictionsForMultiStops(agencyTag,
+		PredReqStop(routeTag, fromStopTag), PredReqStop(routeTag, toStopTag))
+	if err != nil {
+		return nil, err
+	}
+	return EstimateTravelFromPredictions(predictions, routeTag, fromStopTag, toStopTag), nil
+}
+
+// EstimateTravelFromPredictions is EstimateTravel using already fetched
+// predictions.
+func EstimateTravelFromPredictions(predictions []PredictionData, routeTag, fromStopTag, toStopTag string) *TravelEstimate {
+	var estimate TravelEstimate
+	var total time.Duration
+	for _, trip := range AssembleTrips(predictions) {
+		if trip.RouteTag != routeTag {
+			continue
+		}
+		var depart, arrive time.Time
+		for _, s := range trip.Stops {
+			switch {
+			case s.StopTag == fromStopTag && depart.IsZero():
+				depart = s.Prediction.ArrivalTime()
+			case s.StopTag == toStopTag && !depart.IsZero():
+				arrive = s.Prediction.ArrivalTime()
+			}
+		}
+		if depart.IsZero() || arrive.IsZero() {
+			continue
+		}
+		option := TravelOption{trip.TripTag, trip.Vehicle, depart, arrive}
+		estimate.Options = append(estimate.Options, option)
+		total += option.Duration()
+	}
+	if len(estimate.Options) != 0 {
+		estimate.TravelTime = total / time.Duration(len(estimate.Options))
+	}
+	return &estimate
+}
@@ -0,0 +1,34 @@
+package nextbus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEstimateTravel(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	estimate, err := nb.EstimateTravel("alpha", "1", "1123", "1124")
+	ok(t, err)
+
+	equals(t, 1, len(estimate.Options))
+	equals(t, "7318264", estimate.Options[0].TripTag)
+	equals(t, 556486*time.Millisecond, estimate.TravelTime)
+}
+
+func TestEstimateTravelFromPredictionsIgnoresWrongDirection(t *testing.T) {
+	pd := func(stopTag string, ps ...Prediction) PredictionData {
+		return PredictionData{
+			RouteTag:                "1",
+			StopTag:                 stopTag,
+			PredictionDirectionList: []PredictionDirection{{PredictionList: ps}},
+		}
+	}
+	predictions := []PredictionData{
+		pd("A", Prediction{EpochTime: "60000", TripTag: "a"}, Prediction{EpochTime: "120000", TripTag: "b"}),
+		pd("B", Prediction{EpochTime: "240000", TripTag: "a"}, Prediction{EpochTime: "60000", TripTag: "b"}),
+	}
+	estimate := EstimateTravelFromPredictions(predictions, "1", "A", "B")
+	equals(t, 1, len(estimate.Options))
+	equals(t, "a", estimate.Options[0].TripTag)
+	equals(t, 3*time.Minute, estimate.TravelTime)
+}
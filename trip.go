@@ -0,0 +1,75 @@
+package nextbus
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ArrivalTime returns the predicted arrival (or departure) time, or the zero
+// time if EpochTime can't be parsed.
+func (p Prediction) ArrivalTime() time.Time {
+	ms, err := strconv.ParseInt(p.EpochTime, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// TripStop is the predicted arrival of a trip at one of its stops.
+type TripStop struct {
+	StopTag    string
+	StopTitle  string
+	Prediction Prediction
+}
+
+// Trip is a single run of a vehicle along a route, with its upcoming stops
+// ordered by predicted arrival time.
+type Trip struct {
+	TripTag  string
+	RouteTag string
+	DirTag   string
+	Vehicle  string
+	Stops    []TripStop
+}
+
+// AssembleTrips correlates predictions for many stops into trips by their
+// TripTag. Predictions without a TripTag are ignored. Trips are ordered by
+// their earliest predicted arrival.
+func AssembleTrips(predictions []PredictionData) []Trip {
+	index := map[string]int{}
+	var trips []Trip
+	for _, pd := range predictions {
+		for _, dir := range pd.PredictionDirectionList {
+			for _, p := range dir.PredictionList {
+				if p.TripTag == "" {
+					continue
+				}
+				key := pd.RouteTag + "|" + p.TripTag
+				i, seen := index[key]
+				if !seen {
+					i = len(trips)
+					index[key] = i
+					trips = append(trips, Trip{
+						TripTag:  p.TripTag,
+						RouteTag: pd.RouteTag,
+						DirTag:   p.DirTag,
+						Vehicle:  p.Vehicle,
+					})
+				}
+				trips[i].Stops = append(trips[i].Stops, TripStop{pd.StopTag, pd.StopTitle, p})
+			}
+		}
+	}
+
+	for _, trip := range trips {
+		stops := trip.Stops
+		sort.SliceStable(stops, func(i, j int) bool {
+			return stops[i].Prediction.ArrivalTime().Before(stops[j].Prediction.ArrivalTime())
+		})
+	}
+	sort.SliceStable(trips, func(i, j int) bool {
+		return trips[i].Stops[0].Prediction.ArrivalTime().Before(trips[j].Stops[0].Prediction.ArrivalTime())
+	})
+	return trips
+}
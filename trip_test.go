@@ -0,0 +1,41 @@
+package nextbus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPredictionArrivalTime(t *testing.T) {
+	equals(t, time.Unix(1487277081, 162000000), Prediction{EpochTime: "1487277081162"}.ArrivalTime())
+	assert(t, Prediction{}.ArrivalTime().IsZero(), "expected zero time for an empty EpochTime")
+}
+
+func TestAssembleTrips(t *testing.T) {
+	pd := func(stopTag string, ps ...Prediction) PredictionData {
+		return PredictionData{
+			RouteTag:                "1",
+			StopTag:                 stopTag,
+			PredictionDirectionList: []PredictionDirection{{PredictionList: ps}},
+		}
+	}
+	predictions := []PredictionData{
+		pd("C",
+			Prediction{EpochTime: "3000", TripTag: "t1", Vehicle: "v1"},
+			Prediction{EpochTime: "2500", TripTag: "t2", Vehicle: "v2"}),
+		pd("A",
+			Prediction{EpochTime: "1000", TripTag: "t1", Vehicle: "v1"},
+			Prediction{EpochTime: "1500"}),
+		pd("B", Prediction{EpochTime: "2000", TripTag: "t1", Vehicle: "v1"}),
+	}
+
+	trips := AssembleTrips(predictions)
+	equals(t, 2, len(trips))
+	equals(t, "t1", trips[0].TripTag)
+	equals(t, "v1", trips[0].Vehicle)
+	var stops []string
+	for _, s := range trips[0].Stops {
+		stops = append(stops, s.StopTag)
+	}
+	equals(t, []string{"A", "B", "C"}, stops)
+	equals(t, "t2", trips[1].TripTag)
+}
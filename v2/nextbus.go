@@ -0,0 +1,163 @@
+// Package nextbus is version 2 of the client for the NextBus public XML feed.
+//
+// Every request takes a context, parameters are the typed Param, and the
+// types and errors are those of version 1, so values can be passed between
+// the two versions freely. Code can migrate one call at a time: V1 returns the
+// version 1 client sharing the same options, which still provides the
+// features this version doesn't wrap yet, such as watchers and snapshots.
+package nextbus
+
+import (
+	"context"
+	"net/http"
+
+	v1 "github.com/dinedal/nextbus"
+)
+
+// The feed's data types.
+type (
+	Agency              = v1.Agency
+	Route               = v1.Route
+	RouteConfig         = v1.RouteConfig
+	Stop                = v1.Stop
+	Direction           = v1.Direction
+	Path                = v1.Path
+	Point               = v1.Point
+	PredictionData      = v1.PredictionData
+	PredictionDirection = v1.PredictionDirection
+	Prediction          = v1.Prediction
+	Message             = v1.Message
+	LocationResponse    = v1.LocationResponse
+	VehicleLocation     = v1.VehicleLocation
+	Schedule            = v1.Schedule
+	RouteStop           = v1.RouteStop
+	LatLon              = v1.LatLon
+)
+
+// The errors returned by requests.
+type (
+	FeedError      = v1.FeedError
+	RateLimitError = v1.RateLimitError
+	ParamError     = v1.ParamError
+	NotFoundError  = v1.NotFoundError
+)
+
+// ErrRateLimited matches any *RateLimitError with errors.Is.
+var ErrRateLimited = v1.ErrRateLimited
+
+// Param is a typed query parameter of a feed request.
+type Param = v1.Param
+
+// The parameters accepted by the requests.
+var (
+	RouteParam       = v1.RouteParam
+	TimeParam        = v1.TimeParam
+	TerseParam       = v1.TerseParam
+	VerboseParam     = v1.VerboseParam
+	ShortTitlesParam = v1.ShortTitlesParam
+)
+
+// Option configures a Client.
+type Option = v1.Option
+
+// The options a Client can be created with.
+var (
+	WithRetries             = v1.WithRetries
+	WithRateLimit           = v1.WithRateLimit
+	WithUserAgent           = v1.WithUserAgent
+	WithContact             = v1.WithContact
+	WithDiskCache           = v1.WithDiskCache
+	WithClock               = v1.WithClock
+	WithRequestObserver     = v1.WithRequestObserver
+	WithRouteConfigFallback = v1.WithRouteConfigFallback
+	WithStaleVehicleFilter  = v1.WithStaleVehicleFilter
+	WithProxy               = v1.WithProxy
+	WithTLSConfig           = v1.WithTLSConfig
+	WithDialContext         = v1.WithDialContext
+)
+
+// Client makes requests to the NextBus feed.
+type Client struct {
+	v1 *v1.Client
+}
+
+// New creates a client. A nil httpClient uses a client with default
+// settings.
+func New(httpClient *http.Client, opts ...Option) *Client {
+	return &Client{v1.NewClient(httpClient, opts...)}
+}
+
+// V1 returns the version 1 client that c wraps.
+func (c *Client) V1() *v1.Client {
+	return c.v1
+}
+
+// Agencies fetches the agencies NextBus supports.
+func (c *Client) Agencies(ctx context.Context) ([]Agency, error) {
+	return c.v1.GetAgencyListContext(ctx)
+}
+
+// Routes fetches the routes of an agency.
+func (c *Client) Routes(ctx context.Context, agencyTag string) ([]Route, error) {
+	return c.v1.GetRouteListContext(ctx, agencyTag)
+}
+
+// RouteConfigs fetches the configs of an agency's routes, or of a single
+// route with a RouteParam.
+func (c *Client) RouteConfigs(ctx context.Context, agencyTag string, params ...Param) ([]RouteConfig, error) {
+	configParams := make([]v1.RouteConfigParam, len(params))
+	for i, p := range params {
+		configParams[i] = p.Encode
+	}
+	return c.v1.GetRouteConfigContext(ctx, agencyTag, configParams...)
+}
+
+// RouteConfig fetches the config of one route. A route missing from the
+// response is reported as a *NotFoundError.
+func (c *Client) RouteConfig(ctx context.Context, agencyTag, routeTag string) (RouteConfig, error) {
+	configs, err := c.RouteConfigs(ctx, agencyTag, RouteParam(routeTag))
+	if err != nil {
+		return RouteConfig{}, err
+	}
+	for _, rc := range configs {
+		if rc.Tag == routeTag {
+			return rc, nil
+		}
+	}
+	return RouteConfig{}, &NotFoundError{Kind: "route", AgencyTag: agencyTag, RouteTag: routeTag}
+}
+
+// Predictions fetches predictions for stops of an agency in a single
+// request. params may include a ShortTitlesParam.
+func (c *Client) Predictions(ctx context.Context, agencyTag string, stops []RouteStop, params ...Param) ([]PredictionData, error) {
+	predParams := make([]v1.PredReqParam, 0, len(stops)+len(params))
+	for _, rs := range stops {
+		predParams = append(predParams, v1.PredReqStop(rs.RouteTag, rs.StopTag))
+	}
+	for _, p := range params {
+		predParams = append(predParams, p.Encode)
+	}
+	return c.v1.GetPredictionsForMultiStopsContext(ctx, agencyTag, predParams...)
+}
+
+// StopPredictions fetches predictions for every route at a stop, identified
+// by its stop ID rather than its tag.
+func (c *Client) StopPredictions(ctx context.Context, agencyTag, stopID string) ([]PredictionData, error) {
+	return c.v1.GetStopPredictionsContext(ctx, agencyTag, stopID)
+}
+
+// VehicleLocations fetches the locations of an agency's vehicles. params may
+// include a RouteParam and a TimeParam with the lastTime of an earlier
+// response.
+func (c *Client) VehicleLocations(ctx context.Context, agencyTag string, params ...Param) (*LocationResponse, error) {
+	locationParams := make([]v1.VehicleLocationParam, len(params))
+	for i, p := range params {
+		locationParams[i] = p.Encode
+	}
+	return c.v1.GetVehicleLocationsContext(ctx, agencyTag, locationParams...)
+}
+
+// Schedule fetches the schedules of a route.
+func (c *Client) Schedule(ctx context.Context, agencyTag, routeTag string) ([]Schedule, error) {
+	return c.v1.GetScheduleContext(ctx, agencyTag, routeTag)
+}
@@ -0,0 +1,62 @@
+package nextbus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dinedal/nextbus/nextbustest"
+)
+
+func TestClient(t *testing.T) {
+	s := nextbustest.NewServer(nextbustest.Generate(nextbustest.Config{Routes: 2, StopsPerRoute: 5, VehiclesPerRoute: 1}))
+	defer s.Close()
+	nb := New(s.Client())
+	ctx := context.Background()
+
+	routes, err := nb.Routes(ctx, "sim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 {
+		t.Fatalf("routes: got %+v", routes)
+	}
+
+	rc, err := nb.RouteConfig(ctx, "sim", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.Tag != "2" || len(rc.StopList) != 5 {
+		t.Fatalf("unexpected route config %+v", rc)
+	}
+
+	predictions, err := nb.Predictions(ctx, "sim", []RouteStop{{RouteTag: "1", StopTag: "1_3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(predictions) != 1 || predictions[0].StopTag != "1_3" {
+		t.Fatalf("unexpected predictions %+v", predictions)
+	}
+
+	vehicles, err := nb.VehicleLocations(ctx, "sim", RouteParam("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vehicles.VehicleList) != 1 {
+		t.Fatalf("unexpected vehicles %+v", vehicles.VehicleList)
+	}
+
+	// The version 1 client is the same client, for code not migrated yet.
+	if _, err := nb.V1().GetAgencyList(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientCanceled(t *testing.T) {
+	s := nextbustest.NewServer(nextbustest.Generate(nextbustest.Config{}))
+	defer s.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := New(s.Client()).Agencies(ctx); err == nil {
+		t.Error("expected an error for a canceled context")
+	}
+}
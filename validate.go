@@ -0,0 +1,117 @@
+package nextbus
+
+import (
+	"fmt"
+)
+
+// NotFoundError is returned when an agency, route or stop tag doesn't match
+// any known data.
+type NotFoundError struct {
+	// Kind is the kind of identifier that wasn't found: "agency", "route" or
+	// "stop".
+	Kind      string
+	AgencyTag string
+	RouteTag  string
+	StopTag   string
+}
+
+func (e *NotFoundError) Error() string {
+	switch e.Kind {
+	case "stop":
+		return fmt.Sprintf("stop %q not found on route %q of agency %q", e.StopTag, e.RouteTag, e.AgencyTag)
+	case "route":
+		return fmt.Sprintf("route %q not found in agency %q", e.RouteTag, e.AgencyTag)
+	default:
+		return fmt.Sprintf("agency %q not found", e.AgencyTag)
+	}
+}
+
+// Validator checks identifiers against known data. An empty routeTag or
+// stopTag skips the corresponding check. Identifiers that don't match return a
+// *NotFoundError.
+type Validator interface {
+	Validate(agencyTag, routeTag, stopTag string) error
+}
+
+// Validate checks the identifiers against the live feed. It makes up to two
+// requests, so prefer an AgencySnapshot or RouteConfigStore when validating
+// frequently.
+func (c *Client) Validate(agencyTag, routeTag, stopTag string) error {
+	agencies, agencyErr := c.GetAgencyList()
+	if agencyErr != nil {
+		return agencyErr
+	}
+	found := false
+	for _, a := range agencies {
+		if a.Tag == agencyTag {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return &NotFoundError{Kind: "agency", AgencyTag: agencyTag}
+	}
+	if routeTag == "" {
+		return nil
+	}
+
+	routes, routeErr := c.getSingleRouteConfig(agencyTag, routeTag)
+	if routeErr != nil {
+		return routeErr
+	}
+	return validateRoutes(routes, agencyTag, routeTag, stopTag)
+}
+
+// getSingleRouteConfig fetches the config of one route, reporting a route
+// NextBus refuses to look up as a *NotFoundError.
+func (c *Client) getSingleRouteConfig(agencyTag, routeTag string) ([]RouteConfig, error) {
+	routes, err := c.GetRouteConfig(agencyTag, RouteConfigTag(routeTag))
+	if feedErr, isFeedErr := err.(*FeedError); isFeedErr && !feedErr.Retryable() {
+		return nil, &NotFoundError{Kind: "route", AgencyTag: agencyTag, RouteTag: routeTag}
+	}
+	return routes, err
+}
+
+// Validate checks the identifiers against the snapshot.
+func (s *AgencySnapshot) Validate(agencyTag, routeTag, stopTag string) error {
+	if agencyTag != s.Agency.Tag {
+		return &NotFoundError{Kind: "agency", AgencyTag: agencyTag}
+	}
+	if routeTag == "" {
+		return nil
+	}
+	return validateRoutes(s.Routes, agencyTag, routeTag, stopTag)
+}
+
+// Validate checks the identifiers against the stored configs.
+func (s *RouteConfigStore) Validate(agencyTag, routeTag, stopTag string) error {
+	if agencyTag != s.agencyTag {
+		return &NotFoundError{Kind: "agency", AgencyTag: agencyTag}
+	}
+	if routeTag == "" {
+		return nil
+	}
+	rc, found := s.Route(routeTag)
+	if !found {
+		return &NotFoundError{Kind: "route", AgencyTag: agencyTag, RouteTag: routeTag}
+	}
+	return validateRoutes([]RouteConfig{rc}, agencyTag, routeTag, stopTag)
+}
+
+func validateRoutes(routes []RouteConfig, agencyTag, routeTag, stopTag string) error {
+	for _, rc := range routes {
+		if rc.Tag != routeTag {
+			continue
+		}
+		if stopTag == "" {
+			return nil
+		}
+		for _, stop := range rc.StopList {
+			if stop.Tag == stopTag {
+				return nil
+			}
+		}
+		return &NotFoundError{Kind: "stop", AgencyTag: agencyTag, RouteTag: routeTag, StopTag: stopTag}
+	}
+	return &NotFoundError{Kind: "route", AgencyTag: agencyTag, RouteTag: routeTag}
+}
@@ -0,0 +1,38 @@
+package nextbus
+
+import (
+	"testing"
+)
+
+func TestClientValidate(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	ok(t, nb.Validate("alpha", "", ""))
+	ok(t, nb.Validate("alpha", "1", "1234"))
+	equals(t, &NotFoundError{Kind: "agency", AgencyTag: "gamma"}, nb.Validate("gamma", "1", ""))
+	equals(t, &NotFoundError{Kind: "route", AgencyTag: "alpha", RouteTag: "9"}, nb.Validate("alpha", "9", ""))
+	equals(t, &NotFoundError{Kind: "stop", AgencyTag: "alpha", RouteTag: "1", StopTag: "5"}, nb.Validate("alpha", "1", "5"))
+}
+
+func TestSnapshotValidate(t *testing.T) {
+	nb := NewClient(testingClient(t))
+	snapshot, err := nb.GetAgencySnapshot("alpha")
+	ok(t, err)
+	equals(t, "The First", snapshot.Agency.Title)
+
+	var v Validator = snapshot
+	ok(t, v.Validate("alpha", "1", "1123"))
+	equals(t, &NotFoundError{Kind: "route", AgencyTag: "alpha", RouteTag: "2"}, v.Validate("alpha", "2", ""))
+	err = v.Validate("alpha", "1", "5")
+	equals(t, `stop "5" not found on route "1" of agency "alpha"`, err.Error())
+
+	_, err = nb.GetAgencySnapshot("gamma")
+	equals(t, &NotFoundError{Kind: "agency", AgencyTag: "gamma"}, err)
+}
+
+func TestRouteConfigStoreValidate(t *testing.T) {
+	s := NewRouteConfigStore(NewClient(testingClient(t)), "alpha")
+	ok(t, s.Refresh())
+	ok(t, s.Validate("alpha", "1", "1234"))
+	equals(t, &NotFoundError{Kind: "agency", AgencyTag: "beta"}, s.Validate("beta", "", ""))
+	equals(t, &NotFoundError{Kind: "route", AgencyTag: "alpha", RouteTag: "7"}, s.Validate("alpha", "7", "1"))
+}
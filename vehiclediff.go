@@ -0,0 +1,168 @@
+package nextbus
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// VehicleMove describes how a vehicle changed between two reports.
+type VehicleMove struct {
+	Before VehicleLocation
+	After  VehicleLocation
+	// Distance is the displacement in meters.
+	Distance float64
+	// HeadingChange is the change in heading in degrees, between -180 and 180.
+	HeadingChange float64
+}
+
+// VehicleChangeset is the result of applying a batch of vehicle locations to
+// a VehicleDiffer.
+type VehicleChangeset struct {
+	// Added are vehicles that weren't being tracked.
+	Added []VehicleLocation
+	// Moved are tracked vehicles whose position or heading changed.
+	Moved []VehicleMove
+	// Stale are vehicles that have just exceeded StaleAfter without a report.
+	Stale []VehicleLocation
+	// Disappeared are vehicles that have exceeded RemoveAfter without a report
+	// and are no longer tracked.
+	Disappeared []VehicleLocation
+}
+
+// Empty reports whether the changeset contains no changes.
+func (c VehicleChangeset) Empty() bool {
+	return len(c.Added) == 0 && len(c.Moved) == 0 && len(c.Stale) == 0 && len(c.Disappeared) == 0
+}
+
+type trackedVehicle struct {
+	location VehicleLocation
+	reported time.Time
+	stale    bool
+}
+
+// VehicleDiffer compares successive vehicleLocations responses. It works both
+// with full responses and with the partial ones returned when t is set, as a
+// VehicleLocationSession does, since vehicles are only considered gone once
+// they haven't reported for a while. The zero value is ready to use.
+type VehicleDiffer struct {
+	// StaleAfter is how long a vehicle may go without reporting before it is
+	// reported as stale. Zero uses DefaultVehicleStaleAfter.
+	StaleAfter time.Duration
+	// RemoveAfter is how long a vehicle may go without reporting before it is
+	// reported as disappeared and forgotten. Zero uses
+	// DefaultVehicleRemoveAfter.
+	RemoveAfter time.Duration
+	// MinDistance is the displacement in meters below which a vehicle with an
+	// unchanged heading isn't considered to have moved. With zero, any
+	// displacement counts.
+	MinDistance float64
+
+	vehicles map[string]*trackedVehicle
+}
+
+// DefaultVehicleStaleAfter and DefaultVehicleRemoveAfter suit a feed polled
+// every 10 to 30 seconds.
+const (
+	DefaultVehicleStaleAfter  = 2 * time.Minute
+	DefaultVehicleRemoveAfter = 5 * time.Minute
+)
+
+// NewVehicleDiffer creates a VehicleDiffer with defaults suited to a feed
+// polled every 10 to 30 seconds, ignoring moves of less than 5 meters.
+func NewVehicleDiffer() *VehicleDiffer {
+	return &VehicleDiffer{
+		StaleAfter:  DefaultVehicleStaleAfter,
+		RemoveAfter: DefaultVehicleRemoveAfter,
+		MinDistance: 5,
+	}
+}
+
+// reportTime returns when a vehicle in a response fetched at fetched last
+// reported.
+func reportTime(v VehicleLocation, fetched time.Time) time.Time {
+	secs, err := strconv.Atoi(v.SecsSinceReport)
+	if err != nil {
+		return fetched
+	}
+	return fetched.Add(-time.Duration(secs) * time.Second)
+}
+
+// Apply records the vehicles in resp, which was fetched at the given time, and
+// returns what changed since the previous call. Each list in the changeset is
+// ordered by vehicle ID.
+func (d *VehicleDiffer) Apply(resp *LocationResponse, fetched time.Time) VehicleChangeset {
+	staleAfter, removeAfter := d.StaleAfter, d.RemoveAfter
+	if staleAfter <= 0 {
+		staleAfter = DefaultVehicleStaleAfter
+	}
+	if removeAfter <= 0 {
+		removeAfter = DefaultVehicleRemoveAfter
+	}
+	if d.vehicles == nil {
+		d.vehicles = map[string]*trackedVehicle{}
+	}
+
+	var changes VehicleChangeset
+	seen := map[string]bool{}
+	for _, v := range resp.VehicleList {
+		seen[v.ID] = true
+		reported := reportTime(v, fetched)
+		tracked, known := d.vehicles[v.ID]
+		if !known {
+			d.vehicles[v.ID] = &trackedVehicle{v, reported, false}
+			changes.Added = append(changes.Added, v)
+			continue
+		}
+		if reported.Before(tracked.reported) {
+			continue
+		}
+		if move, moved := d.compare(tracked.location, v); moved {
+			changes.Moved = append(changes.Moved, move)
+		}
+		tracked.location = v
+		tracked.reported = reported
+		tracked.stale = false
+	}
+
+	for id, tracked := range d.vehicles {
+		if seen[id] {
+			continue
+		}
+		age := fetched.Sub(tracked.reported)
+		switch {
+		case age >= removeAfter:
+			delete(d.vehicles, id)
+			changes.Disappeared = append(changes.Disappeared, tracked.location)
+		case age >= staleAfter && !tracked.stale:
+			tracked.stale = true
+			changes.Stale = append(changes.Stale, tracked.location)
+		}
+	}
+
+	sortByID(changes.Added)
+	sortByID(changes.Stale)
+	sortByID(changes.Disappeared)
+	sort.Slice(changes.Moved, func(i, j int) bool { return changes.Moved[i].After.ID < changes.Moved[j].After.ID })
+	return changes
+}
+
+func (d *VehicleDiffer) compare(before, after VehicleLocation) (VehicleMove, bool) {
+	move := VehicleMove{Before: before, After: after}
+	lat1, lon1, ok1 := parseLatLon(before.Lat, before.Lon)
+	lat2, lon2, ok2 := parseLatLon(after.Lat, after.Lon)
+	if ok1 && ok2 {
+		move.Distance = haversine(lat1, lon1, lat2, lon2)
+	}
+	h1, err1 := strconv.ParseFloat(before.Heading, 64)
+	h2, err2 := strconv.ParseFloat(after.Heading, 64)
+	if err1 == nil && err2 == nil {
+		move.HeadingChange = math.Mod(h2-h1+540, 360) - 180
+	}
+	return move, move.Distance > 0 && move.Distance >= d.MinDistance || move.HeadingChange != 0
+}
+
+func sortByID(vehicles []VehicleLocation) {
+	sort.Slice(vehicles, func(i, j int) bool { return vehicles[i].ID < vehicles[j].ID })
+}
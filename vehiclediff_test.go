@@ -0,0 +1,56 @@
+package nextbus
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestVehicleDiffer(t *testing.T) {
+	d := NewVehicleDiffer()
+	start := time.Unix(1490564600, 0)
+
+	changes := d.Apply(&LocationResponse{VehicleList: []VehicleLocation{
+		{ID: "1", Lat: "37.7000", Lon: "-122.4000", Heading: "350", SecsSinceReport: "0"},
+		{ID: "2", Lat: "37.7100", Lon: "-122.4000", Heading: "90", SecsSinceReport: "0"},
+	}}, start)
+	equals(t, 2, len(changes.Added))
+	assert(t, !changes.Empty(), "expected changes")
+
+	changes = d.Apply(&LocationResponse{VehicleList: []VehicleLocation{
+		{ID: "1", Lat: "37.7010", Lon: "-122.4000", Heading: "10", SecsSinceReport: "5"},
+		{ID: "2", Lat: "37.7100", Lon: "-122.4000", Heading: "90", SecsSinceReport: "5"},
+	}}, start.Add(30*time.Second))
+	equals(t, 0, len(changes.Added))
+	equals(t, 1, len(changes.Moved))
+	move := changes.Moved[0]
+	equals(t, "1", move.After.ID)
+	equals(t, 20.0, move.HeadingChange)
+	assert(t, math.Abs(move.Distance-111.2) < 1, "unexpected distance %v", move.Distance)
+
+	changes = d.Apply(&LocationResponse{VehicleList: []VehicleLocation{
+		{ID: "1", Lat: "37.7010", Lon: "-122.4000", Heading: "10", SecsSinceReport: "0"},
+	}}, start.Add(3*time.Minute))
+	assert(t, changes.Empty() == false, "expected vehicle 2 to become stale")
+	equals(t, []VehicleLocation{{ID: "2", Lat: "37.7100", Lon: "-122.4000", Heading: "90", SecsSinceReport: "5"}}, changes.Stale)
+
+	changes = d.Apply(&LocationResponse{}, start.Add(4*time.Minute))
+	assert(t, changes.Empty(), "did not expect vehicle 2 to become stale twice: %+v", changes)
+
+	changes = d.Apply(&LocationResponse{}, start.Add(6*time.Minute))
+	equals(t, 1, len(changes.Disappeared))
+	equals(t, "2", changes.Disappeared[0].ID)
+}
+
+func TestVehicleDifferZeroValue(t *testing.T) {
+	var d VehicleDiffer
+	start := time.Unix(1490564600, 0)
+	vehicle := VehicleLocation{ID: "1", Lat: "37.7000", Lon: "-122.4000", Heading: "90", SecsSinceReport: "0"}
+	equals(t, 1, len(d.Apply(&LocationResponse{VehicleList: []VehicleLocation{vehicle}}, start).Added))
+
+	changes := d.Apply(&LocationResponse{VehicleList: []VehicleLocation{vehicle}}, start.Add(30*time.Second))
+	assert(t, changes.Empty(), "did not expect a vehicle that stayed put to have moved: %+v", changes)
+	changes = d.Apply(&LocationResponse{}, start.Add(time.Minute))
+	assert(t, changes.Empty(), "did not expect a vehicle to go stale before DefaultVehicleStaleAfter: %+v", changes)
+	equals(t, 1, len(d.Apply(&LocationResponse{}, start.Add(30*time.Second+DefaultVehicleRemoveAfter)).Disappeared))
+}
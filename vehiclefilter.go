@@ -0,0 +1,59 @@
+package nextbus
+
+import (
+	"strconv"
+)
+
+// Filter returns a copy of the response with only the vehicles keep returns
+// true for.
+func (r *LocationResponse) Filter(keep func(v VehicleLocation) bool) *LocationResponse {
+	result := &LocationResponse{XMLName: r.XMLName, LastTime: r.LastTime, VehicleList: []VehicleLocation{}}
+	for _, v := range r.VehicleList {
+		if keep(v) {
+			result.VehicleList = append(result.VehicleList, v)
+		}
+	}
+	return result
+}
+
+// ByRoute returns a copy of the response with only the vehicles on one of the
+// given routes.
+func (r *LocationResponse) ByRoute(routeTags ...string) *LocationResponse {
+	return r.Filter(func(v VehicleLocation) bool { return contains(routeTags, v.RouteTag) })
+}
+
+// ByDirection returns a copy of the response with only the vehicles traveling
+// in one of the given directions.
+func (r *LocationResponse) ByDirection(dirTags ...string) *LocationResponse {
+	return r.Filter(func(v VehicleLocation) bool { return contains(dirTags, v.DirTag) })
+}
+
+// Predictable returns a copy of the response without the vehicles NextBus
+// reports as not predictable.
+func (r *LocationResponse) Predictable() *LocationResponse {
+	return r.Filter(func(v VehicleLocation) bool { return v.Predictable != "false" })
+}
+
+// GetVehicleLocationsForRoutes fetches the vehicle locations of several routes
+// of an agency, one request per route since the feed accepts a single route,
+// and merges them into one response. Its LastTime is the earliest of the
+// responses', so that passing it back as a VehicleLocationTime misses no
+// reports on any route. configParams apply to every request and should not
+// include a VehicleLocationRoute.
+func (c *Client) GetVehicleLocationsForRoutes(agencyTag string, routeTags []string, configParams ...VehicleLocationParam) (*LocationResponse, error) {
+	merged := &LocationResponse{VehicleList: []VehicleLocation{}}
+	earliest := int64(-1)
+	for _, tag := range routeTags {
+		resp, err := c.GetVehicleLocations(agencyTag, append([]VehicleLocationParam{VehicleLocationRoute(tag)}, configParams...)...)
+		if err != nil {
+			return nil, err
+		}
+		merged.XMLName = resp.XMLName
+		merged.VehicleList = append(merged.VehicleList, resp.VehicleList...)
+		if t, parseErr := strconv.ParseInt(resp.LastTime.Time, 10, 64); parseErr == nil && (earliest < 0 || t < earliest) {
+			earliest = t
+			merged.LastTime = resp.LastTime
+		}
+	}
+	return merged, nil
+}
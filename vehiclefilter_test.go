@@ -0,0 +1,48 @@
+package nextbus
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLocationResponseFilters(t *testing.T) {
+	resp := &LocationResponse{
+		LastTime: LocationLastTime{Time: "1000"},
+		VehicleList: []VehicleLocation{
+			{ID: "1", RouteTag: "N", DirTag: "N_in", Predictable: "true"},
+			{ID: "2", RouteTag: "N", DirTag: "N_out", Predictable: "false"},
+			{ID: "3", RouteTag: "J", DirTag: "J_in", Predictable: "true"},
+		},
+	}
+
+	ids := func(r *LocationResponse) []string {
+		var result []string
+		for _, v := range r.VehicleList {
+			result = append(result, v.ID)
+		}
+		return result
+	}
+	equals(t, []string{"1", "2"}, ids(resp.ByRoute("N")))
+	equals(t, []string{"1", "3"}, ids(resp.ByDirection("N_in", "J_in")))
+	equals(t, []string{"1"}, ids(resp.ByRoute("N").Predictable()))
+	equals(t, "1000", resp.ByRoute("K").LastTime.Time)
+	equals(t, 3, len(resp.VehicleList))
+}
+
+func TestGetVehicleLocationsForRoutes(t *testing.T) {
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		r := req.URL.Query().Get("r")
+		lastTime := map[string]string{"N": "2000", "J": "1500"}[r]
+		res := statusResponse(req, http.StatusOK)
+		res.Body = ioutil.NopCloser(strings.NewReader(`<body><vehicle id="` + r + `1" routeTag="` + r + `"/><lastTime time="` + lastTime + `"/></body>`))
+		return res, nil
+	})}
+
+	resp, err := NewClient(httpClient).GetVehicleLocationsForRoutes("sf-muni", []string{"N", "J"})
+	ok(t, err)
+	equals(t, 2, len(resp.VehicleList))
+	equals(t, "J1", resp.VehicleList[1].ID)
+	equals(t, "1500", resp.LastTime.Time)
+}
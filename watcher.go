@@ -0,0 +1,206 @@
+package nextbus
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PredictionWatcher keeps the latest predictions for a set of stops, fetching
+// them periodically with Run.
+type PredictionWatcher struct {
+	client    *Client
+	agencyTag string
+	stops     []RouteStop
+
+	// OnUpdate, if set, is called after each successful fetch.
+	OnUpdate func(predictions []PredictionData)
+	// OnError, if set, is called with any error from a fetch made by Run.
+	OnError func(error)
+	// Bus, if set, receives TopicPredictions, TopicMessages and TopicAlerts
+	// events.
+	Bus *Bus
+	// AlertWithin, if set, raises an EventArrivalAlert on TopicAlerts when a
+	// vehicle is first predicted to reach a watched stop within it.
+	AlertWithin time.Duration
+	// Smoother, if set, stabilizes the countdowns of each poll before they
+	// are stored, passed to OnUpdate or published.
+	Smoother *Smoother
+	// OnChange, if set, is called with the changes DiffPredictions finds
+	// between each poll and the previous one, when there are any.
+	OnChange func(changes []PredictionChange)
+	// SlipThreshold is the slip reported as an ArrivalSlipped change. Zero
+	// uses DefaultSlipThreshold.
+	SlipThreshold time.Duration
+	// Dedup, if set, suppresses service messages and changes that repeat
+	// those reported within its window, such as a message that disappears
+	// from one poll and returns in the next.
+	Dedup *Deduplicator
+
+	mu          sync.RWMutex
+	predictions []PredictionData
+	updated     time.Time
+	messages    map[string]bool
+	alerted     map[string]bool
+}
+
+// NewPredictionWatcher creates a watcher for the given stops of an agency.
+func NewPredictionWatcher(client *Client, agencyTag string, stops ...RouteStop) *PredictionWatcher {
+	return &PredictionWatcher{client: client, agencyTag: agencyTag, stops: stops}
+}
+
+// AgencyTag returns the agency being watched.
+func (w *PredictionWatcher) AgencyTag() string {
+	return w.agencyTag
+}
+
+// Stops returns the stops being watched.
+func (w *PredictionWatcher) Stops() []RouteStop {
+	return append([]RouteStop(nil), w.stops...)
+}
+
+// Poll fetches predictions for every watched stop once.
+func (w *PredictionWatcher) Poll() error {
+	return w.PollContext(context.Background())
+}
+
+// PollContext is like Poll but uses ctx for the requests.
+func (w *PredictionWatcher) PollContext(ctx context.Context) error {
+	var all []PredictionData
+	for start := 0; start < len(w.stops); start += maxStopsPerRequest {
+		end := start + maxStopsPerRequest
+		if end > len(w.stops) {
+			end = len(w.stops)
+		}
+		params := make([]PredReqParam, 0, end-start)
+		for _, rs := range w.stops[start:end] {
+			params = append(params, PredReqStop(rs.RouteTag, rs.StopTag))
+		}
+		predictions, err := w.client.GetPredictionsForMultiStopsContext(ctx, w.agencyTag, params...)
+		if err != nil {
+			return err
+		}
+		all = append(all, predictions...)
+	}
+	if w.Smoother != nil {
+		all = w.Smoother.Smooth(w.client.now(), all)
+	}
+
+	now := w.client.now()
+	w.mu.Lock()
+	var changes []PredictionChange
+	if !w.updated.IsZero() && (w.OnChange != nil || w.Bus != nil) {
+		slip := w.SlipThreshold
+		if slip <= 0 {
+			slip = DefaultSlipThreshold
+		}
+		changes = DiffPredictions(w.predictions, all, now, slip)
+	}
+	w.predictions = all
+	w.updated = now
+	seen := w.messages
+	w.messages = map[string]bool{}
+	var fresh []Event
+	for _, pd := range all {
+		for _, m := range pd.MessageList {
+			key := pd.RouteTag + "|" + pd.StopTag + "|" + m.Text
+			if !w.messages[key] && !seen[key] {
+				fresh = append(fresh, ServiceMessageEvent(w.agencyTag, pd, m, now))
+			}
+			w.messages[key] = true
+		}
+	}
+	fresh = append(fresh, w.alerts(now, all)...)
+	w.mu.Unlock()
+	if w.Dedup != nil {
+		changes = w.Dedup.Changes(now, changes)
+		allowed := fresh[:0]
+		for _, e := range fresh {
+			if w.Dedup.AllowEvent(e) {
+				allowed = append(allowed, e)
+			}
+		}
+		fresh = allowed
+	}
+	if w.OnUpdate != nil {
+		w.OnUpdate(all)
+	}
+	if len(changes) != 0 && w.OnChange != nil {
+		w.OnChange(changes)
+	}
+	w.Bus.Publish(BusEvent{Topic: TopicPredictions, Time: now, Payload: all})
+	if len(changes) != 0 {
+		w.Bus.Publish(BusEvent{Topic: TopicPredictionChanges, Time: now, Payload: changes})
+	}
+	for _, e := range fresh {
+		topic := TopicMessages
+		if e.Type == EventArrivalAlert {
+			topic = TopicAlerts
+		}
+		w.Bus.Publish(BusEvent{Topic: topic, Time: now, Payload: e})
+	}
+	return nil
+}
+
+// alerts returns the arrival alerts for the vehicles in predictions that
+// arrive within AlertWithin of now and didn't in the previous poll. w.mu must
+// be held.
+func (w *PredictionWatcher) alerts(now time.Time, predictions []PredictionData) []Event {
+	if w.AlertWithin <= 0 {
+		return nil
+	}
+	seen := w.alerted
+	w.alerted = map[string]bool{}
+	var alerts []Event
+	for _, pd := range predictions {
+		for _, dir := range pd.PredictionDirectionList {
+			for _, p := range dir.PredictionList {
+				arrival := p.ArrivalTime()
+				if arrival.IsZero() || arrival.Sub(now) > w.AlertWithin {
+					continue
+				}
+				key := pd.RouteTag + "|" + pd.StopTag + "|" + p.Vehicle + "|" + p.TripTag
+				if !w.alerted[key] && !seen[key] {
+					alerts = append(alerts, ArrivalAlertEvent(w.agencyTag, pd, p, now))
+				}
+				w.alerted[key] = true
+			}
+		}
+	}
+	return alerts
+}
+
+// Predictions returns the latest predictions and when they were fetched. The
+// time is zero if no fetch has succeeded yet.
+func (w *PredictionWatcher) Predictions() ([]PredictionData, time.Time) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.predictions, w.updated
+}
+
+// Run polls immediately and then every interval until ctx is done.
+func (w *PredictionWatcher) Run(ctx context.Context, interval time.Duration) {
+	w.RunStrategy(ctx, FixedInterval(interval))
+}
+
+// RunStrategy polls immediately and then after each interval chosen by
+// strategy until ctx is done. It waits at least MinPollInterval between polls.
+func (w *PredictionWatcher) RunStrategy(ctx context.Context, strategy PollStrategy) {
+	for {
+		if err := w.PollContext(ctx); err != nil && w.OnError != nil {
+			w.OnError(err)
+		}
+		latest, _ := w.Predictions()
+		interval := strategy.Interval(w.client.now(), latest)
+		if interval < MinPollInterval {
+			interval = MinPollInterval
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package nextbus
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPredictionWatcherPoll(t *testing.T) {
+	w := NewPredictionWatcher(NewClient(testingClient(t)), "alpha", RouteStop{"1", "1123"}, RouteStop{"1", "1124"})
+	var updates int
+	w.OnUpdate = func([]PredictionData) { updates++ }
+
+	_, updated := w.Predictions()
+	assert(t, updated.IsZero(), "expected no update before polling")
+	ok(t, w.Poll())
+	predictions, updated := w.Predictions()
+	equals(t, 2, len(predictions))
+	equals(t, 1, updates)
+	assert(t, !updated.IsZero(), "expected an update time after polling")
+}
+
+// hangingClient returns a client whose requests signal started and then wait
+// until they are canceled.
+func hangingClient(started chan<- struct{}) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		started <- struct{}{}
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})}
+}
+
+func TestPredictionWatcherStopCancelsPoll(t *testing.T) {
+	started := make(chan struct{}, 1)
+	w := NewPredictionWatcher(NewClient(hangingClient(started)), "alpha", RouteStop{"1", "1123"})
+	s := w.Service(FixedInterval(time.Hour))
+	ok(t, s.Start(context.Background()))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ok(t, s.Stop(ctx))
+}
+
+func TestPredictionWatcherAlerts(t *testing.T) {
+	now := time.Unix(1487246400, 0)
+	feed := cannedFeed(`<body><predictions routeTag="N" stopTag="5205"><direction title="Inbound">` +
+		`<prediction epochTime="1487246580000" minutes="3" vehicle="1500" tripTag="1"/>` +
+		`<prediction epochTime="1487247000000" minutes="10" vehicle="1501" tripTag="2"/></direction></predictions></body>`)
+	w := NewPredictionWatcher(NewClient(feed.Client(), WithClock(fixedClock(&now))), "sf-muni", RouteStop{"N", "5205"})
+	w.AlertWithin = 5 * time.Minute
+	w.Bus = &Bus{}
+	var alerts []Event
+	w.Bus.Subscribe(func(e BusEvent) { alerts = append(alerts, e.Payload.(Event)) }, TopicAlerts)
+
+	ok(t, w.Poll())
+	equals(t, 1, len(alerts))
+	equals(t, EventArrivalAlert, alerts[0].Type)
+	equals(t, "1500", alerts[0].Vehicle)
+	equals(t, now, alerts[0].Time)
+
+	// Vehicle 1500 was already alerted; 1501 is now within five minutes.
+	now = now.Add(6 * time.Minute)
+	ok(t, w.Poll())
+	equals(t, 2, len(alerts))
+	equals(t, "1501", alerts[1].Vehicle)
+}
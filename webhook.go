@@ -0,0 +1,205 @@
+package nextbus
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+// The set of events that can be delivered to a Notifier.
+const (
+	EventArrivalAlert   EventType = "arrival_alert"
+	EventServiceMessage EventType = "service_message"
+)
+
+// Event is something that happened in a transit feed that a user may want to
+// be told about, such as a vehicle about to arrive at a watched stop or a new
+// informational message from the agency.
+type Event struct {
+	Type        EventType `json:"type"`
+	Time        time.Time `json:"time"`
+	AgencyTag   string    `json:"agency"`
+	RouteTag    string    `json:"route,omitempty"`
+	RouteTitle  string    `json:"routeTitle,omitempty"`
+	StopTag     string    `json:"stop,omitempty"`
+	StopTitle   string    `json:"stopTitle,omitempty"`
+	Vehicle     string    `json:"vehicle,omitempty"`
+	TripTag     string    `json:"trip,omitempty"`
+	DirTag      string    `json:"direction,omitempty"`
+	EpochTime   string    `json:"epochTime,omitempty"`
+	Minutes     string    `json:"minutes,omitempty"`
+	MessageText string    `json:"message,omitempty"`
+	Priority    string    `json:"priority,omitempty"`
+}
+
+// ArrivalAlertEvent creates an EventArrivalAlert, raised at the time at, for
+// the prediction p, which belongs to the prediction data pd.
+func ArrivalAlertEvent(agencyTag string, pd PredictionData, p Prediction, at time.Time) Event {
+	return Event{
+		Type:       EventArrivalAlert,
+		Time:       at,
+		AgencyTag:  agencyTag,
+		RouteTag:   pd.RouteTag,
+		RouteTitle: pd.RouteTitle,
+		StopTag:    pd.StopTag,
+		StopTitle:  pd.StopTitle,
+		Vehicle:    p.Vehicle,
+		TripTag:    p.TripTag,
+		DirTag:     p.DirTag,
+		EpochTime:  p.EpochTime,
+		Minutes:    p.Minutes,
+	}
+}
+
+// ServiceMessageEvent creates an EventServiceMessage, raised at the time at,
+// for the message m, which was attached to the prediction data pd.
+func ServiceMessageEvent(agencyTag string, pd PredictionData, m Message, at time.Time) Event {
+	return Event{
+		Type:        EventServiceMessage,
+		Time:        at,
+		AgencyTag:   agencyTag,
+		RouteTag:    pd.RouteTag,
+		RouteTitle:  pd.RouteTitle,
+		StopTag:     pd.StopTag,
+		StopTitle:   pd.StopTitle,
+		MessageText: m.Text,
+		Priority:    m.Priority,
+	}
+}
+
+// Notifier is anything that can be told about an Event.
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// SignatureHeader is the HTTP header carrying the HMAC-SHA256 signature of a
+// webhook payload, formatted as "sha256=<hex digest>".
+const SignatureHeader = "X-Nextbus-Signature"
+
+// WebhookNotifier is a Notifier that POSTs each Event as JSON to a set of
+// webhook URLs.
+type WebhookNotifier struct {
+	httpClient *http.Client
+	urls       []string
+
+	// Secret, when set, is used to sign each payload with HMAC-SHA256. The
+	// signature is sent in the SignatureHeader header.
+	Secret []byte
+
+	// MaxRetries is the number of additional attempts made for a URL after a
+	// failed delivery. Only network errors and 429 or 5xx responses are retried.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry. It doubles after each
+	// subsequent attempt.
+	RetryBackoff time.Duration
+}
+
+// DefaultWebhookTimeout bounds each delivery made by a WebhookNotifier
+// created without an HTTP client.
+const DefaultWebhookTimeout = 10 * time.Second
+
+// NewWebhookNotifier creates a WebhookNotifier that delivers to urls. A nil
+// httpClient uses a client with DefaultWebhookTimeout.
+func NewWebhookNotifier(httpClient *http.Client, urls ...string) *WebhookNotifier {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	return &WebhookNotifier{
+		httpClient:   httpClient,
+		urls:         urls,
+		MaxRetries:   3,
+		RetryBackoff: time.Second,
+	}
+}
+
+// Sign returns the value of the SignatureHeader for payload using secret.
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify delivers e to every configured URL. Delivery to each URL is attempted
+// even if an earlier one fails; the returned error describes all failures.
+func (w *WebhookNotifier) Notify(ctx context.Context, e Event) error {
+	payload, jsonErr := json.Marshal(e)
+	if jsonErr != nil {
+		return fmt.Errorf("could not encode webhook payload: %v", jsonErr)
+	}
+
+	var failures []string
+	for _, u := range w.urls {
+		if err := w.deliver(ctx, u, payload); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) != 0 {
+		return fmt.Errorf("could not deliver webhook: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (w *WebhookNotifier) deliver(ctx context.Context, u string, payload []byte) error {
+	backoff := w.RetryBackoff
+	var lastErr error
+	for attempt := 0; attempt <= w.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%s: %v", u, ctx.Err())
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		retry, err := w.post(ctx, u, payload)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// post makes a single delivery attempt, reporting whether a failure is worth
+// retrying.
+func (w *WebhookNotifier) post(ctx context.Context, u string, payload []byte) (bool, error) {
+	req, reqErr := http.NewRequest(http.MethodPost, u, bytes.NewReader(payload))
+	if reqErr != nil {
+		return false, fmt.Errorf("%s: %v", u, reqErr)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.Secret) != 0 {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, payload))
+	}
+
+	resp, httpErr := w.httpClient.Do(req)
+	if httpErr != nil {
+		return ctx.Err() == nil, fmt.Errorf("%s: %v", u, httpErr)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("%s: unexpected status %s", u, resp.Status)
+}
@@ -0,0 +1,86 @@
+package nextbus
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func statusResponse(req *http.Request, status int) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+}
+
+func TestWebhookNotifierSignsPayload(t *testing.T) {
+	var got []*http.Request
+	var bodies [][]byte
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(req.Body)
+		got = append(got, req)
+		bodies = append(bodies, body)
+		return statusResponse(req, http.StatusOK), nil
+	})}
+
+	w := NewWebhookNotifier(httpClient, "http://hooks.example/a", "http://hooks.example/b")
+	w.Secret = []byte("s3cret")
+	pd := PredictionData{RouteTag: "N", StopTag: "5205"}
+	p := Prediction{Vehicle: "1234", Minutes: "3"}
+	ok(t, w.Notify(context.Background(), ArrivalAlertEvent("sf-muni", pd, p, time.Now())))
+
+	equals(t, 2, len(got))
+	equals(t, "http://hooks.example/b", got[1].URL.String())
+	equals(t, Sign([]byte("s3cret"), bodies[0]), got[0].Header.Get(SignatureHeader))
+
+	var e Event
+	ok(t, json.Unmarshal(bodies[0], &e))
+	equals(t, EventArrivalAlert, e.Type)
+	equals(t, "1234", e.Vehicle)
+	equals(t, "5205", e.StopTag)
+}
+
+func TestWebhookNotifierRetries(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusNoContent}
+	attempts := 0
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		status := statuses[attempts]
+		attempts++
+		return statusResponse(req, status), nil
+	})}
+
+	w := NewWebhookNotifier(httpClient, "http://hooks.example/a")
+	w.RetryBackoff = 0
+	ok(t, w.Notify(context.Background(), ServiceMessageEvent("sf-muni", PredictionData{}, Message{Text: "hi"}, time.Now())))
+	equals(t, 3, attempts)
+}
+
+func TestWebhookNotifierDoesNotRetryClientErrors(t *testing.T) {
+	attempts := 0
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return statusResponse(req, http.StatusBadRequest), nil
+	})}
+
+	w := NewWebhookNotifier(httpClient, "http://hooks.example/a")
+	w.RetryBackoff = 0
+	err := w.Notify(context.Background(), ServiceMessageEvent("sf-muni", PredictionData{}, Message{Text: "hi"}, time.Now()))
+	assert(t, err != nil, "expected an error for a 400 response")
+	equals(t, 1, attempts)
+}
+
+func TestWebhookNotifierDefaultClient(t *testing.T) {
+	equals(t, DefaultWebhookTimeout, NewWebhookNotifier(nil, "http://hooks.example/a").httpClient.Timeout)
+}